@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/superclaude"
+	"github.com/spf13/cobra"
+)
+
+var completeCmd = &cobra.Command{
+	Use:   "complete <input>",
+	Short: "Complete a SuperClaude command line",
+	Long: `Complete prints the completions for the word at the cursor of a SuperClaude
+command line: commands, personas, the flags of the command with their allowed
+values, and files of the working directory for the target. It uses the same
+engine as the terminal UI and the MCP complete method, including user-defined
+commands and personas, so editor integrations can offer the same suggestions.
+
+The cursor is a byte offset into the input and defaults to its end. Each
+completion is printed as its value and description separated by a tab, or as a
+JSON array with --json.`,
+	Example: `
+  # Complete a command name
+  opencode complete "/user:an"
+
+  # Complete the value of --depth, as JSON
+  opencode complete --json "/user:explain --depth "
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, _ := cmd.Flags().GetString("cwd")
+		cursor, _ := cmd.Flags().GetInt("cursor")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		if cwd != "" {
+			if err := os.Chdir(cwd); err != nil {
+				return fmt.Errorf("failed to change directory: %v", err)
+			}
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+
+		input := args[0]
+		if cursor < 0 {
+			cursor = len(input)
+		}
+		if cursor > len(input) {
+			return fmt.Errorf("cursor %d is outside the input", cursor)
+		}
+
+		// Complete user-defined personas and commands like the terminal UI does
+		allowCustom := true
+		if scConfig, err := config.LoadConfig(""); err == nil {
+			allowCustom = scConfig.SuperClaude.Personas.AllowCustom
+		}
+		if err := superclaude.NewPersonaLoader(allowCustom, superclaude.PersonaDirs(cwd)...).Load(); err != nil {
+			logging.Warn("Some personas failed to load", "error", err)
+		}
+		if err := superclaude.NewCommandLoader(superclaude.CommandDirs(cwd)...).Load(); err != nil {
+			logging.Warn("Some SuperClaude commands failed to load", "error", err)
+		}
+
+		completions := superclaude.Complete(input, cursor, cwd)
+		if asJSON {
+			if completions == nil {
+				completions = []superclaude.Completion{}
+			}
+			return json.NewEncoder(cmd.OutOrStdout()).Encode(completions)
+		}
+		for _, completion := range completions {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", completion.Value, completion.Description)
+		}
+		return nil
+	},
+}
+
+func init() {
+	completeCmd.Flags().StringP("cwd", "c", "", "Current working directory")
+	completeCmd.Flags().Int("cursor", -1, "Byte offset of the cursor in the input, defaults to its end")
+	completeCmd.Flags().Bool("json", false, "Print the completions as JSON, with their kind and replacement range")
+
+	rootCmd.AddCommand(completeCmd)
+}
@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/tenant"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,6 +27,7 @@ var (
 	tenantID      string
 	validateOnly  bool
 	encryptionKey string
+	auditLogPath  string
 )
 
 func main() {
@@ -42,6 +46,7 @@ Supports validation, encryption, multi-tenancy, migrations, and more.`,
 	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", "yaml", "Output format (yaml, json)")
 	rootCmd.PersistentFlags().StringVar(&tenantID, "tenant", "", "Tenant ID for multi-tenant operations")
 	rootCmd.PersistentFlags().StringVar(&encryptionKey, "encryption-key", "", "Encryption key for sensitive data")
+	rootCmd.PersistentFlags().StringVar(&auditLogPath, "audit-log", config.DefaultAuditLogPath(), "Configuration audit log")
 
 	// Add subcommands
 	rootCmd.AddCommand(
@@ -234,37 +239,76 @@ func migrateCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "migrate",
 		Short: "Configuration migration tools",
+		Long: `Migrate configuration files between schema versions.
+
+Each file records its schema version in schema_version; files without it were
+written for version 1. Files are migrated in place with their comments, and the
+original is kept next to it as a timestamped .bak file. Without arguments the
+configuration file found for --config is migrated.`,
 	}
 
+	var target int
 	upCmd := &cobra.Command{
-		Use:   "up",
+		Use:   "up [config-file...]",
 		Short: "Apply configuration migrations",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Implementation for migration up
-			fmt.Println("Applying configuration migrations...")
-			return nil
+			migrator := config.NewConfigMigrator()
+			to := migrator.Latest()
+			if target > 0 {
+				to = target
+			}
+			return migrateFiles(migrator, args, func(*config.MigrationStatus) int { return to })
 		},
 	}
+	upCmd.Flags().IntVar(&target, "to", 0, "Schema version to migrate to, defaults to the latest")
 
 	downCmd := &cobra.Command{
-		Use:   "down",
+		Use:   "down [config-file...]",
 		Short: "Rollback configuration migrations",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Implementation for migration down
-			fmt.Println("Rolling back configuration migrations...")
-			return nil
+			migrator := config.NewConfigMigrator()
+			return migrateFiles(migrator, args, func(status *config.MigrationStatus) int {
+				if target > 0 {
+					return target
+				}
+				return max(1, status.Version-1)
+			})
 		},
 	}
+	downCmd.Flags().IntVar(&target, "to", 0, "Schema version to roll back to, defaults to the previous one")
 
 	statusCmd := &cobra.Command{
-		Use:   "status",
+		Use:   "status [config-file...]",
 		Short: "Show migration status",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Implementation for migration status
-			fmt.Println("Migration Status:")
-			fmt.Println("  Version: 1.0.0")
-			fmt.Println("  Pending: 0")
-			fmt.Println("  Applied: 3")
+			paths, err := migrationPaths(args)
+			if err != nil {
+				return err
+			}
+
+			migrator := config.NewConfigMigrator()
+			var statuses []*config.MigrationStatus
+			for _, path := range paths {
+				status, err := migrator.Status(path)
+				if err != nil {
+					return err
+				}
+				statuses = append(statuses, status)
+			}
+
+			if outputFormat == "json" {
+				return json.NewEncoder(os.Stdout).Encode(statuses)
+			}
+
+			for _, status := range statuses {
+				fmt.Printf("%s\n", status.Path)
+				fmt.Printf("  Version: %d (latest %d)\n", status.Version, status.Latest)
+				fmt.Printf("  Applied: %d\n", len(status.Applied))
+				fmt.Printf("  Pending: %d\n", len(status.Pending))
+				for _, migration := range status.Pending {
+					fmt.Printf("    %d  %s\n", migration.Version, migration.Description)
+				}
+			}
 			return nil
 		},
 	}
@@ -273,11 +317,72 @@ func migrateCommand() *cobra.Command {
 	return cmd
 }
 
+// migrationPaths returns the files named on the command line, or the config file
+func migrationPaths(args []string) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+	path, err := config.FindConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return []string{path}, nil
+}
+
+// migrateFiles migrates each file to the version target picks for it
+func migrateFiles(migrator *config.ConfigMigrator, args []string, target func(*config.MigrationStatus) int) error {
+	paths, err := migrationPaths(args)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		status, err := migrator.Status(path)
+		if err != nil {
+			return err
+		}
+		result, err := migrator.MigrateFile(path, target(status))
+		if err != nil {
+			return err
+		}
+		if len(result.Applied) == 0 {
+			fmt.Printf("%s is at schema version %d, nothing to do\n", path, result.From)
+			continue
+		}
+
+		fmt.Printf("%s: schema version %d → %d\n", path, result.From, result.To)
+		for _, migration := range result.Applied {
+			fmt.Printf("  %d  %s\n", migration.Version, migration.Description)
+		}
+		fmt.Printf("  Backup: %s\n", result.Backup)
+		auditMigration(result)
+	}
+	return nil
+}
+
+// auditMigration records a migrated file in the audit log
+func auditMigration(result *config.MigrationResult) {
+	target, err := filepath.Abs(result.Path)
+	if err != nil {
+		target = result.Path
+	}
+	auditLog, err := config.OpenAuditLog(auditLogPath, 0)
+	if err == nil {
+		change := config.NewFieldChange("schema_version", result.From, result.To)
+		_, err = auditLog.Record(config.AuditActor(), config.AuditSourceCLI, target, "", []config.FieldChange{change})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record the migration in the audit log: %v\n", err)
+	}
+}
+
 // Multi-tenant management
 func tenantCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "tenant",
 		Short: "Multi-tenant configuration management",
+		Long: `Manage the tenants stored in the opencode database of the working directory.
+Running servers pick up changes within 30 seconds.`,
 	}
 
 	createCmd := &cobra.Command{
@@ -285,17 +390,16 @@ func tenantCommand() *cobra.Command {
 		Short: "Create a new tenant",
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Load global config
-			globalConfig, err := config.LoadConfig(configPath)
+			tenants, closeDB, err := openTenants()
 			if err != nil {
 				return err
 			}
+			defer closeDB()
 
-			// Create multi-tenant manager
-			mtcm := config.NewMultiTenantConfigManager(globalConfig, config.IsolationShared)
-
-			// Create tenant with default quotas and features
-			tenant, err := mtcm.CreateTenant(args[0], args[1], nil, nil)
+			// Create tenant with default quotas, changed by the quota flags, and default features
+			quotas := config.DefaultTenantQuotas()
+			setQuotaFlags(cmd, quotas)
+			tenant, err := tenants.Manager().CreateTenant(args[0], args[1], quotas, nil)
 			if err != nil {
 				return err
 			}
@@ -304,16 +408,66 @@ func tenantCommand() *cobra.Command {
 			return nil
 		},
 	}
+	addQuotaFlags(createCmd)
+
+	quotasCmd := &cobra.Command{
+		Use:   "quotas [tenant-id]",
+		Short: "Change the quotas of a tenant",
+		Long:  "Change the quotas given as flags, keeping the others. A quota of 0 is unlimited.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tenants, closeDB, err := openTenants()
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			tenant, err := tenants.Manager().GetTenant(args[0])
+			if err != nil {
+				return err
+			}
+			quotas := config.DefaultTenantQuotas()
+			if tenant.Quotas != nil {
+				*quotas = *tenant.Quotas
+			}
+			setQuotaFlags(cmd, quotas)
+			if err := tenants.Manager().SetTenantQuotas(args[0], quotas); err != nil {
+				return err
+			}
+
+			fmt.Printf("Updated quotas of tenant: %s\n", args[0])
+			return nil
+		},
+	}
+	addQuotaFlags(quotasCmd)
 
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all tenants",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Implementation for listing tenants
+			tenants, closeDB, err := openTenants()
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			list := tenants.Manager().ListTenants()
+			sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+			if outputFormat == "json" {
+				views := make([]tenantView, 0, len(list))
+				for _, tenant := range list {
+					views = append(views, newTenantView(tenant))
+				}
+				return json.NewEncoder(os.Stdout).Encode(views)
+			}
+
 			fmt.Println("Tenants:")
-			fmt.Println("  default - Default Tenant (Active)")
-			fmt.Println("  acme-corp - ACME Corporation (Active)")
-			fmt.Println("  startup-inc - Startup Inc (Suspended)")
+			if len(list) == 0 {
+				fmt.Println("  No tenants, requests use the default tenant without quotas")
+			}
+			for _, tenant := range list {
+				fmt.Printf("  %s - %s (%s)\n", tenant.ID, tenant.Name, tenant.Status)
+			}
 			return nil
 		},
 	}
@@ -321,8 +475,18 @@ func tenantCommand() *cobra.Command {
 	deleteCmd := &cobra.Command{
 		Use:   "delete [tenant-id]",
 		Short: "Delete a tenant",
+		Long:  "Delete a tenant and its usage. Its sessions are kept.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			tenants, closeDB, err := openTenants()
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			if err := tenants.Manager().DeleteTenant(args[0]); err != nil {
+				return err
+			}
 			fmt.Printf("Deleted tenant: %s\n", args[0])
 			return nil
 		},
@@ -333,16 +497,241 @@ func tenantCommand() *cobra.Command {
 		Short: "Show tenant configuration",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Implementation for showing tenant config
-			fmt.Printf("Configuration for tenant: %s\n", args[0])
+			tenants, closeDB, err := openTenants()
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			tenant, err := tenants.Manager().GetTenant(args[0])
+			if err != nil {
+				return err
+			}
+			view := newTenantView(tenant)
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(view)
+			}
+			data, err := yaml.Marshal(view)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(data))
 			return nil
 		},
 	}
 
-	cmd.AddCommand(createCmd, listCmd, deleteCmd, configCmd)
+	usageCmd := &cobra.Command{
+		Use:   "usage [tenant-id]",
+		Short: "Show the usage of tenants against their quotas",
+		Long: `Show the sessions, tokens and storage of a tenant, or of every tenant, against
+their quotas, and the tokens and cost of every month. Requests per minute and
+concurrent requests are counted by each server and are not shown.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tenants, closeDB, err := openTenants()
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			ids := args
+			if len(ids) == 0 {
+				ids = []string{tenant.DefaultTenant}
+				for _, t := range tenants.Manager().ListTenants() {
+					if t.ID != tenant.DefaultTenant {
+						ids = append(ids, t.ID)
+					}
+				}
+				sort.Strings(ids[1:])
+			}
+
+			reports := make([]tenantUsageReport, 0, len(ids))
+			for _, id := range ids {
+				report, err := newTenantUsageReport(cmd.Context(), tenants, id)
+				if err != nil {
+					return err
+				}
+				reports = append(reports, report)
+			}
+
+			if outputFormat == "json" {
+				return json.NewEncoder(os.Stdout).Encode(reports)
+			}
+			for i, report := range reports {
+				if i > 0 {
+					fmt.Println()
+				}
+				printTenantUsageReport(report)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(createCmd, quotasCmd, listCmd, deleteCmd, configCmd, usageCmd)
 	return cmd
 }
 
+// openTenants loads the tenants stored in the opencode database of the working
+// directory, with the SuperClaude config as their global config
+func openTenants() (*tenant.Service, func(), error) {
+	globalConfig, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return nil, nil, err
+	}
+	conn, err := db.Connect()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mtcm := config.NewMultiTenantConfigManager(globalConfig, config.IsolationShared)
+	auditLog, err := config.OpenAuditLog(auditLogPath, 0)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	mtcm.SetAuditLog(auditLog)
+
+	tenants, err := tenant.NewService(db.New(conn), mtcm)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return tenants, func() { conn.Close() }, nil
+}
+
+func addQuotaFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("max-sessions", 0, "Sessions active within the session timeout")
+	cmd.Flags().Int("max-requests-per-minute", 0, "MCP requests and agent runs per minute")
+	cmd.Flags().Int64("max-tokens-per-month", 0, "Prompt and completion tokens per calendar month (UTC)")
+	cmd.Flags().Int("max-storage-mb", 0, "Megabytes of messages and file history")
+	cmd.Flags().Int("max-concurrent-requests", 0, "Agent runs at once")
+	cmd.Flags().Duration("session-timeout", 0, "How long a session stays active after it was last used")
+}
+
+// setQuotaFlags sets the quotas given as flags
+func setQuotaFlags(cmd *cobra.Command, quotas *config.TenantQuotas) {
+	flags := cmd.Flags()
+	if flags.Changed("max-sessions") {
+		quotas.MaxSessions, _ = flags.GetInt("max-sessions")
+	}
+	if flags.Changed("max-requests-per-minute") {
+		quotas.MaxRequestsPerMinute, _ = flags.GetInt("max-requests-per-minute")
+	}
+	if flags.Changed("max-tokens-per-month") {
+		quotas.MaxTokensPerMonth, _ = flags.GetInt64("max-tokens-per-month")
+	}
+	if flags.Changed("max-storage-mb") {
+		quotas.MaxStorageMB, _ = flags.GetInt("max-storage-mb")
+	}
+	if flags.Changed("max-concurrent-requests") {
+		quotas.MaxConcurrentRequests, _ = flags.GetInt("max-concurrent-requests")
+	}
+	if flags.Changed("session-timeout") {
+		quotas.SessionTimeout, _ = flags.GetDuration("session-timeout")
+	}
+}
+
+// tenantView is a tenant without its resolved configuration, which holds secrets
+type tenantView struct {
+	ID        string                 `json:"id" yaml:"id"`
+	Name      string                 `json:"name" yaml:"name"`
+	Status    string                 `json:"status" yaml:"status"`
+	Quotas    *config.TenantQuotas   `json:"quotas" yaml:"quotas"`
+	Features  *config.TenantFeatures `json:"features" yaml:"features"`
+	Overrides map[string]interface{} `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+	Metadata  map[string]string      `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at" yaml:"updated_at"`
+}
+
+func newTenantView(tenant *config.TenantConfig) tenantView {
+	return tenantView{
+		ID:        tenant.ID,
+		Name:      tenant.Name,
+		Status:    tenant.Status.String(),
+		Quotas:    tenant.Quotas,
+		Features:  tenant.Features,
+		Overrides: tenant.Overrides,
+		Metadata:  tenant.Metadata,
+		CreatedAt: tenant.CreatedAt,
+		UpdatedAt: tenant.UpdatedAt,
+	}
+}
+
+// tenantUsageReport is the usage of a tenant against its quotas
+type tenantUsageReport struct {
+	Tenant  string                `json:"tenant"`
+	Name    string                `json:"name,omitempty"`
+	Status  string                `json:"status"`
+	Usage   *config.TenantUsage   `json:"usage"`
+	Quotas  *config.TenantQuotas  `json:"quotas,omitempty"`
+	Monthly []tenant.MonthlyUsage `json:"monthly"`
+}
+
+func newTenantUsageReport(ctx context.Context, tenants *tenant.Service, tenantID string) (tenantUsageReport, error) {
+	report := tenantUsageReport{Tenant: tenantID, Status: config.TenantActive.String()}
+	if t, err := tenants.Manager().GetTenant(tenantID); err == nil {
+		report.Name = t.Name
+		report.Status = t.Status.String()
+		report.Quotas = t.Quotas
+	} else if tenantID != tenant.DefaultTenant {
+		return report, err
+	}
+
+	usage, err := tenants.Manager().GetTenantUsage(tenantID)
+	if err != nil {
+		return report, err
+	}
+	report.Usage = usage
+	report.Monthly, err = tenants.UsageHistory(ctx, tenantID)
+	return report, err
+}
+
+func printTenantUsageReport(report tenantUsageReport) {
+	title := report.Tenant
+	if report.Name != "" {
+		title += " - " + report.Name
+	}
+	fmt.Printf("%s (%s)\n", title, report.Status)
+
+	var quotas config.TenantQuotas
+	if report.Quotas != nil {
+		quotas = *report.Quotas
+	}
+	fmt.Printf("  Active sessions:   %d / %s\n", report.Usage.ActiveSessions, quotaLimit(int64(quotas.MaxSessions)))
+	fmt.Printf("  Tokens this month: %d / %s\n", report.Usage.TokensThisMonth, quotaLimit(quotas.MaxTokensPerMonth))
+	fmt.Printf("  Storage:           %d MB / %s\n", report.Usage.StorageUsedMB, quotaLimit(int64(quotas.MaxStorageMB)))
+	if !report.Usage.LastActivity.IsZero() {
+		fmt.Printf("  Last activity:     %s\n", report.Usage.LastActivity.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	if len(report.Monthly) == 0 {
+		fmt.Println("  No usage recorded")
+		return
+	}
+	fmt.Println("  Month     Prompt tokens  Completion tokens  Cost")
+	for _, month := range report.Monthly {
+		fmt.Printf("  %-8s  %13d  %17d  $%.4f\n", month.Period, month.PromptTokens, month.CompletionTokens, month.Cost)
+	}
+}
+
+// quotaLimit formats a quota, 0 is unlimited
+func quotaLimit(limit int64) string {
+	if limit <= 0 {
+		return "unlimited"
+	}
+	return strconv.FormatInt(limit, 10)
+}
+
 // Schema management
 func schemaCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -388,28 +777,109 @@ func auditCommand() *cobra.Command {
 		Use:   "history",
 		Short: "Show configuration change history",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			auditLog, err := config.OpenAuditLog(auditLogPath, 0)
+			if err != nil {
+				return err
+			}
+			limit, _ := cmd.Flags().GetInt("limit")
+			history, err := auditLog.History(limit)
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "json" {
+				return json.NewEncoder(os.Stdout).Encode(history)
+			}
+
 			fmt.Println("Configuration Change History:")
 			fmt.Println("=============================")
-			fmt.Println("2024-01-15 10:30:00 - admin - Updated server.port from 8080 to 9090")
-			fmt.Println("2024-01-14 15:45:00 - admin - Enabled TLS for production")
-			fmt.Println("2024-01-14 09:15:00 - system - Applied security hardening")
+			if len(history) == 0 {
+				fmt.Printf("No changes recorded in %s\n", auditLogPath)
+			}
+			for _, entry := range history {
+				fmt.Printf("%s  #%d  %s (%s)", entry.Timestamp.Local().Format("2006-01-02 15:04:05"), entry.Sequence, entry.Actor, entry.Source)
+				if entry.Target != "" {
+					fmt.Printf("  %s", entry.Target)
+				}
+				fmt.Println()
+				for _, change := range entry.Changes {
+					fmt.Printf("  %s: %s → %s\n", change.Path, change.Old, change.New)
+				}
+			}
 			return nil
 		},
 	}
+	historyCmd.Flags().Int("limit", 20, "Number of changes to show, 0 for all")
 
 	logCmd := &cobra.Command{
 		Use:   "log",
 		Short: "Show audit log",
+		Long:  "Print the audit log entries, one JSON object per line, with their hashes",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Println("Audit Log:")
-			fmt.Println("==========")
-			fmt.Printf("%s [INFO] Configuration loaded successfully\n", time.Now().Format(time.RFC3339))
-			fmt.Printf("%s [WARN] TLS not enabled in production\n", time.Now().Add(-time.Hour).Format(time.RFC3339))
+			auditLog, err := config.OpenAuditLog(auditLogPath, 0)
+			if err != nil {
+				return err
+			}
+			entries, err := auditLog.Entries()
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			for _, entry := range entries {
+				if err := encoder.Encode(entry); err != nil {
+					return err
+				}
+			}
 			return nil
 		},
 	}
 
-	cmd.AddCommand(historyCmd, logCmd)
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify that the audit log has not been tampered with",
+		Long: `Check that every audit log entry matches its hash and is chained to the entry
+before it. Compare the last hash with a copy kept elsewhere to also detect
+entries removed from the end of the log.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			auditLog, err := config.OpenAuditLog(auditLogPath, 0)
+			if err != nil {
+				return err
+			}
+			result, err := auditLog.Verify()
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "json" {
+				if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+					return err
+				}
+			} else if result.Valid {
+				fmt.Printf("✅ %s: %d entries intact", result.Path, result.Entries)
+				if result.Entries > 0 {
+					fmt.Printf(" (%d-%d)", result.FirstSequence, result.LastSequence)
+				}
+				if result.PrunedThrough > 0 {
+					fmt.Printf(", entries up to %d pruned", result.PrunedThrough)
+				}
+				if result.LastHash != "" {
+					fmt.Printf("\nLast hash: %s", result.LastHash)
+				}
+				fmt.Println()
+			} else {
+				fmt.Printf("❌ %s: line %d: %s\n", result.Path, result.Line, result.Problem)
+				fmt.Printf("%d entries intact before it\n", result.Entries)
+			}
+
+			if !result.Valid {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(historyCmd, logCmd, verifyCmd)
 	return cmd
 }
 
@@ -458,6 +928,10 @@ func importCommand() *cobra.Command {
 			}
 
 			// Validate before import
+			var imported map[string]interface{}
+			if err := yaml.Unmarshal(data, &imported); err != nil {
+				return fmt.Errorf("invalid configuration: %w", err)
+			}
 			fmt.Printf("Importing configuration from %s...\n", args[0])
 			fmt.Println("✅ Import successful")
 			return nil
@@ -776,4 +1250,4 @@ func generateJSONSchema() map[string]interface{} {
 		},
 		"required": []string{"server"},
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/opencode-ai/opencode/internal/app"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Model Context Protocol integration",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve opencode's tools and SuperClaude commands to MCP hosts",
+	Long: `Serve runs opencode as a Model Context Protocol server. The coder tools (view, grep,
+edit, bash, ...) are exposed as MCP tools and SuperClaude commands as MCP prompts.
+
+Tools that need permission, such as edit or bash, are denied unless they are
+approved with --approve or every request is approved with --auto-approve.`,
+	Example: `
+  # Serve over stdio, allowing read-only tools only
+  opencode mcp serve --stdio
+
+  # Allow edits but no shell commands
+  opencode mcp serve --stdio --approve edit,write,patch
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stdio, _ := cmd.Flags().GetBool("stdio")
+		if !stdio {
+			return fmt.Errorf("only the stdio transport is supported, run with --stdio")
+		}
+		cwd, _ := cmd.Flags().GetString("cwd")
+		approved, _ := cmd.Flags().GetStringSlice("approve")
+		autoApprove, _ := cmd.Flags().GetBool("auto-approve")
+
+		if cwd != "" {
+			if err := os.Chdir(cwd); err != nil {
+				return fmt.Errorf("failed to change directory: %v", err)
+			}
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+		if _, err := config.Load(cwd, false); err != nil {
+			return err
+		}
+
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		app, err := app.New(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		opts := []mcp.ToolServerOption{
+			mcp.WithApprovedTools(approved...),
+			mcp.WithSessionTitle("MCP: " + config.WorkingDirectory()),
+			mcp.WithSessionResources(app.Messages, app.History),
+			mcp.WithMCPTools(app.MCP),
+		}
+		if autoApprove {
+			opts = append(opts, mcp.WithAutoApprove())
+		}
+		server := mcp.NewToolServer(
+			agent.CoderAgentTools(app.Permissions, app.Sessions, app.Messages, app.History, app.LSPClients),
+			app.Sessions,
+			app.Permissions,
+			opts...,
+		)
+		return server.ServeStdio(ctx, os.Stdin, os.Stdout)
+	},
+}
+
+var mcpTokenCmd = &cobra.Command{
+	Use:   "token <subject>",
+	Short: "Issue an access and a refresh token for the MCP WebSocket server",
+	Long: `Token signs a JWT access token and refresh token for subject with
+security.auth.jwt_secret from superclaude.yaml. Clients send the access token as
+"Authorization: Bearer <token>" or the access_token query parameter, and renew it
+with the refresh token through auth.refresh or POST /auth/refresh.
+
+With --tenant the requests of the token count against the quotas of that tenant,
+created with superclaude-config tenant create.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			return err
+		}
+		auth, err := mcp.NewAuthenticator(cfg.Security.Auth)
+		if err != nil {
+			return err
+		}
+		tenantID, _ := cmd.Flags().GetString("tenant")
+		tokens, err := auth.IssueTenant(args[0], tenantID)
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(tokens)
+	},
+}
+
+func init() {
+	mcpServeCmd.Flags().Bool("stdio", false, "Serve over stdin and stdout")
+	mcpServeCmd.Flags().StringP("cwd", "c", "", "Current working directory")
+	mcpServeCmd.Flags().StringSlice("approve", nil, "Tools whose permission requests are granted, such as edit,bash")
+	mcpServeCmd.Flags().Bool("auto-approve", false, "Grant every permission request")
+
+	mcpTokenCmd.Flags().String("config", "", "Directory containing superclaude.yaml")
+	mcpTokenCmd.Flags().String("tenant", "", "Tenant whose quotas the requests count against, the default tenant if empty")
+
+	mcpCmd.AddCommand(mcpServeCmd)
+	mcpCmd.AddCommand(mcpTokenCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var rewindCmd = &cobra.Command{
+	Use:   "rewind <session-id> [message-id]",
+	Short: "Restore the working tree to before a message of a session",
+	Long: `Rewind restores the files of the working tree exactly as they were before the
+agent turn started by a user message, including files removed or created by shell
+commands, and drops that message and everything after it from the session.
+
+Without a message ID the snapshots of the session are listed. The working tree as
+it was before the rewind is kept in the snapshot repository.`,
+	Example: `
+  # List the turns of a session that can be rewound
+  opencode rewind 1f0c2a9e-0c1e-4f5b-9f55-8d8c1f2b7a10
+
+  # Rewind to before a message
+  opencode rewind 1f0c2a9e-0c1e-4f5b-9f55-8d8c1f2b7a10 7b3d9e42-5a61-4c1d-b2e8-0f9a6c3d4e51
+  `,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, _ := cmd.Flags().GetString("cwd")
+		if cwd != "" {
+			if err := os.Chdir(cwd); err != nil {
+				return fmt.Errorf("failed to change directory: %v", err)
+			}
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+		cfg, err := config.Load(cwd, false)
+		if err != nil {
+			return err
+		}
+
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		ctx := context.Background()
+		messages := message.NewService(db.New(conn))
+		snapshots := snapshot.NewService(messages, config.WorkingDirectory(), cfg.Data.Directory)
+		sessionID := args[0]
+
+		if len(args) == 1 {
+			return listSnapshots(ctx, cmd, messages, snapshots, sessionID)
+		}
+
+		result, err := snapshots.Rewind(ctx, sessionID, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Restored the working tree to before message %s and removed %d messages.\n", args[1], result.DeletedMessages)
+		fmt.Fprintf(cmd.OutOrStdout(), "The previous working tree is kept as snapshot %s.\n", result.Backup)
+		return nil
+	},
+}
+
+// listSnapshots prints the user messages of a session that can be rewound to
+func listSnapshots(ctx context.Context, cmd *cobra.Command, messages message.Service, snapshots snapshot.Service, sessionID string) error {
+	list, err := snapshots.List(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No snapshots for session %s\n", sessionID)
+		return nil
+	}
+
+	for _, snap := range list {
+		prompt := ""
+		if msg, err := messages.Get(ctx, snap.MessageID); err == nil {
+			prompt = strings.Join(strings.Fields(msg.Content().String()), " ")
+			if len(prompt) > 60 {
+				prompt = prompt[:57] + "..."
+			}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s  %s  %s\n", snap.MessageID, time.Unix(snap.CreatedAt, 0).Format(time.DateTime), prompt)
+	}
+	return nil
+}
+
+func init() {
+	rewindCmd.Flags().StringP("cwd", "c", "", "Current working directory")
+	rootCmd.AddCommand(rewindCmd)
+}
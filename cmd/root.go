@@ -13,7 +13,6 @@ import (
 	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/db"
 	"github.com/opencode-ai/opencode/internal/format"
-	"github.com/opencode-ai/opencode/internal/llm/agent"
 	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/opencode-ai/opencode/internal/pubsub"
 	"github.com/opencode-ai/opencode/internal/tui"
@@ -122,8 +121,13 @@ to assist developers in writing, debugging, and understanding code directly from
 			tea.WithAltScreen(),
 		)
 
+		// Show drift of the SuperClaude config in the status bar
+		configAlerts := config.NewStatusBarChannel()
+		defer configAlerts.Shutdown()
+		watchConfigDrift(ctx, configAlerts)
+
 		// Setup the subscriptions, this will send services events to the TUI
-		ch, cancelSubs := setupSubscriptions(app, ctx)
+		ch, cancelSubs := setupSubscriptions(app, configAlerts, ctx)
 
 		// Create a context for the TUI message handler
 		tuiCtx, tuiCancel := context.WithCancel(ctx)
@@ -192,6 +196,27 @@ func attemptTUIRecovery(program *tea.Program) {
 	program.Quit()
 }
 
+// watchConfigDrift compares the running SuperClaude config with its file and
+// baseline, sending the alerts to the status bar next to the channels the config
+// selects. Without a SuperClaude config there is nothing to watch.
+func watchConfigDrift(ctx context.Context, statusBar *config.StatusBarChannel) {
+	scConfig, err := config.LoadConfig("")
+	if err != nil {
+		logging.Debug("SuperClaude config not loaded, not watching it for drift", "error", err)
+		return
+	}
+	configFile, _ := config.FindConfigFile("")
+	opts, err := config.DriftOptions(configFile, scConfig.Monitoring.Drift)
+	if err != nil {
+		logging.Warn("Invalid drift alert configuration, not watching the config for drift", "error", err)
+		return
+	}
+	opts = append(opts, config.WithAlertChannels(statusBar))
+	if err := config.NewConfigObservability(opts...).Start(ctx, scConfig); err != nil {
+		logging.Warn("Failed to watch the config for drift", "error", err)
+	}
+}
+
 func initMCPTools(ctx context.Context, app *app.App) {
 	go func() {
 		defer logging.RecoverPanic("MCP-goroutine", nil)
@@ -200,8 +225,8 @@ func initMCPTools(ctx context.Context, app *app.App) {
 		ctxWithTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
 		defer cancel()
 
-		// Set this up once with proper error handling
-		agent.GetMcpTools(ctxWithTimeout, app.Permissions)
+		// Start the servers now so the first prompt does not wait for them
+		app.MCP.Tools(ctxWithTimeout)
 		logging.Info("MCP message handling goroutine exiting")
 	}()
 }
@@ -246,7 +271,7 @@ func setupSubscriber[T any](
 	}()
 }
 
-func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg, func()) {
+func setupSubscriptions(app *app.App, configAlerts *config.StatusBarChannel, parentCtx context.Context) (chan tea.Msg, func()) {
 	ch := make(chan tea.Msg, 100)
 
 	wg := sync.WaitGroup{}
@@ -257,6 +282,11 @@ func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg,
 	setupSubscriber(ctx, &wg, "messages", app.Messages.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "permissions", app.Permissions.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "coderAgent", app.CoderAgent.Subscribe, ch)
+	setupSubscriber(ctx, &wg, "superclaude", app.SuperClaude.Subscribe, ch)
+	setupSubscriber(ctx, &wg, "superclaudePlans", app.SuperClaude.SubscribePlans, ch)
+	setupSubscriber(ctx, &wg, "superclaudeDryRuns", app.SuperClaude.SubscribeDryRuns, ch)
+	setupSubscriber(ctx, &wg, "mcp", app.MCP.Subscribe, ch)
+	setupSubscriber(ctx, &wg, "configAlerts", configAlerts.Subscribe, ch)
 
 	cleanupFunc := func() {
 		logging.Info("Cancelling all subscriptions")
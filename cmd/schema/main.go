@@ -186,6 +186,16 @@ func generateSchema() map[string]any {
 					"description": "Whether the provider is disabled",
 					"default":     false,
 				},
+				"requestsPerMinute": map[string]any{
+					"type":        "integer",
+					"description": "Maximum requests per minute sent to the provider by all agents, 0 for no limit",
+					"minimum":     0,
+				},
+				"burst": map[string]any{
+					"type":        "integer",
+					"description": "Requests that may be sent at once before requestsPerMinute applies, defaults to requestsPerMinute",
+					"minimum":     0,
+				},
 			},
 		},
 	}
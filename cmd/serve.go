@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/app"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/mcp"
+	"github.com/opencode-ai/opencode/internal/ratelimit"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+)
+
+// databaseHealthTimeout bounds the database ping of /healthz
+const databaseHealthTimeout = 5 * time.Second
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run opencode headlessly as a long-lived MCP server",
+	Long: `Serve boots opencode without the terminal UI and serves SuperClaude commands to
+MCP clients over WebSocket, next to /healthz and /metrics. The address, timeouts,
+TLS, authentication, allowed origins and rate limits come from superclaude.yaml.
+
+Tools that need permission, such as edit or bash, are denied unless they are
+approved with --approve or every request is approved with --auto-approve.
+
+Without security.auth.jwt_secret the server only listens on loopback hosts, pass
+--insecure-no-auth to serve other hosts anyway.`,
+	Example: `
+  # Serve with the settings of ./config/superclaude.yaml
+  opencode serve
+
+  # Serve on another port, allowing edits but no shell commands
+  opencode serve --port 9000 --approve edit,write,patch
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, _ := cmd.Flags().GetString("cwd")
+		configPath, _ := cmd.Flags().GetString("config")
+		debug, _ := cmd.Flags().GetBool("debug")
+		approved, _ := cmd.Flags().GetStringSlice("approve")
+		autoApprove, _ := cmd.Flags().GetBool("auto-approve")
+		insecureNoAuth, _ := cmd.Flags().GetBool("insecure-no-auth")
+		auditLog, _ := cmd.Flags().GetString("audit-log")
+		auditRetention, _ := cmd.Flags().GetDuration("audit-retention")
+
+		if cwd != "" {
+			if err := os.Chdir(cwd); err != nil {
+				return fmt.Errorf("failed to change directory: %v", err)
+			}
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current working directory: %v", err)
+		}
+		if _, err := config.Load(cwd, debug); err != nil {
+			return err
+		}
+		scConfig, err := config.LoadConfig(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load superclaude.yaml: %w", err)
+		}
+		// Drift is checked against the config as loaded, the flags are not drift
+		loaded := *scConfig
+		if cmd.Flags().Changed("host") {
+			scConfig.Server.Host, _ = cmd.Flags().GetString("host")
+		}
+		if cmd.Flags().Changed("port") {
+			scConfig.Server.Port, _ = cmd.Flags().GetInt("port")
+		}
+
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		app, err := app.New(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		// There is nobody to ask for permission, requests are answered by policy
+		go mcp.AnswerPermissions(app.Permissions, app.Permissions.Subscribe(ctx), mcp.PermissionPolicy{
+			ApprovedTools: approved,
+			AutoApprove:   autoApprove,
+		}, nil)
+
+		opts := []mcp.ServerOption{
+			mcp.WithCORS(scConfig.Security.CORS),
+			mcp.WithRateLimiter(ratelimit.New("mcp", scConfig.RateLimit)),
+			mcp.WithMaxConnections(scConfig.Server.MaxConnections),
+			mcp.WithWebSocket(scConfig.MCP.WebSocket),
+			mcp.WithSessionStore(mcp.NewSessionStore(db.New(conn), app.Sessions)),
+			mcp.WithTenants(app.Tenants),
+		}
+		if scConfig.Security.Auth.JWTSecret != "" {
+			auth, err := mcp.NewAuthenticator(scConfig.Security.Auth)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, mcp.WithAuthenticator(auth))
+		} else if insecureNoAuth {
+			opts = append(opts, mcp.WithInsecureNoAuth())
+		}
+		server := mcp.NewMCPServer(app.SuperClaude, opts...)
+
+		configFile, _ := config.FindConfigFile(configPath)
+		if configFile != "" {
+			// Edits of the config file while serving are recorded in the audit log
+			manager, err := config.NewConfigManager(configFile, config.WithAuditLogging(auditLog, auditRetention))
+			if err != nil {
+				return err
+			}
+			defer manager.Close()
+		}
+		driftOpts, err := config.DriftOptions(configFile, scConfig.Monitoring.Drift)
+		if err != nil {
+			return err
+		}
+		observability := config.NewConfigObservability(driftOpts...)
+		observability.AddHealthCheck(databaseHealthCheck(conn))
+		if err := observability.Start(ctx, &loaded); err != nil {
+			return err
+		}
+		server.Handle("/healthz", healthHandler(observability, scConfig))
+		server.Handle("/metrics", promhttp.Handler())
+
+		err = server.ListenAndServe(ctx, scConfig.Server, scConfig.Security.TLS)
+		logging.Info("MCP server stopped")
+		return err
+	},
+}
+
+// databaseHealthCheck replaces the placeholder database check with a ping
+func databaseHealthCheck(conn *sql.DB) config.HealthCheck {
+	return config.HealthCheck{
+		Name:        "database_connection",
+		Description: "Check database connectivity",
+		Critical:    true,
+		Check: func(*config.SuperClaudeConfig) config.HealthResult {
+			ctx, cancel := context.WithTimeout(context.Background(), databaseHealthTimeout)
+			defer cancel()
+			if err := conn.PingContext(ctx); err != nil {
+				return config.HealthResult{Status: config.HealthUnhealthy, Message: err.Error()}
+			}
+			return config.HealthResult{Status: config.HealthHealthy, Message: "Database connection healthy"}
+		},
+	}
+}
+
+// healthHandler runs the health checks and answers 503 when a critical one fails
+func healthHandler(observability *config.ConfigObservability, cfg *config.SuperClaudeConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, checks := observability.CheckHealth(cfg)
+
+		w.Header().Set("Content-Type", "application/json")
+		if status == config.HealthUnhealthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": status,
+			"checks": checks,
+		})
+	})
+}
+
+func init() {
+	serveCmd.Flags().StringP("cwd", "c", "", "Current working directory")
+	serveCmd.Flags().String("config", "", "Directory containing superclaude.yaml")
+	serveCmd.Flags().BoolP("debug", "d", false, "Debug")
+	serveCmd.Flags().String("host", "", "Host to listen on, overrides server.host")
+	serveCmd.Flags().Int("port", 0, "Port to listen on, overrides server.port")
+	serveCmd.Flags().StringSlice("approve", nil, "Tools whose permission requests are granted, such as edit,bash")
+	serveCmd.Flags().Bool("auto-approve", false, "Grant every permission request")
+	serveCmd.Flags().Bool("insecure-no-auth", false, "Listen on a non-loopback host without authentication")
+	serveCmd.Flags().String("audit-log", config.DefaultAuditLogPath(), "Audit log of configuration changes")
+	serveCmd.Flags().Duration("audit-retention", 0, "How long configuration changes are kept in the audit log, 0 keeps all")
+
+	rootCmd.AddCommand(serveCmd)
+}
@@ -2,7 +2,7 @@
 // Enables SuperClaude commands directly in Cursor
 
 const vscode = require('vscode');
-const { spawn } = require('child_process');
+const { spawn, execFile } = require('child_process');
 const path = require('path');
 
 let superclaudeProcess = null;
@@ -122,15 +122,82 @@ async function runSuperClaudeCommand(command, useSelection = false) {
 }
 
 async function runCustomCommand() {
-    const command = await vscode.window.showInputBox({
-        prompt: 'Enter SuperClaude command',
-        placeHolder: '/user:build --react my app',
-        value: '/user:'
+    const quickPick = vscode.window.createQuickPick();
+    quickPick.placeholder = '/user:build --react my app';
+    quickPick.value = '/user:';
+    
+    // Completions are requested as the user types, stale answers are dropped
+    let request = 0;
+    const update = async (value) => {
+        const current = ++request;
+        const completions = await completeCommand(value);
+        if (current !== request) {
+            return;
+        }
+        quickPick.items = [
+            { label: value, description: 'Run command', alwaysShow: true },
+            ...completions.map((completion) => ({
+                label: applyCompletion(value, completion),
+                description: completion.description,
+                detail: completion.kind,
+                alwaysShow: true
+            }))
+        ];
+    };
+    
+    quickPick.onDidChangeValue(update);
+    quickPick.onDidAccept(() => {
+        const [item] = quickPick.selectedItems;
+        if (item && item.label !== quickPick.value) {
+            // Insert the completion and keep composing
+            quickPick.value = `${item.label} `;
+            update(quickPick.value);
+            return;
+        }
+        const command = quickPick.value.trim();
+        quickPick.hide();
+        if (command) {
+            runSuperClaudeCommand(command);
+        }
     });
+    quickPick.onDidHide(() => quickPick.dispose());
     
-    if (command) {
-        runSuperClaudeCommand(command);
+    quickPick.show();
+    update(quickPick.value);
+}
+
+// completeCommand asks the SuperClaude binary for the completions at the end of input,
+// the same ones the terminal UI and the MCP complete method offer
+function completeCommand(input) {
+    const config = vscode.workspace.getConfiguration('superclaude');
+    const binary = config.get('binaryPath', 'superclaude');
+    const args = ['complete', '--json', input];
+    if (vscode.workspace.rootPath) {
+        args.push('--cwd', vscode.workspace.rootPath);
     }
+    
+    return new Promise((resolve) => {
+        execFile(binary, args, { timeout: 5000 }, (error, stdout) => {
+            if (error) {
+                outputChannel.appendLine(`Completion failed: ${error.message}`);
+                resolve([]);
+                return;
+            }
+            try {
+                resolve(JSON.parse(stdout));
+            } catch (err) {
+                resolve([]);
+            }
+        });
+    });
+}
+
+// applyCompletion replaces the completed word, whose range is given in bytes
+function applyCompletion(input, completion) {
+    const bytes = Buffer.from(input, 'utf8');
+    return bytes.subarray(0, completion.start).toString('utf8') +
+        completion.value +
+        bytes.subarray(completion.end).toString('utf8');
 }
 
 function startSuperClaude() {
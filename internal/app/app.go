@@ -14,11 +14,17 @@ import (
 	"github.com/opencode-ai/opencode/internal/format"
 	"github.com/opencode-ai/opencode/internal/history"
 	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
 	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/opencode-ai/opencode/internal/lsp"
 	"github.com/opencode-ai/opencode/internal/message"
 	"github.com/opencode-ai/opencode/internal/permission"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/opencode-ai/opencode/internal/ratelimit"
 	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/opencode-ai/opencode/internal/snapshot"
+	"github.com/opencode-ai/opencode/internal/superclaude"
+	"github.com/opencode-ai/opencode/internal/tenant"
 	"github.com/opencode-ai/opencode/internal/tui/theme"
 )
 
@@ -27,8 +33,16 @@ type App struct {
 	Messages    message.Service
 	History     history.Service
 	Permissions permission.Service
+	Snapshots   snapshot.Service
 
-	CoderAgent agent.Service
+	CoderAgent  agent.Service
+	SuperClaude *superclaude.SuperClaudeHandler
+
+	// Tenants stores the tenants and enforces their quotas on agent runs
+	Tenants *tenant.Service
+
+	// MCP holds the connections to the configured MCP servers
+	MCP *agent.MCPManager
 
 	LSPClients map[string]*lsp.Client
 
@@ -59,7 +73,29 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 	// Initialize LSP clients in the background
 	go app.initLSPClients(ctx)
 
+	// Start the configured MCP servers when their tools are first needed
+	app.MCP = agent.NewMCPManager(config.Get().MCPServers, app.Permissions)
+	agentOpts := []agent.Option{agent.WithMCPTools(app.MCP)}
+	if cfg := config.Get(); cfg.Snapshots {
+		app.Snapshots = snapshot.NewService(messages, config.WorkingDirectory(), cfg.Data.Directory)
+		agentOpts = append(agentOpts, agent.WithSnapshots(app.Snapshots))
+		go app.forgetDeletedSessions(ctx)
+	}
+
+	// Every agent run counts against the quotas of the tenant of its session
+	scConfig := loadSuperClaudeConfig()
+	tenantConfig := scConfig
+	if tenantConfig == nil {
+		tenantConfig = &config.SuperClaudeConfig{}
+	}
 	var err error
+	app.Tenants, err = tenant.NewService(q, config.NewMultiTenantConfigManager(tenantConfig, config.IsolationShared))
+	if err != nil {
+		logging.Error("Failed to load tenants", err)
+		return nil, err
+	}
+	agentOpts = append(agentOpts, agent.WithQuotas(app.Tenants))
+
 	app.CoderAgent, err = agent.NewAgent(
 		config.AgentCoder,
 		app.Sessions,
@@ -71,15 +107,106 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 			app.History,
 			app.LSPClients,
 		),
+		agentOpts...,
 	)
 	if err != nil {
 		logging.Error("Failed to create coder agent", err)
 		return nil, err
 	}
 
+	handlerOpts := []superclaude.HandlerOption{
+		superclaude.WithQuotas(app.Tenants),
+		superclaude.WithSpawnTools(func() []tools.BaseTool {
+			return agent.CoderAgentTools(
+				app.Permissions,
+				app.Sessions,
+				app.Messages,
+				app.History,
+				app.LSPClients,
+			)
+		}),
+		superclaude.WithMCPTools(app.MCP),
+		superclaude.WithPlanTools(func() []tools.BaseTool {
+			return agent.TaskAgentTools(app.LSPClients)
+		}),
+	}
+
+	// Load user-defined personas and reload them when their files change
+	personas := superclaude.NewPersonaLoader(
+		scConfig == nil || scConfig.SuperClaude.Personas.AllowCustom,
+		superclaude.PersonaDirs(config.WorkingDirectory())...,
+	)
+	if err := personas.Load(); err != nil {
+		logging.Warn("Some personas failed to load", "error", err)
+	}
+	go personas.Watch(ctx)
+
+	// Load file-based SuperClaude commands after the personas they may reference
+	commands := superclaude.NewCommandLoader(superclaude.CommandDirs(config.WorkingDirectory())...)
+	if err := commands.Load(); err != nil {
+		logging.Warn("Some SuperClaude commands failed to load", "error", err)
+	}
+	go commands.Watch(ctx)
+
+	// Cache SuperClaude results until the files they depend on change
+	optimizer := superclaude.NewOptimizer()
+	go optimizer.WatchHistory(ctx, app.History)
+	go optimizer.WatchFiles(ctx)
+	handlerOpts = append(handlerOpts, superclaude.WithOptimizer(optimizer))
+
+	if app.Snapshots != nil {
+		handlerOpts = append(handlerOpts, superclaude.WithSnapshots(app.Snapshots))
+	}
+
+	if scConfig != nil {
+		handlerOpts = append(handlerOpts, superclaude.WithThinkingTokens(scConfig.Performance.ThinkingTokens))
+		handlerOpts = append(handlerOpts, superclaude.WithRateLimiter(ratelimit.New("commands", scConfig.RateLimit)))
+
+		policy, err := superclaude.ParseEvidencePolicy(scConfig.SuperClaude.Flags.EvidencePolicy)
+		if err != nil {
+			logging.Warn("Invalid SuperClaude evidence policy, flagging unverified citations", "error", err)
+		} else {
+			handlerOpts = append(handlerOpts, superclaude.WithEvidencePolicy(policy))
+		}
+	}
+
+	app.SuperClaude = superclaude.NewSuperClaudeHandler(
+		app.CoderAgent,
+		app.Sessions,
+		app.Messages,
+		handlerOpts...,
+	)
+	// Reloaded personas may change their model or tools
+	personas.OnLoad(app.SuperClaude.EvictPersonaAgents)
+
 	return app, nil
 }
 
+// loadSuperClaudeConfig reads the SuperClaude config, returning nil when it does not
+// load. Without a config file, the defaults allow user-defined personas and thinking
+// budgets use the built-in defaults.
+func loadSuperClaudeConfig() *config.SuperClaudeConfig {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		logging.Warn("SuperClaude config not loaded, using defaults", "error", err)
+		return nil
+	}
+	return cfg
+}
+
+// forgetDeletedSessions drops the working tree snapshots of deleted sessions
+func (app *App) forgetDeletedSessions(ctx context.Context) {
+	events := app.Sessions.Subscribe(ctx)
+	for event := range events {
+		if event.Type != pubsub.DeletedEvent {
+			continue
+		}
+		if err := app.Snapshots.DeleteSession(ctx, event.Payload.ID); err != nil {
+			logging.Warn("Failed to delete session snapshots", "session", event.Payload.ID, "error", err)
+		}
+	}
+}
+
 // initTheme sets the application theme based on the configuration
 func (app *App) initTheme() {
 	cfg := config.Get()
@@ -183,4 +310,6 @@ func (app *App) Shutdown() {
 		}
 		cancel()
 	}
+
+	app.MCP.Close()
 }
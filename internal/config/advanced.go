@@ -29,6 +29,7 @@ type ConfigManager struct {
 	validationRules []ValidationRule
 	migrator        *ConfigMigrator
 	hotReload       bool
+	configPath      string
 	ctx             context.Context
 	cancel          context.CancelFunc
 }
@@ -53,6 +54,7 @@ type AuditLogger struct {
 	logPath    string
 	retention  time.Duration
 	encryptLog bool
+	log        *AuditLog
 }
 
 // ValidationRule defines custom validation logic
@@ -73,25 +75,10 @@ const (
 	ValidationCritical
 )
 
-// ConfigMigrator handles configuration migrations
-type ConfigMigrator struct {
-	migrations map[string]Migration
-	mu         sync.RWMutex
-}
-
-// Migration defines a configuration migration
-type Migration struct {
-	FromVersion string
-	ToVersion   string
-	Description string
-	Migrate     func(map[string]interface{}) (map[string]interface{}, error)
-	Rollback    func(map[string]interface{}) (map[string]interface{}, error)
-}
-
 // NewConfigManager creates an advanced configuration manager
 func NewConfigManager(configPath string, opts ...ConfigOption) (*ConfigManager, error) {
 	ctx, cancel := context.WithCancel(context.Background())
-	
+
 	cm := &ConfigManager{
 		watchers:        make([]ConfigWatcher, 0),
 		validationRules: getDefaultValidationRules(),
@@ -99,32 +86,33 @@ func NewConfigManager(configPath string, opts ...ConfigOption) (*ConfigManager,
 		ctx:             ctx,
 		cancel:          cancel,
 		hotReload:       true,
+		configPath:      configPath,
 	}
-	
+
 	// Apply options
 	for _, opt := range opts {
 		opt(cm)
 	}
-	
+
 	// Load initial configuration
 	config, err := cm.LoadWithValidation(configPath)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
-	
+
 	cm.config = config
-	
+
 	// Initialize audit logging
 	if err := cm.initAuditLogging(); err != nil {
 		logging.Warn("Failed to initialize audit logging", "error", err)
 	}
-	
+
 	// Start hot reload if enabled
 	if cm.hotReload {
 		go cm.watchConfigChanges(configPath)
 	}
-	
+
 	return cm, nil
 }
 
@@ -146,7 +134,9 @@ func WithHotReload(enabled bool) ConfigOption {
 	}
 }
 
-// WithAuditLogging enables audit logging
+// WithAuditLogging records configuration changes in the audit log at path, or at
+// DefaultAuditLogPath when path is empty, keeping them for retention. A zero
+// retention keeps every change.
 func WithAuditLogging(path string, retention time.Duration) ConfigOption {
 	return func(cm *ConfigManager) {
 		cm.auditLogger = AuditLogger{
@@ -164,27 +154,27 @@ func (cm *ConfigManager) LoadWithValidation(configPath string) (*SuperClaudeConf
 	if err != nil {
 		return nil, err
 	}
-	
+
 	// Version check
 	if err := cm.validateVersion(config); err != nil {
 		return nil, fmt.Errorf("version validation failed: %w", err)
 	}
-	
+
 	// Custom validation rules
 	if err := cm.runValidationRules(config); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
-	
+
 	// Decrypt sensitive fields
 	if cm.encryptionKey != nil {
 		if err := cm.decryptSensitiveFields(config); err != nil {
 			return nil, fmt.Errorf("decryption failed: %w", err)
 		}
 	}
-	
+
 	// Apply security hardening
 	cm.applySecurityHardening(config)
-	
+
 	return config, nil
 }
 
@@ -192,45 +182,60 @@ func (cm *ConfigManager) LoadWithValidation(configPath string) (*SuperClaudeConf
 func (cm *ConfigManager) GetConfig() *SuperClaudeConfig {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
-	
+
 	// Return a deep copy to prevent mutations
 	return cm.deepCopyConfig(cm.config)
 }
 
-// UpdateConfig updates configuration with validation and audit
+// UpdateConfig applies updates keyed by dotted mapstructure paths, such as
+// performance.batch_size, with their values coerced to the field types. The
+// updates are applied to a copy that replaces the configuration only if it passes
+// validation, so a failed update leaves the configuration as it was. Watchers are
+// notified of every applied update.
 func (cm *ConfigManager) UpdateConfig(updates map[string]interface{}) error {
+	return cm.UpdateConfigAs(AuditActor(), AuditSourceAPI, updates)
+}
+
+// UpdateConfigAs is UpdateConfig recording the change in the audit log as made by
+// actor from source
+func (cm *ConfigManager) UpdateConfigAs(actor string, source AuditSource, updates map[string]interface{}) error {
 	cm.mu.Lock()
-	defer cm.mu.Unlock()
-	
-	oldConfig := cm.deepCopyConfig(cm.config)
-	
-	// Apply updates
-	newConfig, err := cm.applyUpdates(cm.config, updates)
+	oldConfig := cm.config
+	newConfig, changes, err := cm.applyUpdates(oldConfig, updates)
 	if err != nil {
+		cm.mu.Unlock()
 		return fmt.Errorf("failed to apply updates: %w", err)
 	}
-	
+
 	// Validate new configuration
+	if err := validateConfig(newConfig); err != nil {
+		cm.mu.Unlock()
+		return fmt.Errorf("validation failed after update: %w", err)
+	}
 	if err := cm.runValidationRules(newConfig); err != nil {
+		cm.mu.Unlock()
 		return fmt.Errorf("validation failed after update: %w", err)
 	}
-	
+
+	cm.config = newConfig
+	watchers := append([]ConfigWatcher(nil), cm.watchers...)
+
 	// Audit the change
-	cm.auditConfigChange(oldConfig, newConfig, updates)
-	
-	// Notify watchers
-	for _, watcher := range cm.watchers {
-		if err := watcher.OnConfigChange(oldConfig, newConfig); err != nil {
+	cm.auditConfigChange(oldConfig, newConfig, actor, source)
+	cm.mu.Unlock()
+
+	// Notify watchers outside the lock so they can read the new configuration,
+	// each with its own copies so they cannot change it
+	for _, watcher := range watchers {
+		if err := watcher.OnConfigChange(cm.deepCopyConfig(oldConfig), cm.deepCopyConfig(newConfig)); err != nil {
 			logging.Error("Config watcher failed", "error", err)
 		}
 	}
-	
-	cm.config = newConfig
-	
-	logging.Info("Configuration updated successfully", 
-		"changes", len(updates),
+
+	logging.Info("Configuration updated successfully",
+		"changes", len(changes),
 		"version", newConfig.Deployment.Version)
-	
+
 	return nil
 }
 
@@ -246,22 +251,22 @@ func (cm *ConfigManager) Encrypt(plaintext string) (string, error) {
 	if cm.encryptionKey == nil {
 		return plaintext, nil
 	}
-	
+
 	block, err := aes.NewCipher(cm.encryptionKey)
 	if err != nil {
 		return "", err
 	}
-	
+
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
 		return "", err
 	}
-	
+
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
-	
+
 	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
@@ -271,33 +276,33 @@ func (cm *ConfigManager) Decrypt(ciphertext string) (string, error) {
 	if cm.encryptionKey == nil {
 		return ciphertext, nil
 	}
-	
+
 	data, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
 		return "", err
 	}
-	
+
 	block, err := aes.NewCipher(cm.encryptionKey)
 	if err != nil {
 		return "", err
 	}
-	
+
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
 		return "", err
 	}
-	
+
 	nonceSize := gcm.NonceSize()
 	if len(data) < nonceSize {
 		return "", fmt.Errorf("ciphertext too short")
 	}
-	
+
 	nonce, ciphertext_bytes := data[:nonceSize], data[nonceSize:]
 	plaintext, err := gcm.Open(nil, nonce, ciphertext_bytes, nil)
 	if err != nil {
 		return "", err
 	}
-	
+
 	return string(plaintext), nil
 }
 
@@ -308,7 +313,7 @@ func (cm *ConfigManager) ValidateConfiguration() *ValidationResult {
 		Issues:   make([]ValidationIssue, 0),
 		Warnings: make([]ValidationIssue, 0),
 	}
-	
+
 	for _, rule := range cm.validationRules {
 		if err := rule.Validator(cm.config); err != nil {
 			issue := ValidationIssue{
@@ -318,7 +323,7 @@ func (cm *ConfigManager) ValidateConfiguration() *ValidationResult {
 				Severity:    rule.Severity,
 				Category:    rule.Category,
 			}
-			
+
 			switch rule.Severity {
 			case ValidationError, ValidationCritical:
 				result.Valid = false
@@ -328,7 +333,7 @@ func (cm *ConfigManager) ValidateConfiguration() *ValidationResult {
 			}
 		}
 	}
-	
+
 	return result
 }
 
@@ -348,29 +353,27 @@ type ValidationIssue struct {
 	Category    string             `json:"category"`
 }
 
-// GetConfigHistory returns configuration change history
+// GetConfigHistory returns up to limit changes from the audit log, newest first
 func (cm *ConfigManager) GetConfigHistory(limit int) ([]ConfigChange, error) {
-	// Implementation would read from audit log
-	return nil, fmt.Errorf("not implemented")
+	if cm.auditLogger.log == nil {
+		return nil, fmt.Errorf("audit logging is not enabled")
+	}
+	return cm.auditLogger.log.History(limit)
 }
 
-// ConfigChange represents a configuration change event
-type ConfigChange struct {
-	Timestamp time.Time              `json:"timestamp"`
-	User      string                 `json:"user"`
-	Changes   map[string]interface{} `json:"changes"`
-	Version   string                 `json:"version"`
-	Source    string                 `json:"source"`
+// AuditLog returns the audit log, or nil when audit logging is not enabled
+func (cm *ConfigManager) AuditLog() *AuditLog {
+	return cm.auditLogger.log
 }
 
 // ExportConfig exports configuration in various formats
 func (cm *ConfigManager) ExportConfig(format string, includeSecrets bool) ([]byte, error) {
 	config := cm.GetConfig()
-	
+
 	if !includeSecrets {
 		config = cm.redactSecrets(config)
 	}
-	
+
 	switch format {
 	case "yaml":
 		return yaml.Marshal(config)
@@ -393,12 +396,12 @@ func (cm *ConfigManager) watchConfigChanges(configPath string) {
 		return
 	}
 	defer watcher.Close()
-	
+
 	if err := watcher.Add(configPath); err != nil {
 		logging.Error("Failed to watch config path", "error", err)
 		return
 	}
-	
+
 	for {
 		select {
 		case event := <-watcher.Events:
@@ -415,29 +418,30 @@ func (cm *ConfigManager) watchConfigChanges(configPath string) {
 
 func (cm *ConfigManager) handleConfigFileChange() {
 	logging.Info("Configuration file changed, reloading...")
-	
+
 	// Add debouncing to prevent rapid reloads
 	time.Sleep(100 * time.Millisecond)
-	
-	newConfig, err := cm.LoadWithValidation("")
+
+	newConfig, err := cm.LoadWithValidation(cm.configPath)
 	if err != nil {
 		logging.Error("Failed to reload configuration", "error", err)
 		return
 	}
-	
+
 	oldConfig := cm.GetConfig()
-	
+
 	cm.mu.Lock()
 	cm.config = newConfig
+	cm.auditConfigChange(oldConfig, newConfig, AuditActor(), AuditSourceFile)
 	cm.mu.Unlock()
-	
+
 	// Notify watchers
 	for _, watcher := range cm.watchers {
 		if err := watcher.OnConfigChange(oldConfig, newConfig); err != nil {
 			logging.Error("Config watcher failed during hot reload", "error", err)
 		}
 	}
-	
+
 	logging.Info("Configuration reloaded successfully")
 }
 
@@ -460,16 +464,16 @@ func (cm *ConfigManager) runValidationRules(config *SuperClaudeConfig) error {
 func (cm *ConfigManager) decryptSensitiveFields(config *SuperClaudeConfig) error {
 	// Decrypt API keys and other sensitive fields
 	var err error
-	
+
 	if config.Providers.OpenRouter.APIKey != "" {
 		config.Providers.OpenRouter.APIKey, err = cm.Decrypt(config.Providers.OpenRouter.APIKey)
 		if err != nil {
 			return fmt.Errorf("failed to decrypt OpenRouter API key: %w", err)
 		}
 	}
-	
+
 	// Decrypt other sensitive fields...
-	
+
 	return nil
 }
 
@@ -480,7 +484,7 @@ func (cm *ConfigManager) applySecurityHardening(config *SuperClaudeConfig) {
 		config.Security.APIKeyEncryption = true
 		config.Security.SessionEncryption = true
 		config.Monitoring.Profiling.Enabled = false
-		
+
 		// Ensure TLS is enabled
 		if !config.Server.TLS.Enabled {
 			logging.Warn("TLS should be enabled in production")
@@ -489,39 +493,40 @@ func (cm *ConfigManager) applySecurityHardening(config *SuperClaudeConfig) {
 }
 
 func (cm *ConfigManager) deepCopyConfig(config *SuperClaudeConfig) *SuperClaudeConfig {
-	// Implementation would create a deep copy
-	return config // Simplified for now
+	return deepCopy(config)
 }
 
-func (cm *ConfigManager) applyUpdates(config *SuperClaudeConfig, updates map[string]interface{}) (*SuperClaudeConfig, error) {
-	// Implementation would apply updates safely
-	return config, nil
+// applyUpdates returns a copy of config with updates applied and the values it set
+func (cm *ConfigManager) applyUpdates(config *SuperClaudeConfig, updates map[string]interface{}) (*SuperClaudeConfig, map[string]interface{}, error) {
+	updated := cm.deepCopyConfig(config)
+	changes, err := applyConfigUpdates(updated, updates)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, changes, nil
 }
 
-func (cm *ConfigManager) auditConfigChange(old, new *SuperClaudeConfig, updates map[string]interface{}) {
-	if !cm.auditLogger.enabled {
+// auditConfigChange records the fields that differ between old and new in the
+// audit log. A change that cannot be recorded is logged, not refused.
+func (cm *ConfigManager) auditConfigChange(old, new *SuperClaudeConfig, actor string, source AuditSource) {
+	if cm.auditLogger.log == nil {
+		return
+	}
+
+	entry, err := cm.auditLogger.log.Record(actor, source, "", new.Deployment.Version, fieldChanges(diffConfigs(old, new)))
+	if err != nil {
+		logging.Error("Failed to record configuration change in the audit log", "error", err)
 		return
 	}
-	
-	change := ConfigChange{
-		Timestamp: time.Now(),
-		Changes:   updates,
-		Version:   new.Deployment.Version,
-		Source:    "api",
+	if entry != nil {
+		logging.Info("Configuration change audited", "sequence", entry.Sequence, "source", source, "changes", len(entry.Changes))
 	}
-	
-	// Log to audit file
-	data, _ := json.Marshal(change)
-	logging.Info("Configuration change audited", "change", string(data))
 }
 
+// redactSecrets returns a copy of config with its secrets redacted
 func (cm *ConfigManager) redactSecrets(config *SuperClaudeConfig) *SuperClaudeConfig {
-	// Implementation would redact sensitive fields
 	redacted := cm.deepCopyConfig(config)
-	redacted.Providers.OpenRouter.APIKey = "[REDACTED]"
-	redacted.Providers.OpenAI.APIKey = "[REDACTED]"
-	redacted.Providers.Anthropic.APIKey = "[REDACTED]"
-	redacted.Security.Auth.JWTSecret = "[REDACTED]"
+	redactConfig(redacted)
 	return redacted
 }
 
@@ -529,9 +534,17 @@ func (cm *ConfigManager) initAuditLogging() error {
 	if !cm.auditLogger.enabled {
 		return nil
 	}
-	
-	// Initialize audit logging
-	logging.Info("Audit logging initialized", "path", cm.auditLogger.logPath)
+
+	path := cm.auditLogger.logPath
+	if path == "" {
+		path = DefaultAuditLogPath()
+	}
+	log, err := OpenAuditLog(path, cm.auditLogger.retention)
+	if err != nil {
+		return err
+	}
+	cm.auditLogger.log = log
+	logging.Info("Audit logging initialized", "path", path, "retention", cm.auditLogger.retention)
 	return nil
 }
 
@@ -542,13 +555,6 @@ func (cm *ConfigManager) Close() error {
 	return nil
 }
 
-// NewConfigMigrator creates a new configuration migrator
-func NewConfigMigrator() *ConfigMigrator {
-	return &ConfigMigrator{
-		migrations: make(map[string]Migration),
-	}
-}
-
 // Default validation rules
 func getDefaultValidationRules() []ValidationRule {
 	return []ValidationRule{
@@ -582,13 +588,13 @@ func getDefaultValidationRules() []ValidationRule {
 			Severity:    ValidationWarning,
 			Category:    "configuration",
 			Validator: func(config *SuperClaudeConfig) error {
-				if config.Providers.OpenRouter.APIKey == "" && 
-				   config.Providers.OpenAI.APIKey == "" && 
-				   config.Providers.Anthropic.APIKey == "" {
+				if config.Providers.OpenRouter.APIKey == "" &&
+					config.Providers.OpenAI.APIKey == "" &&
+					config.Providers.Anthropic.APIKey == "" {
 					return fmt.Errorf("no API keys configured for any providers")
 				}
 				return nil
 			},
 		},
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSuperClaudeConfig = `
+server:
+  port: 8080
+providers:
+  default: openai
+  openai:
+    api_key: sk-live
+    models: [gpt-4o]
+logging:
+  components:
+    mcp: info
+`
+
+type recordingWatcher struct {
+	changes [][2]*SuperClaudeConfig
+}
+
+func (w *recordingWatcher) OnConfigChange(old, new *SuperClaudeConfig) error {
+	w.changes = append(w.changes, [2]*SuperClaudeConfig{old, new})
+	return nil
+}
+
+func newTestConfigManager(t *testing.T) *ConfigManager {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "superclaude.yaml"), []byte(testSuperClaudeConfig), 0o644))
+	cm, err := NewConfigManager(dir, WithHotReload(false))
+	require.NoError(t, err)
+	t.Cleanup(func() { cm.Close() })
+	return cm
+}
+
+func TestConfigManagerUpdateConfig(t *testing.T) {
+	cm := newTestConfigManager(t)
+	watcher := &recordingWatcher{}
+	cm.AddWatcher(watcher)
+
+	err := cm.UpdateConfig(map[string]interface{}{
+		"performance.batch_size":   "25",
+		"server.timeout":           "45s",
+		"server.tls":               map[string]interface{}{"enabled": "true"},
+		"providers.openai.models":  "gpt-4o, o3",
+		"logging.components.cache": "debug",
+		"logging.components.mcp":   nil,
+	})
+	require.NoError(t, err)
+
+	cfg := cm.GetConfig()
+	assert.Equal(t, 25, cfg.Performance.BatchSize)
+	assert.Equal(t, 45*time.Second, cfg.Server.Timeout)
+	assert.True(t, cfg.Server.TLS.Enabled)
+	assert.Equal(t, []string{"gpt-4o", "o3"}, cfg.Providers.OpenAI.Models)
+	assert.Equal(t, map[string]string{"cache": "debug"}, cfg.Logging.Components)
+
+	require.Len(t, watcher.changes, 1)
+	old, updated := watcher.changes[0][0], watcher.changes[0][1]
+	assert.Equal(t, 10, old.Performance.BatchSize)
+	assert.Equal(t, map[string]string{"mcp": "info"}, old.Logging.Components)
+	assert.Equal(t, 25, updated.Performance.BatchSize)
+
+	// Neither the returned config nor the watchers' copies alias the live one
+	cfg.Providers.OpenAI.Models[0] = "changed"
+	updated.Logging.Components["cache"] = "changed"
+	assert.Equal(t, []string{"gpt-4o", "o3"}, cm.GetConfig().Providers.OpenAI.Models)
+	assert.Equal(t, "debug", cm.GetConfig().Logging.Components["cache"])
+}
+
+func TestConfigManagerUpdateConfigRollsBack(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates map[string]interface{}
+		err     string
+	}{
+		{
+			name:    "unknown key",
+			updates: map[string]interface{}{"performance.batch_size": 25, "performance.turbo": true},
+			err:     `unknown config key "performance.turbo"`,
+		},
+		{
+			name:    "wrong type",
+			updates: map[string]interface{}{"performance.batch_size": "many"},
+			err:     `config key "performance.batch_size": invalid integer "many"`,
+		},
+		{
+			name:    "section",
+			updates: map[string]interface{}{"server.tls": true},
+			err:     `config key "server.tls" is a section`,
+		},
+		{
+			name:    "invalid config",
+			updates: map[string]interface{}{"performance.batch_size": 25, "server.port": 70000},
+			err:     "server.port must be between 1 and 65535",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newTestConfigManager(t)
+			watcher := &recordingWatcher{}
+			cm.AddWatcher(watcher)
+
+			err := cm.UpdateConfig(tt.updates)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+
+			cfg := cm.GetConfig()
+			assert.Equal(t, 10, cfg.Performance.BatchSize)
+			assert.Equal(t, 8080, cfg.Server.Port)
+			assert.Empty(t, watcher.changes)
+		})
+	}
+}
+
+func TestConfigManagerExportRedactsCopy(t *testing.T) {
+	cm := newTestConfigManager(t)
+
+	exported, err := cm.ExportConfig("json", false)
+	require.NoError(t, err)
+	assert.Contains(t, string(exported), redactedValue)
+	assert.NotContains(t, string(exported), "sk-live")
+
+	assert.Equal(t, "sk-live", cm.GetConfig().Providers.OpenAI.APIKey)
+	assert.Empty(t, cm.redactSecrets(cm.GetConfig()).Providers.Anthropic.APIKey)
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// Names of the built-in alert channels, as AlertRule.Channels refers to them
+const (
+	AlertChannelLog       = "log"
+	AlertChannelFile      = "file"
+	AlertChannelWebhook   = "webhook"
+	AlertChannelStatusBar = "tui"
+)
+
+// webhookTimeout bounds the delivery of an alert to a webhook
+const webhookTimeout = 5 * time.Second
+
+// defaultAlertCooldown keeps configured rules without a cooldown from repeating a
+// drift at every check
+const defaultAlertCooldown = time.Hour
+
+// DefaultAlertFilePath is the file channel's file under the data directory
+func DefaultAlertFilePath() string {
+	return filepath.Join(DataDirectory(), "alerts", "config.jsonl")
+}
+
+// LogChannel writes alerts to the application log, at a level that matches their
+// severity
+type LogChannel struct{}
+
+func (LogChannel) Name() string {
+	return AlertChannelLog
+}
+
+func (LogChannel) SendAlert(alert Alert) error {
+	args := []any{
+		"component", alert.Component,
+		"severity", alert.Severity,
+		"message", alert.Message,
+		"id", alert.ID,
+	}
+	switch {
+	case alert.Severity >= AlertCritical:
+		logging.Error(alert.Title, args...)
+	case alert.Severity == AlertWarning:
+		logging.Warn(alert.Title, args...)
+	default:
+		logging.Info(alert.Title, args...)
+	}
+	return nil
+}
+
+// FileChannel appends alerts to a file, one JSON object per line
+type FileChannel struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileChannel returns a channel appending to path, creating its directory
+func NewFileChannel(path string) (*FileChannel, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create alert file directory: %w", err)
+	}
+	return &FileChannel{path: path}, nil
+}
+
+func (c *FileChannel) Name() string {
+	return AlertChannelFile
+}
+
+// Path returns the file alerts are appended to
+func (c *FileChannel) Path() string {
+	return c.path
+}
+
+func (c *FileChannel) SendAlert(alert Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WebhookChannel POSTs alerts as JSON to a URL on the local machine
+type WebhookChannel struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookChannel returns a channel posting to rawURL, which must be an http or
+// https URL on a loopback address. Alerts name the config keys that drifted, so
+// they do not leave the machine.
+func NewWebhookChannel(rawURL string) (*WebhookChannel, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("webhook URL %s must use http or https", rawURL)
+	}
+	if !isLoopbackHost(u.Hostname()) {
+		return nil, fmt.Errorf("webhook URL %s must be on a loopback address", rawURL)
+	}
+	return &WebhookChannel{
+		url: rawURL,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			// A redirect could send the alert to another host
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+func (c *WebhookChannel) Name() string {
+	return AlertChannelWebhook
+}
+
+func (c *WebhookChannel) SendAlert(alert Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", c.url, resp.Status)
+	}
+	return nil
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// StatusBarChannel publishes alerts for the TUI to show in its status bar
+type StatusBarChannel struct {
+	*pubsub.Broker[Alert]
+}
+
+func NewStatusBarChannel() *StatusBarChannel {
+	return &StatusBarChannel{Broker: pubsub.NewBroker[Alert]()}
+}
+
+func (c *StatusBarChannel) Name() string {
+	return AlertChannelStatusBar
+}
+
+func (c *StatusBarChannel) SendAlert(alert Alert) error {
+	c.Publish(pubsub.CreatedEvent, alert)
+	return nil
+}
+
+// DriftOptions returns the options that deliver drift alerts through the log, file
+// and webhook channels of cfg, selected by its rules. When drift detection is
+// enabled, the running config is also compared with configFile and the baseline
+// of cfg every interval.
+func DriftOptions(configFile string, cfg DriftConfig) ([]ObservabilityOption, error) {
+	alertFile := cfg.AlertFile
+	if alertFile == "" {
+		alertFile = DefaultAlertFilePath()
+	}
+	file, err := NewFileChannel(alertFile)
+	if err != nil {
+		return nil, err
+	}
+	channels := []AlertChannel{LogChannel{}, file}
+	if cfg.WebhookURL != "" {
+		webhook, err := NewWebhookChannel(cfg.WebhookURL)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, webhook)
+	}
+
+	opts := []ObservabilityOption{WithAlertChannels(channels...)}
+	if len(cfg.Rules) > 0 {
+		rules, err := driftRules(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithDriftRules(rules...))
+	}
+	if cfg.Enabled {
+		opts = append(opts, WithDriftFiles(configFile, cfg.Baseline))
+		if cfg.Interval > 0 {
+			opts = append(opts, WithDriftInterval(cfg.Interval))
+		}
+	}
+	return opts, nil
+}
+
+// driftRules returns the alert rules cfg configures
+func driftRules(cfg DriftConfig) ([]AlertRule, error) {
+	known := []string{AlertChannelLog, AlertChannelFile, AlertChannelWebhook, AlertChannelStatusBar}
+
+	rules := make([]AlertRule, 0, len(cfg.Rules))
+	for i, ruleConfig := range cfg.Rules {
+		rule := AlertRule{
+			Name:     ruleConfig.Name,
+			Cooldown: ruleConfig.Cooldown,
+			Paths:    ruleConfig.Paths,
+			Channels: ruleConfig.Channels,
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule_%d", i+1)
+		}
+		if rule.Cooldown == 0 {
+			rule.Cooldown = defaultAlertCooldown
+		}
+		if ruleConfig.Severity != "" {
+			severity, err := ParseAlertSeverity(ruleConfig.Severity)
+			if err != nil {
+				return nil, fmt.Errorf("alert rule %s: %w", rule.Name, err)
+			}
+			rule.Severity = severity
+		}
+		for _, name := range ruleConfig.Types {
+			driftType, err := ParseDriftType(name)
+			if err != nil {
+				return nil, fmt.Errorf("alert rule %s: %w", rule.Name, err)
+			}
+			rule.DriftTypes = append(rule.DriftTypes, driftType)
+		}
+		for _, channel := range rule.Channels {
+			if !slices.Contains(known, channel) {
+				return nil, fmt.Errorf("alert rule %s: unknown channel %q, expected one of %s",
+					rule.Name, channel, strings.Join(known, ", "))
+			}
+			if channel == AlertChannelWebhook && cfg.WebhookURL == "" {
+				return nil, fmt.Errorf("alert rule %s: the webhook channel needs monitoring.drift.webhook_url", rule.Name)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
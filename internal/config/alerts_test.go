@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingChannel keeps the alerts sent to it
+type recordingChannel struct {
+	name   string
+	alerts []Alert
+}
+
+func (c *recordingChannel) Name() string { return c.name }
+
+func (c *recordingChannel) SendAlert(alert Alert) error {
+	c.alerts = append(c.alerts, alert)
+	return nil
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "superclaude.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// recordDriftAlerts collects the alerts the detector delivers from now on, by component
+func recordDriftAlerts(cdd *ConfigDriftDetector) map[string]DriftAlert {
+	alerts := make(map[string]DriftAlert)
+	cdd.notify = func(alert DriftAlert) {
+		alerts[alert.Component] = alert
+	}
+	return alerts
+}
+
+func TestCheckFilesReportsFieldDrift(t *testing.T) {
+	configFile := writeConfigFile(t, `
+providers:
+  default: openai
+server:
+  port: 9000
+security:
+  auth:
+    jwt_secret: on-disk
+`)
+	baseline := writeConfigFile(t, `
+providers:
+  default: openai
+server:
+  port: 9000
+  tls:
+    enabled: true
+`)
+	running, err := LoadConfig(configFile)
+	require.NoError(t, err)
+	running.Server.Port = 9100
+	running.Security.Auth.JWTSecret = "in-memory"
+
+	cdd := newConfigDriftDetector()
+	cdd.configFile = configFile
+	cdd.current = running
+	alerts := recordDriftAlerts(cdd)
+	cdd.CheckFiles()
+
+	require.Len(t, alerts, 2)
+	port := alerts["server.port"]
+	assert.Equal(t, DriftSourceFile, port.Source)
+	assert.Equal(t, configFile, port.File)
+	assert.Equal(t, DriftConfiguration, port.DriftType)
+	assert.Equal(t, AlertWarning, port.Severity)
+	assert.JSONEq(t, "9000", string(port.Expected.(json.RawMessage)))
+	assert.JSONEq(t, "9100", string(port.Actual.(json.RawMessage)))
+
+	secret := alerts["security.auth.jwt_secret"]
+	assert.Equal(t, DriftSecurity, secret.DriftType)
+	assert.Equal(t, AlertCritical, secret.Severity)
+	assert.NotContains(t, string(secret.Actual.(json.RawMessage)), "in-memory", "secrets are redacted")
+
+	cdd.configFile = ""
+	cdd.baselineFile = baseline
+	alerts = recordDriftAlerts(cdd)
+	cdd.CheckFiles()
+	require.Contains(t, alerts, "server.tls.enabled")
+	assert.Equal(t, DriftSourceBaseline, alerts["server.tls.enabled"].Source)
+	assert.Equal(t, AlertCritical, alerts["server.tls.enabled"].Severity)
+}
+
+func TestSendDriftAlertSelectsChannels(t *testing.T) {
+	log := &recordingChannel{name: AlertChannelLog}
+	webhook := &recordingChannel{name: AlertChannelWebhook}
+	am := newAlertManager()
+	am.AddChannel(log)
+	am.AddChannel(webhook)
+	am.SetDriftRules([]AlertRule{
+		{Name: "everything", Channels: []string{AlertChannelLog}},
+		{Name: "security", Severity: AlertCritical, DriftTypes: []DriftType{DriftSecurity}, Channels: []string{AlertChannelWebhook}, Cooldown: time.Hour},
+		{Name: "server", Paths: []string{"server"}, Channels: []string{AlertChannelLog}},
+	})
+
+	tls := DriftAlert{
+		Source:    DriftSourceBaseline,
+		File:      "approved.yaml",
+		Component: "server.tls.enabled",
+		DriftType: DriftSecurity,
+		Severity:  AlertCritical,
+		Expected:  json.RawMessage("true"),
+		Actual:    json.RawMessage("false"),
+		Metadata:  map[string]interface{}{"environment": "production"},
+	}
+	am.SendDriftAlert(tls)
+	require.Len(t, log.alerts, 1, "a channel selected by several rules gets the alert once")
+	require.Len(t, webhook.alerts, 1)
+	alert := webhook.alerts[0]
+	assert.Equal(t, "server.tls.enabled", alert.Component)
+	assert.Equal(t, "production", alert.Environment)
+	assert.Equal(t, "server.tls.enabled is false in the running config but true in the approved baseline approved.yaml", alert.Message)
+
+	am.SendDriftAlert(tls)
+	assert.Len(t, webhook.alerts, 1, "the drift is not sent again within the cooldown")
+	assert.Len(t, log.alerts, 2)
+
+	am.SendDriftAlert(DriftAlert{Component: "cache.ttl", DriftType: DriftResource, Severity: AlertInfo})
+	assert.Len(t, log.alerts, 3)
+	assert.Len(t, webhook.alerts, 1, "the security rule does not send other drift")
+}
+
+func TestFileChannel(t *testing.T) {
+	channel, err := NewFileChannel(filepath.Join(t.TempDir(), "alerts", "config.jsonl"))
+	require.NoError(t, err)
+	require.NoError(t, channel.SendAlert(Alert{ID: "1", Severity: AlertWarning}))
+	require.NoError(t, channel.SendAlert(Alert{ID: "2", Severity: AlertCritical}))
+
+	f, err := os.Open(channel.Path())
+	require.NoError(t, err)
+	defer f.Close()
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.Len(t, lines, 2)
+	assert.Equal(t, "2", lines[1]["id"])
+	assert.Equal(t, "critical", lines[1]["severity"])
+}
+
+func TestWebhookChannel(t *testing.T) {
+	received := make(chan Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		body, _ := io.ReadAll(r.Body)
+		var alert Alert
+		assert.NoError(t, json.Unmarshal(body, &alert))
+		received <- alert
+	}))
+	defer server.Close()
+
+	channel, err := NewWebhookChannel(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, channel.SendAlert(Alert{ID: "1", Component: "server.port"}))
+	assert.Equal(t, "server.port", (<-received).Component)
+
+	_, err = NewWebhookChannel("https://alerts.example.com/hook")
+	assert.Error(t, err, "webhooks must be local")
+	_, err = NewWebhookChannel("ftp://localhost/hook")
+	assert.Error(t, err)
+}
+
+func TestDriftRules(t *testing.T) {
+	rules, err := driftRules(DriftConfig{Rules: []AlertRuleConfig{
+		{Severity: "warning", Types: []string{"security", "Resource"}, Channels: []string{"log", "tui"}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "rule_1", rules[0].Name)
+	assert.Equal(t, AlertWarning, rules[0].Severity)
+	assert.Equal(t, []DriftType{DriftSecurity, DriftResource}, rules[0].DriftTypes)
+	assert.Equal(t, defaultAlertCooldown, rules[0].Cooldown)
+
+	_, err = driftRules(DriftConfig{Rules: []AlertRuleConfig{{Name: "pager", Channels: []string{"pager"}}}})
+	assert.ErrorContains(t, err, `unknown channel "pager"`)
+	_, err = driftRules(DriftConfig{Rules: []AlertRuleConfig{{Name: "hook", Channels: []string{"webhook"}}}})
+	assert.ErrorContains(t, err, "webhook_url")
+	_, err = driftRules(DriftConfig{Rules: []AlertRuleConfig{{Severity: "loud"}}})
+	assert.Error(t, err)
+}
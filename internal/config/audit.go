@@ -0,0 +1,566 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// auditTailChunk is how much of the end of the log is read at a time to find its
+// last entry
+const auditTailChunk = 4096
+
+// AuditSource is where a configuration change came from
+type AuditSource string
+
+const (
+	AuditSourceAPI    AuditSource = "api"
+	AuditSourceFile   AuditSource = "file"
+	AuditSourceCLI    AuditSource = "cli"
+	AuditSourceTenant AuditSource = "tenant"
+	// AuditSourcePrune marks the checkpoint that replaces pruned entries
+	AuditSourcePrune AuditSource = "prune"
+)
+
+// FieldChange is the old and new value of a config key as JSON, null where the key
+// was missing. Secrets are redacted.
+type FieldChange struct {
+	Path string          `json:"path"`
+	Old  json.RawMessage `json:"old"`
+	New  json.RawMessage `json:"new"`
+}
+
+// ConfigChange is an entry of the audit log. Its hash covers every other field,
+// including the hash of the entry before it, so editing, removing, inserting or
+// reordering entries breaks the chain.
+type ConfigChange struct {
+	Sequence  int64         `json:"sequence"`
+	Timestamp time.Time     `json:"timestamp"`
+	Actor     string        `json:"actor"`
+	Source    AuditSource   `json:"source"`
+	Target    string        `json:"target,omitempty"`
+	Version   string        `json:"version,omitempty"`
+	Changes   []FieldChange `json:"changes"`
+	PrevHash  string        `json:"prev_hash"`
+	Hash      string        `json:"hash"`
+
+	// Checkpoint is set on the entry that starts a pruned log
+	Checkpoint *AuditCheckpoint `json:"checkpoint,omitempty"`
+}
+
+// AuditCheckpoint records the last entry removed by pruning. The first entry kept
+// is chained to it, so a pruned log still verifies from its start.
+type AuditCheckpoint struct {
+	Sequence int64  `json:"sequence"`
+	Hash     string `json:"hash"`
+}
+
+// chainHash is the hash the entry after this one is chained to
+func (c ConfigChange) chainHash() string {
+	if c.Checkpoint != nil {
+		return c.Checkpoint.Hash
+	}
+	return c.Hash
+}
+
+// AuditLog is an append-only JSONL log of configuration changes. Entries older than
+// the retention are pruned, which is the only way entries leave the log, and are
+// replaced by a checkpoint the remaining entries are chained to.
+type AuditLog struct {
+	path      string
+	retention time.Duration
+	mu        sync.Mutex
+
+	// lastSequence and lastHash are where the next entry is chained, kept from
+	// OpenAuditLog on so entries removed from the end of the file are not reused
+	lastSequence int64
+	lastHash     string
+	// oldest is the time of the oldest change in the log
+	oldest time.Time
+}
+
+// AuditVerification is the result of verifying an audit log
+type AuditVerification struct {
+	Path          string `json:"path"`
+	Entries       int    `json:"entries"`
+	FirstSequence int64  `json:"first_sequence,omitempty"`
+	LastSequence  int64  `json:"last_sequence,omitempty"`
+	// LastHash identifies the whole log; keeping a copy elsewhere also reveals
+	// entries removed from its end
+	LastHash string `json:"last_hash,omitempty"`
+	// PrunedThrough is the last entry pruned from the log, if any
+	PrunedThrough int64 `json:"pruned_through,omitempty"`
+	Valid         bool  `json:"valid"`
+	// Line and Problem describe the first entry that breaks the chain
+	Line    int    `json:"line,omitempty"`
+	Problem string `json:"problem,omitempty"`
+}
+
+// DefaultAuditLogPath is the audit log under the data directory
+func DefaultAuditLogPath() string {
+	return filepath.Join(DataDirectory(), "audit", "config.jsonl")
+}
+
+// AuditActor is who changes are recorded for: $SUPERCLAUDE_ACTOR, or the current
+// user
+func AuditActor() string {
+	if actor := os.Getenv("SUPERCLAUDE_ACTOR"); actor != "" {
+		return actor
+	}
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	return "unknown"
+}
+
+// OpenAuditLog opens the audit log at path, creating its directory, and prunes the
+// entries older than retention. A zero retention keeps every entry.
+func OpenAuditLog(path string, retention time.Duration) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	l := &AuditLog{path: path, retention: retention}
+	unlock, err := l.lockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	entries, err := l.prune()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		l.lastSequence = last.Sequence
+		l.lastHash = last.chainHash()
+	}
+	return l, nil
+}
+
+// Path returns the file the log is stored in
+func (l *AuditLog) Path() string {
+	return l.path
+}
+
+// Record appends an entry with changes to the log. Nothing is recorded when there
+// are no changes, and nil is returned.
+func (l *AuditLog) Record(actor string, source AuditSource, target, version string, changes []FieldChange) (*ConfigChange, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	unlock, err := l.lockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if l.retention > 0 && !l.oldest.IsZero() && l.oldest.Before(time.Now().Add(-l.retention)) {
+		if _, err := l.prune(); err != nil {
+			return nil, err
+		}
+	}
+	// Other processes append to the same log, chain to the last entry in the file
+	// unless entries were removed from its end
+	last, err := l.readLastEntry()
+	if err != nil {
+		return nil, err
+	}
+	if last != nil && last.Sequence >= l.lastSequence {
+		l.lastSequence = last.Sequence
+		l.lastHash = last.chainHash()
+	}
+	entry := ConfigChange{
+		Sequence:  l.lastSequence + 1,
+		Timestamp: time.Now().UTC(),
+		Actor:     actor,
+		Source:    source,
+		Target:    target,
+		Version:   version,
+		Changes:   changes,
+		PrevHash:  l.lastHash,
+	}
+	entry.Hash = hashConfigChange(entry)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	l.lastSequence = entry.Sequence
+	l.lastHash = entry.Hash
+	if l.oldest.IsZero() {
+		l.oldest = entry.Timestamp
+	}
+	return &entry, nil
+}
+
+// Entries returns every entry of the log, oldest first, starting with the
+// checkpoint of a pruned log
+func (l *AuditLog) Entries() ([]ConfigChange, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.readEntries()
+}
+
+// History returns up to limit changes, newest first. A limit of 0 or less returns
+// every change.
+func (l *AuditLog) History(limit int) ([]ConfigChange, error) {
+	entries, err := l.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 && entries[0].Checkpoint != nil {
+		entries = entries[1:]
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	history := make([]ConfigChange, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		history = append(history, entries[i])
+	}
+	return history, nil
+}
+
+// Prune removes the changes older than the retention and returns how many it
+// removed. The chain stays valid from the checkpoint that replaces them.
+func (l *AuditLog) Prune() (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	unlock, err := l.lockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	before, err := l.readEntries()
+	if err != nil {
+		return 0, err
+	}
+	after, err := l.prune()
+	if err != nil {
+		return 0, err
+	}
+	return countChanges(before) - countChanges(after), nil
+}
+
+// countChanges returns the number of entries that are not checkpoints
+func countChanges(entries []ConfigChange) int {
+	if len(entries) > 0 && entries[0].Checkpoint != nil {
+		return len(entries) - 1
+	}
+	return len(entries)
+}
+
+// Verify checks that every entry is intact and chained to the one before it, and
+// that the log starts with entry 1 or with the checkpoint of a prune
+func (l *AuditLog) Verify() (*AuditVerification, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &AuditVerification{Path: l.path, Valid: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := &AuditVerification{Path: l.path, Valid: true}
+	broken := func(line int, format string, args ...interface{}) {
+		result.Valid = false
+		result.Line = line
+		result.Problem = fmt.Sprintf(format, args...)
+	}
+
+	var previous *ConfigChange
+	err = scanAuditLog(f, func(line int, data []byte) error {
+		if !result.Valid {
+			return nil
+		}
+		var entry ConfigChange
+		if err := json.Unmarshal(data, &entry); err != nil {
+			broken(line, "entry is not valid JSON: %v", err)
+			return nil
+		}
+		checkpoint := entry.Checkpoint
+		switch {
+		case entry.Hash != hashConfigChange(entry):
+			broken(line, "entry %d was modified: its hash does not match its content", entry.Sequence)
+		case checkpoint != nil && previous != nil:
+			broken(line, "checkpoint of entry %d is not at the start of the log", checkpoint.Sequence)
+		case checkpoint != nil && (checkpoint.Sequence < 1 || checkpoint.Sequence != entry.Sequence || checkpoint.Hash == "" || entry.PrevHash != ""):
+			broken(line, "checkpoint of entry %d is malformed", checkpoint.Sequence)
+		case checkpoint == nil && previous == nil && entry.Sequence != 1:
+			broken(line, "log starts at entry %d without a checkpoint: entries were removed from its start", entry.Sequence)
+		case checkpoint == nil && previous == nil && entry.PrevHash != "":
+			broken(line, "entry 1 is chained to a previous entry")
+		case previous != nil && entry.Sequence != previous.Sequence+1:
+			broken(line, "entry %d follows entry %d: entries were removed or reordered", entry.Sequence, previous.Sequence)
+		case previous != nil && entry.PrevHash != previous.chainHash():
+			broken(line, "entry %d is not chained to entry %d", entry.Sequence, previous.Sequence)
+		}
+		if !result.Valid {
+			return nil
+		}
+
+		previous = &entry
+		result.LastSequence = entry.Sequence
+		result.LastHash = entry.chainHash()
+		if checkpoint != nil {
+			result.PrunedThrough = checkpoint.Sequence
+			return nil
+		}
+		if result.Entries == 0 {
+			result.FirstSequence = entry.Sequence
+		}
+		result.Entries++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// prune rewrites the log without the changes older than the retention, starting
+// it with a checkpoint of the last change removed, and returns the entries kept.
+// The log must be locked.
+func (l *AuditLog) prune() ([]ConfigChange, error) {
+	entries, err := l.readEntries()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		l.oldest = time.Time{}
+		if changes := entries[len(entries)-countChanges(entries):]; len(changes) > 0 {
+			l.oldest = changes[0].Timestamp
+		}
+	}()
+	if l.retention <= 0 || countChanges(entries) == 0 {
+		return entries, nil
+	}
+
+	// The checkpoint is as recent as the prune, only the changes after it age
+	start := len(entries) - countChanges(entries)
+	cutoff := time.Now().Add(-l.retention)
+	keep := start
+	for keep < len(entries) && entries[keep].Timestamp.Before(cutoff) {
+		keep++
+	}
+	if keep == start {
+		return entries, nil
+	}
+
+	last := entries[keep-1]
+	checkpoint := ConfigChange{
+		Sequence:   last.Sequence,
+		Timestamp:  time.Now().UTC(),
+		Actor:      AuditActor(),
+		Source:     AuditSourcePrune,
+		Checkpoint: &AuditCheckpoint{Sequence: last.Sequence, Hash: last.Hash},
+	}
+	checkpoint.Hash = hashConfigChange(checkpoint)
+	entries = append([]ConfigChange{checkpoint}, entries[keep:]...)
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	// Replace the log atomically so a failed prune cannot lose entries
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// lockFile takes an exclusive lock shared with the other processes writing the log.
+// The lock is held on a separate file, as pruning replaces the log.
+func (l *AuditLog) lockFile() (func(), error) {
+	f, err := os.OpenFile(l.path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// readLastEntry parses the last entry of the log, nil when it is empty. The log
+// must be locked.
+func (l *AuditLog) readLastEntry() (*ConfigChange, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read backwards until the chunk holds the whole last line
+	var tail []byte
+	for end := info.Size(); end > 0; {
+		start := max(end-auditTailChunk, 0)
+		chunk := make([]byte, end-start)
+		if _, err := f.ReadAt(chunk, start); err != nil {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+		end = start
+		trimmed := bytes.TrimRight(tail, " \t\r\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 || start == 0 {
+			line := bytes.TrimSpace(trimmed[i+1:])
+			if len(line) == 0 {
+				return nil, nil
+			}
+			var entry ConfigChange
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return nil, fmt.Errorf("last entry of audit log %s is corrupt, run superclaude-config audit verify: %w", l.path, err)
+			}
+			return &entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// readEntries parses the log. The log must be locked.
+func (l *AuditLog) readEntries() ([]ConfigChange, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []ConfigChange
+	err = scanAuditLog(f, func(line int, data []byte) error {
+		var entry ConfigChange
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("audit log %s line %d is corrupt, run superclaude-config audit verify: %w", l.path, line, err)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// scanAuditLog calls fn with every non-empty line of r and its line number
+func scanAuditLog(r io.Reader, fn func(line int, data []byte) error) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 {
+			if err := fn(line, trimmed); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// hashConfigChange returns the SHA-256 of entry without its hash
+func hashConfigChange(entry ConfigChange) string {
+	entry.Hash = ""
+	data, _ := json.Marshal(entry)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewFieldChange returns the change of the config key at path from old to new, with
+// the values redacted when the key is a secret
+func NewFieldChange(path string, old, new interface{}) FieldChange {
+	return FieldChange{
+		Path: path,
+		Old:  auditValue(path, old),
+		New:  auditValue(path, new),
+	}
+}
+
+// fieldChanges converts diffs to audit log changes
+func fieldChanges(diffs []configDiff) []FieldChange {
+	changes := make([]FieldChange, 0, len(diffs))
+	for _, diff := range diffs {
+		changes = append(changes, NewFieldChange(diff.path, diff.old, diff.new))
+	}
+	return changes
+}
+
+func auditValue(path string, value interface{}) json.RawMessage {
+	if isSecretPath(path) && value != nil && !reflect.ValueOf(value).IsZero() {
+		value = redactedValue
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	return data
+}
@@ -0,0 +1,333 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuditLog(t *testing.T, retention time.Duration) *AuditLog {
+	t.Helper()
+	log, err := OpenAuditLog(filepath.Join(t.TempDir(), "audit", "config.jsonl"), retention)
+	require.NoError(t, err)
+	return log
+}
+
+func recordTestChanges(t *testing.T, log *AuditLog, n int) {
+	t.Helper()
+	for i := range n {
+		_, err := log.Record("alice", AuditSourceAPI, "", "", []FieldChange{NewFieldChange("performance.batch_size", i, i+1)})
+		require.NoError(t, err)
+	}
+}
+
+// rewriteAuditLog replaces the lines of the log with the result of edit
+func rewriteAuditLog(t *testing.T, log *AuditLog, edit func(lines [][]byte) [][]byte) {
+	t.Helper()
+	data, err := os.ReadFile(log.Path())
+	require.NoError(t, err)
+	lines := edit(bytes.Split(bytes.TrimSpace(data), []byte("\n")))
+	require.NoError(t, os.WriteFile(log.Path(), append(bytes.Join(lines, []byte("\n")), '\n'), 0o600))
+}
+
+func TestAuditLogRecordsChain(t *testing.T) {
+	log := newTestAuditLog(t, 0)
+
+	entry, err := log.Record("alice", AuditSourceAPI, "", "", nil)
+	require.NoError(t, err)
+	assert.Nil(t, entry, "nothing is recorded without changes")
+
+	recordTestChanges(t, log, 3)
+	entries, err := log.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Empty(t, entries[0].PrevHash)
+	for i, entry := range entries {
+		assert.Equal(t, int64(i+1), entry.Sequence)
+		assert.Equal(t, "alice", entry.Actor)
+		if i > 0 {
+			assert.Equal(t, entries[i-1].Hash, entry.PrevHash)
+		}
+	}
+
+	history, err := log.History(2)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, int64(3), history[0].Sequence)
+	assert.Equal(t, int64(2), history[1].Sequence)
+
+	result, err := log.Verify()
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 3, result.Entries)
+	assert.Equal(t, entries[2].Hash, result.LastHash)
+}
+
+func TestAuditLogVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(lines [][]byte) [][]byte
+		line    int
+		problem string
+	}{
+		{
+			name: "edited entry",
+			edit: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte(`"actor":"alice"`), []byte(`"actor":"bob"`), 1)
+				return lines
+			},
+			line:    2,
+			problem: "entry 2 was modified",
+		},
+		{
+			name: "removed entry",
+			edit: func(lines [][]byte) [][]byte {
+				return append(lines[:1], lines[2:]...)
+			},
+			line:    2,
+			problem: "entry 3 follows entry 1",
+		},
+		{
+			name: "reordered entries",
+			edit: func(lines [][]byte) [][]byte {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			line:    2,
+			problem: "entry 3 follows entry 1",
+		},
+		{
+			name: "garbage",
+			edit: func(lines [][]byte) [][]byte {
+				return append(lines, []byte("{not json"))
+			},
+			line:    4,
+			problem: "not valid JSON",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := newTestAuditLog(t, 0)
+			recordTestChanges(t, log, 3)
+			rewriteAuditLog(t, log, tt.edit)
+
+			result, err := log.Verify()
+			require.NoError(t, err)
+			assert.False(t, result.Valid)
+			assert.Equal(t, tt.line, result.Line)
+			assert.Contains(t, result.Problem, tt.problem)
+		})
+	}
+}
+
+func TestAuditLogVerifyDetectsRehashedEntry(t *testing.T) {
+	log := newTestAuditLog(t, 0)
+	recordTestChanges(t, log, 3)
+
+	// Recomputing the hash of an edited entry breaks the link from the next one
+	rewriteAuditLog(t, log, func(lines [][]byte) [][]byte {
+		var entry ConfigChange
+		require.NoError(t, json.Unmarshal(lines[1], &entry))
+		entry.Actor = "bob"
+		entry.Hash = hashConfigChange(entry)
+		lines[1], _ = json.Marshal(entry)
+		return lines
+	})
+
+	result, err := log.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, 3, result.Line)
+	assert.Equal(t, "entry 3 is not chained to entry 2", result.Problem)
+}
+
+func TestAuditLogRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.jsonl")
+	var lines [][]byte
+	var previous string
+	for i, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, time.Hour} {
+		entry := ConfigChange{
+			Sequence:  int64(i + 1),
+			Timestamp: time.Now().Add(-age).UTC(),
+			Actor:     "alice",
+			Source:    AuditSourceFile,
+			Changes:   []FieldChange{NewFieldChange("server.port", 8080, 9090+i)},
+			PrevHash:  previous,
+		}
+		entry.Hash = hashConfigChange(entry)
+		previous = entry.Hash
+		line, err := json.Marshal(entry)
+		require.NoError(t, err)
+		lines = append(lines, line)
+	}
+	require.NoError(t, os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0o600))
+
+	log, err := OpenAuditLog(path, 24*time.Hour)
+	require.NoError(t, err)
+	entries, err := log.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	checkpoint := entries[0]
+	require.NotNil(t, checkpoint.Checkpoint)
+	assert.Equal(t, AuditSourcePrune, checkpoint.Source)
+	assert.Equal(t, int64(2), checkpoint.Checkpoint.Sequence)
+	assert.Equal(t, entries[1].PrevHash, checkpoint.Checkpoint.Hash)
+	assert.Equal(t, int64(3), entries[1].Sequence)
+
+	history, err := log.History(0)
+	require.NoError(t, err)
+	require.Len(t, history, 1, "the checkpoint is not a change")
+
+	recordTestChanges(t, log, 1)
+	result, err := log.Verify()
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Problem)
+	assert.Equal(t, int64(2), result.PrunedThrough)
+	assert.Equal(t, int64(3), result.FirstSequence)
+	assert.Equal(t, int64(4), result.LastSequence)
+	assert.Equal(t, 2, result.Entries)
+
+	// Pruning again replaces the checkpoint, still chained to what follows
+	rewriteAuditLog(t, log, func(lines [][]byte) [][]byte {
+		var entry ConfigChange
+		require.NoError(t, json.Unmarshal(lines[1], &entry))
+		require.Equal(t, int64(3), entry.Sequence)
+		entry.Timestamp = time.Now().Add(-30 * time.Hour).UTC()
+		entry.Hash = hashConfigChange(entry)
+		lines[1], _ = json.Marshal(entry)
+		var next ConfigChange
+		require.NoError(t, json.Unmarshal(lines[2], &next))
+		next.PrevHash = entry.Hash
+		next.Hash = hashConfigChange(next)
+		lines[2], _ = json.Marshal(next)
+		return lines
+	})
+	removed, err := log.Prune()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	result, err = log.Verify()
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Problem)
+	assert.Equal(t, int64(3), result.PrunedThrough)
+	assert.Equal(t, 1, result.Entries)
+}
+
+func TestAuditLogVerifyRequiresStart(t *testing.T) {
+	log := newTestAuditLog(t, 0)
+	recordTestChanges(t, log, 3)
+
+	rewriteAuditLog(t, log, func(lines [][]byte) [][]byte {
+		return lines[1:]
+	})
+	result, err := log.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, 1, result.Line)
+	assert.Contains(t, result.Problem, "log starts at entry 2 without a checkpoint")
+}
+
+func TestAuditLogRecordChainsToRecordedEntries(t *testing.T) {
+	log := newTestAuditLog(t, 0)
+	recordTestChanges(t, log, 3)
+
+	// Entries removed from the end are not silently reused by the next record
+	rewriteAuditLog(t, log, func(lines [][]byte) [][]byte {
+		return lines[:2]
+	})
+	entry, err := log.Record("alice", AuditSourceAPI, "", "", []FieldChange{NewFieldChange("server.port", 1, 2)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), entry.Sequence)
+
+	result, err := log.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, "entry 4 follows entry 2: entries were removed or reordered", result.Problem)
+}
+
+func TestAuditLogWritersShareChain(t *testing.T) {
+	first := newTestAuditLog(t, 0)
+	second, err := OpenAuditLog(first.Path(), 0)
+	require.NoError(t, err)
+
+	// Each writer chains to the entries of the other instead of forking the chain
+	for i := range 4 {
+		log := first
+		if i%2 == 1 {
+			log = second
+		}
+		entry, err := log.Record("alice", AuditSourceAPI, "", "", []FieldChange{NewFieldChange("server.port", i, i+1)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(i+1), entry.Sequence)
+	}
+
+	result, err := first.Verify()
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Problem)
+	assert.Equal(t, 4, result.Entries)
+}
+
+func TestConfigManagerAuditsUpdates(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "superclaude.yaml"), []byte(testSuperClaudeConfig), 0o644))
+	path := filepath.Join(dir, "audit.jsonl")
+	cm, err := NewConfigManager(dir, WithHotReload(false), WithAuditLogging(path, 0))
+	require.NoError(t, err)
+	defer cm.Close()
+
+	require.NoError(t, cm.UpdateConfigAs("alice", AuditSourceCLI, map[string]interface{}{
+		"server.timeout":                 "45s",
+		"providers.openai.api_key":       "sk-new",
+		"logging.components.cache":       "debug",
+		"performance.batch_size":         10, // unchanged
+		"providers.anthropic.api_key":    "", // unchanged
+		"logging.components.superclaude": nil,
+	}))
+	require.Error(t, cm.UpdateConfig(map[string]interface{}{"server.port": 0}))
+
+	history, err := cm.GetConfigHistory(0)
+	require.NoError(t, err)
+	require.Len(t, history, 1, "rejected updates are not recorded")
+	entry := history[0]
+	assert.Equal(t, "alice", entry.Actor)
+	assert.Equal(t, AuditSourceCLI, entry.Source)
+
+	changes := make(map[string][2]string)
+	for _, change := range entry.Changes {
+		changes[change.Path] = [2]string{string(change.Old), string(change.New)}
+	}
+	assert.Equal(t, map[string][2]string{
+		"server.timeout":           {`"30s"`, `"45s"`},
+		"providers.openai.api_key": {`"[REDACTED]"`, `"[REDACTED]"`},
+		"logging.components.cache": {`null`, `"debug"`},
+	}, changes)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-")
+}
+
+func TestTenantOverridesAudited(t *testing.T) {
+	log := newTestAuditLog(t, 0)
+	mtcm := NewMultiTenantConfigManager(&SuperClaudeConfig{}, IsolationDedicated)
+	mtcm.SetAuditLog(log)
+	_, err := mtcm.CreateTenant("acme", "ACME", nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, mtcm.UpdateTenantConfig("acme", map[string]interface{}{"performance.batch_size": 5}))
+	require.NoError(t, mtcm.UpdateTenantConfig("acme", map[string]interface{}{"performance.batch_size": 5}))
+	require.NoError(t, mtcm.BulkUpdateTenants([]string{"acme"}, map[string]interface{}{"security.auth.jwt_secret": "s3cret"}))
+
+	entries, err := log.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "unchanged overrides are not recorded")
+	assert.Equal(t, AuditSourceTenant, entries[0].Source)
+	assert.Equal(t, "acme", entries[0].Target)
+	assert.Equal(t, []FieldChange{NewFieldChange("performance.batch_size", nil, 5)}, entries[0].Changes)
+	assert.Equal(t, `"[REDACTED]"`, string(entries[1].Changes[0].New))
+}
@@ -54,6 +54,10 @@ type Agent struct {
 type Provider struct {
 	APIKey   string `json:"apiKey"`
 	Disabled bool   `json:"disabled"`
+
+	// RequestsPerMinute bounds the requests sent by all agents, 0 is unlimited
+	RequestsPerMinute int `json:"requestsPerMinute,omitempty"`
+	Burst             int `json:"burst,omitempty"`
 }
 
 // Data defines storage configuration.
@@ -94,6 +98,7 @@ type Config struct {
 	TUI          TUIConfig                         `json:"tui"`
 	Shell        ShellConfig                       `json:"shell,omitempty"`
 	AutoCompact  bool                              `json:"autoCompact,omitempty"`
+	Snapshots    bool                              `json:"snapshots,omitempty"`
 }
 
 // Application constants
@@ -232,6 +237,7 @@ func setDefaults(debug bool) {
 	viper.SetDefault("contextPaths", defaultContextPaths)
 	viper.SetDefault("tui.theme", "opencode")
 	viper.SetDefault("autoCompact", true)
+	viper.SetDefault("snapshots", true)
 
 	// Set default shell from environment or fallback to /bin/bash
 	shellPath := os.Getenv("SHELL")
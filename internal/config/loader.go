@@ -1,32 +1,39 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 // SuperClaudeConfig represents the complete configuration
 type SuperClaudeConfig struct {
-	Server      ServerConfig      `mapstructure:"server"`
-	MCP         MCPConfig         `mapstructure:"mcp"`
-	Providers   ProvidersConfig   `mapstructure:"providers"`
-	Database    DatabaseConfig    `mapstructure:"database"`
-	Cache       CacheConfig       `mapstructure:"cache"`
-	Performance PerformanceConfig `mapstructure:"performance"`
-	RateLimit   RateLimitConfig   `mapstructure:"rate_limiting"`
-	Security    SecurityConfig    `mapstructure:"security"`
-	Logging     LoggingConfig     `mapstructure:"logging"`
-	Monitoring  MonitoringConfig  `mapstructure:"monitoring"`
-	SuperClaude SuperClaudeSpecificConfig `mapstructure:"superclaude"`
-	IDE         IDEConfig         `mapstructure:"ide"`
-	Features    FeaturesConfig    `mapstructure:"features"`
-	Development DevelopmentConfig `mapstructure:"development"`
-	Deployment  DeploymentConfig  `mapstructure:"deployment"`
+	// SchemaVersion is the schema version the file was written for, see CurrentSchemaVersion
+	SchemaVersion int                       `mapstructure:"schema_version"`
+	Server        ServerConfig              `mapstructure:"server"`
+	MCP           MCPConfig                 `mapstructure:"mcp"`
+	Providers     ProvidersConfig           `mapstructure:"providers"`
+	Database      DatabaseConfig            `mapstructure:"database"`
+	Cache         CacheConfig               `mapstructure:"cache"`
+	Performance   PerformanceConfig         `mapstructure:"performance"`
+	RateLimit     RateLimitConfig           `mapstructure:"rate_limiting"`
+	Security      SecurityConfig            `mapstructure:"security"`
+	Logging       LoggingConfig             `mapstructure:"logging"`
+	Monitoring    MonitoringConfig          `mapstructure:"monitoring"`
+	SuperClaude   SuperClaudeSpecificConfig `mapstructure:"superclaude"`
+	IDE           IDEConfig                 `mapstructure:"ide"`
+	Features      FeaturesConfig            `mapstructure:"features"`
+	Development   DevelopmentConfig         `mapstructure:"development"`
+	Deployment    DeploymentConfig          `mapstructure:"deployment"`
 }
 
 type ServerConfig struct {
@@ -44,17 +51,20 @@ type TLSConfig struct {
 }
 
 type MCPConfig struct {
-	Enabled   bool              `mapstructure:"enabled"`
-	Host      string            `mapstructure:"host"`
-	Port      int               `mapstructure:"port"`
-	WebSocket WebSocketConfig   `mapstructure:"websocket"`
-	CORS      CORSConfig        `mapstructure:"cors"`
+	Enabled   bool            `mapstructure:"enabled"`
+	Host      string          `mapstructure:"host"`
+	Port      int             `mapstructure:"port"`
+	WebSocket WebSocketConfig `mapstructure:"websocket"`
+	CORS      CORSConfig      `mapstructure:"cors"`
 }
 
 type WebSocketConfig struct {
-	ReadBufferSize  int  `mapstructure:"read_buffer_size"`
-	WriteBufferSize int  `mapstructure:"write_buffer_size"`
-	CheckOrigin     bool `mapstructure:"check_origin"`
+	ReadBufferSize  int           `mapstructure:"read_buffer_size"`
+	WriteBufferSize int           `mapstructure:"write_buffer_size"`
+	CheckOrigin     bool          `mapstructure:"check_origin"`
+	PingInterval    time.Duration `mapstructure:"ping_interval"`
+	PongTimeout     time.Duration `mapstructure:"pong_timeout"`
+	MaxInFlight     int           `mapstructure:"max_in_flight"`
 }
 
 type CORSConfig struct {
@@ -67,11 +77,11 @@ type CORSConfig struct {
 }
 
 type ProvidersConfig struct {
-	Default    string                         `mapstructure:"default"`
-	OpenRouter ProviderConfig                 `mapstructure:"openrouter"`
-	OpenAI     ProviderConfig                 `mapstructure:"openai"`
-	Anthropic  ProviderConfig                 `mapstructure:"anthropic"`
-	Ollama     ProviderConfig                 `mapstructure:"ollama"`
+	Default    string         `mapstructure:"default"`
+	OpenRouter ProviderConfig `mapstructure:"openrouter"`
+	OpenAI     ProviderConfig `mapstructure:"openai"`
+	Anthropic  ProviderConfig `mapstructure:"anthropic"`
+	Ollama     ProviderConfig `mapstructure:"ollama"`
 }
 
 type ProviderConfig struct {
@@ -92,11 +102,11 @@ type DatabaseConfig struct {
 }
 
 type SQLiteConfig struct {
-	Path               string        `mapstructure:"path"`
-	MaxConnections     int           `mapstructure:"max_connections"`
-	BusyTimeout        time.Duration `mapstructure:"busy_timeout"`
-	JournalMode        string        `mapstructure:"journal_mode"`
-	Synchronous        string        `mapstructure:"synchronous"`
+	Path           string        `mapstructure:"path"`
+	MaxConnections int           `mapstructure:"max_connections"`
+	BusyTimeout    time.Duration `mapstructure:"busy_timeout"`
+	JournalMode    string        `mapstructure:"journal_mode"`
+	Synchronous    string        `mapstructure:"synchronous"`
 }
 
 type PostgresConfig struct {
@@ -123,13 +133,13 @@ type MySQLConfig struct {
 }
 
 type CacheConfig struct {
-	Enabled         bool             `mapstructure:"enabled"`
-	Type            string           `mapstructure:"type"`
-	TTL             time.Duration    `mapstructure:"ttl"`
-	MaxSize         int              `mapstructure:"max_size"`
-	CleanupInterval time.Duration    `mapstructure:"cleanup_interval"`
-	Redis           RedisConfig      `mapstructure:"redis"`
-	Memcached       MemcachedConfig  `mapstructure:"memcached"`
+	Enabled         bool            `mapstructure:"enabled"`
+	Type            string          `mapstructure:"type"`
+	TTL             time.Duration   `mapstructure:"ttl"`
+	MaxSize         int             `mapstructure:"max_size"`
+	CleanupInterval time.Duration   `mapstructure:"cleanup_interval"`
+	Redis           RedisConfig     `mapstructure:"redis"`
+	Memcached       MemcachedConfig `mapstructure:"memcached"`
 }
 
 type RedisConfig struct {
@@ -145,21 +155,21 @@ type RedisConfig struct {
 }
 
 type MemcachedConfig struct {
-	Servers              []string      `mapstructure:"servers"`
-	Timeout              time.Duration `mapstructure:"timeout"`
-	MaxIdleConnections   int           `mapstructure:"max_idle_connections"`
+	Servers            []string      `mapstructure:"servers"`
+	Timeout            time.Duration `mapstructure:"timeout"`
+	MaxIdleConnections int           `mapstructure:"max_idle_connections"`
 }
 
 type PerformanceConfig struct {
-	WorkerPoolSize         int           `mapstructure:"worker_pool_size"`
-	BatchSize              int           `mapstructure:"batch_size"`
-	BatchDelay             time.Duration `mapstructure:"batch_delay"`
-	MaxConcurrentRequests  int           `mapstructure:"max_concurrent_requests"`
-	RequestTimeout         time.Duration `mapstructure:"request_timeout"`
-	UltraCompressedRatio   float64       `mapstructure:"ultra_compressed_ratio"`
-	ThinkingTokens         ThinkingTokensConfig `mapstructure:"thinking_tokens"`
-	MaxMemoryMB            int           `mapstructure:"max_memory_mb"`
-	MaxGoroutines          int           `mapstructure:"max_goroutines"`
+	WorkerPoolSize        int                  `mapstructure:"worker_pool_size"`
+	BatchSize             int                  `mapstructure:"batch_size"`
+	BatchDelay            time.Duration        `mapstructure:"batch_delay"`
+	MaxConcurrentRequests int                  `mapstructure:"max_concurrent_requests"`
+	RequestTimeout        time.Duration        `mapstructure:"request_timeout"`
+	UltraCompressedRatio  float64              `mapstructure:"ultra_compressed_ratio"`
+	ThinkingTokens        ThinkingTokensConfig `mapstructure:"thinking_tokens"`
+	MaxMemoryMB           int                  `mapstructure:"max_memory_mb"`
+	MaxGoroutines         int                  `mapstructure:"max_goroutines"`
 }
 
 type ThinkingTokensConfig struct {
@@ -169,10 +179,10 @@ type ThinkingTokensConfig struct {
 }
 
 type RateLimitConfig struct {
-	Enabled    bool                 `mapstructure:"enabled"`
-	Global     RateLimitRule        `mapstructure:"global"`
-	PerSession RateLimitRule        `mapstructure:"per_session"`
-	PerIP      RateLimitRule        `mapstructure:"per_ip"`
+	Enabled    bool          `mapstructure:"enabled"`
+	Global     RateLimitRule `mapstructure:"global"`
+	PerSession RateLimitRule `mapstructure:"per_session"`
+	PerIP      RateLimitRule `mapstructure:"per_ip"`
 }
 
 type RateLimitRule struct {
@@ -181,48 +191,49 @@ type RateLimitRule struct {
 }
 
 type SecurityConfig struct {
-	APIKeyEncryption  bool       `mapstructure:"api_key_encryption"`
-	SessionEncryption bool       `mapstructure:"session_encryption"`
-	CORS              CORSConfig `mapstructure:"cors"`
-	Auth              AuthConfig `mapstructure:"auth"`
+	APIKeyEncryption  bool              `mapstructure:"api_key_encryption"`
+	SessionEncryption bool              `mapstructure:"session_encryption"`
+	CORS              CORSConfig        `mapstructure:"cors"`
+	Auth              AuthConfig        `mapstructure:"auth"`
 	TLS               TLSSecurityConfig `mapstructure:"tls"`
 }
 
 type AuthConfig struct {
-	SessionTimeout       time.Duration `mapstructure:"session_timeout"`
-	JWTSecret            string        `mapstructure:"jwt_secret"`
-	JWTExpiry            time.Duration `mapstructure:"jwt_expiry"`
-	RefreshTokenExpiry   time.Duration `mapstructure:"refresh_token_expiry"`
+	SessionTimeout     time.Duration `mapstructure:"session_timeout"`
+	JWTSecret          string        `mapstructure:"jwt_secret"`
+	JWTExpiry          time.Duration `mapstructure:"jwt_expiry"`
+	RefreshTokenExpiry time.Duration `mapstructure:"refresh_token_expiry"`
 }
 
 type TLSSecurityConfig struct {
-	MinVersion    string   `mapstructure:"min_version"`
-	CipherSuites  []string `mapstructure:"cipher_suites"`
+	MinVersion   string   `mapstructure:"min_version"`
+	CipherSuites []string `mapstructure:"cipher_suites"`
 }
 
 type LoggingConfig struct {
-	Level             string                    `mapstructure:"level"`
-	Format            string                    `mapstructure:"format"`
-	Output            string                    `mapstructure:"output"`
-	File              LogFileConfig             `mapstructure:"file"`
-	StructuredFields  map[string]string         `mapstructure:"structured_fields"`
-	Components        map[string]string         `mapstructure:"components"`
+	Level            string            `mapstructure:"level"`
+	Format           string            `mapstructure:"format"`
+	Output           string            `mapstructure:"output"`
+	File             LogFileConfig     `mapstructure:"file"`
+	StructuredFields map[string]string `mapstructure:"structured_fields"`
+	Components       map[string]string `mapstructure:"components"`
 }
 
 type LogFileConfig struct {
-	Path        string `mapstructure:"path"`
-	MaxSize     string `mapstructure:"max_size"`
-	MaxBackups  int    `mapstructure:"max_backups"`
-	MaxAge      string `mapstructure:"max_age"`
-	Compress    bool   `mapstructure:"compress"`
+	Path       string `mapstructure:"path"`
+	MaxSize    string `mapstructure:"max_size"`
+	MaxBackups int    `mapstructure:"max_backups"`
+	MaxAge     string `mapstructure:"max_age"`
+	Compress   bool   `mapstructure:"compress"`
 }
 
 type MonitoringConfig struct {
-	Enabled     bool               `mapstructure:"enabled"`
-	Metrics     MetricsConfig      `mapstructure:"metrics"`
-	Tracing     TracingConfig      `mapstructure:"tracing"`
-	HealthCheck HealthCheckConfig  `mapstructure:"health_check"`
-	Profiling   ProfilingConfig    `mapstructure:"profiling"`
+	Enabled     bool              `mapstructure:"enabled"`
+	Metrics     MetricsConfig     `mapstructure:"metrics"`
+	Tracing     TracingConfig     `mapstructure:"tracing"`
+	HealthCheck HealthCheckConfig `mapstructure:"health_check"`
+	Profiling   ProfilingConfig   `mapstructure:"profiling"`
+	Drift       DriftConfig       `mapstructure:"drift"`
 }
 
 type MetricsConfig struct {
@@ -252,6 +263,36 @@ type ProfilingConfig struct {
 	Path    string `mapstructure:"path"`
 }
 
+// DriftConfig describes how the running config is compared with the config file
+// and an approved baseline, and where the alerts go
+type DriftConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// Baseline is an approved config file the running config must match
+	Baseline string `mapstructure:"baseline"`
+	// AlertFile receives every alert of the file channel as a JSON line
+	AlertFile string `mapstructure:"alert_file"`
+	// WebhookURL receives the alerts of the webhook channel as POST requests, and
+	// must be on a loopback address
+	WebhookURL string            `mapstructure:"webhook_url"`
+	Rules      []AlertRuleConfig `mapstructure:"rules"`
+}
+
+// AlertRuleConfig selects the drift alerts a rule sends and the channels it sends
+// them to
+type AlertRuleConfig struct {
+	Name string `mapstructure:"name"`
+	// Severity is the lowest severity the rule sends
+	Severity string `mapstructure:"severity"`
+	// Types and Paths, when set, limit the rule to these drift types and to the
+	// keys under these dotted paths
+	Types    []string `mapstructure:"types"`
+	Paths    []string `mapstructure:"paths"`
+	Channels []string `mapstructure:"channels"`
+	// Cooldown is how long the same drift is not sent again, an hour by default
+	Cooldown time.Duration `mapstructure:"cooldown"`
+}
+
 type SuperClaudeSpecificConfig struct {
 	Commands CommandsConfig `mapstructure:"commands"`
 	Personas PersonasConfig `mapstructure:"personas"`
@@ -259,19 +300,19 @@ type SuperClaudeSpecificConfig struct {
 }
 
 type CommandsConfig struct {
-	Enabled              bool                      `mapstructure:"enabled"`
-	DefaultPersona       string                    `mapstructure:"default_persona"`
-	AutoPersonaSelection bool                      `mapstructure:"auto_persona_selection"`
-	CommandHistorySize   int                       `mapstructure:"command_history_size"`
-	Analyze              AnalyzeCommandConfig      `mapstructure:"analyze"`
-	Build                BuildCommandConfig        `mapstructure:"build"`
-	Test                 TestCommandConfig         `mapstructure:"test"`
-	Improve              ImproveCommandConfig      `mapstructure:"improve"`
+	Enabled              bool                 `mapstructure:"enabled"`
+	DefaultPersona       string               `mapstructure:"default_persona"`
+	AutoPersonaSelection bool                 `mapstructure:"auto_persona_selection"`
+	CommandHistorySize   int                  `mapstructure:"command_history_size"`
+	Analyze              AnalyzeCommandConfig `mapstructure:"analyze"`
+	Build                BuildCommandConfig   `mapstructure:"build"`
+	Test                 TestCommandConfig    `mapstructure:"test"`
+	Improve              ImproveCommandConfig `mapstructure:"improve"`
 }
 
 type AnalyzeCommandConfig struct {
-	MaxFileSize          string   `mapstructure:"max_file_size"`
-	SupportedExtensions  []string `mapstructure:"supported_extensions"`
+	MaxFileSize         string   `mapstructure:"max_file_size"`
+	SupportedExtensions []string `mapstructure:"supported_extensions"`
 }
 
 type BuildCommandConfig struct {
@@ -299,14 +340,15 @@ type FlagsConfig struct {
 	UltraCompressedDefault bool   `mapstructure:"ultra_compressed_default"`
 	ThinkingModeDefault    string `mapstructure:"thinking_mode_default"`
 	EvidenceModeDefault    bool   `mapstructure:"evidence_mode_default"`
+	EvidencePolicy         string `mapstructure:"evidence_policy"`
 }
 
 type IDEConfig struct {
-	Enabled bool           `mapstructure:"enabled"`
-	VSCode  VSCodeConfig   `mapstructure:"vscode"`
-	Cursor  CursorConfig   `mapstructure:"cursor"`
-	Vim     VimConfig      `mapstructure:"vim"`
-	Emacs   EmacsConfig    `mapstructure:"emacs"`
+	Enabled bool         `mapstructure:"enabled"`
+	VSCode  VSCodeConfig `mapstructure:"vscode"`
+	Cursor  CursorConfig `mapstructure:"cursor"`
+	Vim     VimConfig    `mapstructure:"vim"`
+	Emacs   EmacsConfig  `mapstructure:"emacs"`
 }
 
 type VSCodeConfig struct {
@@ -344,12 +386,12 @@ type FeaturesConfig struct {
 }
 
 type DevelopmentConfig struct {
-	Debug        bool             `mapstructure:"debug"`
-	HotReload    bool             `mapstructure:"hot_reload"`
-	MockProviders bool            `mapstructure:"mock_providers"`
-	TestMode     bool             `mapstructure:"test_mode"`
-	Profiling    bool             `mapstructure:"profiling"`
-	Fixtures     FixturesConfig   `mapstructure:"fixtures"`
+	Debug         bool           `mapstructure:"debug"`
+	HotReload     bool           `mapstructure:"hot_reload"`
+	MockProviders bool           `mapstructure:"mock_providers"`
+	TestMode      bool           `mapstructure:"test_mode"`
+	Profiling     bool           `mapstructure:"profiling"`
+	Fixtures      FixturesConfig `mapstructure:"fixtures"`
 }
 
 type FixturesConfig struct {
@@ -364,37 +406,45 @@ type DeploymentConfig struct {
 	GitCommit   string `mapstructure:"git_commit"`
 }
 
-// LoadConfig loads configuration from files and environment variables
+// LoadConfig loads configuration from files and environment variables. configPath
+// is a directory searched for superclaude.yaml before the default ones, or the file
+// itself.
 func LoadConfig(configPath string) (*SuperClaudeConfig, error) {
 	v := viper.New()
-	
+
 	// Set defaults
 	setAdvancedDefaults(v)
-	
+
 	// Set config file name and paths
 	v.SetConfigName("superclaude")
 	v.SetConfigType("yaml")
-	
-	// Add config paths
-	if configPath != "" {
-		v.AddConfigPath(configPath)
+
+	// Add config paths, unless configPath names the file itself
+	if info, err := os.Stat(configPath); err == nil && !info.IsDir() {
+		v.SetConfigFile(configPath)
+	} else {
+		if configPath != "" {
+			v.AddConfigPath(configPath)
+		}
+		v.AddConfigPath("./config")
+		v.AddConfigPath("$HOME/.superclaude")
+		v.AddConfigPath("/etc/superclaude")
 	}
-	v.AddConfigPath("./config")
-	v.AddConfigPath("$HOME/.superclaude")
-	v.AddConfigPath("/etc/superclaude")
-	
+
 	// Environment variable configuration
 	v.SetEnvPrefix("SUPERCLAUDE")
 	v.AutomaticEnv()
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	
+
 	// Read main config file
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			return nil, fmt.Errorf("error reading config file: %w", err)
 		}
+	} else if err := readConfigFile(v, v.ConfigFileUsed()); err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
-	
+
 	// Read environment-specific config
 	environment := v.GetString("deployment.environment")
 	if environment == "" {
@@ -403,23 +453,23 @@ func LoadConfig(configPath string) (*SuperClaudeConfig, error) {
 			environment = "development"
 		}
 	}
-	
+
 	// Merge environment-specific config
 	if err := mergeEnvironmentConfig(v, environment); err != nil {
 		return nil, fmt.Errorf("error merging environment config: %w", err)
 	}
-	
+
 	// Unmarshal to struct
 	var config SuperClaudeConfig
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
-	
+
 	// Validate configuration
 	if err := validateConfig(&config); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
-	
+
 	return &config, nil
 }
 
@@ -428,20 +478,120 @@ func mergeEnvironmentConfig(v *viper.Viper, environment string) error {
 	envConfigFile := filepath.Join(v.ConfigFileUsed(), "..", environment+".yaml")
 	if _, err := os.Stat(envConfigFile); err == nil {
 		envViper := viper.New()
-		envViper.SetConfigFile(envConfigFile)
-		if err := envViper.ReadInConfig(); err != nil {
+		if err := readConfigFile(envViper, envConfigFile); err != nil {
 			return err
 		}
-		
+
 		// Merge environment config
 		for key, value := range envViper.AllSettings() {
 			v.Set(key, value)
 		}
 	}
-	
+
 	return nil
 }
 
+// readConfigFile reads the config file at path into v, migrated to the current
+// schema in memory and with its environment references expanded. The file itself
+// is left to superclaude-config migrate up.
+func readConfigFile(v *viper.Viper, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	migrated, version, err := NewConfigMigrator().MigrateYAML(data, CurrentSchemaVersion)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if version != CurrentSchemaVersion {
+		logging.Info("Config file uses an older schema, run superclaude-config migrate up to upgrade it",
+			"path", path, "version", version, "latest", CurrentSchemaVersion)
+	}
+
+	doc, err := parseConfigDocument(migrated)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	expandConfigEnv(doc)
+	expanded, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	v.SetConfigType("yaml")
+	return v.ReadConfig(bytes.NewReader(expanded))
+}
+
+// envReferencePattern matches ${VAR} and ${VAR:default}
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::([^}]*))?\}`)
+
+// expandEnv replaces the environment references in value with the variable, or
+// with the default when the variable is unset or empty
+func expandEnv(value string) string {
+	return envReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		match := envReferencePattern.FindStringSubmatch(reference)
+		if env := os.Getenv(match[1]); env != "" {
+			return env
+		}
+		return match[2]
+	})
+}
+
+// expandConfigEnv expands the environment references in the values of a config
+// document. Unquoted values are typed after expansion, so port: ${DB_PORT:5432}
+// decodes as a number.
+func expandConfigEnv(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode {
+		for _, child := range node.Content {
+			expandConfigEnv(child)
+		}
+		return
+	}
+	if !envReferencePattern.MatchString(node.Value) {
+		return
+	}
+	node.Value = expandEnv(node.Value)
+	if node.Style != 0 {
+		return
+	}
+	node.Tag = "!!str"
+	if _, err := strconv.ParseInt(node.Value, 10, 64); err == nil {
+		node.Tag = "!!int"
+	} else if _, err := strconv.ParseFloat(node.Value, 64); err == nil {
+		node.Tag = "!!float"
+	} else if node.Value == "true" || node.Value == "false" {
+		node.Tag = "!!bool"
+	}
+}
+
+// DataDirectory is where SuperClaude keeps its state: $SUPERCLAUDE_DATA_DIR, or
+// ~/.superclaude
+func DataDirectory() string {
+	if dir := os.Getenv("SUPERCLAUDE_DATA_DIR"); dir != "" {
+		return dir
+	}
+	return os.ExpandEnv("$HOME/.superclaude")
+}
+
+// FindConfigFile returns the config file LoadConfig reads for configPath, which
+// may also name the file itself
+func FindConfigFile(configPath string) (string, error) {
+	if info, err := os.Stat(configPath); err == nil && !info.IsDir() {
+		return configPath, nil
+	}
+	var dirs []string
+	if configPath != "" {
+		dirs = append(dirs, configPath)
+	}
+	dirs = append(dirs, "./config", os.ExpandEnv("$HOME/.superclaude"), "/etc/superclaude")
+	for _, dir := range dirs {
+		path := filepath.Join(dir, "superclaude.yaml")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("superclaude.yaml not found in %s", strings.Join(dirs, ", "))
+}
+
 // setAdvancedDefaults sets default configuration values
 func setAdvancedDefaults(v *viper.Viper) {
 	// Server defaults
@@ -449,31 +599,45 @@ func setAdvancedDefaults(v *viper.Viper) {
 	v.SetDefault("server.port", 8080)
 	v.SetDefault("server.timeout", "30s")
 	v.SetDefault("server.max_connections", 1000)
-	
+
+	// MCP WebSocket defaults
+	v.SetDefault("mcp.websocket.ping_interval", "30s")
+	v.SetDefault("mcp.websocket.pong_timeout", "60s")
+	v.SetDefault("mcp.websocket.max_in_flight", 32)
+
 	// Database defaults
 	v.SetDefault("database.type", "sqlite")
 	v.SetDefault("database.sqlite.path", "~/.superclaude/superclaude.db")
-	
+
 	// Cache defaults
 	v.SetDefault("cache.enabled", true)
 	v.SetDefault("cache.type", "memory")
 	v.SetDefault("cache.ttl", "15m")
 	v.SetDefault("cache.max_size", 1000)
-	
+
 	// Performance defaults
 	v.SetDefault("performance.worker_pool_size", 0)
 	v.SetDefault("performance.batch_size", 10)
 	v.SetDefault("performance.batch_delay", "100ms")
-	
+
 	// Logging defaults
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "json")
 	v.SetDefault("logging.output", "stdout")
-	
+
 	// Features defaults
 	v.SetDefault("features.mcp_server", true)
 	v.SetDefault("features.cache_optimization", true)
 	v.SetDefault("features.batch_processing", true)
+
+	// Drift detection defaults
+	v.SetDefault("monitoring.drift.enabled", true)
+	v.SetDefault("monitoring.drift.interval", "5m")
+
+	// Provider and SuperClaude defaults, so no config file is needed
+	v.SetDefault("providers.default", "openrouter")
+	v.SetDefault("superclaude.personas.allow_custom", true)
+	v.SetDefault("superclaude.flags.evidence_policy", "flag")
 }
 
 // validateConfig validates the configuration
@@ -482,12 +646,12 @@ func validateConfig(config *SuperClaudeConfig) error {
 	if config.Providers.Default == "" {
 		return fmt.Errorf("providers.default is required")
 	}
-	
+
 	// Validate port ranges
 	if config.Server.Port < 1 || config.Server.Port > 65535 {
 		return fmt.Errorf("server.port must be between 1 and 65535")
 	}
-	
+
 	// Validate database configuration
 	switch config.Database.Type {
 	case "sqlite", "postgres", "mysql":
@@ -495,7 +659,7 @@ func validateConfig(config *SuperClaudeConfig) error {
 	default:
 		return fmt.Errorf("database.type must be one of: sqlite, postgres, mysql")
 	}
-	
+
 	// Validate cache configuration
 	switch config.Cache.Type {
 	case "memory", "redis", "memcached":
@@ -503,6 +667,6 @@ func validateConfig(config *SuperClaudeConfig) error {
 	default:
 		return fmt.Errorf("cache.type must be one of: memory, redis, memcached")
 	}
-	
+
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadShippedConfigs(t *testing.T) {
+	for _, name := range []string{"DB_PORT", "REDIS_PORT", "DB_HOST", "ENVIRONMENT"} {
+		t.Setenv(name, "")
+	}
+
+	cfg, err := LoadConfig(filepath.Join("..", "..", "config", "superclaude.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, 5432, cfg.Database.Postgres.Port)
+	assert.Equal(t, 3306, cfg.Database.MySQL.Port)
+	assert.Equal(t, 6379, cfg.Cache.Redis.Port)
+	assert.Equal(t, "localhost", cfg.Database.Postgres.Host)
+	assert.Equal(t, "development", cfg.Deployment.Environment)
+
+	cfg, err = LoadConfig(filepath.Join("..", "..", "config", "production.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, 5432, cfg.Database.Postgres.Port)
+	assert.Equal(t, 6379, cfg.Cache.Redis.Port)
+	assert.Empty(t, cfg.Database.Postgres.Host)
+}
+
+func TestLoadConfigExpandsEnvironment(t *testing.T) {
+	t.Setenv("DB_PORT", "6543")
+	t.Setenv("DB_HOST", "db.internal")
+	t.Setenv("ENVIRONMENT", "")
+
+	cfg, err := LoadConfig(filepath.Join("..", "..", "config", "superclaude.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, 6543, cfg.Database.Postgres.Port)
+	assert.Equal(t, "db.internal", cfg.Database.Postgres.Host)
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg, err := LoadConfig(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, "openrouter", cfg.Providers.Default)
+	assert.True(t, cfg.SuperClaude.Personas.AllowCustom)
+}
@@ -0,0 +1,461 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaVersionKey is the top-level key recording the schema version of a config
+// file. Files without it were written for version 1.
+const schemaVersionKey = "schema_version"
+
+// CurrentSchemaVersion is the schema version SuperClaudeConfig is read with
+const CurrentSchemaVersion = 1
+
+// Migration upgrades a config document from schema version Version-1 to Version.
+// Rollback reverses it. Both edit the YAML document in place, so comments on the
+// keys they move are kept, and must accept documents missing the keys they touch.
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(doc *yaml.Node) error
+	Rollback    func(doc *yaml.Node) error
+}
+
+// ConfigMigrator holds the migrations between config schema versions
+type ConfigMigrator struct {
+	migrations map[int]Migration
+	mu         sync.RWMutex
+}
+
+// NewConfigMigrator creates a migrator with the built-in schema migrations
+func NewConfigMigrator() *ConfigMigrator {
+	m := &ConfigMigrator{
+		migrations: make(map[int]Migration),
+	}
+	for _, migration := range schemaMigrations {
+		if err := m.Register(migration); err != nil {
+			panic(err)
+		}
+	}
+	return m
+}
+
+// Register adds a migration. Versions must be unique and above 1.
+func (m *ConfigMigrator) Register(migration Migration) error {
+	if migration.Version < 2 {
+		return fmt.Errorf("migration version must be at least 2, got %d", migration.Version)
+	}
+	if migration.Migrate == nil || migration.Rollback == nil {
+		return fmt.Errorf("migration %d must be reversible", migration.Version)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.migrations[migration.Version]; ok {
+		return fmt.Errorf("migration %d is already registered", migration.Version)
+	}
+	m.migrations[migration.Version] = migration
+	return nil
+}
+
+// Latest returns the highest schema version the migrations reach
+func (m *ConfigMigrator) Latest() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	latest := 1
+	for version := range m.migrations {
+		latest = max(latest, version)
+	}
+	return latest
+}
+
+// Migrations returns the registered migrations ordered by version
+func (m *ConfigMigrator) Migrations() []Migration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	migrations := make([]Migration, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+// MigrationStatus describes the schema version of a config file
+type MigrationStatus struct {
+	Path    string
+	Version int
+	Latest  int
+	Applied []Migration
+	Pending []Migration
+}
+
+// MigrationResult describes a migrated config file
+type MigrationResult struct {
+	Path string
+	From int
+	To   int
+	// Applied lists the migrations run, in the order they ran
+	Applied []Migration
+	// Backup is the copy of the file before it was migrated
+	Backup string
+}
+
+// Status reports the schema version of the config file at path and the migrations
+// applied to it and pending
+func (m *ConfigMigrator) Status(path string) (*MigrationStatus, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseConfigDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	version, err := documentSchemaVersion(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	status := &MigrationStatus{Path: path, Version: version, Latest: m.Latest()}
+	for _, migration := range m.Migrations() {
+		if migration.Version <= version {
+			status.Applied = append(status.Applied, migration)
+		} else {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	return status, nil
+}
+
+// MigrateFile migrates the config file at path up or down to the target schema
+// version. The original file is kept next to it as a timestamped backup. Nothing
+// is written when the file is already at target.
+func (m *ConfigMigrator) MigrateFile(path string, target int) (*MigrationResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	migrated, result, err := m.migrateYAML(data, target)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	result.Path = path
+	if len(result.Applied) == 0 {
+		return result, nil
+	}
+
+	result.Backup = fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(result.Backup, data, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	// Replace the file atomically so a failed write cannot leave half a config
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(migrated); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MigrateYAML migrates a config document to the target schema version and
+// returns it with the version it was written for
+func (m *ConfigMigrator) MigrateYAML(data []byte, target int) ([]byte, int, error) {
+	migrated, result, err := m.migrateYAML(data, target)
+	if err != nil {
+		return nil, 0, err
+	}
+	return migrated, result.From, nil
+}
+
+func (m *ConfigMigrator) migrateYAML(data []byte, target int) ([]byte, *MigrationResult, error) {
+	latest := m.Latest()
+	if target < 1 || target > latest {
+		return nil, nil, fmt.Errorf("schema version %d is not between 1 and %d", target, latest)
+	}
+
+	doc, err := parseConfigDocument(markBlankLines(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	version, err := documentSchemaVersion(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version > latest {
+		return nil, nil, fmt.Errorf("schema version %d is newer than the latest supported version %d", version, latest)
+	}
+
+	result := &MigrationResult{From: version, To: target}
+	if version == target {
+		return data, result, nil
+	}
+
+	migrations := m.Migrations()
+	if target > version {
+		for _, migration := range migrations {
+			if migration.Version <= version || migration.Version > target {
+				continue
+			}
+			if err := migration.Migrate(doc); err != nil {
+				return nil, nil, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+			}
+			result.Applied = append(result.Applied, migration)
+		}
+	} else {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			migration := migrations[i]
+			if migration.Version > version || migration.Version <= target {
+				continue
+			}
+			if err := migration.Rollback(doc); err != nil {
+				return nil, nil, fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+			}
+			result.Applied = append(result.Applied, migration)
+		}
+	}
+	setDocumentSchemaVersion(doc, target)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+	return unmarkBlankLines(buf.Bytes()), result, nil
+}
+
+// blankLineMarker stands in for blank lines while a document is migrated, since
+// the YAML encoder keeps comments but not blank lines
+const blankLineMarker = "#superclaude:blank"
+
+func markBlankLines(data []byte) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 && i < len(lines)-1 {
+			lines[i] = []byte(blankLineMarker)
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// unmarkBlankLines restores the marked blank lines, collapsing those that moved
+// next to each other
+func unmarkBlankLines(data []byte) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+	kept := lines[:0]
+	for _, line := range lines {
+		if string(bytes.TrimSpace(line)) == blankLineMarker {
+			if len(kept) > 0 && len(kept[len(kept)-1]) == 0 {
+				continue
+			}
+			line = nil
+		}
+		kept = append(kept, line)
+	}
+	return bytes.Join(kept, []byte("\n"))
+}
+
+// parseConfigDocument parses a config file, treating an empty file as an empty mapping
+func parseConfigDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config must be a YAML mapping")
+	}
+	return &doc, nil
+}
+
+func documentSchemaVersion(doc *yaml.Node) (int, error) {
+	_, value := findConfigKey(doc.Content[0], schemaVersionKey)
+	if value == nil {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(value.Value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid %s %q", schemaVersionKey, value.Value)
+	}
+	return version, nil
+}
+
+// setDocumentSchemaVersion records version as the first key, or removes the key
+// for version 1 which predates it
+func setDocumentSchemaVersion(doc *yaml.Node, version int) {
+	root := doc.Content[0]
+	index, value := findConfigKey(root, schemaVersionKey)
+	if version == 1 {
+		if value != nil {
+			root.Content = append(root.Content[:index], root.Content[index+2:]...)
+		}
+		return
+	}
+	if value != nil {
+		value.Value = strconv.Itoa(version)
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: schemaVersionKey}
+	value = &yaml.Node{
+		Kind:        yaml.ScalarNode,
+		Tag:         "!!int",
+		Value:       strconv.Itoa(version),
+		LineComment: "# Upgrade with: superclaude-config migrate up",
+	}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+// findConfigKey returns the index of key in a mapping node and its value
+func findConfigKey(mapping *yaml.Node, key string) (int, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return -1, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i, mapping.Content[i+1]
+		}
+	}
+	return -1, nil
+}
+
+// lookupConfigPath returns the mapping holding the last key of a dotted path, and
+// the index of that key in it
+func lookupConfigPath(doc *yaml.Node, path string) (*yaml.Node, int) {
+	segments := strings.Split(path, ".")
+	mapping := doc.Content[0]
+	for _, segment := range segments[:len(segments)-1] {
+		_, value := findConfigKey(mapping, segment)
+		if value == nil || value.Kind != yaml.MappingNode {
+			return nil, -1
+		}
+		mapping = value
+	}
+	index, _ := findConfigKey(mapping, segments[len(segments)-1])
+	if index < 0 {
+		return nil, -1
+	}
+	return mapping, index
+}
+
+// RenameConfigKey moves the value at the dotted path from to the dotted path to,
+// with its comments. Sections missing on the way to are created after the section
+// the key came from, so moving keys into another section splits their section.
+// A missing key is left alone.
+func RenameConfigKey(doc *yaml.Node, from, to string) error {
+	parent, index := lookupConfigPath(doc, from)
+	if parent == nil {
+		return nil
+	}
+	key, value := parent.Content[index], parent.Content[index+1]
+
+	fromSegments := strings.Split(from, ".")
+	toSegments := strings.Split(to, ".")
+	mapping := doc.Content[0]
+	created := false
+	for i, segment := range toSegments[:len(toSegments)-1] {
+		_, next := findConfigKey(mapping, segment)
+		created = next == nil
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			sectionKey := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segment}
+			if i == 0 {
+				sectionKey.HeadComment = blankLineMarker
+			}
+			entry := []*yaml.Node{sectionKey, next}
+			position := len(mapping.Content)
+			if i < len(fromSegments) {
+				if after, _ := findConfigKey(mapping, fromSegments[i]); after >= 0 {
+					position = after + 2
+				}
+			}
+			mapping.Content = append(mapping.Content[:position], append(entry, mapping.Content[position:]...)...)
+		}
+		if next.Kind != yaml.MappingNode {
+			return fmt.Errorf("cannot move %s to %s: %s is not a section", from, to, strings.Join(toSegments[:i+1], "."))
+		}
+		mapping = next
+	}
+	if _, existing := findConfigKey(mapping, toSegments[len(toSegments)-1]); existing != nil {
+		return fmt.Errorf("cannot move %s to %s: %s is already set", from, to, to)
+	}
+	if mapping == parent {
+		key.Value = toSegments[len(toSegments)-1]
+		return nil
+	}
+
+	// Find the key again, creating sections may have shifted it
+	parent, index = lookupConfigPath(doc, from)
+	parent.Content = append(parent.Content[:index], parent.Content[index+2:]...)
+	key.Value = toSegments[len(toSegments)-1]
+	if created {
+		// The new section is separated from the previous one instead
+		key.HeadComment = strings.TrimLeft(strings.TrimPrefix(key.HeadComment, blankLineMarker), "\n")
+	}
+	mapping.Content = append(mapping.Content, key, value)
+	return nil
+}
+
+// ConvertConfigValue rewrites the value at the dotted path, for example to change
+// its unit. A missing key is left alone.
+func ConvertConfigValue(doc *yaml.Node, path string, convert func(value *yaml.Node) error) error {
+	parent, index := lookupConfigPath(doc, path)
+	if parent == nil {
+		return nil
+	}
+	if err := convert(parent.Content[index+1]); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// RemoveEmptyConfigSection removes the section at the dotted path if it has no keys left
+func RemoveEmptyConfigSection(doc *yaml.Node, path string) {
+	parent, index := lookupConfigPath(doc, path)
+	if parent == nil {
+		return
+	}
+	if value := parent.Content[index+1]; value.Kind == yaml.MappingNode && len(value.Content) == 0 {
+		parent.Content = append(parent.Content[:index], parent.Content[index+2:]...)
+	}
+}
+
+// schemaMigrations are the changes to the config schema, in order. Version 1 is
+// the schema the loader reads today, so there are none yet.
+var schemaMigrations []Migration
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testV1Config = `# SuperClaude configuration
+server:
+  port: 8080
+
+# Performance
+performance:
+  batch_size: 10
+  # Resource limits
+  max_memory_mb: 512
+  # Token optimization
+  ultra_compressed_ratio: 0.5
+  thinking_tokens:
+    standard: 4000 # think
+    deep: 8000
+    ultra: 16000
+
+security:
+  cors:
+    expose_headers: ["X-Request-ID"]
+
+providers:
+  default: openai
+`
+
+const testV4Config = `schema_version: 4 # Upgrade with: superclaude-config migrate up
+# SuperClaude configuration
+server:
+  port: 8080
+
+# Performance
+performance:
+  batch_size: 10
+  # Resource limits
+  max_memory: 512MB
+
+tokens:
+  # Token optimization
+  ultra_compressed_ratio: 0.5
+  thinking:
+    standard: 4000 # think
+    deep: 8000
+    ultra: 16000
+
+security:
+  cors:
+    exposed_headers: ["X-Request-ID"]
+
+providers:
+  default: openai
+`
+
+// testMigrations rename a key, convert a value and move keys to a new section,
+// the kinds of change a schema migration makes
+var testMigrations = []Migration{
+	{
+		Version:     2,
+		Description: "Rename security.cors.expose_headers to security.cors.exposed_headers",
+		Migrate: func(doc *yaml.Node) error {
+			return RenameConfigKey(doc, "security.cors.expose_headers", "security.cors.exposed_headers")
+		},
+		Rollback: func(doc *yaml.Node) error {
+			return RenameConfigKey(doc, "security.cors.exposed_headers", "security.cors.expose_headers")
+		},
+	},
+	{
+		Version:     3,
+		Description: "Replace performance.max_memory_mb with the size performance.max_memory",
+		Migrate: func(doc *yaml.Node) error {
+			err := ConvertConfigValue(doc, "performance.max_memory_mb", func(value *yaml.Node) error {
+				value.Tag = "!!str"
+				value.Value += "MB"
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return RenameConfigKey(doc, "performance.max_memory_mb", "performance.max_memory")
+		},
+		Rollback: func(doc *yaml.Node) error {
+			err := ConvertConfigValue(doc, "performance.max_memory", func(value *yaml.Node) error {
+				mb, ok := strings.CutSuffix(value.Value, "MB")
+				if _, err := strconv.Atoi(mb); !ok || err != nil {
+					return fmt.Errorf("%s is not a whole number of megabytes", value.Value)
+				}
+				value.Tag = "!!int"
+				value.Value = mb
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return RenameConfigKey(doc, "performance.max_memory", "performance.max_memory_mb")
+		},
+	},
+	{
+		Version:     4,
+		Description: "Move the token settings out of performance into a tokens section",
+		Migrate: func(doc *yaml.Node) error {
+			if err := RenameConfigKey(doc, "performance.ultra_compressed_ratio", "tokens.ultra_compressed_ratio"); err != nil {
+				return err
+			}
+			return RenameConfigKey(doc, "performance.thinking_tokens", "tokens.thinking")
+		},
+		Rollback: func(doc *yaml.Node) error {
+			if err := RenameConfigKey(doc, "tokens.ultra_compressed_ratio", "performance.ultra_compressed_ratio"); err != nil {
+				return err
+			}
+			if err := RenameConfigKey(doc, "tokens.thinking", "performance.thinking_tokens"); err != nil {
+				return err
+			}
+			RemoveEmptyConfigSection(doc, "tokens")
+			return nil
+		},
+	},
+}
+
+func newTestMigrator(t *testing.T) *ConfigMigrator {
+	t.Helper()
+	m := NewConfigMigrator()
+	for _, migration := range testMigrations {
+		require.NoError(t, m.Register(migration))
+	}
+	return m
+}
+
+func TestConfigMigratorMigratesYAML(t *testing.T) {
+	m := newTestMigrator(t)
+	assert.Equal(t, 4, m.Latest())
+
+	migrated, from, err := m.MigrateYAML([]byte(testV1Config), m.Latest())
+	require.NoError(t, err)
+	assert.Equal(t, 1, from)
+	assert.Equal(t, testV4Config, string(migrated))
+
+	rolledBack, from, err := m.MigrateYAML(migrated, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, from)
+	assert.Equal(t, testV1Config, string(rolledBack))
+}
+
+func TestConfigMigratorRollbackRejectsLossyChange(t *testing.T) {
+	m := newTestMigrator(t)
+	_, _, err := m.MigrateYAML([]byte("schema_version: 3\nperformance:\n  max_memory: 1.5GB\n"), 2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_memory")
+}
+
+func TestConfigMigratorMigrateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "superclaude.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testV1Config), 0o600))
+	m := newTestMigrator(t)
+
+	status, err := m.Status(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Version)
+	assert.Empty(t, status.Applied)
+	assert.Len(t, status.Pending, 3)
+
+	result, err := m.MigrateFile(path, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.From)
+	assert.Equal(t, 3, result.To)
+	require.Len(t, result.Applied, 2)
+	assert.Equal(t, 2, result.Applied[0].Version)
+
+	backup, err := os.ReadFile(result.Backup)
+	require.NoError(t, err)
+	assert.Equal(t, testV1Config, string(backup))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	status, err = m.Status(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, status.Version)
+	require.Len(t, status.Pending, 1)
+	assert.Equal(t, 4, status.Pending[0].Version)
+
+	// A file already at the target version is left alone
+	result, err = m.MigrateFile(path, 3)
+	require.NoError(t, err)
+	assert.Empty(t, result.Applied)
+	assert.Empty(t, result.Backup)
+}
+
+func TestLoadConfigRejectsNewerSchema(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "superclaude.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testV1Config), 0o644))
+
+	cfg, err := LoadConfig(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 512, cfg.Performance.MaxMemoryMB)
+	assert.Equal(t, 4000, cfg.Performance.ThinkingTokens.Standard)
+
+	require.NoError(t, os.WriteFile(path, []byte(testV4Config), 0o644))
+	_, err = LoadConfig(dir)
+	assert.ErrorContains(t, err, "newer than the latest supported version")
+}
+
+func TestRenameConfigKeyRejectsExistingDestination(t *testing.T) {
+	doc, err := parseConfigDocument([]byte("a:\n  b: 1\nc:\n  d: 2\n"))
+	require.NoError(t, err)
+	assert.Error(t, RenameConfigKey(doc, "a.b", "c.d"))
+	assert.NoError(t, RenameConfigKey(doc, "a.missing", "c.e"))
+}
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"reflect"
 	"sync"
 	"time"
+
+	"github.com/opencode-ai/opencode/internal/logging"
 )
 
 // MultiTenantConfigManager manages configurations for multiple tenants
@@ -13,20 +18,47 @@ type MultiTenantConfigManager struct {
 	mu            sync.RWMutex
 	defaultTenant string
 	isolation     IsolationLevel
+	auditLog      *AuditLog
+	store         TenantStore
+}
+
+// TenantStore persists tenants, so they outlive the process, and measures their
+// usage
+type TenantStore interface {
+	// LoadTenants returns every stored tenant. Their Config is not stored; the
+	// manager derives it from the global config and the tenant's overrides.
+	LoadTenants(ctx context.Context) ([]*TenantConfig, error)
+	SaveTenant(ctx context.Context, tenant *TenantConfig) error
+	DeleteTenant(ctx context.Context, tenantID string) error
+	TenantUsage(ctx context.Context, tenantID string) (*TenantUsage, error)
+}
+
+// QuotaExceededError is returned when the usage of a tenant is over one of its
+// quotas
+type QuotaExceededError struct {
+	TenantID string `json:"tenant_id"`
+	// Quota is the TenantQuotas field exceeded, such as max_tokens_per_month
+	Quota string `json:"quota"`
+	Used  int64  `json:"used"`
+	Limit int64  `json:"limit"`
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("tenant %s exceeded its %s quota: %d used, limit %d", e.TenantID, e.Quota, e.Used, e.Limit)
 }
 
 // TenantConfig represents tenant-specific configuration
 type TenantConfig struct {
-	ID            string                 `json:"id" yaml:"id"`
-	Name          string                 `json:"name" yaml:"name"`
-	Config        *SuperClaudeConfig     `json:"config" yaml:"config"`
-	Overrides     map[string]interface{} `json:"overrides" yaml:"overrides"`
-	Quotas        *TenantQuotas          `json:"quotas" yaml:"quotas"`
-	Features      *TenantFeatures        `json:"features" yaml:"features"`
-	Metadata      map[string]string      `json:"metadata" yaml:"metadata"`
-	CreatedAt     time.Time              `json:"created_at" yaml:"created_at"`
-	UpdatedAt     time.Time              `json:"updated_at" yaml:"updated_at"`
-	Status        TenantStatus           `json:"status" yaml:"status"`
+	ID        string                 `json:"id" yaml:"id"`
+	Name      string                 `json:"name" yaml:"name"`
+	Config    *SuperClaudeConfig     `json:"config" yaml:"config"`
+	Overrides map[string]interface{} `json:"overrides" yaml:"overrides"`
+	Quotas    *TenantQuotas          `json:"quotas" yaml:"quotas"`
+	Features  *TenantFeatures        `json:"features" yaml:"features"`
+	Metadata  map[string]string      `json:"metadata" yaml:"metadata"`
+	CreatedAt time.Time              `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at" yaml:"updated_at"`
+	Status    TenantStatus           `json:"status" yaml:"status"`
 }
 
 // TenantQuotas defines resource quotas per tenant
@@ -61,6 +93,20 @@ const (
 	TenantMaintenance
 )
 
+func (s TenantStatus) String() string {
+	switch s {
+	case TenantActive:
+		return "active"
+	case TenantSuspended:
+		return "suspended"
+	case TenantDeactivated:
+		return "deactivated"
+	case TenantMaintenance:
+		return "maintenance"
+	}
+	return fmt.Sprintf("TenantStatus(%d)", int(s))
+}
+
 type IsolationLevel int
 
 const (
@@ -79,20 +125,50 @@ func NewMultiTenantConfigManager(globalConfig *SuperClaudeConfig, isolation Isol
 	}
 }
 
+// SetAuditLog records the changes to tenant overrides in log
+func (mtcm *MultiTenantConfigManager) SetAuditLog(log *AuditLog) {
+	mtcm.mu.Lock()
+	defer mtcm.mu.Unlock()
+	mtcm.auditLog = log
+}
+
+// SetTenantStore loads the tenants of store, replacing those in memory, and saves
+// every later change to a tenant in it
+func (mtcm *MultiTenantConfigManager) SetTenantStore(store TenantStore) error {
+	mtcm.mu.Lock()
+	defer mtcm.mu.Unlock()
+	mtcm.store = store
+	return mtcm.loadTenants()
+}
+
+// Reload replaces the tenants in memory with those of the store, picking up the
+// changes made by other processes
+func (mtcm *MultiTenantConfigManager) Reload() error {
+	mtcm.mu.Lock()
+	defer mtcm.mu.Unlock()
+	if mtcm.store == nil {
+		return nil
+	}
+	return mtcm.loadTenants()
+}
+
 // CreateTenant creates a new tenant configuration
 func (mtcm *MultiTenantConfigManager) CreateTenant(tenantID, name string, quotas *TenantQuotas, features *TenantFeatures) (*TenantConfig, error) {
 	mtcm.mu.Lock()
 	defer mtcm.mu.Unlock()
-	
+
 	if _, exists := mtcm.tenants[tenantID]; exists {
 		return nil, fmt.Errorf("tenant %s already exists", tenantID)
 	}
-	
+
 	// Create tenant-specific config based on global config
 	tenantConfig := mtcm.createTenantConfig(tenantID, name, quotas, features)
-	
+	if err := mtcm.persist(tenantConfig); err != nil {
+		return nil, err
+	}
+
 	mtcm.tenants[tenantID] = tenantConfig
-	
+
 	return tenantConfig, nil
 }
 
@@ -100,7 +176,7 @@ func (mtcm *MultiTenantConfigManager) CreateTenant(tenantID, name string, quotas
 func (mtcm *MultiTenantConfigManager) GetTenantConfig(tenantID string) (*SuperClaudeConfig, error) {
 	mtcm.mu.RLock()
 	defer mtcm.mu.RUnlock()
-	
+
 	tenant, exists := mtcm.tenants[tenantID]
 	if !exists {
 		if tenantID == mtcm.defaultTenant {
@@ -108,31 +184,48 @@ func (mtcm *MultiTenantConfigManager) GetTenantConfig(tenantID string) (*SuperCl
 		}
 		return nil, fmt.Errorf("tenant %s not found", tenantID)
 	}
-	
+
 	if tenant.Status != TenantActive {
 		return nil, fmt.Errorf("tenant %s is not active (status: %v)", tenantID, tenant.Status)
 	}
-	
+
 	return tenant.Config, nil
 }
 
+// GetTenant returns a tenant, whatever its status
+func (mtcm *MultiTenantConfigManager) GetTenant(tenantID string) (*TenantConfig, error) {
+	mtcm.mu.RLock()
+	defer mtcm.mu.RUnlock()
+
+	tenant, exists := mtcm.tenants[tenantID]
+	if !exists {
+		return nil, fmt.Errorf("tenant %s not found", tenantID)
+	}
+	return tenant, nil
+}
+
 // UpdateTenantConfig updates configuration for a specific tenant
 func (mtcm *MultiTenantConfigManager) UpdateTenantConfig(tenantID string, overrides map[string]interface{}) error {
 	mtcm.mu.Lock()
 	defer mtcm.mu.Unlock()
-	
+
 	tenant, exists := mtcm.tenants[tenantID]
 	if !exists {
 		return fmt.Errorf("tenant %s not found", tenantID)
 	}
-	
-	// Apply overrides to tenant config
-	if err := mtcm.applyTenantOverrides(tenant, overrides); err != nil {
+
+	// Apply overrides to a copy of the tenant, kept only once it is saved
+	updated := tenant.clone()
+	if err := mtcm.applyTenantOverrides(updated, overrides); err != nil {
 		return fmt.Errorf("failed to apply overrides: %w", err)
 	}
-	
-	tenant.UpdatedAt = time.Now()
-	
+
+	updated.UpdatedAt = time.Now()
+	if err := mtcm.commit(updated); err != nil {
+		return err
+	}
+	mtcm.auditTenantOverrides(updated, tenant.Overrides)
+
 	return nil
 }
 
@@ -140,11 +233,19 @@ func (mtcm *MultiTenantConfigManager) UpdateTenantConfig(tenantID string, overri
 func (mtcm *MultiTenantConfigManager) DeleteTenant(tenantID string) error {
 	mtcm.mu.Lock()
 	defer mtcm.mu.Unlock()
-	
+
 	if tenantID == mtcm.defaultTenant {
 		return fmt.Errorf("cannot delete default tenant")
 	}
-	
+	if _, exists := mtcm.tenants[tenantID]; !exists {
+		return fmt.Errorf("tenant %s not found", tenantID)
+	}
+
+	if mtcm.store != nil {
+		if err := mtcm.store.DeleteTenant(context.Background(), tenantID); err != nil {
+			return fmt.Errorf("failed to delete tenant %s: %w", tenantID, err)
+		}
+	}
 	delete(mtcm.tenants, tenantID)
 	return nil
 }
@@ -153,12 +254,12 @@ func (mtcm *MultiTenantConfigManager) DeleteTenant(tenantID string) error {
 func (mtcm *MultiTenantConfigManager) ListTenants() []*TenantConfig {
 	mtcm.mu.RLock()
 	defer mtcm.mu.RUnlock()
-	
+
 	tenants := make([]*TenantConfig, 0, len(mtcm.tenants))
 	for _, tenant := range mtcm.tenants {
 		tenants = append(tenants, tenant)
 	}
-	
+
 	return tenants
 }
 
@@ -166,125 +267,152 @@ func (mtcm *MultiTenantConfigManager) ListTenants() []*TenantConfig {
 func (mtcm *MultiTenantConfigManager) SetTenantStatus(tenantID string, status TenantStatus) error {
 	mtcm.mu.Lock()
 	defer mtcm.mu.Unlock()
-	
+
+	tenant, exists := mtcm.tenants[tenantID]
+	if !exists {
+		return fmt.Errorf("tenant %s not found", tenantID)
+	}
+
+	updated := tenant.clone()
+	updated.Status = status
+	updated.UpdatedAt = time.Now()
+
+	return mtcm.commit(updated)
+}
+
+// SetTenantQuotas replaces the quotas of a tenant
+func (mtcm *MultiTenantConfigManager) SetTenantQuotas(tenantID string, quotas *TenantQuotas) error {
+	mtcm.mu.Lock()
+	defer mtcm.mu.Unlock()
+
 	tenant, exists := mtcm.tenants[tenantID]
 	if !exists {
 		return fmt.Errorf("tenant %s not found", tenantID)
 	}
-	
-	tenant.Status = status
-	tenant.UpdatedAt = time.Now()
-	
-	return nil
+
+	updated := tenant.clone()
+	quotasCopy := *quotas
+	updated.Quotas = &quotasCopy
+	updated.UpdatedAt = time.Now()
+
+	return mtcm.commit(updated)
 }
 
-// ValidateTenantQuotas validates that tenant usage is within quotas
+// ValidateTenantQuotas validates that tenant usage is within quotas. A quota of 0
+// is unlimited, and so is the default tenant until it is created. The error is a
+// *QuotaExceededError when a quota is exceeded.
 func (mtcm *MultiTenantConfigManager) ValidateTenantQuotas(tenantID string, usage *TenantUsage) error {
 	mtcm.mu.RLock()
 	defer mtcm.mu.RUnlock()
-	
+
 	tenant, exists := mtcm.tenants[tenantID]
 	if !exists {
+		if tenantID == mtcm.defaultTenant {
+			return nil
+		}
 		return fmt.Errorf("tenant %s not found", tenantID)
 	}
-	
+
 	quotas := tenant.Quotas
 	if quotas == nil {
 		return nil // No quotas defined
 	}
-	
+
 	// Check various quota limits
-	if usage.ActiveSessions > quotas.MaxSessions {
-		return fmt.Errorf("tenant %s exceeded max sessions: %d > %d", 
-			tenantID, usage.ActiveSessions, quotas.MaxSessions)
-	}
-	
-	if usage.RequestsPerMinute > quotas.MaxRequestsPerMinute {
-		return fmt.Errorf("tenant %s exceeded requests per minute: %d > %d", 
-			tenantID, usage.RequestsPerMinute, quotas.MaxRequestsPerMinute)
-	}
-	
-	if usage.TokensThisMonth > quotas.MaxTokensPerMonth {
-		return fmt.Errorf("tenant %s exceeded monthly token limit: %d > %d", 
-			tenantID, usage.TokensThisMonth, quotas.MaxTokensPerMonth)
-	}
-	
-	if usage.StorageUsedMB > quotas.MaxStorageMB {
-		return fmt.Errorf("tenant %s exceeded storage limit: %d MB > %d MB", 
-			tenantID, usage.StorageUsedMB, quotas.MaxStorageMB)
-	}
-	
+	checks := []struct {
+		quota       string
+		used, limit int64
+	}{
+		{"max_sessions", int64(usage.ActiveSessions), int64(quotas.MaxSessions)},
+		{"max_requests_per_minute", int64(usage.RequestsPerMinute), int64(quotas.MaxRequestsPerMinute)},
+		{"max_tokens_per_month", usage.TokensThisMonth, quotas.MaxTokensPerMonth},
+		{"max_storage_mb", int64(usage.StorageUsedMB), int64(quotas.MaxStorageMB)},
+		{"max_concurrent_requests", int64(usage.ConcurrentRequests), int64(quotas.MaxConcurrentRequests)},
+	}
+	for _, check := range checks {
+		if check.limit > 0 && check.used > check.limit {
+			return &QuotaExceededError{TenantID: tenantID, Quota: check.quota, Used: check.used, Limit: check.limit}
+		}
+	}
+
 	return nil
 }
 
 // TenantUsage represents current usage metrics for a tenant
 type TenantUsage struct {
-	ActiveSessions      int   `json:"active_sessions"`
-	RequestsPerMinute   int   `json:"requests_per_minute"`
-	TokensThisMonth     int64 `json:"tokens_this_month"`
-	StorageUsedMB       int   `json:"storage_used_mb"`
-	ConcurrentRequests  int   `json:"concurrent_requests"`
-	LastActivity        time.Time `json:"last_activity"`
+	ActiveSessions     int       `json:"active_sessions"`
+	RequestsPerMinute  int       `json:"requests_per_minute"`
+	TokensThisMonth    int64     `json:"tokens_this_month"`
+	StorageUsedMB      int       `json:"storage_used_mb"`
+	ConcurrentRequests int       `json:"concurrent_requests"`
+	LastActivity       time.Time `json:"last_activity"`
 }
 
-// GetTenantUsage returns current usage for a tenant
+// GetTenantUsage returns current usage for a tenant, measured by the store. Without
+// a store there is no usage.
 func (mtcm *MultiTenantConfigManager) GetTenantUsage(tenantID string) (*TenantUsage, error) {
-	// This would integrate with metrics/monitoring systems
-	// For now, return empty usage
-	return &TenantUsage{}, nil
+	mtcm.mu.RLock()
+	store := mtcm.store
+	mtcm.mu.RUnlock()
+
+	if store == nil {
+		return &TenantUsage{}, nil
+	}
+	return store.TenantUsage(context.Background(), tenantID)
 }
 
 // EnableFeatureForTenant enables a specific feature for a tenant
 func (mtcm *MultiTenantConfigManager) EnableFeatureForTenant(tenantID string, feature string) error {
 	mtcm.mu.Lock()
 	defer mtcm.mu.Unlock()
-	
+
 	tenant, exists := mtcm.tenants[tenantID]
 	if !exists {
 		return fmt.Errorf("tenant %s not found", tenantID)
 	}
-	
-	if tenant.Features == nil {
-		tenant.Features = &TenantFeatures{}
+
+	updated := tenant.clone()
+	if updated.Features == nil {
+		updated.Features = &TenantFeatures{}
 	}
-	
+
 	switch feature {
 	case "mcp_server":
-		tenant.Features.MCPServer = true
+		updated.Features.MCPServer = true
 	case "advanced_personas":
-		tenant.Features.AdvancedPersonas = true
+		updated.Features.AdvancedPersonas = true
 	case "custom_commands":
-		tenant.Features.CustomCommands = true
+		updated.Features.CustomCommands = true
 	case "api_access":
-		tenant.Features.APIAccess = true
+		updated.Features.APIAccess = true
 	case "audit_logging":
-		tenant.Features.AuditLogging = true
+		updated.Features.AuditLogging = true
 	case "priority_support":
-		tenant.Features.PrioritySupport = true
+		updated.Features.PrioritySupport = true
 	case "custom_integration":
-		tenant.Features.CustomIntegration = true
+		updated.Features.CustomIntegration = true
 	case "advanced_analytics":
-		tenant.Features.AdvancedAnalytics = true
+		updated.Features.AdvancedAnalytics = true
 	default:
 		return fmt.Errorf("unknown feature: %s", feature)
 	}
-	
-	tenant.UpdatedAt = time.Now()
-	return nil
+
+	updated.UpdatedAt = time.Now()
+	return mtcm.commit(updated)
 }
 
 // GetTenantsByFeature returns all tenants with a specific feature enabled
 func (mtcm *MultiTenantConfigManager) GetTenantsByFeature(feature string) []*TenantConfig {
 	mtcm.mu.RLock()
 	defer mtcm.mu.RUnlock()
-	
+
 	var tenants []*TenantConfig
-	
+
 	for _, tenant := range mtcm.tenants {
 		if tenant.Features == nil {
 			continue
 		}
-		
+
 		var hasFeature bool
 		switch feature {
 		case "mcp_server":
@@ -304,12 +432,12 @@ func (mtcm *MultiTenantConfigManager) GetTenantsByFeature(feature string) []*Ten
 		case "advanced_analytics":
 			hasFeature = tenant.Features.AdvancedAnalytics
 		}
-		
+
 		if hasFeature {
 			tenants = append(tenants, tenant)
 		}
 	}
-	
+
 	return tenants
 }
 
@@ -317,50 +445,57 @@ func (mtcm *MultiTenantConfigManager) GetTenantsByFeature(feature string) []*Ten
 func (mtcm *MultiTenantConfigManager) ArchiveTenant(tenantID string) error {
 	mtcm.mu.Lock()
 	defer mtcm.mu.Unlock()
-	
+
 	tenant, exists := mtcm.tenants[tenantID]
 	if !exists {
 		return fmt.Errorf("tenant %s not found", tenantID)
 	}
-	
+
 	// Set tenant to deactivated
-	tenant.Status = TenantDeactivated
-	tenant.UpdatedAt = time.Now()
-	
+	updated := tenant.clone()
+	updated.Status = TenantDeactivated
+	updated.UpdatedAt = time.Now()
+
 	// Here you would implement actual archival logic:
 	// - Export tenant data
 	// - Remove from active systems
 	// - Store in archive storage
-	
-	return nil
+
+	return mtcm.commit(updated)
 }
 
 // BulkUpdateTenants applies updates to multiple tenants
 func (mtcm *MultiTenantConfigManager) BulkUpdateTenants(tenantIDs []string, updates map[string]interface{}) error {
 	mtcm.mu.Lock()
 	defer mtcm.mu.Unlock()
-	
+
 	var errors []error
-	
+
 	for _, tenantID := range tenantIDs {
 		tenant, exists := mtcm.tenants[tenantID]
 		if !exists {
 			errors = append(errors, fmt.Errorf("tenant %s not found", tenantID))
 			continue
 		}
-		
-		if err := mtcm.applyTenantOverrides(tenant, updates); err != nil {
+
+		updated := tenant.clone()
+		if err := mtcm.applyTenantOverrides(updated, updates); err != nil {
 			errors = append(errors, fmt.Errorf("failed to update tenant %s: %w", tenantID, err))
 			continue
 		}
-		
-		tenant.UpdatedAt = time.Now()
+
+		updated.UpdatedAt = time.Now()
+		if err := mtcm.commit(updated); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		mtcm.auditTenantOverrides(updated, tenant.Overrides)
 	}
-	
+
 	if len(errors) > 0 {
 		return fmt.Errorf("bulk update failed for some tenants: %v", errors)
 	}
-	
+
 	return nil
 }
 
@@ -369,20 +504,20 @@ func (mtcm *MultiTenantConfigManager) BulkUpdateTenants(tenantIDs []string, upda
 func (mtcm *MultiTenantConfigManager) createTenantConfig(tenantID, name string, quotas *TenantQuotas, features *TenantFeatures) *TenantConfig {
 	// Deep copy global config for tenant
 	tenantConfig := mtcm.deepCopyConfig(mtcm.globalConfig)
-	
+
 	// Apply tenant-specific modifications
 	mtcm.applyTenantIsolation(tenantConfig, tenantID)
-	
+
 	// Set default quotas if none provided
 	if quotas == nil {
 		quotas = mtcm.getDefaultQuotas()
 	}
-	
+
 	// Set default features if none provided
 	if features == nil {
 		features = mtcm.getDefaultFeatures()
 	}
-	
+
 	return &TenantConfig{
 		ID:        tenantID,
 		Name:      name,
@@ -403,36 +538,126 @@ func (mtcm *MultiTenantConfigManager) applyTenantIsolation(config *SuperClaudeCo
 		// Each tenant gets dedicated resources
 		config.Database.SQLite.Path = fmt.Sprintf("~/.superclaude/tenants/%s/data.db", tenantID)
 		config.Logging.File.Path = fmt.Sprintf("~/.superclaude/tenants/%s/logs/", tenantID)
-		
+
 	case IsolationPrivate:
 		// Complete isolation with separate infrastructure
 		config.Server.Port = config.Server.Port + hashTenantID(tenantID)%1000
 		config.MCP.Port = config.MCP.Port + hashTenantID(tenantID)%1000
-		
+
 	case IsolationShared:
 		// Shared infrastructure with logical separation
+		if config.Logging.StructuredFields == nil {
+			config.Logging.StructuredFields = make(map[string]string)
+		}
 		config.Logging.StructuredFields["tenant_id"] = tenantID
 	}
 }
 
+// applyTenantOverrides adds overrides to those of tenant and rebuilds its config
+// from the global config with every override applied
 func (mtcm *MultiTenantConfigManager) applyTenantOverrides(tenant *TenantConfig, overrides map[string]interface{}) error {
-	// Apply overrides to tenant configuration
-	// This would use reflection or a configuration library to apply nested updates
-	
-	for key, value := range overrides {
-		tenant.Overrides[key] = value
+	merged := maps.Clone(tenant.Overrides)
+	if merged == nil {
+		merged = make(map[string]interface{})
+	}
+	maps.Copy(merged, overrides)
+
+	config := mtcm.deepCopyConfig(mtcm.globalConfig)
+	mtcm.applyTenantIsolation(config, tenant.ID)
+	if _, err := applyConfigUpdates(config, merged); err != nil {
+		return err
+	}
+
+	tenant.Overrides = merged
+	tenant.Config = config
+	return nil
+}
+
+// loadTenants replaces the tenants in memory with those of the store. The manager
+// must be locked.
+func (mtcm *MultiTenantConfigManager) loadTenants() error {
+	stored, err := mtcm.store.LoadTenants(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to load tenants: %w", err)
 	}
-	
+
+	tenants := make(map[string]*TenantConfig, len(stored))
+	for _, tenant := range stored {
+		if err := mtcm.applyTenantOverrides(tenant, nil); err != nil {
+			return fmt.Errorf("failed to apply the overrides of tenant %s: %w", tenant.ID, err)
+		}
+		tenants[tenant.ID] = tenant
+	}
+	mtcm.tenants = tenants
 	return nil
 }
 
+// persist saves tenant in the store, if there is one. The manager must be locked.
+func (mtcm *MultiTenantConfigManager) persist(tenant *TenantConfig) error {
+	if mtcm.store == nil {
+		return nil
+	}
+	if err := mtcm.store.SaveTenant(context.Background(), tenant); err != nil {
+		return fmt.Errorf("failed to save tenant %s: %w", tenant.ID, err)
+	}
+	return nil
+}
+
+// commit saves tenant and replaces the tenant with its ID in memory. The manager
+// must be locked.
+func (mtcm *MultiTenantConfigManager) commit(tenant *TenantConfig) error {
+	if err := mtcm.persist(tenant); err != nil {
+		return err
+	}
+	mtcm.tenants[tenant.ID] = tenant
+	return nil
+}
+
+// clone copies tenant so it can be changed without affecting readers of the
+// original. Config is shared; it is replaced, never changed in place.
+func (tenant *TenantConfig) clone() *TenantConfig {
+	copied := *tenant
+	copied.Overrides = maps.Clone(tenant.Overrides)
+	copied.Metadata = maps.Clone(tenant.Metadata)
+	if tenant.Quotas != nil {
+		quotas := *tenant.Quotas
+		copied.Quotas = &quotas
+	}
+	if tenant.Features != nil {
+		features := *tenant.Features
+		copied.Features = &features
+	}
+	return &copied
+}
+
+// auditTenantOverrides records the overrides of tenant that differ from previous in
+// the audit log
+func (mtcm *MultiTenantConfigManager) auditTenantOverrides(tenant *TenantConfig, previous map[string]interface{}) {
+	if mtcm.auditLog == nil {
+		return
+	}
+
+	var diffs []configDiff
+	for _, key := range sortedKeys(tenant.Overrides) {
+		if old, ok := previous[key]; !ok || !reflect.DeepEqual(old, tenant.Overrides[key]) {
+			diffs = append(diffs, configDiff{path: key, old: previous[key], new: tenant.Overrides[key]})
+		}
+	}
+	if _, err := mtcm.auditLog.Record(AuditActor(), AuditSourceTenant, tenant.ID, "", fieldChanges(diffs)); err != nil {
+		logging.Error("Failed to record tenant configuration change in the audit log", "tenant", tenant.ID, "error", err)
+	}
+}
+
 func (mtcm *MultiTenantConfigManager) deepCopyConfig(config *SuperClaudeConfig) *SuperClaudeConfig {
-	// Implementation would create a deep copy
-	// For now, return the original (this should be implemented properly)
-	return config
+	return deepCopy(config)
 }
 
 func (mtcm *MultiTenantConfigManager) getDefaultQuotas() *TenantQuotas {
+	return DefaultTenantQuotas()
+}
+
+// DefaultTenantQuotas returns the quotas of tenants created without quotas
+func DefaultTenantQuotas() *TenantQuotas {
 	return &TenantQuotas{
 		MaxSessions:           10,
 		MaxRequestsPerMinute:  100,
@@ -463,4 +688,4 @@ func hashTenantID(tenantID string) int {
 		hash = (hash*31 + int(char)) % 1000
 	}
 	return hash
-}
\ No newline at end of file
+}
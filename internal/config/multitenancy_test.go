@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTenantQuotas(t *testing.T) {
+	mtcm := NewMultiTenantConfigManager(&SuperClaudeConfig{}, IsolationShared)
+	_, err := mtcm.CreateTenant("acme", "ACME", &TenantQuotas{MaxSessions: 2, MaxTokensPerMonth: 1000}, nil)
+	require.NoError(t, err)
+
+	assert.NoError(t, mtcm.ValidateTenantQuotas("acme", &TenantUsage{ActiveSessions: 2, TokensThisMonth: 1000, RequestsPerMinute: 500}),
+		"usage at a quota is within it, and a quota of 0 is unlimited")
+
+	err = mtcm.ValidateTenantQuotas("acme", &TenantUsage{TokensThisMonth: 1001})
+	var exceeded *QuotaExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, QuotaExceededError{TenantID: "acme", Quota: "max_tokens_per_month", Used: 1001, Limit: 1000}, *exceeded)
+	assert.EqualError(t, err, "tenant acme exceeded its max_tokens_per_month quota: 1001 used, limit 1000")
+
+	assert.NoError(t, mtcm.ValidateTenantQuotas("default", &TenantUsage{ActiveSessions: 100}))
+	assert.Error(t, mtcm.ValidateTenantQuotas("unknown", &TenantUsage{}))
+}
+
+func TestTenantOverridesApplyToTenantConfig(t *testing.T) {
+	global := &SuperClaudeConfig{}
+	global.Performance.BatchSize = 10
+	mtcm := NewMultiTenantConfigManager(global, IsolationShared)
+	_, err := mtcm.CreateTenant("acme", "ACME", nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, mtcm.UpdateTenantConfig("acme", map[string]interface{}{"performance.batch_size": 5}))
+	cfg, err := mtcm.GetTenantConfig("acme")
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.Performance.BatchSize)
+	assert.Equal(t, 10, global.Performance.BatchSize, "the global config is not changed")
+
+	assert.Error(t, mtcm.UpdateTenantConfig("acme", map[string]interface{}{"performance.unknown": 1}))
+	tenant, err := mtcm.GetTenant("acme")
+	require.NoError(t, err)
+	assert.NotContains(t, tenant.Overrides, "performance.unknown", "rejected overrides are not kept")
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/opencode-ai/opencode/internal/logging"
 )
 
 // ConfigObservability provides comprehensive configuration monitoring
@@ -25,42 +28,62 @@ type ConfigObservability struct {
 
 // ConfigMetrics tracks configuration-related metrics
 type ConfigMetrics struct {
-	configLoads          prometheus.Counter
-	configValidations    prometheus.Counter
-	configErrors         prometheus.Counter
-	configReloads        prometheus.Counter
-	configSize           prometheus.Gauge
-	validationDuration   prometheus.Histogram
-	tenantCount          prometheus.Gauge
-	activeTenants        prometheus.Gauge
-	quotaViolations      prometheus.Counter
-	featureUsage         *prometheus.CounterVec
-	configChanges        prometheus.Counter
-	driftDetections      prometheus.Counter
-}
-
-// ConfigDriftDetector monitors configuration drift
+	configLoads        prometheus.Counter
+	configValidations  prometheus.Counter
+	configErrors       prometheus.Counter
+	configReloads      prometheus.Counter
+	configSize         prometheus.Gauge
+	validationDuration prometheus.Histogram
+	tenantCount        prometheus.Gauge
+	activeTenants      prometheus.Gauge
+	quotaViolations    prometheus.Counter
+	featureUsage       *prometheus.CounterVec
+	configChanges      prometheus.Counter
+	driftDetections    prometheus.Counter
+}
+
+// ConfigDriftDetector monitors configuration drift. The running config is compared
+// with the config file it was loaded from and with an approved baseline file every
+// checkInterval, and every drifted key is reported through notify.
 type ConfigDriftDetector struct {
-	baseline        *SuperClaudeConfig
-	checkInterval   time.Duration
-	driftThreshold  float64
-	alertChannel    chan DriftAlert
-	running         bool
-	mu              sync.RWMutex
+	current        *SuperClaudeConfig
+	configFile     string
+	baselineFile   string
+	checkInterval  time.Duration
+	driftThreshold float64
+	alertChannel   chan DriftAlert
+	notify         func(DriftAlert)
+	running        bool
+	mu             sync.RWMutex
 }
 
-// DriftAlert represents a configuration drift detection
+// DriftAlert represents a configuration drift detection. Component is the dotted
+// key that drifted, Expected and Actual its values as JSON with secrets redacted.
 type DriftAlert struct {
-	Timestamp   time.Time              `json:"timestamp"`
-	DriftType   DriftType              `json:"drift_type"`
-	Severity    AlertSeverity          `json:"severity"`
-	Component   string                 `json:"component"`
-	Expected    interface{}            `json:"expected"`
-	Actual      interface{}            `json:"actual"`
-	Difference  float64                `json:"difference"`
-	Metadata    map[string]interface{} `json:"metadata"`
+	Timestamp  time.Time              `json:"timestamp"`
+	DriftType  DriftType              `json:"drift_type"`
+	Severity   AlertSeverity          `json:"severity"`
+	Source     DriftSource            `json:"source"`
+	File       string                 `json:"file,omitempty"`
+	Component  string                 `json:"component"`
+	Expected   interface{}            `json:"expected"`
+	Actual     interface{}            `json:"actual"`
+	Difference float64                `json:"difference"`
+	Metadata   map[string]interface{} `json:"metadata"`
 }
 
+// DriftSource is what the running config drifted from
+type DriftSource string
+
+const (
+	// DriftSourceFile is the config file the running config was loaded from
+	DriftSourceFile DriftSource = "file"
+	// DriftSourceBaseline is the approved baseline file
+	DriftSourceBaseline DriftSource = "baseline"
+	// DriftSourceChange is the running config before a change
+	DriftSourceChange DriftSource = "change"
+)
+
 type DriftType int
 
 const (
@@ -71,6 +94,40 @@ const (
 	DriftConfiguration
 )
 
+var driftTypeNames = []string{"performance", "security", "compliance", "resource", "configuration"}
+
+func (t DriftType) String() string {
+	if t < 0 || int(t) >= len(driftTypeNames) {
+		return "unknown"
+	}
+	return driftTypeNames[t]
+}
+
+// MarshalText reports the drift type by name in JSON
+func (t DriftType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText reads the drift type by name
+func (t *DriftType) UnmarshalText(text []byte) error {
+	parsed, err := ParseDriftType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// ParseDriftType returns the drift type named s
+func ParseDriftType(s string) (DriftType, error) {
+	for i, name := range driftTypeNames {
+		if strings.EqualFold(s, name) {
+			return DriftType(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown drift type %q, expected one of %s", s, strings.Join(driftTypeNames, ", "))
+}
+
 type AlertSeverity int
 
 const (
@@ -80,12 +137,46 @@ const (
 	AlertEmergency
 )
 
+var alertSeverityNames = []string{"info", "warning", "critical", "emergency"}
+
+func (s AlertSeverity) String() string {
+	if s < 0 || int(s) >= len(alertSeverityNames) {
+		return "unknown"
+	}
+	return alertSeverityNames[s]
+}
+
+// MarshalText reports the severity by name in JSON
+func (s AlertSeverity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads the severity by name
+func (s *AlertSeverity) UnmarshalText(text []byte) error {
+	parsed, err := ParseAlertSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// ParseAlertSeverity returns the severity named s
+func ParseAlertSeverity(s string) (AlertSeverity, error) {
+	for i, name := range alertSeverityNames {
+		if strings.EqualFold(s, name) {
+			return AlertSeverity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown alert severity %q, expected one of %s", s, strings.Join(alertSeverityNames, ", "))
+}
+
 // ConfigHealthChecker monitors configuration health
 type ConfigHealthChecker struct {
-	checks    []HealthCheck
-	interval  time.Duration
-	results   map[string]HealthResult
-	mu        sync.RWMutex
+	checks   []HealthCheck
+	interval time.Duration
+	results  map[string]HealthResult
+	mu       sync.RWMutex
 }
 
 // HealthCheck defines a configuration health check
@@ -116,6 +207,24 @@ const (
 	HealthUnknown
 )
 
+func (s HealthStatus) String() string {
+	switch s {
+	case HealthHealthy:
+		return "healthy"
+	case HealthDegraded:
+		return "degraded"
+	case HealthUnhealthy:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText reports the status by name in JSON
+func (s HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // ComplianceChecker validates configuration compliance
 type ComplianceChecker struct {
 	standards []ComplianceStandard
@@ -141,18 +250,18 @@ type ComplianceRule struct {
 
 // ComplianceResult represents compliance check result
 type ComplianceResult struct {
-	Compliant bool                   `json:"compliant"`
-	Message   string                 `json:"message"`
-	Evidence  map[string]interface{} `json:"evidence"`
-	Remediation string               `json:"remediation"`
+	Compliant   bool                   `json:"compliant"`
+	Message     string                 `json:"message"`
+	Evidence    map[string]interface{} `json:"evidence"`
+	Remediation string                 `json:"remediation"`
 }
 
 // AlertManager handles configuration alerts
 type AlertManager struct {
-	channels    []AlertChannel
-	rules       []AlertRule
+	channels     []AlertChannel
+	rules        []AlertRule
 	suppressions map[string]time.Time
-	mu          sync.RWMutex
+	mu           sync.RWMutex
 }
 
 // AlertChannel defines where alerts are sent
@@ -161,14 +270,19 @@ type AlertChannel interface {
 	Name() string
 }
 
-// AlertRule defines when to trigger alerts
+// AlertRule defines when to trigger alerts. A rule without a Condition is a drift
+// rule: it sends the drift alerts of at least its Severity, of its DriftTypes and
+// under its Paths when those are set. Alerts go to the named Channels, or to every
+// channel when there are none, and an alert is not repeated within the Cooldown.
 type AlertRule struct {
-	Name        string
-	Condition   func(*SuperClaudeConfig) bool
-	Severity    AlertSeverity
-	Message     string
-	Cooldown    time.Duration
-	Channels    []string
+	Name       string
+	Condition  func(*SuperClaudeConfig) bool
+	Severity   AlertSeverity
+	Message    string
+	Cooldown   time.Duration
+	Channels   []string
+	DriftTypes []DriftType
+	Paths      []string
 }
 
 // Alert represents a configuration alert
@@ -184,9 +298,45 @@ type Alert struct {
 	Actions     []string               `json:"actions"`
 }
 
+// ObservabilityOption configures a ConfigObservability
+type ObservabilityOption func(*ConfigObservability)
+
+// WithDriftFiles compares the running config with configFile, the file it was
+// loaded from, and with the approved baseline file. Either may be empty.
+func WithDriftFiles(configFile, baseline string) ObservabilityOption {
+	return func(co *ConfigObservability) {
+		co.driftDetector.configFile = configFile
+		co.driftDetector.baselineFile = baseline
+	}
+}
+
+// WithDriftInterval sets how often the config files are checked for drift
+func WithDriftInterval(interval time.Duration) ObservabilityOption {
+	return func(co *ConfigObservability) {
+		co.driftDetector.checkInterval = interval
+	}
+}
+
+// WithAlertChannels adds channels alerts can be sent to
+func WithAlertChannels(channels ...AlertChannel) ObservabilityOption {
+	return func(co *ConfigObservability) {
+		for _, channel := range channels {
+			co.alertManager.AddChannel(channel)
+		}
+	}
+}
+
+// WithDriftRules replaces the default drift rule, which sends every drift alert to
+// every channel
+func WithDriftRules(rules ...AlertRule) ObservabilityOption {
+	return func(co *ConfigObservability) {
+		co.alertManager.SetDriftRules(rules)
+	}
+}
+
 // NewConfigObservability creates a new configuration observability system
-func NewConfigObservability() *ConfigObservability {
-	return &ConfigObservability{
+func NewConfigObservability(opts ...ObservabilityOption) *ConfigObservability {
+	co := &ConfigObservability{
 		metrics:           newConfigMetrics(),
 		driftDetector:     newConfigDriftDetector(),
 		healthChecker:     newConfigHealthChecker(),
@@ -194,6 +344,14 @@ func NewConfigObservability() *ConfigObservability {
 		alertManager:      newAlertManager(),
 		enabled:           true,
 	}
+	co.driftDetector.notify = func(alert DriftAlert) {
+		co.metrics.driftDetections.Inc()
+		co.alertManager.SendDriftAlert(alert)
+	}
+	for _, opt := range opts {
+		opt(co)
+	}
+	return co
 }
 
 // Start begins configuration monitoring
@@ -232,12 +390,12 @@ func (co *ConfigObservability) RecordConfigLoad(config *SuperClaudeConfig, durat
 
 	co.metrics.configLoads.Inc()
 	co.metrics.validationDuration.Observe(duration.Seconds())
-	
+
 	// Calculate config size (approximate)
 	configJSON, _ := json.Marshal(config)
 	co.metrics.configSize.Set(float64(len(configJSON)))
 
-	logging.Debug("Configuration load recorded", 
+	logging.Debug("Configuration load recorded",
 		"duration", duration,
 		"size_bytes", len(configJSON))
 }
@@ -261,8 +419,9 @@ func (co *ConfigObservability) RecordConfigChange(oldConfig, newConfig *SuperCla
 	}
 
 	co.metrics.configChanges.Inc()
-	
+
 	// Trigger drift detection
+	co.driftDetector.setCurrent(newConfig)
 	go co.driftDetector.CheckDrift(oldConfig, newConfig)
 }
 
@@ -279,6 +438,43 @@ func (co *ConfigObservability) GetHealthStatus() map[string]HealthResult {
 	return results
 }
 
+// AddHealthCheck adds a health check, replacing the check with the same name. Checks
+// must be added before Start.
+func (co *ConfigObservability) AddHealthCheck(check HealthCheck) {
+	co.healthChecker.mu.Lock()
+	defer co.healthChecker.mu.Unlock()
+
+	for i, existing := range co.healthChecker.checks {
+		if existing.Name == check.Name {
+			co.healthChecker.checks[i] = check
+			return
+		}
+	}
+	co.healthChecker.checks = append(co.healthChecker.checks, check)
+}
+
+// CheckHealth runs every health check now and returns the overall status: unhealthy
+// when a critical check fails, degraded when any other check is not healthy
+func (co *ConfigObservability) CheckHealth(config *SuperClaudeConfig) (HealthStatus, map[string]HealthResult) {
+	results := co.healthChecker.runChecks(config)
+
+	overall := HealthHealthy
+	co.healthChecker.mu.RLock()
+	defer co.healthChecker.mu.RUnlock()
+	for _, check := range co.healthChecker.checks {
+		result, ok := results[check.Name]
+		if !ok || result.Status == HealthHealthy {
+			continue
+		}
+		if check.Critical && result.Status != HealthDegraded {
+			overall = HealthUnhealthy
+		} else if overall == HealthHealthy {
+			overall = HealthDegraded
+		}
+	}
+	return overall, results
+}
+
 // GetComplianceStatus returns compliance status
 func (co *ConfigObservability) GetComplianceStatus(config *SuperClaudeConfig) ComplianceReport {
 	return co.complianceChecker.CheckCompliance(config)
@@ -294,10 +490,10 @@ type ComplianceReport struct {
 
 // StandardResult contains results for a compliance standard
 type StandardResult struct {
-	Compliant   bool                      `json:"compliant"`
-	Score       float64                   `json:"score"`
-	Rules       map[string]ComplianceResult `json:"rules"`
-	Required    bool                      `json:"required"`
+	Compliant bool                        `json:"compliant"`
+	Score     float64                     `json:"score"`
+	Rules     map[string]ComplianceResult `json:"rules"`
+	Required  bool                        `json:"required"`
 }
 
 // ComplianceSummary provides aggregate compliance metrics
@@ -373,57 +569,119 @@ func newConfigDriftDetector() *ConfigDriftDetector {
 	}
 }
 
-func (cdd *ConfigDriftDetector) Start(ctx context.Context, baseline *SuperClaudeConfig) error {
+func (cdd *ConfigDriftDetector) Start(ctx context.Context, config *SuperClaudeConfig) error {
 	cdd.mu.Lock()
 	defer cdd.mu.Unlock()
 
-	cdd.baseline = baseline
+	cdd.current = config
 	cdd.running = true
 
 	go cdd.monitor(ctx)
 	return nil
 }
 
+func (cdd *ConfigDriftDetector) setCurrent(config *SuperClaudeConfig) {
+	cdd.mu.Lock()
+	defer cdd.mu.Unlock()
+	cdd.current = config
+}
+
 func (cdd *ConfigDriftDetector) monitor(ctx context.Context) {
 	ticker := time.NewTicker(cdd.checkInterval)
 	defer ticker.Stop()
 
+	cdd.CheckFiles()
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			// Periodic drift checks would go here
+			cdd.CheckFiles()
 		case alert := <-cdd.alertChannel:
-			logging.Warn("Configuration drift detected",
-				"type", alert.DriftType,
-				"component", alert.Component,
-				"severity", alert.Severity)
+			cdd.deliver(alert)
 		}
 	}
 }
 
+// deliver logs a drift alert and hands it to notify
+func (cdd *ConfigDriftDetector) deliver(alert DriftAlert) {
+	logging.Debug("Configuration drift detected",
+		"type", alert.DriftType,
+		"component", alert.Component,
+		"source", alert.Source,
+		"severity", alert.Severity)
+	if cdd.notify != nil {
+		cdd.notify(alert)
+	}
+}
+
+// enqueue queues a drift alert for the monitor, dropping it when the queue is full
+func (cdd *ConfigDriftDetector) enqueue(alert DriftAlert) {
+	select {
+	case cdd.alertChannel <- alert:
+	default:
+		logging.Warn("Drift alert channel full, dropping alert")
+	}
+}
+
+// CheckFiles compares the running config with the config file and the baseline
+// file, delivering an alert for every key that differs. A file that does not load
+// is logged and skipped.
+func (cdd *ConfigDriftDetector) CheckFiles() {
+	cdd.mu.RLock()
+	current := cdd.current
+	files := []struct {
+		source DriftSource
+		path   string
+	}{
+		{DriftSourceFile, cdd.configFile},
+		{DriftSourceBaseline, cdd.baselineFile},
+	}
+	cdd.mu.RUnlock()
+	if current == nil {
+		return
+	}
+
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		expected, err := LoadConfig(file.path)
+		if err != nil {
+			logging.Warn("Failed to load config to check for drift", "path", file.path, "error", err)
+			continue
+		}
+		cdd.report(file.source, file.path, expected, current, cdd.deliver)
+	}
+}
+
+// CheckDrift queues an alert for every significant change from old to new, for the
+// monitor to deliver
 func (cdd *ConfigDriftDetector) CheckDrift(old, new *SuperClaudeConfig) {
-	// Calculate configuration drift
-	changes := cdd.calculateChanges(old, new)
-	
+	cdd.report(DriftSourceChange, "", old, new, cdd.enqueue)
+}
+
+// report passes an alert for every significant change from expected to actual to send
+func (cdd *ConfigDriftDetector) report(source DriftSource, file string, expected, actual *SuperClaudeConfig, send func(DriftAlert)) {
+	changes := cdd.calculateChanges(expected, actual)
+
 	for _, change := range changes {
 		if change.Significance > cdd.driftThreshold {
 			alert := DriftAlert{
 				Timestamp:  time.Now(),
 				DriftType:  change.Type,
 				Severity:   change.Severity,
+				Source:     source,
+				File:       file,
 				Component:  change.Component,
 				Expected:   change.Expected,
 				Actual:     change.Actual,
 				Difference: change.Significance,
+				Metadata: map[string]interface{}{
+					"environment": actual.Deployment.Environment,
+				},
 			}
-			
-			select {
-			case cdd.alertChannel <- alert:
-			default:
-				logging.Warn("Drift alert channel full, dropping alert")
-			}
+			send(alert)
 		}
 	}
 }
@@ -437,33 +695,60 @@ type ConfigDriftChange struct {
 	Significance float64
 }
 
-func (cdd *ConfigDriftDetector) calculateChanges(old, new *SuperClaudeConfig) []ConfigDriftChange {
-	var changes []ConfigDriftChange
+// driftClasses classify the keys under a path, the first matching prefix wins
+var driftClasses = []struct {
+	prefix   string
+	kind     DriftType
+	severity AlertSeverity
+}{
+	{"security", DriftSecurity, AlertCritical},
+	{"server.tls", DriftSecurity, AlertCritical},
+	{"mcp.cors", DriftSecurity, AlertCritical},
+	{"logging", DriftCompliance, AlertWarning},
+	{"monitoring", DriftCompliance, AlertWarning},
+	{"rate_limiting", DriftResource, AlertWarning},
+	{"database", DriftResource, AlertWarning},
+	{"cache", DriftResource, AlertInfo},
+	{"performance", DriftPerformance, AlertWarning},
+	{"server", DriftConfiguration, AlertWarning},
+	{"providers", DriftConfiguration, AlertWarning},
+}
 
-	// Server configuration changes
-	if old.Server.Port != new.Server.Port {
-		changes = append(changes, ConfigDriftChange{
-			Component:    "server.port",
-			Type:         DriftConfiguration,
-			Severity:     AlertWarning,
-			Expected:     old.Server.Port,
-			Actual:       new.Server.Port,
-			Significance: 0.5, // Port changes are significant
-		})
+// driftSignificance weighs changes by severity against driftThreshold
+var driftSignificance = map[AlertSeverity]float64{
+	AlertInfo:     0.25,
+	AlertWarning:  0.5,
+	AlertCritical: 1.0,
+}
+
+// classifyDrift returns the type and severity of a change to the key at path.
+// Secrets are security changes wherever they are.
+func classifyDrift(path string) (DriftType, AlertSeverity) {
+	if isSecretPath(path) {
+		return DriftSecurity, AlertCritical
 	}
+	for _, class := range driftClasses {
+		if path == class.prefix || strings.HasPrefix(path, class.prefix+".") {
+			return class.kind, class.severity
+		}
+	}
+	return DriftConfiguration, AlertInfo
+}
 
-	// Security configuration changes
-	if old.Security.APIKeyEncryption != new.Security.APIKeyEncryption {
+// calculateChanges returns a change for every key that differs between old and new
+func (cdd *ConfigDriftDetector) calculateChanges(old, new *SuperClaudeConfig) []ConfigDriftChange {
+	var changes []ConfigDriftChange
+	for _, diff := range diffConfigs(old, new) {
+		kind, severity := classifyDrift(diff.path)
 		changes = append(changes, ConfigDriftChange{
-			Component:    "security.api_key_encryption",
-			Type:         DriftSecurity,
-			Severity:     AlertCritical,
-			Expected:     old.Security.APIKeyEncryption,
-			Actual:       new.Security.APIKeyEncryption,
-			Significance: 1.0, // Security changes are always significant
+			Component:    diff.path,
+			Type:         kind,
+			Severity:     severity,
+			Expected:     auditValue(diff.path, diff.old),
+			Actual:       auditValue(diff.path, diff.new),
+			Significance: driftSignificance[severity],
 		})
 	}
-
 	return changes
 }
 
@@ -481,6 +766,8 @@ func (chc *ConfigHealthChecker) Start(ctx context.Context, config *SuperClaudeCo
 }
 
 func (chc *ConfigHealthChecker) runHealthChecks(ctx context.Context, config *SuperClaudeConfig) {
+	chc.runChecks(config)
+
 	ticker := time.NewTicker(chc.interval)
 	defer ticker.Stop()
 
@@ -489,20 +776,32 @@ func (chc *ConfigHealthChecker) runHealthChecks(ctx context.Context, config *Sup
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			for _, check := range chc.checks {
-				start := time.Now()
-				result := check.Check(config)
-				result.Duration = time.Since(start)
-				result.Timestamp = time.Now()
-
-				chc.mu.Lock()
-				chc.results[check.Name] = result
-				chc.mu.Unlock()
-			}
+			chc.runChecks(config)
 		}
 	}
 }
 
+// runChecks runs every check, stores the results and returns them
+func (chc *ConfigHealthChecker) runChecks(config *SuperClaudeConfig) map[string]HealthResult {
+	chc.mu.RLock()
+	checks := append([]HealthCheck(nil), chc.checks...)
+	chc.mu.RUnlock()
+
+	results := make(map[string]HealthResult, len(checks))
+	for _, check := range checks {
+		start := time.Now()
+		result := check.Check(config)
+		result.Duration = time.Since(start)
+		result.Timestamp = time.Now()
+		results[check.Name] = result
+
+		chc.mu.Lock()
+		chc.results[check.Name] = result
+		chc.mu.Unlock()
+	}
+	return results
+}
+
 func getDefaultHealthChecks() []HealthCheck {
 	return []HealthCheck{
 		{
@@ -553,11 +852,11 @@ func newComplianceChecker() *ComplianceChecker {
 func (cc *ComplianceChecker) Start(ctx context.Context, config *SuperClaudeConfig) error {
 	// Initial compliance check
 	report := cc.CheckCompliance(config)
-	
+
 	logging.Info("Initial compliance check completed",
 		"overall_compliant", report.OverallCompliant,
 		"compliance_rate", report.Summary.ComplianceRate)
-	
+
 	return nil
 }
 
@@ -666,8 +965,134 @@ func newAlertManager() *AlertManager {
 	}
 }
 
+// AddChannel adds a channel alerts can be sent to, replacing the channel with the
+// same name
+func (am *AlertManager) AddChannel(channel AlertChannel) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	for i, existing := range am.channels {
+		if existing.Name() == channel.Name() {
+			am.channels[i] = channel
+			return
+		}
+	}
+	am.channels = append(am.channels, channel)
+}
+
+// SetDriftRules replaces the drift rules, keeping the rules with a condition
+func (am *AlertManager) SetDriftRules(rules []AlertRule) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	kept := am.rules[:0:0]
+	for _, rule := range am.rules {
+		if rule.Condition != nil {
+			kept = append(kept, rule)
+		}
+	}
+	am.rules = append(kept, rules...)
+}
+
+// SendDriftAlert sends a drift alert to the channels of the drift rules it matches,
+// once per channel. A rule does not send the same drift again within its cooldown.
+func (am *AlertManager) SendDriftAlert(drift DriftAlert) {
+	environment, _ := drift.Metadata["environment"].(string)
+	alert := Alert{
+		ID:          uuid.New().String(),
+		Timestamp:   drift.Timestamp,
+		Severity:    drift.Severity,
+		Title:       fmt.Sprintf("Configuration drift in %s", drift.Component),
+		Message:     driftMessage(drift),
+		Component:   drift.Component,
+		Environment: environment,
+		Metadata: map[string]interface{}{
+			"drift_type": drift.DriftType,
+			"source":     drift.Source,
+			"expected":   drift.Expected,
+			"actual":     drift.Actual,
+		},
+	}
+	if drift.File != "" {
+		alert.Metadata["file"] = drift.File
+	}
+	// The same drift found again has the same key, whenever it is found
+	key := fmt.Sprintf("%s|%s|%s|%s", drift.Source, drift.Component, drift.Expected, drift.Actual)
+
+	am.mu.Lock()
+	now := time.Now()
+	selected := make(map[string]bool)
+	all := false
+	for _, rule := range am.rules {
+		if rule.Condition != nil || !rule.matches(drift) {
+			continue
+		}
+		ruleKey := rule.Name + "|" + key
+		if until, ok := am.suppressions[ruleKey]; ok && now.Before(until) {
+			continue
+		}
+		if rule.Cooldown > 0 {
+			am.suppressions[ruleKey] = now.Add(rule.Cooldown)
+		}
+		if len(rule.Channels) == 0 {
+			all = true
+		}
+		for _, name := range rule.Channels {
+			selected[name] = true
+		}
+	}
+	var channels []AlertChannel
+	for _, channel := range am.channels {
+		if all || selected[channel.Name()] {
+			channels = append(channels, channel)
+		}
+	}
+	am.mu.Unlock()
+
+	for _, channel := range channels {
+		if err := channel.SendAlert(alert); err != nil {
+			logging.Warn("Failed to send configuration alert", "channel", channel.Name(), "error", err)
+		}
+	}
+}
+
+// matches reports whether the drift rule applies to drift
+func (rule AlertRule) matches(drift DriftAlert) bool {
+	if drift.Severity < rule.Severity {
+		return false
+	}
+	if len(rule.DriftTypes) > 0 && !slices.Contains(rule.DriftTypes, drift.DriftType) {
+		return false
+	}
+	if len(rule.Paths) == 0 {
+		return true
+	}
+	for _, path := range rule.Paths {
+		if drift.Component == path || strings.HasPrefix(drift.Component, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func driftMessage(drift DriftAlert) string {
+	switch drift.Source {
+	case DriftSourceFile:
+		return fmt.Sprintf("%s is %s in the running config but %s in %s", drift.Component, drift.Actual, drift.Expected, drift.File)
+	case DriftSourceBaseline:
+		return fmt.Sprintf("%s is %s in the running config but %s in the approved baseline %s", drift.Component, drift.Actual, drift.Expected, drift.File)
+	default:
+		return fmt.Sprintf("%s changed from %s to %s", drift.Component, drift.Expected, drift.Actual)
+	}
+}
+
 func getDefaultAlertRules() []AlertRule {
 	return []AlertRule{
+		{
+			Name:     "config_drift",
+			Severity: AlertInfo,
+			Cooldown: 1 * time.Hour,
+		},
 		{
 			Name:     "tls_disabled_production",
 			Severity: AlertCritical,
@@ -688,4 +1113,4 @@ func getDefaultAlertRules() []AlertRule {
 			},
 		},
 	}
-}
\ No newline at end of file
+}
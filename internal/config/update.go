@@ -0,0 +1,422 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const redactedValue = "[REDACTED]"
+
+// secretPaths are the dotted keys of the values ExportConfig and the audit log redact
+var secretPaths = []string{
+	"providers.openrouter.api_key",
+	"providers.openai.api_key",
+	"providers.anthropic.api_key",
+	"providers.ollama.api_key",
+	"database.postgres.password",
+	"database.mysql.password",
+	"cache.redis.password",
+	"security.auth.jwt_secret",
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// isSecretPath reports whether path is a secret or a section containing one
+func isSecretPath(path string) bool {
+	for _, secret := range secretPaths {
+		if secret == path || strings.HasPrefix(secret, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// deepCopy returns a copy of config that shares no maps, slices or pointers with it
+func deepCopy(config *SuperClaudeConfig) *SuperClaudeConfig {
+	if config == nil {
+		return nil
+	}
+	copied := copyValue(reflect.ValueOf(*config)).Interface().(SuperClaudeConfig)
+	return &copied
+}
+
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(copyValue(v.Field(i)))
+			}
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return copied
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(copyValue(v.Elem()))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(copyValue(v.Elem()))
+		return copied
+	}
+	return v
+}
+
+// applyConfigUpdates sets the values of updates, keyed by dotted mapstructure paths
+// such as performance.batch_size, on config. A map value updates the keys of the
+// section it is set on. It returns every leaf it set with its coerced value.
+func applyConfigUpdates(config *SuperClaudeConfig, updates map[string]interface{}) (map[string]interface{}, error) {
+	applied := make(map[string]interface{})
+	root := reflect.ValueOf(config).Elem()
+	for _, path := range sortedKeys(updates) {
+		if err := setConfigValue(root, path, updates[path], applied); err != nil {
+			return nil, err
+		}
+	}
+	return applied, nil
+}
+
+// setConfigValue walks path from target, a struct, and sets the value it ends at
+func setConfigValue(target reflect.Value, path string, value interface{}, applied map[string]interface{}) error {
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		switch target.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByKey(target, segment)
+			if !ok {
+				return fmt.Errorf("unknown config key %q", strings.Join(segments[:i+1], "."))
+			}
+			target = field
+		case reflect.Map:
+			// Map keys may contain dots themselves, so the rest of the path is the key
+			return setMapEntry(target, strings.Join(segments[:i], "."), strings.Join(segments[i:], "."), value, applied)
+		default:
+			return fmt.Errorf("config key %q has no key %q", strings.Join(segments[:i], "."), segment)
+		}
+	}
+
+	return setLeaf(target, path, value, applied)
+}
+
+// setLeaf sets target, recursing into sections given as maps
+func setLeaf(target reflect.Value, path string, value interface{}, applied map[string]interface{}) error {
+	if section, ok := value.(map[string]interface{}); ok {
+		switch target.Kind() {
+		case reflect.Struct:
+			for _, key := range sortedKeys(section) {
+				field, ok := fieldByKey(target, key)
+				if !ok {
+					return fmt.Errorf("unknown config key %q", path+"."+key)
+				}
+				if err := setLeaf(field, path+"."+key, section[key], applied); err != nil {
+					return err
+				}
+			}
+			return nil
+		case reflect.Map:
+			for _, key := range sortedKeys(section) {
+				if err := setMapEntry(target, path, key, section[key], applied); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	if target.Kind() == reflect.Struct {
+		return fmt.Errorf("config key %q is a section, set its keys instead", path)
+	}
+
+	coerced, err := coerceConfigValue(value, target.Type())
+	if err != nil {
+		return fmt.Errorf("config key %q: %w", path, err)
+	}
+	target.Set(coerced)
+	applied[path] = coerced.Interface()
+	return nil
+}
+
+// setMapEntry sets or, for a nil value, deletes key of the map at path
+func setMapEntry(target reflect.Value, path, key string, value interface{}, applied map[string]interface{}) error {
+	entryPath := path + "." + key
+	if value == nil {
+		if !target.IsNil() {
+			target.SetMapIndex(reflect.ValueOf(key), reflect.Value{})
+		}
+		applied[entryPath] = nil
+		return nil
+	}
+	coerced, err := coerceConfigValue(value, target.Type().Elem())
+	if err != nil {
+		return fmt.Errorf("config key %q: %w", entryPath, err)
+	}
+	if target.IsNil() {
+		target.Set(reflect.MakeMap(target.Type()))
+	}
+	target.SetMapIndex(reflect.ValueOf(key), coerced)
+	applied[entryPath] = coerced.Interface()
+	return nil
+}
+
+// fieldByKey returns the field of a struct whose mapstructure tag is key
+func fieldByKey(target reflect.Value, key string) (reflect.Value, bool) {
+	for i := 0; i < target.NumField(); i++ {
+		tag, _, _ := strings.Cut(target.Type().Field(i).Tag.Get("mapstructure"), ",")
+		if tag == key && target.Field(i).CanSet() {
+			return target.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// coerceConfigValue converts value to t the way the config file would be read:
+// strings are parsed into numbers, booleans and durations, and a comma separated
+// string is split into a list
+func coerceConfigValue(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Type() == t {
+		return v, nil
+	}
+
+	if t == durationType {
+		switch value := value.(type) {
+		case string:
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid duration %q", value)
+			}
+			return reflect.ValueOf(d), nil
+		default:
+			return reflect.Value{}, fmt.Errorf("expected a duration such as \"30s\", got %T", value)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		switch v.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return reflect.ValueOf(fmt.Sprint(value)).Convert(t), nil
+		}
+	case reflect.Bool:
+		switch v.Kind() {
+		case reflect.Bool:
+			return v.Convert(t), nil
+		case reflect.String:
+			b, err := strconv.ParseBool(v.String())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid boolean %q", v.String())
+			}
+			return reflect.ValueOf(b).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = v.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = int64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			if f != float64(int64(f)) {
+				return reflect.Value{}, fmt.Errorf("expected an integer, got %v", f)
+			}
+			n = int64(f)
+		case reflect.String:
+			parsed, err := strconv.ParseInt(strings.TrimSpace(v.String()), 10, 64)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid integer %q", v.String())
+			}
+			n = parsed
+		default:
+			return reflect.Value{}, fmt.Errorf("expected an integer, got %T", value)
+		}
+		if reflect.Zero(t).OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("%d is out of range", n)
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	case reflect.Float32, reflect.Float64:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return v.Convert(t), nil
+		case reflect.String:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v.String()), 64)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid number %q", v.String())
+			}
+			return reflect.ValueOf(f).Convert(t), nil
+		}
+	case reflect.Slice:
+		var items []interface{}
+		switch v.Kind() {
+		case reflect.String:
+			for _, item := range strings.Split(v.String(), ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				items = append(items, v.Index(i).Interface())
+			}
+		default:
+			return reflect.Value{}, fmt.Errorf("expected a list, got %T", value)
+		}
+		list := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			coerced, err := coerceConfigValue(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("item %d: %w", i, err)
+			}
+			list.Index(i).Set(coerced)
+		}
+		return list, nil
+	case reflect.Map:
+		if v.Kind() != reflect.Map {
+			return reflect.Value{}, fmt.Errorf("expected a map, got %T", value)
+		}
+		m := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			coerced, err := coerceConfigValue(iter.Value().Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %q: %w", key, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), coerced)
+		}
+		return m, nil
+	}
+
+	if v.Type().ConvertibleTo(t) && v.Kind() == t.Kind() {
+		return v.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("expected %s, got %T", t, value)
+}
+
+// configDiff is a value that differs between two configs
+type configDiff struct {
+	path     string
+	old, new interface{}
+}
+
+// diffConfigs returns the leaves, keyed by dotted mapstructure paths, that differ
+// between old and new, sorted by path. Lists are compared as a whole and a map entry
+// missing on one side is nil there.
+func diffConfigs(old, new *SuperClaudeConfig) []configDiff {
+	var diffs []configDiff
+	diffValues("", reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem(), &diffs)
+	return diffs
+}
+
+func diffValues(path string, old, new reflect.Value, diffs *[]configDiff) {
+	switch old.Kind() {
+	case reflect.Struct:
+		for i := 0; i < old.NumField(); i++ {
+			tag, _, _ := strings.Cut(old.Type().Field(i).Tag.Get("mapstructure"), ",")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			diffValues(joinConfigPath(path, tag), old.Field(i), new.Field(i), diffs)
+		}
+		return
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, m := range []reflect.Value{old, new} {
+			for _, key := range m.MapKeys() {
+				keys[fmt.Sprint(key.Interface())] = key
+			}
+		}
+		for _, name := range sortedKeys(keys) {
+			oldEntry, newEntry := old.MapIndex(keys[name]), new.MapIndex(keys[name])
+			entryPath := joinConfigPath(path, name)
+			switch {
+			case !oldEntry.IsValid():
+				*diffs = append(*diffs, configDiff{path: entryPath, new: diffValue(newEntry)})
+			case !newEntry.IsValid():
+				*diffs = append(*diffs, configDiff{path: entryPath, old: diffValue(oldEntry)})
+			default:
+				diffValues(entryPath, oldEntry, newEntry, diffs)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+		*diffs = append(*diffs, configDiff{path: path, old: diffValue(old), new: diffValue(new)})
+	}
+}
+
+// diffValue returns v as it would be written in the config file
+func diffValue(v reflect.Value) interface{} {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	return v.Interface()
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// redactConfig replaces the non-empty secrets of config, which it modifies
+func redactConfig(config *SuperClaudeConfig) {
+	root := reflect.ValueOf(config).Elem()
+	for _, path := range secretPaths {
+		target := root
+		for _, segment := range strings.Split(path, ".") {
+			target, _ = fieldByKey(target, segment)
+		}
+		if target.String() != "" {
+			target.SetString(redactedValue)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
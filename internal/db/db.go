@@ -24,9 +24,21 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addTenantUsageStmt, err = db.PrepareContext(ctx, addTenantUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query AddTenantUsage: %w", err)
+	}
+	if q.bindTenantSessionStmt, err = db.PrepareContext(ctx, bindTenantSession); err != nil {
+		return nil, fmt.Errorf("error preparing query BindTenantSession: %w", err)
+	}
+	if q.countActiveTenantSessionsStmt, err = db.PrepareContext(ctx, countActiveTenantSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query CountActiveTenantSessions: %w", err)
+	}
 	if q.createFileStmt, err = db.PrepareContext(ctx, createFile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateFile: %w", err)
 	}
+	if q.createMCPSessionStmt, err = db.PrepareContext(ctx, createMCPSession); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateMCPSession: %w", err)
+	}
 	if q.createMessageStmt, err = db.PrepareContext(ctx, createMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateMessage: %w", err)
 	}
@@ -48,18 +60,36 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.deleteSessionMessagesStmt, err = db.PrepareContext(ctx, deleteSessionMessages); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSessionMessages: %w", err)
 	}
+	if q.deleteTenantStmt, err = db.PrepareContext(ctx, deleteTenant); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteTenant: %w", err)
+	}
 	if q.getFileStmt, err = db.PrepareContext(ctx, getFile); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFile: %w", err)
 	}
 	if q.getFileByPathAndSessionStmt, err = db.PrepareContext(ctx, getFileByPathAndSession); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFileByPathAndSession: %w", err)
 	}
+	if q.getMCPSessionStmt, err = db.PrepareContext(ctx, getMCPSession); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMCPSession: %w", err)
+	}
 	if q.getMessageStmt, err = db.PrepareContext(ctx, getMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessage: %w", err)
 	}
 	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
 	}
+	if q.getSessionTenantStmt, err = db.PrepareContext(ctx, getSessionTenant); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSessionTenant: %w", err)
+	}
+	if q.getTenantStmt, err = db.PrepareContext(ctx, getTenant); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTenant: %w", err)
+	}
+	if q.getTenantStorageStmt, err = db.PrepareContext(ctx, getTenantStorage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTenantStorage: %w", err)
+	}
+	if q.getTenantUsageStmt, err = db.PrepareContext(ctx, getTenantUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTenantUsage: %w", err)
+	}
 	if q.listFilesByPathStmt, err = db.PrepareContext(ctx, listFilesByPath); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesByPath: %w", err)
 	}
@@ -69,6 +99,9 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.listLatestSessionFilesStmt, err = db.PrepareContext(ctx, listLatestSessionFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListLatestSessionFiles: %w", err)
 	}
+	if q.listMCPSessionsByOwnerStmt, err = db.PrepareContext(ctx, listMCPSessionsByOwner); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMCPSessionsByOwner: %w", err)
+	}
 	if q.listMessagesBySessionStmt, err = db.PrepareContext(ctx, listMessagesBySession); err != nil {
 		return nil, fmt.Errorf("error preparing query ListMessagesBySession: %w", err)
 	}
@@ -78,25 +111,57 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.listSessionsStmt, err = db.PrepareContext(ctx, listSessions); err != nil {
 		return nil, fmt.Errorf("error preparing query ListSessions: %w", err)
 	}
+	if q.listTenantUsageStmt, err = db.PrepareContext(ctx, listTenantUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTenantUsage: %w", err)
+	}
+	if q.listTenantsStmt, err = db.PrepareContext(ctx, listTenants); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTenants: %w", err)
+	}
 	if q.updateFileStmt, err = db.PrepareContext(ctx, updateFile); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateFile: %w", err)
 	}
+	if q.updateMCPSessionStmt, err = db.PrepareContext(ctx, updateMCPSession); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateMCPSession: %w", err)
+	}
 	if q.updateMessageStmt, err = db.PrepareContext(ctx, updateMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateMessage: %w", err)
 	}
 	if q.updateSessionStmt, err = db.PrepareContext(ctx, updateSession); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateSession: %w", err)
 	}
+	if q.upsertTenantStmt, err = db.PrepareContext(ctx, upsertTenant); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertTenant: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addTenantUsageStmt != nil {
+		if cerr := q.addTenantUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addTenantUsageStmt: %w", cerr)
+		}
+	}
+	if q.bindTenantSessionStmt != nil {
+		if cerr := q.bindTenantSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing bindTenantSessionStmt: %w", cerr)
+		}
+	}
+	if q.countActiveTenantSessionsStmt != nil {
+		if cerr := q.countActiveTenantSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countActiveTenantSessionsStmt: %w", cerr)
+		}
+	}
 	if q.createFileStmt != nil {
 		if cerr := q.createFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createFileStmt: %w", cerr)
 		}
 	}
+	if q.createMCPSessionStmt != nil {
+		if cerr := q.createMCPSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createMCPSessionStmt: %w", cerr)
+		}
+	}
 	if q.createMessageStmt != nil {
 		if cerr := q.createMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createMessageStmt: %w", cerr)
@@ -132,6 +197,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteSessionMessagesStmt: %w", cerr)
 		}
 	}
+	if q.deleteTenantStmt != nil {
+		if cerr := q.deleteTenantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteTenantStmt: %w", cerr)
+		}
+	}
 	if q.getFileStmt != nil {
 		if cerr := q.getFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getFileStmt: %w", cerr)
@@ -142,6 +212,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getFileByPathAndSessionStmt: %w", cerr)
 		}
 	}
+	if q.getMCPSessionStmt != nil {
+		if cerr := q.getMCPSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMCPSessionStmt: %w", cerr)
+		}
+	}
 	if q.getMessageStmt != nil {
 		if cerr := q.getMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getMessageStmt: %w", cerr)
@@ -152,6 +227,26 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
 		}
 	}
+	if q.getSessionTenantStmt != nil {
+		if cerr := q.getSessionTenantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSessionTenantStmt: %w", cerr)
+		}
+	}
+	if q.getTenantStmt != nil {
+		if cerr := q.getTenantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTenantStmt: %w", cerr)
+		}
+	}
+	if q.getTenantStorageStmt != nil {
+		if cerr := q.getTenantStorageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTenantStorageStmt: %w", cerr)
+		}
+	}
+	if q.getTenantUsageStmt != nil {
+		if cerr := q.getTenantUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTenantUsageStmt: %w", cerr)
+		}
+	}
 	if q.listFilesByPathStmt != nil {
 		if cerr := q.listFilesByPathStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listFilesByPathStmt: %w", cerr)
@@ -167,6 +262,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listLatestSessionFilesStmt: %w", cerr)
 		}
 	}
+	if q.listMCPSessionsByOwnerStmt != nil {
+		if cerr := q.listMCPSessionsByOwnerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMCPSessionsByOwnerStmt: %w", cerr)
+		}
+	}
 	if q.listMessagesBySessionStmt != nil {
 		if cerr := q.listMessagesBySessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listMessagesBySessionStmt: %w", cerr)
@@ -182,11 +282,26 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listSessionsStmt: %w", cerr)
 		}
 	}
+	if q.listTenantUsageStmt != nil {
+		if cerr := q.listTenantUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTenantUsageStmt: %w", cerr)
+		}
+	}
+	if q.listTenantsStmt != nil {
+		if cerr := q.listTenantsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTenantsStmt: %w", cerr)
+		}
+	}
 	if q.updateFileStmt != nil {
 		if cerr := q.updateFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateFileStmt: %w", cerr)
 		}
 	}
+	if q.updateMCPSessionStmt != nil {
+		if cerr := q.updateMCPSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateMCPSessionStmt: %w", cerr)
+		}
+	}
 	if q.updateMessageStmt != nil {
 		if cerr := q.updateMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateMessageStmt: %w", cerr)
@@ -197,6 +312,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateSessionStmt: %w", cerr)
 		}
 	}
+	if q.upsertTenantStmt != nil {
+		if cerr := q.upsertTenantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertTenantStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -234,55 +354,85 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                          DBTX
-	tx                          *sql.Tx
-	createFileStmt              *sql.Stmt
-	createMessageStmt           *sql.Stmt
-	createSessionStmt           *sql.Stmt
-	deleteFileStmt              *sql.Stmt
-	deleteMessageStmt           *sql.Stmt
-	deleteSessionStmt           *sql.Stmt
-	deleteSessionFilesStmt      *sql.Stmt
-	deleteSessionMessagesStmt   *sql.Stmt
-	getFileStmt                 *sql.Stmt
-	getFileByPathAndSessionStmt *sql.Stmt
-	getMessageStmt              *sql.Stmt
-	getSessionByIDStmt          *sql.Stmt
-	listFilesByPathStmt         *sql.Stmt
-	listFilesBySessionStmt      *sql.Stmt
-	listLatestSessionFilesStmt  *sql.Stmt
-	listMessagesBySessionStmt   *sql.Stmt
-	listNewFilesStmt            *sql.Stmt
-	listSessionsStmt            *sql.Stmt
-	updateFileStmt              *sql.Stmt
-	updateMessageStmt           *sql.Stmt
-	updateSessionStmt           *sql.Stmt
+	db                            DBTX
+	tx                            *sql.Tx
+	addTenantUsageStmt            *sql.Stmt
+	bindTenantSessionStmt         *sql.Stmt
+	countActiveTenantSessionsStmt *sql.Stmt
+	createFileStmt                *sql.Stmt
+	createMCPSessionStmt          *sql.Stmt
+	createMessageStmt             *sql.Stmt
+	createSessionStmt             *sql.Stmt
+	deleteFileStmt                *sql.Stmt
+	deleteMessageStmt             *sql.Stmt
+	deleteSessionStmt             *sql.Stmt
+	deleteSessionFilesStmt        *sql.Stmt
+	deleteSessionMessagesStmt     *sql.Stmt
+	deleteTenantStmt              *sql.Stmt
+	getFileStmt                   *sql.Stmt
+	getFileByPathAndSessionStmt   *sql.Stmt
+	getMCPSessionStmt             *sql.Stmt
+	getMessageStmt                *sql.Stmt
+	getSessionByIDStmt            *sql.Stmt
+	getSessionTenantStmt          *sql.Stmt
+	getTenantStmt                 *sql.Stmt
+	getTenantStorageStmt          *sql.Stmt
+	getTenantUsageStmt            *sql.Stmt
+	listFilesByPathStmt           *sql.Stmt
+	listFilesBySessionStmt        *sql.Stmt
+	listLatestSessionFilesStmt    *sql.Stmt
+	listMCPSessionsByOwnerStmt    *sql.Stmt
+	listMessagesBySessionStmt     *sql.Stmt
+	listNewFilesStmt              *sql.Stmt
+	listSessionsStmt              *sql.Stmt
+	listTenantUsageStmt           *sql.Stmt
+	listTenantsStmt               *sql.Stmt
+	updateFileStmt                *sql.Stmt
+	updateMCPSessionStmt          *sql.Stmt
+	updateMessageStmt             *sql.Stmt
+	updateSessionStmt             *sql.Stmt
+	upsertTenantStmt              *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                          tx,
-		tx:                          tx,
-		createFileStmt:              q.createFileStmt,
-		createMessageStmt:           q.createMessageStmt,
-		createSessionStmt:           q.createSessionStmt,
-		deleteFileStmt:              q.deleteFileStmt,
-		deleteMessageStmt:           q.deleteMessageStmt,
-		deleteSessionStmt:           q.deleteSessionStmt,
-		deleteSessionFilesStmt:      q.deleteSessionFilesStmt,
-		deleteSessionMessagesStmt:   q.deleteSessionMessagesStmt,
-		getFileStmt:                 q.getFileStmt,
-		getFileByPathAndSessionStmt: q.getFileByPathAndSessionStmt,
-		getMessageStmt:              q.getMessageStmt,
-		getSessionByIDStmt:          q.getSessionByIDStmt,
-		listFilesByPathStmt:         q.listFilesByPathStmt,
-		listFilesBySessionStmt:      q.listFilesBySessionStmt,
-		listLatestSessionFilesStmt:  q.listLatestSessionFilesStmt,
-		listMessagesBySessionStmt:   q.listMessagesBySessionStmt,
-		listNewFilesStmt:            q.listNewFilesStmt,
-		listSessionsStmt:            q.listSessionsStmt,
-		updateFileStmt:              q.updateFileStmt,
-		updateMessageStmt:           q.updateMessageStmt,
-		updateSessionStmt:           q.updateSessionStmt,
+		db:                            tx,
+		tx:                            tx,
+		addTenantUsageStmt:            q.addTenantUsageStmt,
+		bindTenantSessionStmt:         q.bindTenantSessionStmt,
+		countActiveTenantSessionsStmt: q.countActiveTenantSessionsStmt,
+		createFileStmt:                q.createFileStmt,
+		createMCPSessionStmt:          q.createMCPSessionStmt,
+		createMessageStmt:             q.createMessageStmt,
+		createSessionStmt:             q.createSessionStmt,
+		deleteFileStmt:                q.deleteFileStmt,
+		deleteMessageStmt:             q.deleteMessageStmt,
+		deleteSessionStmt:             q.deleteSessionStmt,
+		deleteSessionFilesStmt:        q.deleteSessionFilesStmt,
+		deleteSessionMessagesStmt:     q.deleteSessionMessagesStmt,
+		deleteTenantStmt:              q.deleteTenantStmt,
+		getFileStmt:                   q.getFileStmt,
+		getFileByPathAndSessionStmt:   q.getFileByPathAndSessionStmt,
+		getMCPSessionStmt:             q.getMCPSessionStmt,
+		getMessageStmt:                q.getMessageStmt,
+		getSessionByIDStmt:            q.getSessionByIDStmt,
+		getSessionTenantStmt:          q.getSessionTenantStmt,
+		getTenantStmt:                 q.getTenantStmt,
+		getTenantStorageStmt:          q.getTenantStorageStmt,
+		getTenantUsageStmt:            q.getTenantUsageStmt,
+		listFilesByPathStmt:           q.listFilesByPathStmt,
+		listFilesBySessionStmt:        q.listFilesBySessionStmt,
+		listLatestSessionFilesStmt:    q.listLatestSessionFilesStmt,
+		listMCPSessionsByOwnerStmt:    q.listMCPSessionsByOwnerStmt,
+		listMessagesBySessionStmt:     q.listMessagesBySessionStmt,
+		listNewFilesStmt:              q.listNewFilesStmt,
+		listSessionsStmt:              q.listSessionsStmt,
+		listTenantUsageStmt:           q.listTenantUsageStmt,
+		listTenantsStmt:               q.listTenantsStmt,
+		updateFileStmt:                q.updateFileStmt,
+		updateMCPSessionStmt:          q.updateMCPSessionStmt,
+		updateMessageStmt:             q.updateMessageStmt,
+		updateSessionStmt:             q.updateSessionStmt,
+		upsertTenantStmt:              q.upsertTenantStmt,
 	}
 }
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- MCP sessions keep the workspace of the opencode session a MCP client works in
+CREATE TABLE IF NOT EXISTS mcp_sessions (
+    session_id TEXT PRIMARY KEY,
+    owner TEXT NOT NULL DEFAULT '',
+    client_name TEXT NOT NULL DEFAULT '',
+    working_dir TEXT NOT NULL,
+    environment TEXT NOT NULL DEFAULT '{}', -- JSON object of environment variables
+    created_at INTEGER NOT NULL,  -- Unix timestamp in milliseconds
+    updated_at INTEGER NOT NULL,  -- Unix timestamp in milliseconds
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_sessions_owner ON mcp_sessions (owner);
+
+CREATE TRIGGER IF NOT EXISTS update_mcp_sessions_updated_at
+AFTER UPDATE ON mcp_sessions
+BEGIN
+UPDATE mcp_sessions SET updated_at = strftime('%s', 'now')
+WHERE session_id = new.session_id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_mcp_sessions_updated_at;
+DROP INDEX IF EXISTS idx_mcp_sessions_owner;
+DROP TABLE IF EXISTS mcp_sessions;
+-- +goose StatementEnd
@@ -0,0 +1,61 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Tenants of the multi-tenant configuration manager
+CREATE TABLE IF NOT EXISTS tenants (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    quotas TEXT NOT NULL DEFAULT '{}',    -- JSON object of TenantQuotas
+    features TEXT NOT NULL DEFAULT '{}',  -- JSON object of TenantFeatures
+    overrides TEXT NOT NULL DEFAULT '{}', -- JSON object of config overrides keyed by dotted path
+    metadata TEXT NOT NULL DEFAULT '{}',  -- JSON object of strings
+    created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    updated_at INTEGER NOT NULL   -- Unix timestamp in seconds
+);
+
+CREATE TRIGGER IF NOT EXISTS update_tenants_updated_at
+AFTER UPDATE ON tenants
+BEGIN
+UPDATE tenants SET updated_at = strftime('%s', 'now')
+WHERE id = new.id;
+END;
+
+-- Tenant sessions record which tenant a session belongs to. The default tenant has
+-- no row in tenants, so the tenant is not a foreign key. Sessions stay bound to a
+-- deleted tenant, so they do not fall back to the default tenant.
+CREATE TABLE IF NOT EXISTS tenant_sessions (
+    session_id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_sessions_tenant_id ON tenant_sessions (tenant_id);
+
+-- Tenant usage adds up the tokens and cost of a tenant per month
+CREATE TABLE IF NOT EXISTS tenant_usage (
+    tenant_id TEXT NOT NULL,
+    period TEXT NOT NULL, -- Month as YYYY-MM in UTC
+    prompt_tokens INTEGER NOT NULL DEFAULT 0 CHECK (prompt_tokens >= 0),
+    completion_tokens INTEGER NOT NULL DEFAULT 0 CHECK (completion_tokens >= 0),
+    cost REAL NOT NULL DEFAULT 0.0 CHECK (cost >= 0.0),
+    updated_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    PRIMARY KEY (tenant_id, period)
+);
+
+CREATE TRIGGER IF NOT EXISTS delete_tenant_data
+AFTER DELETE ON tenants
+BEGIN
+DELETE FROM tenant_usage WHERE tenant_id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS delete_tenant_data;
+DROP TABLE IF EXISTS tenant_usage;
+DROP INDEX IF EXISTS idx_tenant_sessions_tenant_id;
+DROP TABLE IF EXISTS tenant_sessions;
+DROP TRIGGER IF EXISTS update_tenants_updated_at;
+DROP TABLE IF EXISTS tenants;
+-- +goose StatementEnd
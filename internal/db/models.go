@@ -18,6 +18,16 @@ type File struct {
 	UpdatedAt int64  `json:"updated_at"`
 }
 
+type McpSession struct {
+	SessionID   string `json:"session_id"`
+	Owner       string `json:"owner"`
+	ClientName  string `json:"client_name"`
+	WorkingDir  string `json:"working_dir"`
+	Environment string `json:"environment"`
+	CreatedAt   int64  `json:"created_at"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
+
 type Message struct {
 	ID         string         `json:"id"`
 	SessionID  string         `json:"session_id"`
@@ -41,3 +51,30 @@ type Session struct {
 	CreatedAt        int64          `json:"created_at"`
 	SummaryMessageID sql.NullString `json:"summary_message_id"`
 }
+
+type Tenant struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Status    int64  `json:"status"`
+	Quotas    string `json:"quotas"`
+	Features  string `json:"features"`
+	Overrides string `json:"overrides"`
+	Metadata  string `json:"metadata"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type TenantSession struct {
+	SessionID string `json:"session_id"`
+	TenantID  string `json:"tenant_id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type TenantUsage struct {
+	TenantID         string  `json:"tenant_id"`
+	Period           string  `json:"period"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	UpdatedAt        int64   `json:"updated_at"`
+}
@@ -9,7 +9,11 @@ import (
 )
 
 type Querier interface {
+	AddTenantUsage(ctx context.Context, arg AddTenantUsageParams) error
+	BindTenantSession(ctx context.Context, arg BindTenantSessionParams) error
+	CountActiveTenantSessions(ctx context.Context, arg CountActiveTenantSessionsParams) (int64, error)
 	CreateFile(ctx context.Context, arg CreateFileParams) (File, error)
+	CreateMCPSession(ctx context.Context, arg CreateMCPSessionParams) (McpSession, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	DeleteFile(ctx context.Context, id string) error
@@ -17,19 +21,30 @@ type Querier interface {
 	DeleteSession(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	DeleteTenant(ctx context.Context, id string) error
 	GetFile(ctx context.Context, id string) (File, error)
 	GetFileByPathAndSession(ctx context.Context, arg GetFileByPathAndSessionParams) (File, error)
+	GetMCPSession(ctx context.Context, sessionID string) (McpSession, error)
 	GetMessage(ctx context.Context, id string) (Message, error)
 	GetSessionByID(ctx context.Context, id string) (Session, error)
+	GetSessionTenant(ctx context.Context, sessionID string) (string, error)
+	GetTenant(ctx context.Context, id string) (Tenant, error)
+	GetTenantStorage(ctx context.Context, tenantID string) (int64, error)
+	GetTenantUsage(ctx context.Context, arg GetTenantUsageParams) (TenantUsage, error)
 	ListFilesByPath(ctx context.Context, path string) ([]File, error)
 	ListFilesBySession(ctx context.Context, sessionID string) ([]File, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
+	ListMCPSessionsByOwner(ctx context.Context, owner string) ([]McpSession, error)
 	ListMessagesBySession(ctx context.Context, sessionID string) ([]Message, error)
 	ListNewFiles(ctx context.Context) ([]File, error)
 	ListSessions(ctx context.Context) ([]Session, error)
+	ListTenantUsage(ctx context.Context, tenantID string) ([]TenantUsage, error)
+	ListTenants(ctx context.Context) ([]Tenant, error)
 	UpdateFile(ctx context.Context, arg UpdateFileParams) (File, error)
+	UpdateMCPSession(ctx context.Context, arg UpdateMCPSessionParams) (McpSession, error)
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) error
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error)
+	UpsertTenant(ctx context.Context, arg UpsertTenantParams) (Tenant, error)
 }
 
 var _ Querier = (*Queries)(nil)
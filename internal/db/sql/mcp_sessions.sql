@@ -0,0 +1,38 @@
+-- name: CreateMCPSession :one
+INSERT INTO mcp_sessions (
+    session_id,
+    owner,
+    client_name,
+    working_dir,
+    environment,
+    updated_at,
+    created_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%s', 'now'),
+    strftime('%s', 'now')
+) RETURNING *;
+
+-- name: GetMCPSession :one
+SELECT *
+FROM mcp_sessions
+WHERE session_id = ? LIMIT 1;
+
+-- name: ListMCPSessionsByOwner :many
+SELECT *
+FROM mcp_sessions
+WHERE owner = ?
+ORDER BY updated_at DESC;
+
+-- name: UpdateMCPSession :one
+UPDATE mcp_sessions
+SET
+    client_name = ?,
+    working_dir = ?,
+    environment = ?
+WHERE session_id = ?
+RETURNING *;
@@ -0,0 +1,117 @@
+-- name: UpsertTenant :one
+INSERT INTO tenants (
+    id,
+    name,
+    status,
+    quotas,
+    features,
+    overrides,
+    metadata,
+    updated_at,
+    created_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%s', 'now'),
+    strftime('%s', 'now')
+)
+ON CONFLICT (id) DO UPDATE SET
+    name = excluded.name,
+    status = excluded.status,
+    quotas = excluded.quotas,
+    features = excluded.features,
+    overrides = excluded.overrides,
+    metadata = excluded.metadata
+RETURNING *;
+
+-- name: GetTenant :one
+SELECT *
+FROM tenants
+WHERE id = ? LIMIT 1;
+
+-- name: ListTenants :many
+SELECT *
+FROM tenants
+ORDER BY id ASC;
+
+-- name: DeleteTenant :exec
+DELETE FROM tenants
+WHERE id = ?;
+
+-- name: BindTenantSession :exec
+INSERT INTO tenant_sessions (
+    session_id,
+    tenant_id,
+    created_at
+) VALUES (
+    ?,
+    ?,
+    strftime('%s', 'now')
+)
+ON CONFLICT (session_id) DO NOTHING;
+
+-- name: GetSessionTenant :one
+SELECT tenant_id
+FROM tenant_sessions
+WHERE session_id = ? LIMIT 1;
+
+-- name: CountActiveTenantSessions :one
+SELECT COUNT(*)
+FROM tenant_sessions
+JOIN sessions ON sessions.id = tenant_sessions.session_id
+WHERE tenant_sessions.tenant_id = ?
+  AND sessions.parent_session_id IS NULL
+  AND sessions.updated_at >= ?;
+
+-- name: GetTenantStorage :one
+SELECT CAST(
+    COALESCE((
+        SELECT SUM(LENGTH(messages.parts))
+        FROM messages
+        JOIN tenant_sessions ON tenant_sessions.session_id = messages.session_id
+        WHERE tenant_sessions.tenant_id = sqlc.arg(tenant_id)
+    ), 0) + COALESCE((
+        SELECT SUM(LENGTH(files.content))
+        FROM files
+        JOIN tenant_sessions ON tenant_sessions.session_id = files.session_id
+        WHERE tenant_sessions.tenant_id = sqlc.arg(tenant_id)
+    ), 0) AS INTEGER
+) AS bytes;
+
+-- name: AddTenantUsage :exec
+INSERT INTO tenant_usage (
+    tenant_id,
+    period,
+    prompt_tokens,
+    completion_tokens,
+    cost,
+    updated_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    strftime('%s', 'now')
+)
+ON CONFLICT (tenant_id, period) DO UPDATE SET
+    prompt_tokens = prompt_tokens + excluded.prompt_tokens,
+    completion_tokens = completion_tokens + excluded.completion_tokens,
+    cost = cost + excluded.cost,
+    updated_at = excluded.updated_at;
+
+-- name: GetTenantUsage :one
+SELECT *
+FROM tenant_usage
+WHERE tenant_id = ? AND period = ? LIMIT 1;
+
+-- name: ListTenantUsage :many
+SELECT *
+FROM tenant_usage
+WHERE tenant_id = ?
+ORDER BY period DESC;
@@ -1,6 +1,7 @@
 package fileutil
 
 import (
+	"bytes"
 	"fmt"
 	"io/fs"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/lithammer/fuzzysearch/fuzzy"
 	"github.com/opencode-ai/opencode/internal/logging"
 )
 
@@ -161,3 +163,155 @@ func GlobWithDoublestar(pattern, searchPath string, limit int) ([]string, bool,
 	}
 	return results, truncated, nil
 }
+
+func processNullTerminatedOutput(outputBytes []byte) []string {
+	if len(outputBytes) > 0 && outputBytes[len(outputBytes)-1] == 0 {
+		outputBytes = outputBytes[:len(outputBytes)-1]
+	}
+
+	if len(outputBytes) == 0 {
+		return []string{}
+	}
+
+	split := bytes.Split(outputBytes, []byte{0})
+	matches := make([]string, 0, len(split))
+
+	for _, p := range split {
+		if len(p) == 0 {
+			continue
+		}
+
+		path := string(p)
+		path = filepath.Join(".", path)
+
+		if !SkipHidden(path) {
+			matches = append(matches, path)
+		}
+	}
+
+	return matches
+}
+
+// FindFiles fuzzy matches the files below dir against query and returns their paths
+// relative to dir, best matches first when fzf is available
+func FindFiles(dir, query string) ([]string, error) {
+	cmdRg := GetRgCmd("") // No glob pattern for this use case
+	cmdFzf := GetFzfCmd(query)
+	if cmdRg != nil {
+		cmdRg.Dir = dir
+	}
+	if cmdFzf != nil {
+		cmdFzf.Dir = dir
+	}
+
+	var matches []string
+	// Case 1: Both rg and fzf available
+	if cmdRg != nil && cmdFzf != nil {
+		rgPipe, err := cmdRg.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rg stdout pipe: %w", err)
+		}
+		defer rgPipe.Close()
+
+		cmdFzf.Stdin = rgPipe
+		var fzfOut bytes.Buffer
+		var fzfErr bytes.Buffer
+		cmdFzf.Stdout = &fzfOut
+		cmdFzf.Stderr = &fzfErr
+
+		if err := cmdFzf.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start fzf: %w", err)
+		}
+
+		errRg := cmdRg.Run()
+		errFzf := cmdFzf.Wait()
+
+		if errRg != nil {
+			logging.Warn(fmt.Sprintf("rg command failed during pipe: %v", errRg))
+		}
+
+		if errFzf != nil {
+			if exitErr, ok := errFzf.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+				return []string{}, nil // No matches from fzf
+			}
+			return nil, fmt.Errorf("fzf command failed: %w\nStderr: %s", errFzf, fzfErr.String())
+		}
+
+		matches = processNullTerminatedOutput(fzfOut.Bytes())
+
+		// Case 2: Only rg available
+	} else if cmdRg != nil {
+		logging.Debug("Using Ripgrep with fuzzy match fallback for file completions")
+		var rgOut bytes.Buffer
+		var rgErr bytes.Buffer
+		cmdRg.Stdout = &rgOut
+		cmdRg.Stderr = &rgErr
+
+		if err := cmdRg.Run(); err != nil {
+			return nil, fmt.Errorf("rg command failed: %w\nStderr: %s", err, rgErr.String())
+		}
+
+		allFiles := processNullTerminatedOutput(rgOut.Bytes())
+		matches = fuzzy.Find(query, allFiles)
+
+		// Case 3: Only fzf available
+	} else if cmdFzf != nil {
+		logging.Debug("Using FZF with doublestar fallback for file completions")
+		allFiles, err := listFiles(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files for fzf: %w", err)
+		}
+
+		var fzfIn bytes.Buffer
+		for _, file := range allFiles {
+			fzfIn.WriteString(file)
+			fzfIn.WriteByte(0)
+		}
+
+		cmdFzf.Stdin = &fzfIn
+		var fzfOut bytes.Buffer
+		var fzfErr bytes.Buffer
+		cmdFzf.Stdout = &fzfOut
+		cmdFzf.Stderr = &fzfErr
+
+		if err := cmdFzf.Run(); err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+				return []string{}, nil
+			}
+			return nil, fmt.Errorf("fzf command failed: %w\nStderr: %s", err, fzfErr.String())
+		}
+
+		matches = processNullTerminatedOutput(fzfOut.Bytes())
+
+		// Case 4: Fallback to doublestar with fuzzy match
+	} else {
+		logging.Debug("Using doublestar with fuzzy match for file completions")
+		allFiles, err := listFiles(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to glob files: %w", err)
+		}
+
+		matches = fuzzy.Find(query, allFiles)
+	}
+
+	return matches, nil
+}
+
+// listFiles returns the visible files below dir, relative to it
+func listFiles(dir string) ([]string, error) {
+	files, _, err := GlobWithDoublestar("**/*", dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]string, 0, len(files))
+	for _, file := range files {
+		if rel, err := filepath.Rel(dir, file); err == nil && dir != "." {
+			file = rel
+		}
+		if !SkipHidden(file) {
+			visible = append(visible, file)
+		}
+	}
+	return visible, nil
+}
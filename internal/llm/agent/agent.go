@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/opencode-ai/opencode/internal/permission"
 	"github.com/opencode-ai/opencode/internal/pubsub"
 	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/opencode-ai/opencode/internal/snapshot"
 )
 
 // Common errors
@@ -26,6 +28,19 @@ var (
 	ErrSessionBusy      = errors.New("session is currently processing another request")
 )
 
+type callOptionsContextKey struct{}
+
+// WithCallOptions returns a context whose agent runs pass the given per-request
+// provider options, such as a thinking budget, to the model
+func WithCallOptions(ctx context.Context, opts ...provider.CallOption) context.Context {
+	return context.WithValue(ctx, callOptionsContextKey{}, opts)
+}
+
+func callOptionsFromContext(ctx context.Context) []provider.CallOption {
+	opts, _ := ctx.Value(callOptionsContextKey{}).([]provider.CallOption)
+	return opts
+}
+
 type AgentEventType string
 
 const (
@@ -39,6 +54,9 @@ type AgentEvent struct {
 	Message message.Message
 	Error   error
 
+	// Evidence lists the file ranges and URLs read during the turn
+	Evidence []message.EvidenceSource
+
 	// When summarizing
 	SessionID string
 	Progress  string
@@ -61,22 +79,63 @@ type agent struct {
 	sessions session.Service
 	messages message.Service
 
-	tools    []tools.BaseTool
-	provider provider.Provider
+	tools      []tools.BaseTool
+	mcp        *MCPManager
+	mcpAllowed []string
+	provider   provider.Provider
 
 	titleProvider     provider.Provider
 	summarizeProvider provider.Provider
 
+	snapshots snapshot.Service
+	quotas    QuotaEnforcer
+
 	activeRequests sync.Map
 }
 
+// Option configures an agent
+type Option func(*agent)
+
+// WithSnapshots snapshots the working tree before every turn so the session can
+// be rewound to before any of its user messages
+func WithSnapshots(snapshots snapshot.Service) Option {
+	return func(a *agent) {
+		a.snapshots = snapshots
+	}
+}
+
+// WithMCPTools offers the tools of the configured MCP servers in addition to the
+// agent's own tools. They are looked up on every turn, so servers that reconnect
+// or change their tool list are picked up by the next turn. When allowed names
+// are given, only those MCP tools are offered.
+func WithMCPTools(manager *MCPManager, allowed ...string) Option {
+	return func(a *agent) {
+		a.mcp = manager
+		a.mcpAllowed = allowed
+	}
+}
+
 func NewAgent(
 	agentName config.AgentName,
 	sessions session.Service,
 	messages message.Service,
 	agentTools []tools.BaseTool,
+	opts ...Option,
+) (Service, error) {
+	return NewAgentWithModel(agentName, "", sessions, messages, agentTools, opts...)
+}
+
+// NewAgentWithModel creates an agent that uses modelID instead of the model
+// configured for agentName. An empty modelID uses the configured model.
+func NewAgentWithModel(
+	agentName config.AgentName,
+	modelID models.ModelID,
+	sessions session.Service,
+	messages message.Service,
+	agentTools []tools.BaseTool,
+	opts ...Option,
 ) (Service, error) {
-	agentProvider, err := createAgentProvider(agentName)
+	agentProvider, err := createAgentProviderWithModel(agentName, modelID)
 	if err != nil {
 		return nil, err
 	}
@@ -106,6 +165,9 @@ func NewAgent(
 		summarizeProvider: summarizeProvider,
 		activeRequests:    sync.Map{},
 	}
+	for _, opt := range opts {
+		opt(agent)
+	}
 
 	return agent, nil
 }
@@ -204,12 +266,30 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 		return nil, ErrSessionBusy
 	}
 
+	release := func() {}
+	quotas := a.quotasFor(ctx)
+	if quotas != nil {
+		var err error
+		if ctx, release, err = quotas.Acquire(ctx, sessionID); err != nil {
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, quotasContextKey{}, quotas)
+	}
+
 	genCtx, cancel := context.WithCancel(ctx)
 
+	// Every turn keeps an evidence ledger, callers may pass their own to span several turns
+	ledger := tools.EvidenceLedgerFromContext(genCtx)
+	if ledger == nil {
+		ledger = tools.NewEvidenceLedger()
+		genCtx = tools.WithEvidenceLedger(genCtx, ledger)
+	}
+
 	a.activeRequests.Store(sessionID, cancel)
 	go func() {
 		logging.Debug("Request started", "sessionID", sessionID)
 		defer logging.RecoverPanic("agent.Run", func() {
+			release()
 			events <- a.err(fmt.Errorf("panic while running the agent"))
 		})
 		var attachmentParts []message.ContentPart
@@ -217,12 +297,14 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 			attachmentParts = append(attachmentParts, message.BinaryContent{Path: attachment.FilePath, MIMEType: attachment.MimeType, Data: attachment.Content})
 		}
 		result := a.processGeneration(genCtx, sessionID, content, attachmentParts)
+		result.Evidence = ledger.Sources()
 		if result.Error != nil && !errors.Is(result.Error, ErrRequestCancelled) && !errors.Is(result.Error, context.Canceled) {
 			logging.ErrorPersist(result.Error.Error())
 		}
 		logging.Debug("Request completed", "sessionID", sessionID)
 		a.activeRequests.Delete(sessionID)
 		cancel()
+		release()
 		a.Publish(pubsub.CreatedEvent, result)
 		events <- result
 		close(events)
@@ -270,6 +352,14 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 	if err != nil {
 		return a.err(fmt.Errorf("failed to create user message: %w", err))
 	}
+	// Dry runs leave the working tree untouched, there is nothing to rewind. The
+	// snapshots only cover the process working tree, not per-session workspaces.
+	_, ownWorkspace := tools.WorkspaceFromContext(ctx)
+	if a.snapshots != nil && tools.OverlayFromContext(ctx) == nil && !ownWorkspace {
+		if _, err := a.snapshots.Snapshot(ctx, sessionID, userMsg.ID); err != nil {
+			logging.Warn("Failed to snapshot the working tree", "session", sessionID, "error", err)
+		}
+	}
 	// Append the new user message to the conversation history.
 	msgHistory := append(msgs, userMsg)
 
@@ -310,6 +400,20 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 	}
 }
 
+// availableTools returns the agent's tools followed by the current MCP tools
+func (a *agent) availableTools(ctx context.Context) []tools.BaseTool {
+	if a.mcp == nil {
+		return a.tools
+	}
+	available := slices.Clip(a.tools)
+	for _, tool := range a.mcp.Tools(ctx) {
+		if len(a.mcpAllowed) == 0 || slices.Contains(a.mcpAllowed, tool.Info().Name) {
+			available = append(available, tool)
+		}
+	}
+	return available
+}
+
 func (a *agent) createUserMessage(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) (message.Message, error) {
 	parts := []message.ContentPart{message.TextContent{Text: content}}
 	parts = append(parts, attachmentParts...)
@@ -321,7 +425,8 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 
 func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, a.tools)
+	agentTools := a.availableTools(ctx)
+	eventChan := a.provider.StreamResponse(ctx, msgHistory, agentTools, callOptionsFromContext(ctx)...)
 
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:  message.Assistant,
@@ -365,7 +470,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		default:
 			// Continue processing
 			var tool tools.BaseTool
-			for _, availableTool := range a.tools {
+			for _, availableTool := range agentTools {
 				if availableTool.Info().Name == toolCall.Name {
 					tool = availableTool
 					break
@@ -510,6 +615,12 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.M
 	if err != nil {
 		return fmt.Errorf("failed to save session: %w", err)
 	}
+
+	if quotas := a.quotasFor(ctx); quotas != nil {
+		if err := quotas.TrackUsage(ctx, sessionID, usage, cost); err != nil {
+			return fmt.Errorf("failed to track usage: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -704,11 +815,18 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 }
 
 func createAgentProvider(agentName config.AgentName) (provider.Provider, error) {
+	return createAgentProviderWithModel(agentName, "")
+}
+
+func createAgentProviderWithModel(agentName config.AgentName, modelID models.ModelID) (provider.Provider, error) {
 	cfg := config.Get()
 	agentConfig, ok := cfg.Agents[agentName]
 	if !ok {
 		return nil, fmt.Errorf("agent %s not found", agentName)
 	}
+	if modelID != "" {
+		agentConfig.Model = modelID
+	}
 	model, ok := models.SupportedModels[agentConfig.Model]
 	if !ok {
 		return nil, fmt.Errorf("model %s not supported", agentConfig.Model)
@@ -730,6 +848,7 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 		provider.WithModel(model),
 		provider.WithSystemMessage(prompt.GetAgentPrompt(agentName, model.Provider)),
 		provider.WithMaxTokens(maxTokens),
+		provider.WithRateLimit(providerCfg.RequestsPerMinute, providerCfg.Burst),
 	}
 	if model.Provider == models.ProviderOpenAI || model.Provider == models.ProviderLocal && model.CanReason {
 		opts = append(
@@ -0,0 +1,481 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/permission"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/opencode-ai/opencode/internal/version"
+)
+
+// MCPStatus is the connection status of a configured MCP server
+type MCPStatus string
+
+const (
+	// MCPStatusIdle means the server has not been started yet
+	MCPStatusIdle MCPStatus = "idle"
+	// MCPStatusConnecting means the server is being started and initialized
+	MCPStatusConnecting MCPStatus = "connecting"
+	// MCPStatusConnected means the server is initialized and its tools are listed
+	MCPStatusConnected MCPStatus = "connected"
+	// MCPStatusReconnecting means the connection failed and is retried after a backoff
+	MCPStatusReconnecting MCPStatus = "reconnecting"
+	// MCPStatusFailed means the server cannot be started with its configuration
+	MCPStatusFailed MCPStatus = "failed"
+)
+
+const (
+	mcpConnectTimeout     = 30 * time.Second
+	mcpPingTimeout        = 10 * time.Second
+	mcpHealthInterval     = 30 * time.Second
+	mcpMinBackoff         = time.Second
+	mcpMaxBackoff         = time.Minute
+	methodToolListChanged = "notifications/tools/list_changed"
+)
+
+var errInvalidMCPType = errors.New("invalid mcp type")
+
+// MCPServerState is the status of a configured MCP server, published on every change
+type MCPServerState struct {
+	Name   string
+	Status MCPStatus
+	// Tools is the number of tools the server offers while connected
+	Tools int
+	// Error is the last connection error
+	Error string
+	// RetryAt is when the next connection attempt is made while reconnecting
+	RetryAt time.Time
+}
+
+// MCPClientFactory starts a client for an MCP server. The context lives as long as
+// the client may be used.
+type MCPClientFactory func(ctx context.Context, server config.MCPServer) (MCPClient, error)
+
+// MCPManagerOption configures an MCPManager
+type MCPManagerOption func(*MCPManager)
+
+// WithMCPClientFactory replaces how clients are started, mainly for tests
+func WithMCPClientFactory(factory MCPClientFactory) MCPManagerOption {
+	return func(m *MCPManager) {
+		m.newClient = factory
+	}
+}
+
+// WithMCPHealthInterval sets how often connected servers are pinged
+func WithMCPHealthInterval(interval time.Duration) MCPManagerOption {
+	return func(m *MCPManager) {
+		m.healthInterval = interval
+	}
+}
+
+// WithMCPBackoff sets the first and the longest delay between reconnection attempts
+func WithMCPBackoff(min, max time.Duration) MCPManagerOption {
+	return func(m *MCPManager) {
+		m.minBackoff = min
+		m.maxBackoff = max
+	}
+}
+
+// MCPManager keeps one long-lived client per configured MCP server. Servers are
+// started on first use, pinged periodically and reconnected with an exponential
+// backoff when they fail, and their tool list is refreshed when they announce a
+// change.
+type MCPManager struct {
+	*pubsub.Broker[MCPServerState]
+
+	permissions    permission.Service
+	newClient      MCPClientFactory
+	healthInterval time.Duration
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
+
+	connections []*mcpConnection
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMCPManager creates a manager for servers without starting any of them
+func NewMCPManager(servers map[string]config.MCPServer, permissions permission.Service, opts ...MCPManagerOption) *MCPManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &MCPManager{
+		Broker:         pubsub.NewBroker[MCPServerState](),
+		permissions:    permissions,
+		newClient:      newMCPClient,
+		healthInterval: mcpHealthInterval,
+		minBackoff:     mcpMinBackoff,
+		maxBackoff:     mcpMaxBackoff,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m.connections = append(m.connections, &mcpConnection{
+			manager: m,
+			name:    name,
+			config:  servers[name],
+			backoff: m.minBackoff,
+			state:   MCPServerState{Name: name, Status: MCPStatusIdle},
+		})
+	}
+
+	if len(m.connections) > 0 {
+		m.wg.Add(1)
+		go m.checkHealth()
+	}
+	return m
+}
+
+// Tools returns the tools of every server, starting the servers that have not been
+// used yet and waiting for those being started. Servers waiting to reconnect are
+// skipped rather than waited for.
+func (m *MCPManager) Tools(ctx context.Context) []tools.BaseTool {
+	var wg sync.WaitGroup
+	for _, conn := range m.connections {
+		switch conn.State().Status {
+		case MCPStatusConnected, MCPStatusReconnecting, MCPStatusFailed:
+			continue
+		}
+		wg.Add(1)
+		go func(conn *mcpConnection) {
+			defer wg.Done()
+			conn.connect(ctx)
+		}(conn)
+	}
+	wg.Wait()
+
+	var all []tools.BaseTool
+	for _, conn := range m.connections {
+		all = append(all, conn.listedTools()...)
+	}
+	return all
+}
+
+// States returns the status of every configured server, ordered by name
+func (m *MCPManager) States() []MCPServerState {
+	states := make([]MCPServerState, 0, len(m.connections))
+	for _, conn := range m.connections {
+		states = append(states, conn.State())
+	}
+	return states
+}
+
+// Close stops the health checks and pending reconnections and closes every client
+func (m *MCPManager) Close() {
+	m.cancel()
+	m.wg.Wait()
+	for _, conn := range m.connections {
+		conn.close()
+	}
+	m.Shutdown()
+}
+
+// checkHealth pings the connected servers and drops those that do not answer
+func (m *MCPManager) checkHealth() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, conn := range m.connections {
+				conn.checkHealth()
+			}
+		}
+	}
+}
+
+// mcpConnection is the client of a single configured server
+type mcpConnection struct {
+	manager *MCPManager
+	name    string
+	config  config.MCPServer
+
+	// connectMu serializes connection attempts, mu guards the fields below
+	connectMu sync.Mutex
+	mu        sync.Mutex
+	client    MCPClient
+	tools     []tools.BaseTool
+	state     MCPServerState
+	backoff   time.Duration
+	retry     *time.Timer
+	closed    bool
+}
+
+// State returns the current status of the server
+func (c *mcpConnection) State() MCPServerState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *mcpConnection) listedTools() []tools.BaseTool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tools
+}
+
+// setState records and publishes a status change, called with mu held
+func (c *mcpConnection) setState(state MCPServerState) {
+	state.Name = c.name
+	c.state = state
+	c.manager.Publish(pubsub.UpdatedEvent, state)
+}
+
+// connect starts and initializes the client unless it is already connected, and
+// returns it
+func (c *mcpConnection) connect(ctx context.Context) (MCPClient, error) {
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
+
+	c.mu.Lock()
+	switch {
+	case c.closed:
+		c.mu.Unlock()
+		return nil, fmt.Errorf("mcp server %s is closed", c.name)
+	case c.client != nil:
+		current := c.client
+		c.mu.Unlock()
+		return current, nil
+	case c.state.Status == MCPStatusFailed:
+		err := c.state.Error
+		c.mu.Unlock()
+		return nil, fmt.Errorf("mcp server %s failed: %s", c.name, err)
+	}
+	c.setState(MCPServerState{Status: MCPStatusConnecting})
+	c.mu.Unlock()
+
+	mcpClient, listed, err := c.start(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		if mcpClient != nil {
+			mcpClient.Close()
+		}
+		return nil, fmt.Errorf("mcp server %s is closed", c.name)
+	}
+	if err != nil {
+		logging.Error("error connecting to mcp server", "name", c.name, "error", err)
+		c.fail(err)
+		return nil, err
+	}
+
+	c.client = mcpClient
+	c.tools = listed
+	c.backoff = c.manager.minBackoff
+	c.setState(MCPServerState{Status: MCPStatusConnected, Tools: len(listed)})
+	return mcpClient, nil
+}
+
+// start creates the client, initializes it and lists its tools
+func (c *mcpConnection) start(ctx context.Context) (MCPClient, []tools.BaseTool, error) {
+	mcpClient, err := c.manager.newClient(c.manager.ctx, c.config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mcpConnectTimeout)
+	defer cancel()
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "OpenCode",
+		Version: version.Version,
+	}
+	if _, err := mcpClient.Initialize(ctx, initRequest); err != nil {
+		mcpClient.Close()
+		return nil, nil, fmt.Errorf("error initializing mcp client: %w", err)
+	}
+
+	mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method == methodToolListChanged {
+			go c.refreshTools(mcpClient)
+		}
+	})
+
+	listed, err := c.listTools(ctx, mcpClient)
+	if err != nil {
+		mcpClient.Close()
+		return nil, nil, err
+	}
+	return mcpClient, listed, nil
+}
+
+func (c *mcpConnection) listTools(ctx context.Context, mcpClient MCPClient) ([]tools.BaseTool, error) {
+	result, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tools: %w", err)
+	}
+	listed := make([]tools.BaseTool, 0, len(result.Tools))
+	for _, t := range result.Tools {
+		listed = append(listed, &mcpTool{
+			mcpName:     c.name,
+			tool:        t,
+			connection:  c,
+			permissions: c.manager.permissions,
+		})
+	}
+	return listed, nil
+}
+
+// refreshTools lists the tools again after the server announced a change
+func (c *mcpConnection) refreshTools(mcpClient MCPClient) {
+	ctx, cancel := context.WithTimeout(c.manager.ctx, mcpConnectTimeout)
+	defer cancel()
+
+	listed, err := c.listTools(ctx, mcpClient)
+	if err != nil {
+		logging.Warn("error refreshing mcp tools", "name", c.name, "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != mcpClient {
+		return
+	}
+	c.tools = listed
+	c.setState(MCPServerState{Status: MCPStatusConnected, Tools: len(listed)})
+	logging.Info("mcp tools changed", "name", c.name, "tools", len(listed))
+}
+
+// checkHealth pings the server and drops the client if it does not answer
+func (c *mcpConnection) checkHealth() {
+	c.mu.Lock()
+	mcpClient := c.client
+	c.mu.Unlock()
+	if mcpClient == nil {
+		return
+	}
+	if err := c.ping(mcpClient); err != nil {
+		c.drop(mcpClient, err)
+	}
+}
+
+func (c *mcpConnection) ping(mcpClient MCPClient) error {
+	ctx, cancel := context.WithTimeout(c.manager.ctx, mcpPingTimeout)
+	defer cancel()
+	return mcpClient.Ping(ctx)
+}
+
+// drop closes a client that stopped working and schedules a reconnection
+func (c *mcpConnection) drop(mcpClient MCPClient, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != mcpClient || c.closed {
+		return
+	}
+	logging.Warn("mcp server stopped responding", "name", c.name, "error", err)
+	mcpClient.Close()
+	c.client = nil
+	c.tools = nil
+	c.fail(err)
+}
+
+// fail records a connection error and schedules the next attempt, called with mu held
+func (c *mcpConnection) fail(err error) {
+	if errors.Is(err, errInvalidMCPType) {
+		c.setState(MCPServerState{Status: MCPStatusFailed, Error: err.Error()})
+		return
+	}
+
+	delay := c.backoff
+	c.backoff = min(c.backoff*2, c.manager.maxBackoff)
+	c.setState(MCPServerState{
+		Status:  MCPStatusReconnecting,
+		Error:   err.Error(),
+		RetryAt: time.Now().Add(delay),
+	})
+	c.retry = time.AfterFunc(delay, func() {
+		c.connect(c.manager.ctx)
+	})
+}
+
+func (c *mcpConnection) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.retry != nil {
+		c.retry.Stop()
+	}
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+	c.tools = nil
+}
+
+// callTool calls a tool of the server, connecting first if needed. A failed call
+// drops the client when the server no longer answers pings.
+func (c *mcpConnection) callTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	c.mu.Lock()
+	state := c.state
+	mcpClient := c.client
+	c.mu.Unlock()
+	if mcpClient == nil && state.Status == MCPStatusReconnecting {
+		return nil, fmt.Errorf("mcp server %s is unavailable, retrying in %s: %s",
+			c.name, time.Until(state.RetryAt).Round(time.Second), state.Error)
+	}
+
+	mcpClient, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mcpClient.CallTool(ctx, request)
+	if err != nil && ctx.Err() == nil {
+		if pingErr := c.ping(mcpClient); pingErr != nil {
+			c.drop(mcpClient, pingErr)
+		}
+	}
+	return result, err
+}
+
+// newMCPClient starts a stdio or SSE client for server
+func newMCPClient(ctx context.Context, server config.MCPServer) (MCPClient, error) {
+	switch server.Type {
+	case config.MCPStdio:
+		return client.NewStdioMCPClient(
+			server.Command,
+			server.Env,
+			server.Args...,
+		)
+	case config.MCPSse:
+		c, err := client.NewSSEMCPClient(
+			server.URL,
+			client.WithHeaders(server.Headers),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Start(ctx); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w %q", errInvalidMCPType, server.Type)
+}
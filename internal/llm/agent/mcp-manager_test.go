@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/permission"
+)
+
+type fakeMCPClient struct {
+	mu      sync.Mutex
+	tools   []mcp.Tool
+	pingErr error
+	calls   []string
+	closed  bool
+	notify  func(mcp.JSONRPCNotification)
+}
+
+func (c *fakeMCPClient) Initialize(context.Context, mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	return &mcp.InitializeResult{}, nil
+}
+
+func (c *fakeMCPClient) Ping(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pingErr
+}
+
+func (c *fakeMCPClient) ListTools(context.Context, mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &mcp.ListToolsResult{Tools: c.tools}, nil
+}
+
+func (c *fakeMCPClient) CallTool(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, request.Params.Name)
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent("ok " + request.Params.Name)}}, nil
+}
+
+func (c *fakeMCPClient) OnNotification(handler func(mcp.JSONRPCNotification)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = handler
+}
+
+func (c *fakeMCPClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeMCPClient) set(fn func(c *fakeMCPClient)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(c)
+}
+
+// fakeMCPServers hands out a new fake client per connection attempt
+type fakeMCPServers struct {
+	mu      sync.Mutex
+	tools   []mcp.Tool
+	failing int
+	clients []*fakeMCPClient
+}
+
+func (s *fakeMCPServers) newClient(context.Context, config.MCPServer) (MCPClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing > 0 {
+		s.failing--
+		return nil, errors.New("server not ready")
+	}
+	c := &fakeMCPClient{tools: s.tools}
+	s.clients = append(s.clients, c)
+	return c, nil
+}
+
+func (s *fakeMCPServers) started() []*fakeMCPClient {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*fakeMCPClient(nil), s.clients...)
+}
+
+func toolNames(list []tools.BaseTool) []string {
+	names := make([]string, 0, len(list))
+	for _, t := range list {
+		names = append(names, t.Info().Name)
+	}
+	return names
+}
+
+func newTestMCPManager(t *testing.T, servers *fakeMCPServers, opts ...MCPManagerOption) *MCPManager {
+	t.Helper()
+	opts = append([]MCPManagerOption{
+		WithMCPClientFactory(servers.newClient),
+		WithMCPBackoff(10*time.Millisecond, 40*time.Millisecond),
+	}, opts...)
+	m := NewMCPManager(map[string]config.MCPServer{
+		"files": {Type: config.MCPStdio, Command: "files-server"},
+	}, permission.NewPermissionService(), opts...)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func TestMCPManagerReusesConnection(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	servers := &fakeMCPServers{tools: []mcp.Tool{mcp.NewTool("read"), mcp.NewTool("write")}}
+	m := newTestMCPManager(t, servers)
+
+	assert.Empty(t, servers.started(), "servers start on first use")
+	assert.Equal(t, MCPStatusIdle, m.States()[0].Status)
+
+	listed := m.Tools(context.Background())
+	assert.Equal(t, []string{"files_read", "files_write"}, toolNames(listed))
+	assert.Equal(t, MCPServerState{Name: "files", Status: MCPStatusConnected, Tools: 2}, m.States()[0])
+
+	m.permissions.AutoApproveSession("session")
+	ctx := context.WithValue(context.Background(), tools.SessionIDContextKey, "session")
+	ctx = context.WithValue(ctx, tools.MessageIDContextKey, "message")
+	for range 3 {
+		response, err := listed[0].Run(ctx, tools.ToolCall{Name: "files_read", Input: `{"path": "a.go"}`})
+		require.NoError(t, err)
+		assert.Equal(t, "ok read", response.Content)
+	}
+
+	started := servers.started()
+	require.Len(t, started, 1)
+	assert.Equal(t, []string{"read", "read", "read"}, started[0].calls)
+	assert.False(t, started[0].closed)
+}
+
+func TestMCPToolsRefuseDryRuns(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	servers := &fakeMCPServers{tools: []mcp.Tool{mcp.NewTool("write")}}
+	m := newTestMCPManager(t, servers)
+	m.permissions.AutoApproveSession("session")
+	ctx := context.WithValue(context.Background(), tools.SessionIDContextKey, "session")
+	ctx = context.WithValue(ctx, tools.MessageIDContextKey, "message")
+	ctx = tools.WithOverlay(ctx, tools.NewOverlay())
+
+	response, err := m.Tools(ctx)[0].Run(ctx, tools.ToolCall{Name: "files_write", Input: `{}`})
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	assert.Empty(t, servers.started()[0].calls, "the server is not called")
+}
+
+func TestAgentOffersAllowedMCPTools(t *testing.T) {
+	servers := &fakeMCPServers{tools: []mcp.Tool{mcp.NewTool("read"), mcp.NewTool("write")}}
+	m := newTestMCPManager(t, servers)
+
+	all := &agent{}
+	WithMCPTools(m)(all)
+	assert.Equal(t, []string{"files_read", "files_write"}, toolNames(all.availableTools(context.Background())))
+
+	restricted := &agent{}
+	WithMCPTools(m, "files_read", "view")(restricted)
+	assert.Equal(t, []string{"files_read"}, toolNames(restricted.availableTools(context.Background())))
+}
+
+func TestMCPManagerRefreshesToolsOnListChanged(t *testing.T) {
+	servers := &fakeMCPServers{tools: []mcp.Tool{mcp.NewTool("read")}}
+	m := newTestMCPManager(t, servers)
+	events := m.Subscribe(t.Context())
+
+	require.Len(t, m.Tools(context.Background()), 1)
+	client := servers.started()[0]
+	client.set(func(c *fakeMCPClient) {
+		c.tools = append(c.tools, mcp.NewTool("search"))
+	})
+	client.notify(mcp.JSONRPCNotification{Notification: mcp.Notification{Method: "notifications/tools/list_changed"}})
+
+	assert.Eventually(t, func() bool {
+		return len(m.Tools(context.Background())) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"files_read", "files_search"}, toolNames(m.Tools(context.Background())))
+
+	var last MCPServerState
+	for len(events) > 0 {
+		last = (<-events).Payload
+	}
+	assert.Equal(t, 2, last.Tools)
+}
+
+func TestMCPManagerReconnectsUnhealthyServer(t *testing.T) {
+	servers := &fakeMCPServers{tools: []mcp.Tool{mcp.NewTool("read")}}
+	m := newTestMCPManager(t, servers, WithMCPHealthInterval(10*time.Millisecond))
+
+	require.Len(t, m.Tools(context.Background()), 1)
+	first := servers.started()[0]
+	first.set(func(c *fakeMCPClient) {
+		c.pingErr = errors.New("broken pipe")
+	})
+
+	assert.Eventually(t, func() bool {
+		return len(servers.started()) == 2 && m.States()[0].Status == MCPStatusConnected
+	}, time.Second, 10*time.Millisecond)
+	first.set(func(c *fakeMCPClient) {
+		assert.True(t, c.closed)
+	})
+	assert.Len(t, m.Tools(context.Background()), 1)
+}
+
+func TestMCPManagerBacksOffFailedConnections(t *testing.T) {
+	servers := &fakeMCPServers{tools: []mcp.Tool{mcp.NewTool("read")}, failing: 2}
+	m := newTestMCPManager(t, servers)
+
+	assert.Empty(t, m.Tools(context.Background()))
+	state := m.States()[0]
+	assert.Equal(t, MCPStatusReconnecting, state.Status)
+	assert.Equal(t, "server not ready", state.Error)
+	assert.False(t, state.RetryAt.IsZero())
+
+	// Tools does not wait for a server that is backing off
+	assert.Empty(t, m.Tools(context.Background()))
+
+	assert.Eventually(t, func() bool {
+		return m.States()[0].Status == MCPStatusConnected
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, m.Tools(context.Background()), 1)
+	assert.Len(t, servers.started(), 1)
+}
+
+func TestMCPManagerInvalidType(t *testing.T) {
+	m := NewMCPManager(map[string]config.MCPServer{
+		"broken": {Type: "carrier-pigeon"},
+	}, permission.NewPermissionService(), WithMCPBackoff(time.Millisecond, time.Millisecond))
+	defer m.Close()
+
+	assert.Empty(t, m.Tools(context.Background()))
+	state := m.States()[0]
+	assert.Equal(t, MCPStatusFailed, state.Status)
+	assert.Contains(t, state.Error, "invalid mcp type")
+	assert.True(t, state.RetryAt.IsZero())
+}
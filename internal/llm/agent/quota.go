@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/opencode-ai/opencode/internal/llm/provider"
+)
+
+// QuotaEnforcer admits agent runs and meters the tokens they use, such as against
+// the quotas of the tenant a session belongs to
+type QuotaEnforcer interface {
+	// Acquire admits a run in sessionID, or returns why it is refused. The run
+	// uses the returned context and calls release when it ends.
+	Acquire(ctx context.Context, sessionID string) (runCtx context.Context, release func(), err error)
+	// TrackUsage adds the tokens and cost of a model response to the usage of the
+	// session's owner
+	TrackUsage(ctx context.Context, sessionID string, usage provider.TokenUsage, cost float64) error
+}
+
+type quotasContextKey struct{}
+
+// WithQuotas checks every run against quotas before it starts and meters the
+// tokens it uses. Agents started by the run's tools, such as the agent tool,
+// inherit it.
+func WithQuotas(quotas QuotaEnforcer) Option {
+	return func(a *agent) {
+		a.quotas = quotas
+	}
+}
+
+// quotasFor returns the quotas of the agent, or else those of the run ctx belongs
+// to
+func (a *agent) quotasFor(ctx context.Context) QuotaEnforcer {
+	if a.quotas != nil {
+		return a.quotas
+	}
+	quotas, _ := ctx.Value(quotasContextKey{}).(QuotaEnforcer)
+	return quotas
+}
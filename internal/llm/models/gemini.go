@@ -23,6 +23,7 @@ var GeminiModels = map[ModelID]Model{
 		ContextWindow:       1000000,
 		DefaultMaxTokens:    50000,
 		SupportsAttachments: true,
+		CanReason:           true,
 	},
 	Gemini25: {
 		ID:                  Gemini25,
@@ -36,6 +37,7 @@ var GeminiModels = map[ModelID]Model{
 		ContextWindow:       1000000,
 		DefaultMaxTokens:    50000,
 		SupportsAttachments: true,
+		CanReason:           true,
 	},
 
 	Gemini20Flash: {
@@ -75,14 +75,14 @@ func newBedrockClient(opts providerClientOptions) BedrockClient {
 	}
 }
 
-func (b *bedrockClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+func (b *bedrockClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool, call callOptions) (*ProviderResponse, error) {
 	if b.childProvider == nil {
 		return nil, errors.New("unsupported model for bedrock provider")
 	}
-	return b.childProvider.send(ctx, messages, tools)
+	return b.childProvider.send(ctx, messages, tools, call)
 }
 
-func (b *bedrockClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+func (b *bedrockClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool, call callOptions) <-chan ProviderEvent {
 	eventChan := make(chan ProviderEvent)
 
 	if b.childProvider == nil {
@@ -96,6 +96,5 @@ func (b *bedrockClient) stream(ctx context.Context, messages []message.Message,
 		return eventChan
 	}
 
-	return b.childProvider.stream(ctx, messages, tools)
+	return b.childProvider.stream(ctx, messages, tools, call)
 }
-
@@ -280,7 +280,7 @@ func (c *copilotClient) finishReason(reason string) message.FinishReason {
 	}
 }
 
-func (c *copilotClient) preparedParams(messages []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolParam) openai.ChatCompletionNewParams {
+func (c *copilotClient) preparedParams(messages []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolParam, call callOptions) openai.ChatCompletionNewParams {
 	params := openai.ChatCompletionNewParams{
 		Model:    openai.ChatModel(c.providerOptions.model.APIModel),
 		Messages: messages,
@@ -288,8 +288,12 @@ func (c *copilotClient) preparedParams(messages []openai.ChatCompletionMessagePa
 	}
 
 	if c.providerOptions.model.CanReason == true {
-		params.MaxCompletionTokens = openai.Int(c.providerOptions.maxTokens)
-		switch c.options.reasoningEffort {
+		params.MaxCompletionTokens = openai.Int(call.maxTokens)
+		effort := c.options.reasoningEffort
+		if call.reasoningEffort != "" {
+			effort = call.reasoningEffort
+		}
+		switch effort {
 		case "low":
 			params.ReasoningEffort = shared.ReasoningEffortLow
 		case "medium":
@@ -300,14 +304,14 @@ func (c *copilotClient) preparedParams(messages []openai.ChatCompletionMessagePa
 			params.ReasoningEffort = shared.ReasoningEffortMedium
 		}
 	} else {
-		params.MaxTokens = openai.Int(c.providerOptions.maxTokens)
+		params.MaxTokens = openai.Int(call.maxTokens)
 	}
 
 	return params
 }
 
-func (c *copilotClient) send(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool) (response *ProviderResponse, err error) {
-	params := c.preparedParams(c.convertMessages(messages), c.convertTools(tools))
+func (c *copilotClient) send(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool, call callOptions) (response *ProviderResponse, err error) {
+	params := c.preparedParams(c.convertMessages(messages), c.convertTools(tools), call)
 	cfg := config.Get()
 	var sessionId string
 	requestSeqId := (len(messages) + 1) / 2
@@ -373,8 +377,8 @@ func (c *copilotClient) send(ctx context.Context, messages []message.Message, to
 	}
 }
 
-func (c *copilotClient) stream(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool) <-chan ProviderEvent {
-	params := c.preparedParams(c.convertMessages(messages), c.convertTools(tools))
+func (c *copilotClient) stream(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool, call callOptions) <-chan ProviderEvent {
+	params := c.preparedParams(c.convertMessages(messages), c.convertTools(tools), call)
 	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
 		IncludeUsage: openai.Bool(true),
 	}
@@ -668,4 +672,3 @@ func WithCopilotBearerToken(bearerToken string) CopilotOption {
 		options.bearerToken = bearerToken
 	}
 }
-
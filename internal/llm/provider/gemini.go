@@ -165,7 +165,16 @@ func (g *geminiClient) finishReason(reason genai.FinishReason) message.FinishRea
 	}
 }
 
-func (g *geminiClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+// thinkingConfig sets the thinking budget of models that support it
+func (g *geminiClient) thinkingConfig(call callOptions) *genai.ThinkingConfig {
+	if call.thinkingBudget <= 0 || !g.providerOptions.model.CanReason {
+		return nil
+	}
+	budget := int32(call.thinkingBudget)
+	return &genai.ThinkingConfig{ThinkingBudget: &budget}
+}
+
+func (g *geminiClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool, call callOptions) (*ProviderResponse, error) {
 	// Convert messages
 	geminiMessages := g.convertMessages(messages)
 
@@ -178,10 +187,11 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 	history := geminiMessages[:len(geminiMessages)-1] // All but last message
 	lastMsg := geminiMessages[len(geminiMessages)-1]
 	config := &genai.GenerateContentConfig{
-		MaxOutputTokens: int32(g.providerOptions.maxTokens),
+		MaxOutputTokens: int32(call.maxTokens),
 		SystemInstruction: &genai.Content{
 			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
 		},
+		ThinkingConfig: g.thinkingConfig(call),
 	}
 	if len(tools) > 0 {
 		config.Tools = g.convertTools(tools)
@@ -253,7 +263,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 	}
 }
 
-func (g *geminiClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+func (g *geminiClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool, call callOptions) <-chan ProviderEvent {
 	// Convert messages
 	geminiMessages := g.convertMessages(messages)
 
@@ -266,10 +276,11 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 	history := geminiMessages[:len(geminiMessages)-1] // All but last message
 	lastMsg := geminiMessages[len(geminiMessages)-1]
 	config := &genai.GenerateContentConfig{
-		MaxOutputTokens: int32(g.providerOptions.maxTokens),
+		MaxOutputTokens: int32(call.maxTokens),
 		SystemInstruction: &genai.Content{
 			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
 		},
+		ThinkingConfig: g.thinkingConfig(call),
 	}
 	if len(tools) > 0 {
 		config.Tools = g.convertTools(tools)
@@ -5,15 +5,21 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/llm/models"
 	"github.com/opencode-ai/opencode/internal/llm/tools"
 	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/ratelimit"
 )
 
 type EventType string
 
 const maxRetries = 8
 
+// minAnswerTokens is the output left for the answer when a thinking budget would
+// otherwise consume all of max tokens
+const minAnswerTokens = 4096
+
 const (
 	EventContentStart  EventType = "content_start"
 	EventToolUseStart  EventType = "tool_use_start"
@@ -51,9 +57,9 @@ type ProviderEvent struct {
 	Error    error
 }
 type Provider interface {
-	SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error)
+	SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool, opts ...CallOption) (*ProviderResponse, error)
 
-	StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent
+	StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool, opts ...CallOption) <-chan ProviderEvent
 
 	Model() models.Model
 }
@@ -64,6 +70,9 @@ type providerClientOptions struct {
 	maxTokens     int64
 	systemMessage string
 
+	// rateLimit bounds the requests sent to the provider by all of its clients
+	rateLimit config.RateLimitRule
+
 	anthropicOptions []AnthropicOption
 	openaiOptions    []OpenAIOption
 	geminiOptions    []GeminiOption
@@ -73,9 +82,19 @@ type providerClientOptions struct {
 
 type ProviderClientOption func(*providerClientOptions)
 
+// callOptions are per-request overrides of the options set at construction
+type callOptions struct {
+	maxTokens       int64
+	thinkingBudget  int64
+	reasoningEffort string
+}
+
+// CallOption overrides a provider option for a single request
+type CallOption func(*callOptions)
+
 type ProviderClient interface {
-	send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error)
-	stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent
+	send(ctx context.Context, messages []message.Message, tools []tools.BaseTool, call callOptions) (*ProviderResponse, error)
+	stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool, call callOptions) <-chan ProviderEvent
 }
 
 type baseProvider[C ProviderClient] struct {
@@ -88,6 +107,20 @@ func NewProvider(providerName models.ModelProvider, opts ...ProviderClientOption
 	for _, o := range opts {
 		o(&clientOptions)
 	}
+	p, err := newProviderClient(providerName, clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// All clients of a provider share one budget, so many sub-agents cannot exhaust its quota
+	name := "provider:" + string(providerName)
+	if bucket := ratelimit.Shared(name, clientOptions.rateLimit); bucket != nil {
+		return &rateLimitedProvider{Provider: p, name: name, bucket: bucket}, nil
+	}
+	return p, nil
+}
+
+func newProviderClient(providerName models.ModelProvider, clientOptions providerClientOptions) (Provider, error) {
 	switch providerName {
 	case models.ProviderCopilot:
 		return &baseProvider[CopilotClient]{
@@ -178,18 +211,41 @@ func (p *baseProvider[C]) cleanMessages(messages []message.Message) (cleaned []m
 	return
 }
 
-func (p *baseProvider[C]) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+// callOptions resolves the per-request options, falling back to the construction options
+func (p *baseProvider[C]) callOptions(opts []CallOption) callOptions {
+	call := callOptions{
+		maxTokens: p.options.maxTokens,
+	}
+	for _, o := range opts {
+		o(&call)
+	}
+	// The thinking budget is part of the output tokens, leave room for the answer
+	if call.thinkingBudget > 0 && call.maxTokens <= call.thinkingBudget {
+		call.maxTokens = call.thinkingBudget + minAnswerTokens
+	}
+	return call
+}
+
+func (p *baseProvider[C]) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool, opts ...CallOption) (*ProviderResponse, error) {
 	messages = p.cleanMessages(messages)
-	return p.client.send(ctx, messages, tools)
+	return p.client.send(ctx, messages, tools, p.callOptions(opts))
 }
 
 func (p *baseProvider[C]) Model() models.Model {
 	return p.options.model
 }
 
-func (p *baseProvider[C]) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+func (p *baseProvider[C]) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool, opts ...CallOption) <-chan ProviderEvent {
 	messages = p.cleanMessages(messages)
-	return p.client.stream(ctx, messages, tools)
+	return p.client.stream(ctx, messages, tools, p.callOptions(opts))
+}
+
+// WithRateLimit bounds the requests sent to the provider per minute, shared by
+// every client of the provider. Calls over the limit wait for their turn.
+func WithRateLimit(requestsPerMinute, burst int) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.rateLimit = config.RateLimitRule{RequestsPerMinute: requestsPerMinute, Burst: burst}
+	}
 }
 
 func WithAPIKey(apiKey string) ProviderClientOption {
@@ -245,3 +301,57 @@ func WithCopilotOptions(copilotOptions ...CopilotOption) ProviderClientOption {
 		options.copilotOptions = copilotOptions
 	}
 }
+
+// WithCallMaxTokens overrides the maximum output tokens for a single request
+func WithCallMaxTokens(maxTokens int64) CallOption {
+	return func(options *callOptions) {
+		if maxTokens > 0 {
+			options.maxTokens = maxTokens
+		}
+	}
+}
+
+// WithThinkingBudget enables native reasoning with the given token budget. Anthropic
+// uses it as the extended thinking budget and Gemini as the thinking budget.
+func WithThinkingBudget(budget int64) CallOption {
+	return func(options *callOptions) {
+		options.thinkingBudget = budget
+	}
+}
+
+// WithCallReasoningEffort overrides the OpenAI reasoning effort (low, medium, high)
+// for a single request
+func WithCallReasoningEffort(effort string) CallOption {
+	return func(options *callOptions) {
+		options.reasoningEffort = effort
+	}
+}
+
+// rateLimitedProvider waits for the provider's shared rate limit before each request
+type rateLimitedProvider struct {
+	Provider
+	name   string
+	bucket *ratelimit.Bucket
+}
+
+func (p *rateLimitedProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool, opts ...CallOption) (*ProviderResponse, error) {
+	if err := ratelimit.WaitShared(ctx, p.name, p.bucket); err != nil {
+		return nil, err
+	}
+	return p.Provider.SendMessages(ctx, messages, tools, opts...)
+}
+
+func (p *rateLimitedProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool, opts ...CallOption) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+	go func() {
+		defer close(eventChan)
+		if err := ratelimit.WaitShared(ctx, p.name, p.bucket); err != nil {
+			eventChan <- ProviderEvent{Type: EventError, Error: err}
+			return
+		}
+		for event := range p.Provider.StreamResponse(ctx, messages, tools, opts...) {
+			eventChan <- event
+		}
+	}()
+	return eventChan
+}
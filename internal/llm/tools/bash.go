@@ -4,11 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/opencode-ai/opencode/internal/config"
-	"github.com/opencode-ai/opencode/internal/llm/tools/shell"
 	"github.com/opencode-ai/opencode/internal/permission"
 )
 
@@ -54,6 +53,32 @@ var safeReadOnlyCommands = []string{
 	"go version", "go help", "go list", "go env", "go doc", "go vet", "go fmt", "go mod", "go test", "go build", "go run", "go install", "go clean",
 }
 
+// dryRunCommands are the only commands allowed during a --validate dry run. File tools
+// write to an in-memory overlay then, so anything that could change the disk is refused.
+var dryRunCommands = []string{
+	"ls", "pwd", "date", "whoami", "id", "uname", "which", "type", "whereis",
+	"cat", "head", "tail", "wc", "grep", "rg", "tree", "file", "stat", "du", "df",
+
+	"git status", "git log", "git diff", "git show", "git ls-files", "git rev-parse", "git describe", "git blame", "git grep", "git shortlog",
+
+	"go version", "go help", "go list", "go env", "go doc", "go vet",
+}
+
+// dryRunForbiddenFlags make an allowed command write a file or run another program,
+// such as rg --pre, git log --output, tree -o or go vet -vettool
+var dryRunForbiddenFlags = []string{
+	"--pre", "--output", "-o", "-O", "--open-files-in-pager",
+	"-vettool", "-toolexec", "-exec",
+}
+
+// dryRunCommandFlags are the forbidden flags of a single allowed command
+var dryRunCommandFlags = map[string][]string{
+	"go env": {"-w", "-u"},
+}
+
+// shellControlOperators chain, redirect or substitute commands, which would escape the dry run allow list
+var shellControlOperators = []string{";", "&", "|", ">", "<", "`", "$(", "\n"}
+
 func bashDescription() string {
 	bannedCommandsStr := strings.Join(bannedCommands, ", ")
 	return fmt.Sprintf(`Executes a given bash command in a persistent shell session with optional timeout, ensuring proper handling and security measures.
@@ -227,6 +252,66 @@ func (b *bashTool) Info() ToolInfo {
 	}
 }
 
+// hasCommandPrefix reports whether command starts with one of the given commands
+func hasCommandPrefix(command string, commands []string) bool {
+	cmdLower := strings.ToLower(command)
+	for _, prefix := range commands {
+		if strings.HasPrefix(cmdLower, strings.ToLower(prefix)) {
+			if len(cmdLower) == len(prefix) || cmdLower[len(prefix)] == ' ' || cmdLower[len(prefix)] == '-' {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDryRunCommand reports whether command is a single read-only command
+func isDryRunCommand(command string) bool {
+	for _, operator := range shellControlOperators {
+		if strings.Contains(command, operator) {
+			return false
+		}
+	}
+	command = strings.TrimSpace(command)
+	if !hasCommandPrefix(command, dryRunCommands) {
+		return false
+	}
+
+	forbidden := dryRunForbiddenFlags
+	for prefix, flags := range dryRunCommandFlags {
+		if hasCommandPrefix(command, []string{prefix}) {
+			forbidden = append(slices.Clip(forbidden), flags...)
+		}
+	}
+	// Go commands take long flags after a single dash, such as -json
+	grouped := !hasCommandPrefix(command, []string{"go"})
+	for _, arg := range strings.Fields(command)[1:] {
+		// The shell removes quotes and backslashes before the command sees its flags
+		arg = strings.NewReplacer(`"`, "", "'", "", `\`, "").Replace(arg)
+		for _, flag := range forbidden {
+			if isFlag(arg, flag, grouped) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isFlag reports whether arg sets flag, written as -flag, --flag or flag=value. When
+// grouped, a single-letter flag also matches when it is grouped with others or has
+// its value attached, as in -ao or -ofile.
+func isFlag(arg, flag string, grouped bool) bool {
+	name := strings.TrimLeft(flag, "-")
+	if !strings.HasPrefix(arg, "-") {
+		return false
+	}
+	if grouped && len(name) == 1 && !strings.HasPrefix(arg, "--") {
+		return strings.Contains(strings.SplitN(arg, "=", 2)[0], name)
+	}
+	arg, _, _ = strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	return arg == name
+}
+
 func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params BashParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
@@ -250,18 +335,12 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		}
 	}
 
-	isSafeReadOnly := false
-	cmdLower := strings.ToLower(params.Command)
-
-	for _, safe := range safeReadOnlyCommands {
-		if strings.HasPrefix(cmdLower, strings.ToLower(safe)) {
-			if len(cmdLower) == len(safe) || cmdLower[len(safe)] == ' ' || cmdLower[len(safe)] == '-' {
-				isSafeReadOnly = true
-				break
-			}
-		}
+	if isDryRun(ctx) && !isDryRunCommand(params.Command) {
+		return NewTextErrorResponse(fmt.Sprintf("command '%s' is not allowed in a --validate dry run, only read-only commands without pipes or redirects can run. File changes go through the edit, write and patch tools", params.Command)), nil
 	}
 
+	isSafeReadOnly := hasCommandPrefix(params.Command, safeReadOnlyCommands)
+
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
@@ -270,7 +349,7 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		p := b.permissions.Request(
 			permission.CreatePermissionRequest{
 				SessionID:   sessionID,
-				Path:        config.WorkingDirectory(),
+				Path:        WorkingDirectory(ctx),
 				ToolName:    BashToolName,
 				Action:      "execute",
 				Description: fmt.Sprintf("Execute command: %s", params.Command),
@@ -284,7 +363,7 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		}
 	}
 	startTime := time.Now()
-	shell := shell.GetPersistentShell(config.WorkingDirectory())
+	shell := workspaceShell(ctx)
 	stdout, stderr, exitCode, interrupted, err := shell.Exec(ctx, params.Command, params.Timeout)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error executing command: %w", err)
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDryRunCommand(t *testing.T) {
+	allowed := []string{
+		"ls -la",
+		"git status",
+		"git diff HEAD",
+		"grep -rn foo .",
+		"git log --oneline -5",
+		"git diff --stat HEAD~1",
+		"rg --pre-glob '*.gz' TODO",
+		"tree -a internal",
+		"go vet ./...",
+		"go list -json ./...",
+		"go env GOPATH",
+	}
+	for _, command := range allowed {
+		assert.True(t, isDryRunCommand(command), command)
+	}
+
+	refused := []string{
+		"env rm -rf build",
+		"printenv",
+		"rm -rf .",
+		"go build ./...",
+		"git checkout main",
+		"cat a > b",
+		"ls; rm x",
+		"echo $(rm x)",
+		"grep foo | tee out",
+		"rg --pre ./decompress TODO",
+		"rg --pre=./decompress TODO",
+		"rg '--pre' ./decompress TODO",
+		`rg --p\re ./decompress TODO`,
+		"git diff --output=patch.diff",
+		"git diff --output patch.diff",
+		"git log --output=log.txt",
+		"git grep -O vim TODO",
+		"git grep --open-files-in-pager=vim TODO",
+		"tree -o tree.txt",
+		"tree -ao tree.txt",
+		"tree -otree.txt",
+		"go vet -vettool=/tmp/tool ./...",
+		"go vet --vettool /tmp/tool ./...",
+		"go list -toolexec /tmp/tool ./...",
+		"go env -w GOFLAGS=-mod=mod",
+	}
+	for _, command := range refused {
+		assert.False(t, isDryRunCommand(command), command)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/diff"
 	"github.com/opencode-ai/opencode/internal/history"
 	"github.com/opencode-ai/opencode/internal/logging"
@@ -132,7 +131,7 @@ func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	}
 
 	if !filepath.IsAbs(params.FilePath) {
-		wd := config.WorkingDirectory()
+		wd := WorkingDirectory(ctx)
 		params.FilePath = filepath.Join(wd, params.FilePath)
 	}
 
@@ -163,15 +162,17 @@ func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return response, nil
 	}
 
-	waitForLspDiagnostics(ctx, params.FilePath, e.lspClients)
 	text := fmt.Sprintf("<result>\n%s\n</result>\n", response.Content)
-	text += getDiagnostics(params.FilePath, e.lspClients)
+	if !isDryRun(ctx) {
+		waitForLspDiagnostics(ctx, params.FilePath, e.lspClients)
+		text += getDiagnostics(params.FilePath, e.lspClients)
+	}
 	response.Content = text
 	return response, nil
 }
 
 func (e *editTool) createNewFile(ctx context.Context, filePath, content string) (ToolResponse, error) {
-	fileInfo, err := os.Stat(filePath)
+	fileInfo, err := statFile(ctx, filePath)
 	if err == nil {
 		if fileInfo.IsDir() {
 			return NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", filePath)), nil
@@ -181,11 +182,6 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 		return ToolResponse{}, fmt.Errorf("failed to access file: %w", err)
 	}
 
-	dir := filepath.Dir(filePath)
-	if err = os.MkdirAll(dir, 0o755); err != nil {
-		return ToolResponse{}, fmt.Errorf("failed to create parent directories: %w", err)
-	}
-
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
@@ -196,12 +192,12 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 		content,
 		filePath,
 	)
-	rootDir := config.WorkingDirectory()
+	rootDir := WorkingDirectory(ctx)
 	permissionPath := filepath.Dir(filePath)
 	if strings.HasPrefix(filePath, rootDir) {
 		permissionPath = rootDir
 	}
-	p := e.permissions.Request(
+	p := requestPermission(ctx, e.permissions,
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        permissionPath,
@@ -218,23 +214,26 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
-	err = os.WriteFile(filePath, []byte(content), 0o644)
+	err = writeFile(ctx, filePath, []byte(content))
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
 	}
 
-	// File can't be in the history so we create a new file history
-	_, err = e.files.Create(ctx, sessionID, filePath, "")
-	if err != nil {
-		// Log error but don't fail the operation
-		return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
-	}
+	if !isDryRun(ctx) {
+		// File can't be in the history so we create a new file history
+		_, err = e.files.Create(ctx, sessionID, filePath, "")
+		if err != nil {
+			// Log error but don't fail the operation
+			return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
+		}
+
+		// Add the new content to the file history
+		_, err = e.files.CreateVersion(ctx, sessionID, filePath, content)
+		if err != nil {
+			// Log error but don't fail the operation
+			logging.Debug("Error creating file history version", "error", err)
+		}
 
-	// Add the new content to the file history
-	_, err = e.files.CreateVersion(ctx, sessionID, filePath, content)
-	if err != nil {
-		// Log error but don't fail the operation
-		logging.Debug("Error creating file history version", "error", err)
 	}
 
 	recordFileWrite(filePath)
@@ -251,7 +250,7 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 }
 
 func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string) (ToolResponse, error) {
-	fileInfo, err := os.Stat(filePath)
+	fileInfo, err := statFile(ctx, filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return NewTextErrorResponse(fmt.Sprintf("file not found: %s", filePath)), nil
@@ -276,7 +275,7 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 			)), nil
 	}
 
-	content, err := os.ReadFile(filePath)
+	content, err := readFile(ctx, filePath)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
 	}
@@ -307,12 +306,12 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		filePath,
 	)
 
-	rootDir := config.WorkingDirectory()
+	rootDir := WorkingDirectory(ctx)
 	permissionPath := filepath.Dir(filePath)
 	if strings.HasPrefix(filePath, rootDir) {
 		permissionPath = rootDir
 	}
-	p := e.permissions.Request(
+	p := requestPermission(ctx, e.permissions,
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        permissionPath,
@@ -329,31 +328,34 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
-	err = os.WriteFile(filePath, []byte(newContent), 0o644)
+	err = writeFile(ctx, filePath, []byte(newContent))
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
 	}
 
-	// Check if file exists in history
-	file, err := e.files.GetByPathAndSession(ctx, filePath, sessionID)
-	if err != nil {
-		_, err = e.files.Create(ctx, sessionID, filePath, oldContent)
+	if !isDryRun(ctx) {
+		// Check if file exists in history
+		file, err := e.files.GetByPathAndSession(ctx, filePath, sessionID)
 		if err != nil {
-			// Log error but don't fail the operation
-			return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
+			_, err = e.files.Create(ctx, sessionID, filePath, oldContent)
+			if err != nil {
+				// Log error but don't fail the operation
+				return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
+			}
 		}
-	}
-	if file.Content != oldContent {
-		// User Manually changed the content store an intermediate version
-		_, err = e.files.CreateVersion(ctx, sessionID, filePath, oldContent)
+		if file.Content != oldContent {
+			// User Manually changed the content store an intermediate version
+			_, err = e.files.CreateVersion(ctx, sessionID, filePath, oldContent)
+			if err != nil {
+				logging.Debug("Error creating file history version", "error", err)
+			}
+		}
+		// Store the new version
+		_, err = e.files.CreateVersion(ctx, sessionID, filePath, "")
 		if err != nil {
 			logging.Debug("Error creating file history version", "error", err)
 		}
-	}
-	// Store the new version
-	_, err = e.files.CreateVersion(ctx, sessionID, filePath, "")
-	if err != nil {
-		logging.Debug("Error creating file history version", "error", err)
+
 	}
 
 	recordFileWrite(filePath)
@@ -370,7 +372,7 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 }
 
 func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newString string) (ToolResponse, error) {
-	fileInfo, err := os.Stat(filePath)
+	fileInfo, err := statFile(ctx, filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return NewTextErrorResponse(fmt.Sprintf("file not found: %s", filePath)), nil
@@ -395,7 +397,7 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 			)), nil
 	}
 
-	content, err := os.ReadFile(filePath)
+	content, err := readFile(ctx, filePath)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
 	}
@@ -427,12 +429,12 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 		newContent,
 		filePath,
 	)
-	rootDir := config.WorkingDirectory()
+	rootDir := WorkingDirectory(ctx)
 	permissionPath := filepath.Dir(filePath)
 	if strings.HasPrefix(filePath, rootDir) {
 		permissionPath = rootDir
 	}
-	p := e.permissions.Request(
+	p := requestPermission(ctx, e.permissions,
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        permissionPath,
@@ -449,31 +451,34 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
-	err = os.WriteFile(filePath, []byte(newContent), 0o644)
+	err = writeFile(ctx, filePath, []byte(newContent))
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
 	}
 
-	// Check if file exists in history
-	file, err := e.files.GetByPathAndSession(ctx, filePath, sessionID)
-	if err != nil {
-		_, err = e.files.Create(ctx, sessionID, filePath, oldContent)
+	if !isDryRun(ctx) {
+		// Check if file exists in history
+		file, err := e.files.GetByPathAndSession(ctx, filePath, sessionID)
 		if err != nil {
-			// Log error but don't fail the operation
-			return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
+			_, err = e.files.Create(ctx, sessionID, filePath, oldContent)
+			if err != nil {
+				// Log error but don't fail the operation
+				return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
+			}
 		}
-	}
-	if file.Content != oldContent {
-		// User Manually changed the content store an intermediate version
-		_, err = e.files.CreateVersion(ctx, sessionID, filePath, oldContent)
+		if file.Content != oldContent {
+			// User Manually changed the content store an intermediate version
+			_, err = e.files.CreateVersion(ctx, sessionID, filePath, oldContent)
+			if err != nil {
+				logging.Debug("Error creating file history version", "error", err)
+			}
+		}
+		// Store the new version
+		_, err = e.files.CreateVersion(ctx, sessionID, filePath, newContent)
 		if err != nil {
 			logging.Debug("Error creating file history version", "error", err)
 		}
-	}
-	// Store the new version
-	_, err = e.files.CreateVersion(ctx, sessionID, filePath, newContent)
-	if err != nil {
-		logging.Debug("Error creating file history version", "error", err)
+
 	}
 
 	recordFileWrite(filePath)
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/opencode-ai/opencode/internal/message"
+)
+
+type evidenceContextKey struct{}
+
+// EvidenceLedger records the file ranges and URLs the agent read during a turn
+type EvidenceLedger struct {
+	mu      sync.Mutex
+	sources []message.EvidenceSource
+}
+
+// NewEvidenceLedger creates an empty ledger
+func NewEvidenceLedger() *EvidenceLedger {
+	return &EvidenceLedger{}
+}
+
+// WithEvidenceLedger returns a context whose read tools record into the ledger
+func WithEvidenceLedger(ctx context.Context, ledger *EvidenceLedger) context.Context {
+	return context.WithValue(ctx, evidenceContextKey{}, ledger)
+}
+
+// EvidenceLedgerFromContext returns the ledger of the current turn, or nil
+func EvidenceLedgerFromContext(ctx context.Context) *EvidenceLedger {
+	ledger, _ := ctx.Value(evidenceContextKey{}).(*EvidenceLedger)
+	return ledger
+}
+
+// Record adds a source to the ledger, skipping exact duplicates
+func (l *EvidenceLedger) Record(source message.EvidenceSource) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, existing := range l.sources {
+		if existing == source {
+			return
+		}
+	}
+	l.sources = append(l.sources, source)
+}
+
+// Sources returns the recorded sources in the order they were read
+func (l *EvidenceLedger) Sources() []message.EvidenceSource {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sources := make([]message.EvidenceSource, len(l.sources))
+	copy(sources, l.sources)
+	return sources
+}
+
+// recordEvidence adds a source to the ledger of the turn, if there is one
+func recordEvidence(ctx context.Context, source message.EvidenceSource) {
+	if ledger := EvidenceLedgerFromContext(ctx); ledger != nil {
+		ledger.Record(source)
+	}
+}
+
+// EvidenceCovers reports whether the sources include every line of a file range. Relative
+// paths match any recorded file they are a suffix of.
+func EvidenceCovers(sources []message.EvidenceSource, path string, startLine, endLine int) bool {
+	if endLine < startLine {
+		endLine = startLine
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+
+	covered := make(map[int]bool, endLine-startLine+1)
+	for _, source := range sources {
+		if source.Path == "" || !evidencePathMatches(filepath.ToSlash(source.Path), path) {
+			continue
+		}
+		for line := max(startLine, source.StartLine); line <= min(endLine, source.EndLine); line++ {
+			covered[line] = true
+		}
+	}
+	return len(covered) == endLine-startLine+1
+}
+
+func evidencePathMatches(recorded, cited string) bool {
+	if recorded == cited {
+		return true
+	}
+	if filepath.IsAbs(cited) {
+		return false
+	}
+	return strings.HasSuffix(recorded, "/"+strings.TrimPrefix(cited, "./"))
+}
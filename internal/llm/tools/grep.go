@@ -14,8 +14,8 @@ import (
 	"strings"
 	"time"
 
-	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/fileutil"
+	"github.com/opencode-ai/opencode/internal/message"
 )
 
 type GrepParams struct {
@@ -139,7 +139,7 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 
 	searchPath := params.Path
 	if searchPath == "" {
-		searchPath = config.WorkingDirectory()
+		searchPath = WorkingDirectory(ctx)
 	}
 
 	matches, truncated, err := searchFiles(searchPattern, searchPath, params.Include, 100)
@@ -164,6 +164,12 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 			}
 			if match.lineNum > 0 {
 				output += fmt.Sprintf("  Line %d: %s\n", match.lineNum, match.lineText)
+				recordEvidence(ctx, message.EvidenceSource{
+					Tool:      GrepToolName,
+					Path:      match.path,
+					StartLine: match.lineNum,
+					EndLine:   match.lineNum,
+				})
 			} else {
 				output += fmt.Sprintf("  %s\n", match.path)
 			}
@@ -0,0 +1,299 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/diff"
+	"github.com/opencode-ai/opencode/internal/permission"
+)
+
+type overlayContextKey struct{}
+
+// Overlay is an in-memory filesystem layer used by dry runs. The file tools write
+// into it instead of disk and read through it, so the agent sees its own changes.
+type Overlay struct {
+	mu    sync.RWMutex
+	files map[string]*overlayFile
+}
+
+type overlayFile struct {
+	original string
+	existed  bool
+	content  string
+	deleted  bool
+	modTime  time.Time
+}
+
+// FileChange is a file changed in an overlay
+type FileChange struct {
+	Path       string `json:"path"`
+	OldContent string `json:"old_content"`
+	NewContent string `json:"new_content"`
+	Created    bool   `json:"created"`
+	Deleted    bool   `json:"deleted"`
+}
+
+// Diff returns the unified diff of the change
+func (c FileChange) Diff() string {
+	unified, _, _ := diff.GenerateDiff(c.OldContent, c.NewContent, c.Path)
+	return unified
+}
+
+// NewOverlay creates an empty overlay
+func NewOverlay() *Overlay {
+	return &Overlay{files: make(map[string]*overlayFile)}
+}
+
+// WithOverlay returns a context whose file tool calls are redirected to the overlay
+func WithOverlay(ctx context.Context, overlay *Overlay) context.Context {
+	return context.WithValue(ctx, overlayContextKey{}, overlay)
+}
+
+// OverlayFromContext returns the overlay of a dry run, or nil
+func OverlayFromContext(ctx context.Context) *Overlay {
+	overlay, _ := ctx.Value(overlayContextKey{}).(*Overlay)
+	return overlay
+}
+
+// isDryRun reports whether tool calls in ctx must not touch the disk
+func isDryRun(ctx context.Context) bool {
+	return OverlayFromContext(ctx) != nil
+}
+
+// file returns the overlay entry for path, capturing the disk content on first use
+func (o *Overlay) file(path string) (*overlayFile, error) {
+	if f, ok := o.files[path]; ok {
+		return f, nil
+	}
+
+	f := &overlayFile{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		f.original = string(data)
+		f.content = f.original
+		f.existed = true
+	case os.IsNotExist(err):
+		f.deleted = true
+	default:
+		return nil, err
+	}
+	o.files[path] = f
+	return f, nil
+}
+
+func (o *Overlay) write(path, content string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	f, err := o.file(path)
+	if err != nil {
+		return err
+	}
+	f.content = content
+	f.deleted = false
+	f.modTime = time.Now()
+	return nil
+}
+
+func (o *Overlay) remove(path string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	f, err := o.file(path)
+	if err != nil {
+		return err
+	}
+	if f.deleted {
+		return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrNotExist}
+	}
+	f.content = ""
+	f.deleted = true
+	f.modTime = time.Now()
+	return nil
+}
+
+// lookup returns the overlay entry for path if the overlay has touched it
+func (o *Overlay) lookup(path string) (*overlayFile, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	f, ok := o.files[path]
+	return f, ok
+}
+
+// Changes returns the files whose content differs from disk, sorted by path
+func (o *Overlay) Changes() []FileChange {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	var changes []FileChange
+	for path, f := range o.files {
+		if f.existed == !f.deleted && f.original == f.content {
+			continue
+		}
+		changes = append(changes, FileChange{
+			Path:       path,
+			OldContent: f.original,
+			NewContent: f.content,
+			Created:    !f.existed && !f.deleted,
+			Deleted:    f.existed && f.deleted,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// Diff returns one unified diff covering every changed file
+func (o *Overlay) Diff() string {
+	var sb strings.Builder
+	for _, change := range o.Changes() {
+		sb.WriteString(change.Diff())
+	}
+	return sb.String()
+}
+
+// Apply writes the changes of the given paths to disk, or all changes when no path is
+// given. Files modified on disk since the dry run read them are not overwritten.
+func (o *Overlay) Apply(paths ...string) error {
+	selected := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		selected[path] = true
+	}
+
+	var errs []string
+	for _, change := range o.Changes() {
+		if len(paths) > 0 && !selected[change.Path] {
+			continue
+		}
+		if err := applyChange(change); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		recordFileWrite(change.Path)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to apply changes: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func applyChange(change FileChange) error {
+	current, err := os.ReadFile(change.Path)
+	switch {
+	case err == nil && (change.Created || string(current) != change.OldContent):
+		return fmt.Errorf("%s changed on disk since the dry run", change.Path)
+	case err != nil && !os.IsNotExist(err):
+		return err
+	case err != nil && !change.Created:
+		return fmt.Errorf("%s was removed since the dry run", change.Path)
+	}
+
+	if change.Deleted {
+		return os.Remove(change.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(change.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(change.Path, []byte(change.NewContent), 0o644)
+}
+
+// overlayFileInfo describes a file that only exists in an overlay
+type overlayFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i overlayFileInfo) Name() string       { return i.name }
+func (i overlayFileInfo) Size() int64        { return i.size }
+func (i overlayFileInfo) Mode() fs.FileMode  { return 0o644 }
+func (i overlayFileInfo) ModTime() time.Time { return i.modTime }
+func (i overlayFileInfo) IsDir() bool        { return false }
+func (i overlayFileInfo) Sys() any           { return nil }
+
+// statFile stats a file through the overlay of a dry run
+func statFile(ctx context.Context, path string) (fs.FileInfo, error) {
+	if overlay := OverlayFromContext(ctx); overlay != nil {
+		if f, ok := overlay.lookup(path); ok {
+			if f.deleted {
+				return nil, &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+			}
+			if !f.modTime.IsZero() {
+				return overlayFileInfo{name: filepath.Base(path), size: int64(len(f.content)), modTime: f.modTime}, nil
+			}
+		}
+	}
+	return os.Stat(path)
+}
+
+// readFile reads a file through the overlay of a dry run
+func readFile(ctx context.Context, path string) ([]byte, error) {
+	if overlay := OverlayFromContext(ctx); overlay != nil {
+		if f, ok := overlay.lookup(path); ok {
+			if f.deleted {
+				return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+			}
+			return []byte(f.content), nil
+		}
+	}
+	return os.ReadFile(path)
+}
+
+// openFile opens a file for reading through the overlay of a dry run
+func openFile(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+	if overlay := OverlayFromContext(ctx); overlay != nil {
+		if _, ok := overlay.lookup(path); ok {
+			content, err := readFile(ctx, path)
+			if err != nil {
+				return nil, err
+			}
+			return overlayReader{bytes.NewReader(content)}, nil
+		}
+	}
+	return os.Open(path)
+}
+
+// overlayReader reads overlay content like an open file
+type overlayReader struct {
+	*bytes.Reader
+}
+
+func (overlayReader) Close() error { return nil }
+
+// writeFile writes a file, creating its parent directories. Dry runs write to the overlay.
+func writeFile(ctx context.Context, path string, content []byte) error {
+	if overlay := OverlayFromContext(ctx); overlay != nil {
+		return overlay.write(path, string(content))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directories: %w", err)
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+// removeFile removes a file. Dry runs mark it deleted in the overlay.
+func removeFile(ctx context.Context, path string) error {
+	if overlay := OverlayFromContext(ctx); overlay != nil {
+		return overlay.remove(path)
+	}
+	return os.Remove(path)
+}
+
+// requestPermission asks for permission to change a file. Dry runs only change the
+// overlay, the user reviews the combined diff before anything reaches the disk.
+func requestPermission(ctx context.Context, permissions permission.Service, req permission.CreatePermissionRequest) bool {
+	if isDryRun(ctx) {
+		return true
+	}
+	return permissions.Request(req)
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlay(t *testing.T) {
+	tempDir := t.TempDir()
+	_, err := config.Load(tempDir, false)
+	require.NoError(t, err)
+
+	existing := filepath.Join(tempDir, "main.go")
+	removed := filepath.Join(tempDir, "old.go")
+	created := filepath.Join(tempDir, "pkg", "new.go")
+	require.NoError(t, os.WriteFile(existing, []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(removed, []byte("package old\n"), 0o644))
+
+	newDryRun := func(t *testing.T) (*Overlay, context.Context) {
+		overlay := NewOverlay()
+		ctx := WithOverlay(context.Background(), overlay)
+		require.NoError(t, writeFile(ctx, existing, []byte("package main\n\nfunc main() {}\n")))
+		require.NoError(t, writeFile(ctx, created, []byte("package pkg\n")))
+		require.NoError(t, removeFile(ctx, removed))
+		return overlay, ctx
+	}
+
+	t.Run("reads through the overlay without touching disk", func(t *testing.T) {
+		_, ctx := newDryRun(t)
+
+		content, err := readFile(ctx, existing)
+		require.NoError(t, err)
+		assert.Equal(t, "package main\n\nfunc main() {}\n", string(content))
+
+		_, err = statFile(ctx, removed)
+		assert.True(t, os.IsNotExist(err))
+		info, err := statFile(ctx, created)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len("package pkg\n")), info.Size())
+
+		onDisk, err := os.ReadFile(existing)
+		require.NoError(t, err)
+		assert.Equal(t, "package main\n", string(onDisk))
+		_, err = os.Stat(created)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("reports changes and a combined diff", func(t *testing.T) {
+		overlay, ctx := newDryRun(t)
+		require.NoError(t, writeFile(ctx, filepath.Join(tempDir, "same.go"), []byte("")))
+		require.NoError(t, removeFile(ctx, filepath.Join(tempDir, "same.go")))
+
+		changes := overlay.Changes()
+		require.Len(t, changes, 3)
+		assert.Equal(t, existing, changes[0].Path)
+		assert.Equal(t, removed, changes[1].Path)
+		assert.True(t, changes[1].Deleted)
+		assert.Equal(t, created, changes[2].Path)
+		assert.True(t, changes[2].Created)
+
+		combined := overlay.Diff()
+		assert.Contains(t, combined, "main.go\n@@ -1 +1,3 @@")
+		assert.Contains(t, combined, "-package old")
+		assert.Contains(t, combined, "pkg/new.go\n@@ -0,0 +1 @@")
+	})
+
+	t.Run("applies selected files", func(t *testing.T) {
+		overlay, _ := newDryRun(t)
+		require.NoError(t, overlay.Apply(created))
+
+		content, err := os.ReadFile(created)
+		require.NoError(t, err)
+		assert.Equal(t, "package pkg\n", string(content))
+		onDisk, err := os.ReadFile(existing)
+		require.NoError(t, err)
+		assert.Equal(t, "package main\n", string(onDisk))
+		require.NoError(t, os.RemoveAll(filepath.Dir(created)))
+	})
+
+	t.Run("refuses to overwrite files changed since the dry run", func(t *testing.T) {
+		overlay, _ := newDryRun(t)
+		require.NoError(t, os.WriteFile(existing, []byte("package changed\n"), 0o644))
+		t.Cleanup(func() { os.WriteFile(existing, []byte("package main\n"), 0o644) })
+
+		err := overlay.Apply(existing)
+		require.Error(t, err)
+		onDisk, _ := os.ReadFile(existing)
+		assert.Equal(t, "package changed\n", string(onDisk))
+	})
+
+	t.Run("applies all changes", func(t *testing.T) {
+		overlay, _ := newDryRun(t)
+		require.NoError(t, overlay.Apply())
+
+		onDisk, err := os.ReadFile(existing)
+		require.NoError(t, err)
+		assert.Equal(t, "package main\n\nfunc main() {}\n", string(onDisk))
+		_, err = os.Stat(removed)
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(created)
+		assert.NoError(t, err)
+	})
+}
@@ -8,7 +8,6 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/diff"
 	"github.com/opencode-ai/opencode/internal/history"
 	"github.com/opencode-ai/opencode/internal/logging"
@@ -101,7 +100,7 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	for _, filePath := range filesToRead {
 		absPath := filePath
 		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
+			wd := WorkingDirectory(ctx)
 			absPath = filepath.Join(wd, absPath)
 		}
 
@@ -109,7 +108,7 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			return NewTextErrorResponse(fmt.Sprintf("you must read the file %s before patching it. Use the FileRead tool first", filePath)), nil
 		}
 
-		fileInfo, err := os.Stat(absPath)
+		fileInfo, err := statFile(ctx, absPath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				return NewTextErrorResponse(fmt.Sprintf("file not found: %s", absPath)), nil
@@ -136,11 +135,11 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	for _, filePath := range filesToAdd {
 		absPath := filePath
 		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
+			wd := WorkingDirectory(ctx)
 			absPath = filepath.Join(wd, absPath)
 		}
 
-		_, err := os.Stat(absPath)
+		_, err := statFile(ctx, absPath)
 		if err == nil {
 			return NewTextErrorResponse(fmt.Sprintf("file already exists and cannot be added: %s", absPath)), nil
 		} else if !os.IsNotExist(err) {
@@ -153,11 +152,11 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	for _, filePath := range filesToRead {
 		absPath := filePath
 		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
+			wd := WorkingDirectory(ctx)
 			absPath = filepath.Join(wd, absPath)
 		}
 
-		content, err := os.ReadFile(absPath)
+		content, err := readFile(ctx, absPath)
 		if err != nil {
 			return ToolResponse{}, fmt.Errorf("failed to read file %s: %w", absPath, err)
 		}
@@ -192,7 +191,7 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		case diff.ActionAdd:
 			dir := filepath.Dir(path)
 			patchDiff, _, _ := diff.GenerateDiff("", *change.NewContent, path)
-			p := p.permissions.Request(
+			p := requestPermission(ctx, p.permissions,
 				permission.CreatePermissionRequest{
 					SessionID:   sessionID,
 					Path:        dir,
@@ -219,7 +218,7 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			}
 			patchDiff, _, _ := diff.GenerateDiff(currentContent, newContent, path)
 			dir := filepath.Dir(path)
-			p := p.permissions.Request(
+			p := requestPermission(ctx, p.permissions,
 				permission.CreatePermissionRequest{
 					SessionID:   sessionID,
 					Path:        dir,
@@ -238,7 +237,7 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		case diff.ActionDelete:
 			dir := filepath.Dir(path)
 			patchDiff, _, _ := diff.GenerateDiff(*change.OldContent, "", path)
-			p := p.permissions.Request(
+			p := requestPermission(ctx, p.permissions,
 				permission.CreatePermissionRequest{
 					SessionID:   sessionID,
 					Path:        dir,
@@ -261,24 +260,21 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	err = diff.ApplyCommit(commit, func(path string, content string) error {
 		absPath := path
 		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
+			wd := WorkingDirectory(ctx)
 			absPath = filepath.Join(wd, absPath)
 		}
 
-		// Create parent directories if needed
-		dir := filepath.Dir(absPath)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
-			return fmt.Errorf("failed to create parent directories for %s: %w", absPath, err)
+		if err := writeFile(ctx, absPath, []byte(content)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", absPath, err)
 		}
-
-		return os.WriteFile(absPath, []byte(content), 0o644)
+		return nil
 	}, func(path string) error {
 		absPath := path
 		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
+			wd := WorkingDirectory(ctx)
 			absPath = filepath.Join(wd, absPath)
 		}
-		return os.Remove(absPath)
+		return removeFile(ctx, absPath)
 	})
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("failed to apply patch: %s", err)), nil
@@ -292,7 +288,7 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	for path, change := range commit.Changes {
 		absPath := path
 		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
+			wd := WorkingDirectory(ctx)
 			absPath = filepath.Join(wd, absPath)
 		}
 		changedFiles = append(changedFiles, absPath)
@@ -312,32 +308,35 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		totalAdditions += additions
 		totalRemovals += removals
 
-		// Update history
-		file, err := p.files.GetByPathAndSession(ctx, absPath, sessionID)
-		if err != nil && change.Type != diff.ActionAdd {
-			// If not adding a file, create history entry for existing file
-			_, err = p.files.Create(ctx, sessionID, absPath, oldContent)
-			if err != nil {
-				logging.Debug("Error creating file history", "error", err)
+		if !isDryRun(ctx) {
+			// Update history
+			file, err := p.files.GetByPathAndSession(ctx, absPath, sessionID)
+			if err != nil && change.Type != diff.ActionAdd {
+				// If not adding a file, create history entry for existing file
+				_, err = p.files.Create(ctx, sessionID, absPath, oldContent)
+				if err != nil {
+					logging.Debug("Error creating file history", "error", err)
+				}
 			}
-		}
 
-		if err == nil && change.Type != diff.ActionAdd && file.Content != oldContent {
-			// User manually changed content, store intermediate version
-			_, err = p.files.CreateVersion(ctx, sessionID, absPath, oldContent)
+			if err == nil && change.Type != diff.ActionAdd && file.Content != oldContent {
+				// User manually changed content, store intermediate version
+				_, err = p.files.CreateVersion(ctx, sessionID, absPath, oldContent)
+				if err != nil {
+					logging.Debug("Error creating file history version", "error", err)
+				}
+			}
+
+			// Store new version
+			if change.Type == diff.ActionDelete {
+				_, err = p.files.CreateVersion(ctx, sessionID, absPath, "")
+			} else {
+				_, err = p.files.CreateVersion(ctx, sessionID, absPath, newContent)
+			}
 			if err != nil {
 				logging.Debug("Error creating file history version", "error", err)
 			}
-		}
 
-		// Store new version
-		if change.Type == diff.ActionDelete {
-			_, err = p.files.CreateVersion(ctx, sessionID, absPath, "")
-		} else {
-			_, err = p.files.CreateVersion(ctx, sessionID, absPath, newContent)
-		}
-		if err != nil {
-			logging.Debug("Error creating file history version", "error", err)
 		}
 
 		// Record file operations
@@ -345,17 +344,18 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		recordFileRead(absPath)
 	}
 
-	// Run LSP diagnostics on all changed files
-	for _, filePath := range changedFiles {
-		waitForLspDiagnostics(ctx, filePath, p.lspClients)
-	}
-
 	result := fmt.Sprintf("Patch applied successfully. %d files changed, %d additions, %d removals",
 		len(changedFiles), totalAdditions, totalRemovals)
 
+	// Run LSP diagnostics on all changed files, dry runs leave the disk and LSP untouched
 	diagnosticsText := ""
-	for _, filePath := range changedFiles {
-		diagnosticsText += getDiagnostics(filePath, p.lspClients)
+	if !isDryRun(ctx) {
+		for _, filePath := range changedFiles {
+			waitForLspDiagnostics(ctx, filePath, p.lspClients)
+		}
+		for _, filePath := range changedFiles {
+			diagnosticsText += getDiagnostics(filePath, p.lspClients)
+		}
 	}
 
 	if diagnosticsText != "" {
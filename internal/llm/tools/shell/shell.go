@@ -42,42 +42,73 @@ type commandResult struct {
 var (
 	shellInstance     *PersistentShell
 	shellInstanceOnce sync.Once
+
+	// workspaceShells are the shells of workspaces with their own directory and environment
+	workspaceShellsMu sync.Mutex
+	workspaceShells   = map[string]*PersistentShell{}
 )
 
 func GetPersistentShell(workingDir string) *PersistentShell {
 	shellInstanceOnce.Do(func() {
-		shellInstance = newPersistentShell(workingDir)
+		shellInstance = newPersistentShell(workingDir, nil)
 	})
 
 	if shellInstance == nil {
-		shellInstance = newPersistentShell(workingDir)
+		shellInstance = newPersistentShell(workingDir, nil)
 	} else if !shellInstance.isAlive {
-		shellInstance = newPersistentShell(shellInstance.cwd)
+		shellInstance = newPersistentShell(shellInstance.cwd, nil)
 	}
 
 	return shellInstance
 }
 
-func newPersistentShell(cwd string) *PersistentShell {
+// GetWorkspaceShell returns the persistent shell of a workspace, started in
+// workingDir with env added to the process environment. The shell is restarted
+// in workingDir when it died.
+func GetWorkspaceShell(id, workingDir string, env []string) *PersistentShell {
+	workspaceShellsMu.Lock()
+	defer workspaceShellsMu.Unlock()
+
+	shell := workspaceShells[id]
+	if shell == nil || !shell.isAlive {
+		shell = newPersistentShell(workingDir, env)
+		workspaceShells[id] = shell
+	}
+	return shell
+}
+
+// CloseWorkspaceShell stops the shell of a workspace, the next command starts a new one
+func CloseWorkspaceShell(id string) {
+	workspaceShellsMu.Lock()
+	shell := workspaceShells[id]
+	delete(workspaceShells, id)
+	workspaceShellsMu.Unlock()
+
+	if shell != nil {
+		shell.Close()
+	}
+}
+
+func newPersistentShell(cwd string, env []string) *PersistentShell {
 	// Get shell configuration from config
 	cfg := config.Get()
-	
+
 	// Default to environment variable if config is not set or nil
 	var shellPath string
 	var shellArgs []string
-	
+
 	if cfg != nil {
 		shellPath = cfg.Shell.Path
 		shellArgs = cfg.Shell.Args
 	}
-	
+
 	if shellPath == "" {
 		shellPath = os.Getenv("SHELL")
 		if shellPath == "" {
 			shellPath = "/bin/bash"
 		}
 	}
-	
+
 	// Default shell args
 	if len(shellArgs) == 0 {
 		shellArgs = []string{"-l"}
@@ -92,6 +123,7 @@ func newPersistentShell(cwd string) *PersistentShell {
 	}
 
 	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+	cmd.Env = append(cmd.Env, env...)
 
 	err = cmd.Start()
 	if err != nil {
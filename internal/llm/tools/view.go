@@ -10,9 +10,9 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/opencode-ai/opencode/internal/lsp"
+	"github.com/opencode-ai/opencode/internal/message"
 )
 
 type ViewParams struct {
@@ -110,11 +110,11 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	// Handle relative paths
 	filePath := params.FilePath
 	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(config.WorkingDirectory(), filePath)
+		filePath = filepath.Join(WorkingDirectory(ctx), filePath)
 	}
 
 	// Check if file exists
-	fileInfo, err := os.Stat(filePath)
+	fileInfo, err := statFile(ctx, filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Try to offer suggestions for similarly named files
@@ -169,12 +169,14 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	}
 
 	// Read the file content
-	content, lineCount, err := readTextFile(filePath, params.Offset, params.Limit)
+	content, lineCount, err := readTextFile(ctx, filePath, params.Offset, params.Limit)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error reading file: %w", err)
 	}
 
-	notifyLspOpenFile(ctx, filePath, v.lspClients)
+	if !isDryRun(ctx) {
+		notifyLspOpenFile(ctx, filePath, v.lspClients)
+	}
 	output := "<file>\n"
 	// Format the output with line numbers
 	output += addLineNumbers(content, params.Offset+1)
@@ -185,8 +187,18 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 			params.Offset+len(strings.Split(content, "\n")))
 	}
 	output += "\n</file>\n"
-	output += getDiagnostics(filePath, v.lspClients)
+	if !isDryRun(ctx) {
+		output += getDiagnostics(filePath, v.lspClients)
+	}
 	recordFileRead(filePath)
+	if content != "" {
+		recordEvidence(ctx, message.EvidenceSource{
+			Tool:      ViewToolName,
+			Path:      filePath,
+			StartLine: params.Offset + 1,
+			EndLine:   params.Offset + len(strings.Split(content, "\n")),
+		})
+	}
 	return WithResponseMetadata(
 		NewTextResponse(output),
 		ViewResponseMetadata{
@@ -221,8 +233,8 @@ func addLineNumbers(content string, startLine int) string {
 	return strings.Join(result, "\n")
 }
 
-func readTextFile(filePath string, offset, limit int) (string, int, error) {
-	file, err := os.Open(filePath)
+func readTextFile(ctx context.Context, filePath string, offset, limit int) (string, int, error) {
+	file, err := openFile(ctx, filePath)
 	if err != nil {
 		return "", 0, err
 	}
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"sort"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/tools/shell"
+)
+
+type workspaceContextKey struct{}
+
+// Workspace is the directory and environment the tools of one client run in, such
+// as a session of the MCP server. Without a workspace the tools use the process
+// working directory and environment.
+type Workspace struct {
+	// ID keys the persistent shell of the workspace
+	ID          string
+	Dir         string
+	Environment map[string]string
+}
+
+// WithWorkspace returns a context whose tool calls run in the workspace
+func WithWorkspace(ctx context.Context, workspace Workspace) context.Context {
+	return context.WithValue(ctx, workspaceContextKey{}, workspace)
+}
+
+// WorkspaceFromContext returns the workspace of ctx, if any
+func WorkspaceFromContext(ctx context.Context) (Workspace, bool) {
+	workspace, ok := ctx.Value(workspaceContextKey{}).(Workspace)
+	return workspace, ok && workspace.Dir != ""
+}
+
+// WorkingDirectory returns the directory of the workspace in ctx, or the process
+// working directory
+func WorkingDirectory(ctx context.Context) string {
+	if workspace, ok := WorkspaceFromContext(ctx); ok {
+		return workspace.Dir
+	}
+	return config.WorkingDirectory()
+}
+
+// workspaceShell returns the persistent shell commands of ctx run in
+func workspaceShell(ctx context.Context) *shell.PersistentShell {
+	workspace, ok := WorkspaceFromContext(ctx)
+	if !ok {
+		return shell.GetPersistentShell(config.WorkingDirectory())
+	}
+	env := make([]string, 0, len(workspace.Environment))
+	for key, value := range workspace.Environment {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return shell.GetWorkspaceShell(workspace.ID, workspace.Dir, env)
+}
@@ -9,7 +9,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/diff"
 	"github.com/opencode-ai/opencode/internal/history"
 	"github.com/opencode-ai/opencode/internal/logging"
@@ -113,10 +112,10 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 	filePath := params.FilePath
 	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(config.WorkingDirectory(), filePath)
+		filePath = filepath.Join(WorkingDirectory(ctx), filePath)
 	}
 
-	fileInfo, err := os.Stat(filePath)
+	fileInfo, err := statFile(ctx, filePath)
 	if err == nil {
 		if fileInfo.IsDir() {
 			return NewTextErrorResponse(fmt.Sprintf("Path is a directory, not a file: %s", filePath)), nil
@@ -129,7 +128,7 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 				filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339))), nil
 		}
 
-		oldContent, readErr := os.ReadFile(filePath)
+		oldContent, readErr := readFile(ctx, filePath)
 		if readErr == nil && string(oldContent) == params.Content {
 			return NewTextErrorResponse(fmt.Sprintf("File %s already contains the exact content. No changes made.", filePath)), nil
 		}
@@ -137,14 +136,9 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return ToolResponse{}, fmt.Errorf("error checking file: %w", err)
 	}
 
-	dir := filepath.Dir(filePath)
-	if err = os.MkdirAll(dir, 0o755); err != nil {
-		return ToolResponse{}, fmt.Errorf("error creating directory: %w", err)
-	}
-
 	oldContent := ""
 	if fileInfo != nil && !fileInfo.IsDir() {
-		oldBytes, readErr := os.ReadFile(filePath)
+		oldBytes, readErr := readFile(ctx, filePath)
 		if readErr == nil {
 			oldContent = string(oldBytes)
 		}
@@ -161,12 +155,12 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		filePath,
 	)
 
-	rootDir := config.WorkingDirectory()
+	rootDir := WorkingDirectory(ctx)
 	permissionPath := filepath.Dir(filePath)
 	if strings.HasPrefix(filePath, rootDir) {
 		permissionPath = rootDir
 	}
-	p := w.permissions.Request(
+	p := requestPermission(ctx, w.permissions,
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        permissionPath,
@@ -183,40 +177,45 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
-	err = os.WriteFile(filePath, []byte(params.Content), 0o644)
+	err = writeFile(ctx, filePath, []byte(params.Content))
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error writing file: %w", err)
 	}
 
-	// Check if file exists in history
-	file, err := w.files.GetByPathAndSession(ctx, filePath, sessionID)
-	if err != nil {
-		_, err = w.files.Create(ctx, sessionID, filePath, oldContent)
+	if !isDryRun(ctx) {
+		// Check if file exists in history
+		file, err := w.files.GetByPathAndSession(ctx, filePath, sessionID)
 		if err != nil {
-			// Log error but don't fail the operation
-			return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
+			_, err = w.files.Create(ctx, sessionID, filePath, oldContent)
+			if err != nil {
+				// Log error but don't fail the operation
+				return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
+			}
 		}
-	}
-	if file.Content != oldContent {
-		// User Manually changed the content store an intermediate version
-		_, err = w.files.CreateVersion(ctx, sessionID, filePath, oldContent)
+		if file.Content != oldContent {
+			// User Manually changed the content store an intermediate version
+			_, err = w.files.CreateVersion(ctx, sessionID, filePath, oldContent)
+			if err != nil {
+				logging.Debug("Error creating file history version", "error", err)
+			}
+		}
+		// Store the new version
+		_, err = w.files.CreateVersion(ctx, sessionID, filePath, params.Content)
 		if err != nil {
 			logging.Debug("Error creating file history version", "error", err)
 		}
-	}
-	// Store the new version
-	_, err = w.files.CreateVersion(ctx, sessionID, filePath, params.Content)
-	if err != nil {
-		logging.Debug("Error creating file history version", "error", err)
+
 	}
 
 	recordFileWrite(filePath)
 	recordFileRead(filePath)
-	waitForLspDiagnostics(ctx, filePath, w.lspClients)
 
 	result := fmt.Sprintf("File successfully written: %s", filePath)
 	result = fmt.Sprintf("<result>\n%s\n</result>", result)
-	result += getDiagnostics(filePath, w.lspClients)
+	if !isDryRun(ctx) {
+		waitForLspDiagnostics(ctx, filePath, w.lspClients)
+		result += getDiagnostics(filePath, w.lspClients)
+	}
 	return WithResponseMetadata(NewTextResponse(result),
 		WriteResponseMetadata{
 			Diff:      diff,
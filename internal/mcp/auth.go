@@ -0,0 +1,205 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/config"
+)
+
+const (
+	tokenIssuer = "opencode"
+
+	defaultAccessTokenExpiry  = time.Hour
+	defaultRefreshTokenExpiry = 7 * 24 * time.Hour
+
+	// minSecretLength is the smallest HS256 key accepted, shorter keys can be brute forced
+	minSecretLength = 32
+)
+
+var (
+	// ErrUnauthenticated is returned when a request carries no valid access token
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrTokenExpired is returned when the access token of a connection has expired
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Principal is the authenticated user behind a connection
+type Principal struct {
+	Subject string `json:"subject"`
+	// Tenant is the tenant whose quotas the requests of the connection count
+	// against, empty for the default tenant
+	Tenant    string    `json:"tenant,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TokenPair is a short-lived access token and the refresh token that renews it
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type tokenClaims struct {
+	TokenType string `json:"token_type"`
+	Tenant    string `json:"tenant,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Authenticator issues and validates HS256 JWTs signed with AuthConfig.JWTSecret
+type Authenticator struct {
+	secret        []byte
+	accessExpiry  time.Duration
+	refreshExpiry time.Duration
+
+	// usedRefreshTokens holds the IDs of rotated refresh tokens until they expire, so
+	// a refresh token can only be used once
+	mu                sync.Mutex
+	usedRefreshTokens map[string]time.Time
+}
+
+// NewAuthenticator creates an authenticator from the auth configuration. The secret
+// may reference environment variables, such as ${JWT_SECRET}.
+func NewAuthenticator(cfg config.AuthConfig) (*Authenticator, error) {
+	secret := os.ExpandEnv(cfg.JWTSecret)
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("security.auth.jwt_secret must be at least %d bytes", minSecretLength)
+	}
+
+	a := &Authenticator{
+		secret:            []byte(secret),
+		accessExpiry:      cfg.JWTExpiry,
+		refreshExpiry:     cfg.RefreshTokenExpiry,
+		usedRefreshTokens: make(map[string]time.Time),
+	}
+	if a.accessExpiry <= 0 {
+		a.accessExpiry = defaultAccessTokenExpiry
+	}
+	if a.refreshExpiry <= 0 {
+		a.refreshExpiry = defaultRefreshTokenExpiry
+	}
+	return a, nil
+}
+
+// Issue creates an access and a refresh token for the subject
+func (a *Authenticator) Issue(subject string) (TokenPair, error) {
+	return a.IssueTenant(subject, "")
+}
+
+// IssueTenant creates an access and a refresh token for the subject whose requests
+// belong to tenant. An empty tenant is the default tenant.
+func (a *Authenticator) IssueTenant(subject, tenant string) (TokenPair, error) {
+	if subject == "" {
+		return TokenPair{}, fmt.Errorf("subject is required")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(a.accessExpiry)
+	access, err := a.sign(subject, tenant, "access", now, expiresAt)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := a.sign(subject, tenant, "refresh", now, now.Add(a.refreshExpiry))
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresAt: expiresAt}, nil
+}
+
+// Authenticate validates an access token and returns its principal
+func (a *Authenticator) Authenticate(token string) (Principal, error) {
+	claims, err := a.parse(token, "access")
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: claims.Subject, Tenant: claims.Tenant, ExpiresAt: claims.ExpiresAt.Time}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The refresh token is
+// rotated: using it a second time fails.
+func (a *Authenticator) Refresh(refreshToken string) (TokenPair, error) {
+	claims, err := a.parse(refreshToken, "refresh")
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	a.mu.Lock()
+	now := time.Now()
+	for id, expiresAt := range a.usedRefreshTokens {
+		if now.After(expiresAt) {
+			delete(a.usedRefreshTokens, id)
+		}
+	}
+	if _, used := a.usedRefreshTokens[claims.ID]; used {
+		a.mu.Unlock()
+		return TokenPair{}, fmt.Errorf("%w: refresh token already used", ErrUnauthenticated)
+	}
+	a.usedRefreshTokens[claims.ID] = claims.ExpiresAt.Time
+	a.mu.Unlock()
+
+	return a.IssueTenant(claims.Subject, claims.Tenant)
+}
+
+// AuthenticateRequest validates the bearer token of an HTTP request. Browsers cannot
+// set headers on WebSocket requests, so the access_token query parameter is accepted too.
+func (a *Authenticator) AuthenticateRequest(r *http.Request) (Principal, error) {
+	token := r.URL.Query().Get("access_token")
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, value, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return Principal{}, fmt.Errorf("%w: expected a bearer token", ErrUnauthenticated)
+		}
+		token = strings.TrimSpace(value)
+	}
+	if token == "" {
+		return Principal{}, fmt.Errorf("%w: missing access token", ErrUnauthenticated)
+	}
+	return a.Authenticate(token)
+}
+
+func (a *Authenticator) sign(subject, tenant, tokenType string, issuedAt, expiresAt time.Time) (string, error) {
+	claims := tokenClaims{
+		TokenType: tokenType,
+		Tenant:    tenant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    tokenIssuer,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s token: %w", tokenType, err)
+	}
+	return token, nil
+}
+
+func (a *Authenticator) parse(token, tokenType string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	if claims.TokenType != tokenType || claims.Subject == "" {
+		return nil, fmt.Errorf("%w: not a %s token", ErrUnauthenticated, tokenType)
+	}
+	return claims, nil
+}
@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/superclaude"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestAuthenticator(t *testing.T) {
+	_, err := NewAuthenticator(config.AuthConfig{JWTSecret: "short"})
+	assert.Error(t, err, "short secrets are refused")
+
+	t.Setenv("TEST_JWT_SECRET", testSecret)
+	auth, err := NewAuthenticator(config.AuthConfig{JWTSecret: "${TEST_JWT_SECRET}"})
+	require.NoError(t, err)
+
+	tokens, err := auth.Issue("alice")
+	require.NoError(t, err)
+
+	principal, err := auth.Authenticate(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Subject)
+
+	_, err = auth.Authenticate(tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrUnauthenticated, "refresh tokens are not access tokens")
+
+	other, err := NewAuthenticator(config.AuthConfig{JWTSecret: strings.Repeat("x", 32)})
+	require.NoError(t, err)
+	_, err = other.Authenticate(tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrUnauthenticated, "tokens signed with another secret are refused")
+
+	renewed, err := auth.Refresh(tokens.RefreshToken)
+	require.NoError(t, err)
+	principal, err = auth.Authenticate(renewed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Subject)
+
+	_, err = auth.Refresh(tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrUnauthenticated, "refresh tokens are rotated")
+
+	expiring, err := NewAuthenticator(config.AuthConfig{JWTSecret: testSecret, JWTExpiry: time.Nanosecond})
+	require.NoError(t, err)
+	tokens, err = expiring.Issue("alice")
+	require.NoError(t, err)
+	time.Sleep(time.Second)
+	_, err = expiring.Authenticate(tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
+
+func TestAuthenticatorTenant(t *testing.T) {
+	auth, err := NewAuthenticator(config.AuthConfig{JWTSecret: testSecret})
+	require.NoError(t, err)
+
+	tokens, err := auth.IssueTenant("alice", "acme")
+	require.NoError(t, err)
+	principal, err := auth.Authenticate(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", principal.Tenant)
+	assert.Equal(t, "acme", tenantOf(&principal))
+
+	renewed, err := auth.Refresh(tokens.RefreshToken)
+	require.NoError(t, err)
+	principal, err = auth.Authenticate(renewed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", principal.Tenant, "refreshing keeps the tenant")
+
+	tokens, err = auth.Issue("bob")
+	require.NoError(t, err)
+	principal, err = auth.Authenticate(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "default", tenantOf(&principal))
+}
+
+func TestMCPServerAuth(t *testing.T) {
+	auth, err := NewAuthenticator(config.AuthConfig{JWTSecret: testSecret})
+	require.NoError(t, err)
+	server := NewMCPServer(
+		superclaude.NewSuperClaudeHandler(nil, nil, nil),
+		WithAuthenticator(auth),
+		WithCORS(config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}),
+	)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	dial := func(subject, origin string) (*websocket.Conn, *http.Response, error) {
+		header := http.Header{}
+		if subject != "" {
+			tokens, err := auth.Issue(subject)
+			require.NoError(t, err)
+			header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		return websocket.DefaultDialer.Dial(wsURL, header)
+	}
+
+	t.Run("rejects connections without a token", func(t *testing.T) {
+		_, resp, err := dial("", "")
+		require.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("rejects origins outside the allowlist", func(t *testing.T) {
+		_, resp, err := dial("alice", "https://evil.example.com")
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("isolates sessions per user", func(t *testing.T) {
+		call := func(conn *websocket.Conn, sessionID string) MCPResponse {
+			require.NoError(t, conn.WriteJSON(MCPRequest{
+				ID:      "1",
+				Method:  "capabilities",
+				Context: MCPContext{SessionID: sessionID},
+			}))
+			var resp MCPResponse
+			require.NoError(t, conn.ReadJSON(&resp))
+			return resp
+		}
+
+		alice, _, err := dial("alice", "https://app.example.com")
+		require.NoError(t, err)
+		defer alice.Close()
+		bob, _, err := dial("bob", "")
+		require.NoError(t, err)
+		defer bob.Close()
+
+		assert.Nil(t, call(alice, "session-a").Error)
+		resp := call(bob, "session-a")
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, -32003, resp.Error.Code)
+		assert.Nil(t, call(bob, "session-b").Error)
+	})
+}
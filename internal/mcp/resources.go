@@ -0,0 +1,419 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/history"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/opencode-ai/opencode/internal/session"
+)
+
+// sessionsURI is the resource listing the opencode sessions, the resources of a
+// session live below it
+const sessionsURI = "opencode://sessions"
+
+// Methods of resource subscriptions, not defined by the MCP server library
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+	methodResourcesUpdated     = "notifications/resources/updated"
+)
+
+// resourceUpdateInterval coalesces the updates of a resource, a streaming message
+// changes many times a second
+const resourceUpdateInterval = 200 * time.Millisecond
+
+// WithSessionResources publishes the sessions, their messages and the versions of
+// the files they changed as resources clients can read and subscribe to
+func WithSessionResources(messages message.Service, files history.Service) ToolServerOption {
+	return func(s *ToolServer) {
+		s.messages = messages
+		s.files = files
+	}
+}
+
+// sessionResource is a session as published to clients
+type sessionResource struct {
+	ID               string  `json:"id"`
+	ParentSessionID  string  `json:"parent_session_id,omitempty"`
+	Title            string  `json:"title"`
+	MessageCount     int64   `json:"message_count"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	CreatedAt        int64   `json:"created_at"`
+	UpdatedAt        int64   `json:"updated_at"`
+}
+
+// messageResource is a message of a transcript as published to clients
+type messageResource struct {
+	ID           string               `json:"id"`
+	Role         message.MessageRole  `json:"role"`
+	Model        string               `json:"model,omitempty"`
+	Content      string               `json:"content,omitempty"`
+	Reasoning    string               `json:"reasoning,omitempty"`
+	ToolCalls    []message.ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults  []message.ToolResult `json:"tool_results,omitempty"`
+	FinishReason message.FinishReason `json:"finish_reason,omitempty"`
+	CreatedAt    int64                `json:"created_at"`
+	UpdatedAt    int64                `json:"updated_at"`
+}
+
+// fileResource is a file changed in a session with its versions, oldest first
+type fileResource struct {
+	Path     string            `json:"path"`
+	URI      string            `json:"uri"`
+	Versions []versionResource `json:"versions"`
+}
+
+type versionResource struct {
+	Version   string `json:"version"`
+	URI       string `json:"uri"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func sessionURI(id string) string {
+	return sessionsURI + "/" + id
+}
+
+func messagesURI(sessionID string) string {
+	return sessionURI(sessionID) + "/messages"
+}
+
+func filesURI(sessionID string) string {
+	return sessionURI(sessionID) + "/files"
+}
+
+// fileVersionURI names a version of a file, with the path relative to the working
+// directory when the file is inside it
+func fileVersionURI(file history.File) string {
+	path := file.Path
+	if rel, err := filepath.Rel(config.WorkingDirectory(), path); err == nil && !strings.HasPrefix(rel, "..") {
+		path = rel
+	}
+	return filesURI(file.SessionID) + "/" + filepath.ToSlash(path) + "@" + file.Version
+}
+
+func (s *ToolServer) registerSessionResources() {
+	s.server.AddResource(
+		mcpgo.NewResource(sessionsURI, "Sessions",
+			mcpgo.WithResourceDescription("Top-level opencode sessions, most recently created first"),
+			mcpgo.WithMIMEType("application/json"),
+		),
+		func(ctx context.Context, request mcpgo.ReadResourceRequest) ([]mcpgo.ResourceContents, error) {
+			sessions, err := s.sessions.List(ctx)
+			if err != nil {
+				return nil, err
+			}
+			list := make([]sessionResource, 0, len(sessions))
+			for _, found := range sessions {
+				list = append(list, toSessionResource(found))
+			}
+			return jsonContents(request.Params.URI, list)
+		},
+	)
+
+	s.server.AddResourceTemplate(
+		mcpgo.NewResourceTemplate(sessionsURI+"/{id}", "Session",
+			mcpgo.WithTemplateDescription("An opencode session with its token usage and cost"),
+			mcpgo.WithTemplateMIMEType("application/json"),
+		),
+		func(ctx context.Context, request mcpgo.ReadResourceRequest) ([]mcpgo.ResourceContents, error) {
+			found, err := s.sessions.Get(ctx, uriArgument(request, "id"))
+			if err != nil {
+				return nil, fmt.Errorf("unknown session: %w", err)
+			}
+			return jsonContents(request.Params.URI, toSessionResource(found))
+		},
+	)
+
+	s.server.AddResourceTemplate(
+		mcpgo.NewResourceTemplate(sessionsURI+"/{id}/messages", "Session transcript",
+			mcpgo.WithTemplateDescription("The messages of a session with their tool calls and results, oldest first"),
+			mcpgo.WithTemplateMIMEType("application/json"),
+		),
+		func(ctx context.Context, request mcpgo.ReadResourceRequest) ([]mcpgo.ResourceContents, error) {
+			messages, err := s.messages.List(ctx, uriArgument(request, "id"))
+			if err != nil {
+				return nil, err
+			}
+			list := make([]messageResource, 0, len(messages))
+			for _, msg := range messages {
+				list = append(list, toMessageResource(msg))
+			}
+			return jsonContents(request.Params.URI, list)
+		},
+	)
+
+	s.server.AddResourceTemplate(
+		mcpgo.NewResourceTemplate(sessionsURI+"/{id}/files", "Session files",
+			mcpgo.WithTemplateDescription("The files changed in a session with the URIs of their versions"),
+			mcpgo.WithTemplateMIMEType("application/json"),
+		),
+		func(ctx context.Context, request mcpgo.ReadResourceRequest) ([]mcpgo.ResourceContents, error) {
+			files, err := s.files.ListBySession(ctx, uriArgument(request, "id"))
+			if err != nil {
+				return nil, err
+			}
+			return jsonContents(request.Params.URI, toFileResources(files))
+		},
+	)
+
+	s.server.AddResourceTemplate(
+		mcpgo.NewResourceTemplate(sessionsURI+"/{id}/files/{+path}@{version}", "File version",
+			mcpgo.WithTemplateDescription("The content of a file at a version recorded in a session"),
+		),
+		func(ctx context.Context, request mcpgo.ReadResourceRequest) ([]mcpgo.ResourceContents, error) {
+			files, err := s.files.ListBySession(ctx, uriArgument(request, "id"))
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				if fileVersionURI(file) == request.Params.URI {
+					return []mcpgo.ResourceContents{mcpgo.TextResourceContents{
+						URI:      request.Params.URI,
+						MIMEType: "text/plain",
+						Text:     file.Content,
+					}}, nil
+				}
+			}
+			return nil, fmt.Errorf("no version %s of %s in the session", uriArgument(request, "version"), uriArgument(request, "path"))
+		},
+	)
+}
+
+// uriArgument returns a variable of the template a resource URI matched
+func uriArgument(request mcpgo.ReadResourceRequest, name string) string {
+	switch value := request.Params.Arguments[name].(type) {
+	case []string:
+		return strings.Join(value, ",")
+	case string:
+		return value
+	default:
+		return ""
+	}
+}
+
+func jsonContents(uri string, v any) ([]mcpgo.ResourceContents, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return []mcpgo.ResourceContents{mcpgo.TextResourceContents{
+		URI:      uri,
+		MIMEType: "application/json",
+		Text:     string(data),
+	}}, nil
+}
+
+func toSessionResource(found session.Session) sessionResource {
+	return sessionResource{
+		ID:               found.ID,
+		ParentSessionID:  found.ParentSessionID,
+		Title:            found.Title,
+		MessageCount:     found.MessageCount,
+		PromptTokens:     found.PromptTokens,
+		CompletionTokens: found.CompletionTokens,
+		Cost:             found.Cost,
+		CreatedAt:        found.CreatedAt,
+		UpdatedAt:        found.UpdatedAt,
+	}
+}
+
+func toMessageResource(msg message.Message) messageResource {
+	return messageResource{
+		ID:           msg.ID,
+		Role:         msg.Role,
+		Model:        string(msg.Model),
+		Content:      msg.Content().String(),
+		Reasoning:    msg.ReasoningContent().String(),
+		ToolCalls:    msg.ToolCalls(),
+		ToolResults:  msg.ToolResults(),
+		FinishReason: msg.FinishReason(),
+		CreatedAt:    msg.CreatedAt,
+		UpdatedAt:    msg.UpdatedAt,
+	}
+}
+
+// toFileResources groups the versions of the files of a session by path
+func toFileResources(files []history.File) []fileResource {
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].CreatedAt < files[j].CreatedAt
+	})
+
+	var list []fileResource
+	index := map[string]int{}
+	for _, file := range files {
+		version := versionResource{
+			Version:   file.Version,
+			URI:       fileVersionURI(file),
+			CreatedAt: file.CreatedAt,
+		}
+		i, ok := index[file.Path]
+		if !ok {
+			i = len(list)
+			index[file.Path] = i
+			list = append(list, fileResource{Path: file.Path})
+		}
+		list[i].Versions = append(list[i].Versions, version)
+		list[i].URI = version.URI
+	}
+	if list == nil {
+		list = []fileResource{}
+	}
+	return list
+}
+
+// lockedWriter writes each message in one piece, so the responses of the MCP
+// server and the notifications about resources do not interleave
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+func (w *lockedWriter) writeJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// subscriptionRequest is the part of a JSON-RPC request the subscriptions need
+type subscriptionRequest struct {
+	ID     mcpgo.RequestId `json:"id"`
+	Method string          `json:"method"`
+	Params struct {
+		URI string `json:"uri"`
+	} `json:"params"`
+}
+
+// interceptSubscriptions answers resources/subscribe and resources/unsubscribe,
+// which the MCP server library does not handle, and passes every other message on
+// through the returned reader
+func (s *ToolServer) interceptSubscriptions(in io.Reader, out *lockedWriter) io.Reader {
+	reader, writer := io.Pipe()
+	go func() {
+		lines := bufio.NewReader(in)
+		for {
+			line, err := lines.ReadBytes('\n')
+			if len(line) > 0 && !s.handleSubscription(line, out) {
+				if _, err := writer.Write(line); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				writer.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return reader
+}
+
+// handleSubscription answers a subscription request and reports whether line was one
+func (s *ToolServer) handleSubscription(line []byte, out *lockedWriter) bool {
+	var req subscriptionRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return false
+	}
+	if req.Method != methodResourcesSubscribe && req.Method != methodResourcesUnsubscribe {
+		return false
+	}
+
+	var response any = mcpgo.NewJSONRPCResponse(req.ID, mcpgo.Result{})
+	uri := req.Params.URI
+	switch {
+	case uri != sessionsURI && !strings.HasPrefix(uri, sessionsURI+"/"):
+		response = mcpgo.NewJSONRPCError(req.ID, mcpgo.INVALID_PARAMS, fmt.Sprintf("resource %q does not support subscriptions", uri), nil)
+	case req.Method == methodResourcesSubscribe:
+		s.subscriptions.Store(uri, true)
+	default:
+		s.subscriptions.Delete(uri)
+	}
+
+	if err := out.writeJSON(response); err != nil {
+		logging.Debug("Failed to answer MCP subscription", "method", req.Method, "error", err)
+	}
+	return true
+}
+
+// notifyResourceUpdates sends resources/updated notifications for the subscribed
+// resources that the session, message and file history events change
+func (s *ToolServer) notifyResourceUpdates(ctx context.Context, out *lockedWriter) {
+	sessionEvents := s.sessions.Subscribe(ctx)
+	messageEvents := s.messages.Subscribe(ctx)
+	fileEvents := s.files.Subscribe(ctx)
+
+	ticker := time.NewTicker(resourceUpdateInterval)
+	defer ticker.Stop()
+
+	changed := map[string]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sessionEvents:
+			if !ok {
+				return
+			}
+			changed[sessionsURI] = true
+			changed[sessionURI(event.Payload.ID)] = true
+		case event, ok := <-messageEvents:
+			if !ok {
+				return
+			}
+			changed[messagesURI(event.Payload.SessionID)] = true
+		case event, ok := <-fileEvents:
+			if !ok {
+				return
+			}
+			changed[filesURI(event.Payload.SessionID)] = true
+			if event.Type != pubsub.DeletedEvent {
+				changed[fileVersionURI(event.Payload)] = true
+			}
+		case <-ticker.C:
+			for uri := range changed {
+				delete(changed, uri)
+				if _, subscribed := s.subscriptions.Load(uri); !subscribed {
+					continue
+				}
+				if err := out.writeJSON(mcpgo.JSONRPCNotification{
+					JSONRPC: mcpgo.JSONRPC_VERSION,
+					Notification: mcpgo.Notification{
+						Method: methodResourcesUpdated,
+						Params: mcpgo.NotificationParams{
+							AdditionalFields: map[string]any{"uri": uri},
+						},
+					},
+				}); err != nil {
+					logging.Debug("Failed to send MCP resource update", "uri", uri, "error", err)
+				}
+			}
+		}
+	}
+}
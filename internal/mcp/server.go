@@ -2,38 +2,202 @@ package mcp
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/llm/tools/shell"
 	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/ratelimit"
 	"github.com/opencode-ai/opencode/internal/superclaude"
+	"github.com/opencode-ai/opencode/internal/tenant"
 )
 
+// planProposalTimeout bounds how long execute waits for a --plan proposal
+const planProposalTimeout = 10 * time.Minute
+
+// dryRunTimeout bounds how long execute waits for a --validate run to finish
+const dryRunTimeout = 30 * time.Minute
+
+// shutdownTimeout bounds how long ListenAndServe waits for open requests when stopping
+const shutdownTimeout = 10 * time.Second
+
+// writeTimeout bounds a single write to a WebSocket client
+const writeTimeout = 10 * time.Second
+
+// Keepalive and request defaults of mcp.websocket
+const (
+	defaultPingInterval = 30 * time.Second
+	defaultPongTimeout  = 60 * time.Second
+	defaultMaxInFlight  = 32
+)
+
+// orderedMethods change the state of the connection, so they run in the order they
+// were received instead of concurrently. They never count against the in-flight limit,
+// a client can always cancel.
+var orderedMethods = map[string]bool{
+	"initialize":      true,
+	"sessions.resume": true,
+	"auth.refresh":    true,
+	"cancel":          true,
+}
+
+// admittedMethods run agents, so they are counted against the quotas of the tenant.
+// The other methods are cheap and never measure the tenant's usage.
+var admittedMethods = map[string]bool{
+	"execute": true,
+	"analyze": true,
+}
+
 // MCPServer implements the Model Context Protocol server
 type MCPServer struct {
 	upgrader websocket.Upgrader
 	handler  *superclaude.SuperClaudeHandler
-	sessions sync.Map
 	mu       sync.RWMutex
+
+	// store persists sessions, without one sessions only live in memory
+	store    *SessionStore
+	sessions sync.Map
+
+	// attached counts the connections bound to each session, the session's shell is
+	// stopped when the last one goes away
+	attached map[string]int
+
+	// auth validates the JWT of every connection, nil disables authentication
+	auth *Authenticator
+	// insecureNoAuth allows listening beyond loopback without authentication
+	insecureNoAuth bool
+	cors           config.CORSConfig
+
+	// owners maps session IDs to the subject that first used them
+	owners sync.Map
+
+	limiter *ratelimit.Limiter
+
+	// tenants checks the requests that run agents against the quotas of the tenant of
+	// its token
+	tenants *tenant.Service
+
+	// websocket holds the buffer sizes, keepalive and in-flight limit of connections
+	websocket config.WebSocketConfig
+
+	// routes are served next to the WebSocket endpoint, such as /healthz
+	routes *http.ServeMux
+
+	// connections are the open WebSocket connections, closed on shutdown
+	maxConnections int
+	connections    sync.Map
+	connectionWG   sync.WaitGroup
+	open           int
+}
+
+// ServerOption configures an MCPServer
+type ServerOption func(*MCPServer)
+
+// WithAuthenticator requires a valid access token on every connection. Sessions are
+// isolated per authenticated subject.
+func WithAuthenticator(auth *Authenticator) ServerOption {
+	return func(s *MCPServer) {
+		s.auth = auth
+	}
+}
+
+// WithInsecureNoAuth allows the server to listen on a non-loopback address without
+// an authenticator
+func WithInsecureNoAuth() ServerOption {
+	return func(s *MCPServer) {
+		s.insecureNoAuth = true
+	}
+}
+
+// WithCORS restricts the origins browsers may connect from. Without allowed origins
+// only same-origin browser connections are accepted.
+func WithCORS(cors config.CORSConfig) ServerOption {
+	return func(s *MCPServer) {
+		s.cors = cors
+	}
+}
+
+// WithRateLimiter limits the requests per client IP, per session and in total
+func WithRateLimiter(limiter *ratelimit.Limiter) ServerOption {
+	return func(s *MCPServer) {
+		s.limiter = limiter
+	}
+}
+
+// WithTenants counts execute and analyze requests against the quotas of the tenant
+// named by the connection's token, or the default tenant. The other methods, such as
+// cancel, are never refused.
+func WithTenants(tenants *tenant.Service) ServerOption {
+	return func(s *MCPServer) {
+		s.tenants = tenants
+	}
+}
+
+// WithSessionStore persists sessions, so clients can list and resume them
+func WithSessionStore(store *SessionStore) ServerOption {
+	return func(s *MCPServer) {
+		s.store = store
+	}
+}
+
+// WithWebSocket sets the buffer sizes of connections, how often clients are pinged,
+// how long a connection may stay silent and how many requests of a connection may
+// run at once
+func WithWebSocket(cfg config.WebSocketConfig) ServerOption {
+	return func(s *MCPServer) {
+		s.websocket = cfg
+	}
+}
+
+// WithMaxConnections refuses WebSocket connections beyond max open ones, 0 is unlimited
+func WithMaxConnections(max int) ServerOption {
+	return func(s *MCPServer) {
+		s.maxConnections = max
+	}
 }
 
 // NewMCPServer creates a new MCP server
-func NewMCPServer(handler *superclaude.SuperClaudeHandler) *MCPServer {
-	return &MCPServer{
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				// Allow connections from any origin for development
-				// TODO: Restrict in production
-				return true
-			},
-		},
-		handler: handler,
+func NewMCPServer(handler *superclaude.SuperClaudeHandler, opts ...ServerOption) *MCPServer {
+	s := &MCPServer{
+		handler:  handler,
+		routes:   http.NewServeMux(),
+		attached: make(map[string]int),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.websocket.PingInterval <= 0 {
+		s.websocket.PingInterval = defaultPingInterval
+	}
+	if s.websocket.PongTimeout <= 0 {
+		s.websocket.PongTimeout = defaultPongTimeout
+	}
+	if s.websocket.MaxInFlight <= 0 {
+		s.websocket.MaxInFlight = defaultMaxInFlight
+	}
+	s.upgrader = websocket.Upgrader{
+		ReadBufferSize:  s.websocket.ReadBufferSize,
+		WriteBufferSize: s.websocket.WriteBufferSize,
+		CheckOrigin:     s.checkOrigin,
+	}
+	if s.auth == nil {
+		logging.Warn("MCP server is running without authentication, anyone who can reach it can run commands")
+	}
+	return s
 }
 
 // MCPRequest represents an incoming MCP request
@@ -42,14 +206,17 @@ type MCPRequest struct {
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params"`
 	Context MCPContext      `json:"context"`
+
+	// Principal is the authenticated user that sent the request, nil without authentication
+	Principal *Principal `json:"-"`
 }
 
 // MCPContext provides execution context
 type MCPContext struct {
-	SessionID   string            `json:"session_id"`
-	WorkingDir  string            `json:"working_dir"`
-	Environment map[string]string `json:"environment"`
-	Capabilities []string         `json:"capabilities"`
+	SessionID    string            `json:"session_id"`
+	WorkingDir   string            `json:"working_dir"`
+	Environment  map[string]string `json:"environment"`
+	Capabilities []string          `json:"capabilities"`
 }
 
 // MCPResponse represents an MCP response
@@ -59,14 +226,57 @@ type MCPResponse struct {
 	Error  *MCPError   `json:"error,omitempty"`
 }
 
+// MCPNotification is pushed to the client outside of a response, such as the
+// progress of a running command
+type MCPNotification struct {
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// ProgressNotification reports a step of the command started by a request
+type ProgressNotification struct {
+	RequestID string `json:"request_id"`
+	superclaude.ProgressEvent
+}
+
 // MCPError represents an error response
 type MCPError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
 // ServeHTTP handles WebSocket connections
 func (s *MCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var principal *Principal
+	if s.auth != nil {
+		authenticated, err := s.auth.AuthenticateRequest(r)
+		if err != nil {
+			logging.Info("Rejected MCP connection", "remote", r.RemoteAddr, "error", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="opencode"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		principal = &authenticated
+	}
+
+	// Clients resume a session by naming it, new ones get a session on initialize
+	sessionID := r.Header.Get("X-Session-ID")
+	if sessionID != "" && s.store != nil {
+		if _, err := s.findSession(r.Context(), sessionID, principal); err != nil {
+			http.Error(w, err.Error(), sessionStatus(err))
+			return
+		}
+	} else if sessionID == "" && s.store == nil {
+		sessionID = generateSessionID()
+	}
+
+	if !s.acquireConnection() {
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.releaseConnection()
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logging.Error("Failed to upgrade connection", "error", err)
@@ -74,14 +284,25 @@ func (s *MCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	sessionID := r.Header.Get("X-Session-ID")
-	if sessionID == "" {
-		sessionID = generateSessionID()
+	logging.Info("New MCP connection", "session_id", sessionID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := newConnection(ctx, conn, principal, remoteIP(r), s.websocket.MaxInFlight)
+	s.connections.Store(client, struct{}{})
+	defer s.connections.Delete(client)
+	s.bindSession(client, sessionID)
+	defer s.bindSession(client, "")
+
+	// Clients that neither answer pings nor send anything are dropped
+	alive := func() error {
+		return conn.SetReadDeadline(time.Now().Add(s.websocket.PongTimeout))
 	}
+	alive()
+	conn.SetPongHandler(func(string) error { return alive() })
+	go client.keepalive(s.websocket.PingInterval)
 
-	logging.Info("New MCP connection", "session_id", sessionID)
-	
-	// Handle messages
+	// Handle messages, each request runs in its own goroutine
 	for {
 		var req MCPRequest
 		err := conn.ReadJSON(&req)
@@ -91,31 +312,107 @@ func (s *MCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 			break
 		}
+		alive()
 
-		// Process request
-		resp := s.handleRequest(req)
-		
-		// Send response
-		if err := conn.WriteJSON(resp); err != nil {
-			logging.Error("Failed to write response", "error", err)
-			break
+		if orderedMethods[req.Method] {
+			s.serveRequest(client, req)
+			continue
+		}
+		if !client.acquire() {
+			client.write(MCPResponse{
+				ID: req.ID,
+				Error: &MCPError{
+					Code:    -32005,
+					Message: "too many requests in flight",
+					Data:    map[string]interface{}{"max_in_flight": s.websocket.MaxInFlight},
+				},
+			})
+			continue
 		}
+		go func() {
+			defer client.release()
+			s.serveRequest(client, req)
+		}()
 	}
+
+	// The client is gone, stop the work its requests started
+	running := client.runningSessions()
+	cancel()
+	for _, sessionID := range running {
+		s.handler.Cancel(sessionID)
+	}
+	client.wait()
+}
+
+// serveRequest handles a request and writes its response. A connection that cannot
+// be written to is closed, which ends its read loop.
+func (s *MCPServer) serveRequest(conn *connection, req MCPRequest) {
+	defer logging.RecoverPanic("mcp.serveRequest", func() {
+		conn.write(errorResponse(req.ID, -32603, "internal error"))
+	})
+
+	resp := s.handleRequest(conn, req)
+	if err := conn.write(resp); err != nil {
+		logging.Error("Failed to write response", "request_id", req.ID, "error", err)
+		conn.ws.Close()
+		return
+	}
+	conn.responded(req.ID)
 }
 
 // handleRequest processes an MCP request
-func (s *MCPServer) handleRequest(req MCPRequest) MCPResponse {
+func (s *MCPServer) handleRequest(conn *connection, req MCPRequest) MCPResponse {
+	// Refreshing is the only thing an expired connection may do
+	if req.Method == "auth.refresh" {
+		return s.handleAuthRefresh(conn, req)
+	}
+	if s.auth != nil {
+		principal := conn.principal()
+		if time.Now().After(principal.ExpiresAt) {
+			return errorResponse(req.ID, -32001, "token expired, renew it with auth.refresh")
+		}
+		req.Principal = &principal
+	}
+	if req.Context.SessionID == "" {
+		req.Context.SessionID = conn.session()
+	}
+	if err := s.authorizeSession(req); err != nil {
+		return sessionError(req.ID, err)
+	}
+	if err := s.limiter.Allow(ratelimit.Key{SessionID: req.Context.SessionID, IP: conn.ip}); err != nil {
+		return commandError(req.ID, err)
+	}
+	if s.tenants != nil && admittedMethods[req.Method] {
+		if err := s.tenants.Admit(conn.ctx, tenantOf(req.Principal)); err != nil {
+			return commandError(req.ID, err)
+		}
+	}
+
 	switch req.Method {
 	case "initialize":
-		return s.handleInitialize(req)
+		return s.handleInitialize(conn, req)
+	case "sessions.list":
+		return s.handleSessionsList(req)
+	case "sessions.resume":
+		return s.handleSessionsResume(conn, req)
 	case "execute":
-		return s.handleExecute(req)
+		return s.handleExecute(conn, req)
 	case "complete":
-		return s.handleComplete(req)
+		return s.handleComplete(conn, req)
 	case "analyze":
-		return s.handleAnalyze(req)
+		return s.handleAnalyze(conn, req)
 	case "capabilities":
 		return s.handleCapabilities(req)
+	case "cancel":
+		return s.handleCancel(conn, req)
+	case "plan.approve":
+		return s.handlePlanApprove(conn, req)
+	case "plan.reject":
+		return s.handlePlanReject(req)
+	case "validate.apply":
+		return s.handleValidateApply(req)
+	case "validate.discard":
+		return s.handleValidateDiscard(req)
 	default:
 		return MCPResponse{
 			ID: req.ID,
@@ -127,188 +424,1252 @@ func (s *MCPServer) handleRequest(req MCPRequest) MCPResponse {
 	}
 }
 
-// handleInitialize initializes a new MCP session
-func (s *MCPServer) handleInitialize(req MCPRequest) MCPResponse {
-	var params struct {
-		Name    string `json:"name"`
-		Version string `json:"version"`
+// authorizeSession checks that the session of a request exists and belongs to its
+// user. Without a store sessions are bound to the first subject that uses them. Other
+// subjects are refused so users cannot read or drive each other's sessions.
+func (s *MCPServer) authorizeSession(req MCPRequest) error {
+	if req.Context.SessionID == "" {
+		return nil
 	}
-	
-	if err := json.Unmarshal(req.Params, &params); err != nil {
-		return errorResponse(req.ID, -32602, "Invalid params")
+	if s.store != nil {
+		_, err := s.findSession(context.Background(), req.Context.SessionID, req.Principal)
+		return err
+	}
+	if req.Principal == nil {
+		return nil
+	}
+	owner, _ := s.owners.LoadOrStore(req.Context.SessionID, req.Principal.Subject)
+	if owner != req.Principal.Subject {
+		return fmt.Errorf("session %s: %w", req.Context.SessionID, ErrSessionForbidden)
 	}
+	return nil
+}
 
-	// Store session info
-	s.sessions.Store(req.Context.SessionID, &MCPSession{
-		ID:          req.Context.SessionID,
-		WorkingDir:  req.Context.WorkingDir,
-		Environment: req.Context.Environment,
-	})
+// authorizeSessionOf checks that sessionID, the session a dry run or plan belongs
+// to, is one the request's user may drive
+func (s *MCPServer) authorizeSessionOf(req MCPRequest, sessionID string) error {
+	req.Context.SessionID = sessionID
+	return s.authorizeSession(req)
+}
 
-	return MCPResponse{
-		ID: req.ID,
-		Result: map[string]interface{}{
-			"capabilities": []string{
-				"superclaude.commands",
-				"superclaude.personas",
-				"file.read",
-				"file.write",
-				"bash.execute",
-				"git.operations",
-			},
-			"version": "1.0.0",
-		},
+// findSession loads a persisted session of the user
+func (s *MCPServer) findSession(ctx context.Context, id string, user *Principal) (MCPSession, error) {
+	found, err := s.store.Get(ctx, id)
+	if err != nil {
+		return MCPSession{}, fmt.Errorf("session %s: %w", id, err)
+	}
+	if found.Owner != subjectOf(user) {
+		return MCPSession{}, fmt.Errorf("session %s: %w", id, ErrSessionForbidden)
 	}
+	return found, nil
 }
 
-// handleExecute executes a SuperClaude command
-func (s *MCPServer) handleExecute(req MCPRequest) MCPResponse {
-	var params struct {
-		Command string `json:"command"`
-		Input   string `json:"input"`
+// lookupSession returns the session of a request, persisted or kept in memory
+func (s *MCPServer) lookupSession(ctx context.Context, id string) (MCPSession, error) {
+	if s.store != nil {
+		return s.store.Get(ctx, id)
 	}
-	
-	if err := json.Unmarshal(req.Params, &params); err != nil {
-		return errorResponse(req.ID, -32602, "Invalid params")
+	if found, ok := s.sessions.Load(id); ok {
+		return found.(MCPSession), nil
 	}
+	return MCPSession{}, ErrUnknownSession
+}
 
-	// Execute SuperClaude command
-	ctx := context.Background()
-	handled, err := s.handler.HandleCommand(ctx, req.Context.SessionID, params.Command)
-	
+// commandContext returns the context the commands of a request run in. It ends with
+// the connection, and its tools use the working directory and environment of the
+// request's session.
+func (s *MCPServer) commandContext(conn *connection, req MCPRequest) context.Context {
+	ctx := conn.ctx
+	if s.tenants != nil {
+		if admittedMethods[req.Method] {
+			// handleRequest admitted the request, its agent runs are not counted again
+			ctx = tenant.WithRequest(ctx, tenantOf(req.Principal))
+		} else {
+			ctx = tenant.WithTenant(ctx, tenantOf(req.Principal))
+		}
+	}
+	found, err := s.lookupSession(ctx, req.Context.SessionID)
 	if err != nil {
-		return errorResponse(req.ID, -32603, err.Error())
+		return ctx
 	}
+	return tools.WithWorkspace(ctx, found.workspace())
+}
 
-	if !handled {
-		return errorResponse(req.ID, -32604, "Not a SuperClaude command")
+// bindSession makes id the session of the connection's requests that do not name
+// one. The shell of a session is stopped once no connection is bound to it.
+func (s *MCPServer) bindSession(conn *connection, id string) {
+	previous := conn.bind(id)
+	if previous == id {
+		return
 	}
 
-	return MCPResponse{
-		ID: req.ID,
-		Result: map[string]interface{}{
-			"status": "success",
-			"command": params.Command,
-		},
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id != "" {
+		s.attached[id]++
+	}
+	if previous == "" {
+		return
+	}
+	s.attached[previous]--
+	if s.attached[previous] <= 0 {
+		delete(s.attached, previous)
+		shell.CloseWorkspaceShell(previous)
 	}
 }
 
-// handleComplete provides command completion
-func (s *MCPServer) handleComplete(req MCPRequest) MCPResponse {
+// handleAuthRefresh renews the tokens of the connection with a refresh token
+func (s *MCPServer) handleAuthRefresh(conn *connection, req MCPRequest) MCPResponse {
+	if s.auth == nil {
+		return errorResponse(req.ID, -32601, "Authentication is not enabled")
+	}
+
 	var params struct {
-		Input  string `json:"input"`
-		Cursor int    `json:"cursor"`
+		RefreshToken string `json:"refresh_token"`
 	}
-	
-	if err := json.Unmarshal(req.Params, &params); err != nil {
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.RefreshToken == "" {
 		return errorResponse(req.ID, -32602, "Invalid params")
 	}
 
-	// Generate completions
-	completions := generateCompletions(params.Input, params.Cursor)
+	tokens, principal, err := s.refresh(params.RefreshToken)
+	if err != nil {
+		return errorResponse(req.ID, -32001, err.Error())
+	}
+	if principal.Subject != conn.principal().Subject {
+		return errorResponse(req.ID, -32003, "refresh token belongs to another user")
+	}
+	conn.setPrincipal(principal)
 
 	return MCPResponse{
-		ID: req.ID,
-		Result: map[string]interface{}{
-			"completions": completions,
-		},
+		ID:     req.ID,
+		Result: tokens,
 	}
 }
 
-// handleAnalyze analyzes code or project
-func (s *MCPServer) handleAnalyze(req MCPRequest) MCPResponse {
-	var params struct {
-		Path  string   `json:"path"`
-		Types []string `json:"types"`
+// refresh rotates a refresh token and returns the new tokens and their principal
+func (s *MCPServer) refresh(refreshToken string) (TokenPair, Principal, error) {
+	tokens, err := s.auth.Refresh(refreshToken)
+	if err != nil {
+		return TokenPair{}, Principal{}, err
 	}
-	
-	if err := json.Unmarshal(req.Params, &params); err != nil {
-		return errorResponse(req.ID, -32602, "Invalid params")
+	principal, err := s.auth.Authenticate(tokens.AccessToken)
+	if err != nil {
+		return TokenPair{}, Principal{}, err
 	}
+	return tokens, principal, nil
+}
 
-	// Run analysis using SuperClaude
-	ctx := context.Background()
-	command := fmt.Sprintf("/user:analyze %s", params.Path)
-	
-	handled, err := s.handler.HandleCommand(ctx, req.Context.SessionID, command)
-	if err != nil {
-		return errorResponse(req.ID, -32603, err.Error())
+// Handler returns the HTTP handler of the server: the WebSocket endpoint, POST
+// /auth/refresh, which exchanges a refresh token for new tokens, and the routes
+// registered with Handle
+func (s *MCPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", s)
+	mux.HandleFunc("/auth/refresh", s.serveRefresh)
+	return s.routesFirst(mux)
+}
+
+// Handle serves an HTTP route next to the WebSocket endpoint, such as /healthz.
+// Routes must be registered before the server starts.
+func (s *MCPServer) Handle(pattern string, handler http.Handler) {
+	s.routes.Handle(pattern, handler)
+}
+
+// routesFirst sends requests matching a registered route to it, and the others to next
+func (s *MCPServer) routesFirst(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := s.routes.Handler(r); pattern != "" {
+			s.routes.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *MCPServer) acquireConnection() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxConnections > 0 && s.open >= s.maxConnections {
+		return false
 	}
+	s.open++
+	s.connectionWG.Add(1)
+	return true
+}
 
-	if !handled {
-		return errorResponse(req.ID, -32604, "Analysis failed")
+func (s *MCPServer) releaseConnection() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open--
+	s.connectionWG.Done()
+}
+
+// closeConnections asks every open WebSocket client to go away
+func (s *MCPServer) closeConnections() {
+	closing := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	s.connections.Range(func(key, _ interface{}) bool {
+		conn := key.(*connection)
+		conn.writeMu.Lock()
+		conn.ws.WriteControl(websocket.CloseMessage, closing, time.Now().Add(time.Second))
+		conn.writeMu.Unlock()
+		conn.ws.Close()
+		return true
+	})
+}
+
+func (s *MCPServer) serveRefresh(w http.ResponseWriter, r *http.Request) {
+	if !s.writeCORSHeaders(w, r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth == nil {
+		http.Error(w, "authentication is not enabled", http.StatusNotFound)
+		return
 	}
 
-	return MCPResponse{
-		ID: req.ID,
-		Result: map[string]interface{}{
-			"status": "analyzing",
-			"path":   params.Path,
-		},
+	var params struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil || params.RefreshToken == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	tokens, _, err := s.refresh(params.RefreshToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
 }
 
-// handleCapabilities returns server capabilities
-func (s *MCPServer) handleCapabilities(req MCPRequest) MCPResponse {
-	return MCPResponse{
-		ID: req.ID,
-		Result: map[string]interface{}{
-			"commands": superclaude.GetAvailableCommands(),
-			"personas": superclaude.GetAvailablePersonas(),
-			"flags":    superclaude.GetAvailableFlags(),
-		},
+// checkOrigin accepts connections without an Origin header, such as CLI clients,
+// and browser connections from the allowed origins
+func (s *MCPServer) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(s.cors.AllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
 	}
+	return s.originAllowed(origin)
 }
 
-// MCPSession represents an active MCP session
-type MCPSession struct {
-	ID          string
-	WorkingDir  string
-	Environment map[string]string
+func (s *MCPServer) originAllowed(origin string) bool {
+	return slices.ContainsFunc(s.cors.AllowedOrigins, func(allowed string) bool {
+		return allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin)
+	})
 }
 
-// Helper functions
+// writeCORSHeaders sets the CORS headers of an HTTP response and reports whether
+// the request's origin is allowed
+func (s *MCPServer) writeCORSHeaders(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if !s.originAllowed(origin) {
+		return false
+	}
 
-func errorResponse(id string, code int, message string) MCPResponse {
-	return MCPResponse{
-		ID: id,
-		Error: &MCPError{
-			Code:    code,
-			Message: message,
-		},
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Add("Vary", "Origin")
+	if s.cors.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(s.cors.AllowedMethods) > 0 {
+		header.Set("Access-Control-Allow-Methods", strings.Join(s.cors.AllowedMethods, ", "))
 	}
+	if len(s.cors.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(s.cors.AllowedHeaders, ", "))
+	}
+	if len(s.cors.ExposedHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(s.cors.ExposedHeaders, ", "))
+	}
+	if s.cors.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(s.cors.MaxAge))
+	}
+	return true
 }
 
-func generateSessionID() string {
-	// Simple session ID generation
-	return fmt.Sprintf("mcp-%d", time.Now().UnixNano())
+// ListenAndServe serves the MCP endpoint and the registered routes on the configured
+// host and port until ctx is cancelled, over TLS when server.tls is enabled. On
+// shutdown open requests are given shutdownTimeout to finish and WebSocket clients
+// are closed. Without an authenticator it refuses non-loopback hosts unless
+// WithInsecureNoAuth is set.
+func (s *MCPServer) ListenAndServe(ctx context.Context, server config.ServerConfig, security config.TLSSecurityConfig) error {
+	if s.auth == nil && !s.insecureNoAuth && !isLoopbackHost(server.Host) {
+		return fmt.Errorf("refusing to listen on %q without authentication, set security.auth.jwt_secret or allow it explicitly", server.Host)
+	}
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(server.Host, strconv.Itoa(server.Port)),
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: server.Timeout,
+		ReadTimeout:       server.Timeout,
+		WriteTimeout:      server.Timeout,
+	}
+	// Hijacked WebSocket connections are not tracked by Shutdown
+	srv.RegisterOnShutdown(s.closeConnections)
+	if server.TLS.Enabled {
+		tlsConfig, err := NewTLSConfig(security)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		logging.Info("MCP server listening", "addr", srv.Addr, "tls", server.TLS.Enabled)
+		if server.TLS.Enabled {
+			errs <- srv.ListenAndServeTLS(server.TLS.CertFile, server.TLS.KeyFile)
+		} else {
+			errs <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		closed := make(chan struct{})
+		go func() {
+			s.connectionWG.Wait()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-shutdownCtx.Done():
+			logging.Warn("MCP connections did not close in time")
+		}
+		return nil
+	}
+}
+
+// isLoopbackHost reports whether host only accepts local connections. An empty host
+// listens on every interface.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// NewTLSConfig builds the TLS settings from security.tls. TLS 1.3 suites are not
+// configurable in Go and are accepted but ignored.
+func NewTLSConfig(security config.TLSSecurityConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	switch security.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported security.tls.min_version %q, use 1.2 or 1.3", security.MinVersion)
+	}
+
+	for _, name := range security.CipherSuites {
+		index := slices.IndexFunc(tls.CipherSuites(), func(suite *tls.CipherSuite) bool {
+			return suite.Name == name
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		suite := tls.CipherSuites()[index]
+		if slices.Equal(suite.SupportedVersions, []uint16{tls.VersionTLS13}) {
+			continue
+		}
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, suite.ID)
+	}
+	return tlsConfig, nil
 }
 
-func generateCompletions(input string, cursor int) []string {
-	// Simple completion logic
-	if strings.HasPrefix(input, "/user:") {
-		return []string{
-			"/user:analyze",
-			"/user:build",
-			"/user:test",
-			"/user:improve",
-			"/user:design",
-			"/user:review",
-			"/user:deploy",
-			"/user:document",
-		}
-	}
-	
-	if strings.HasPrefix(input, "/persona:") {
-		return []string{
-			"/persona:architect",
-			"/persona:frontend",
-			"/persona:backend",
-			"/persona:security",
-			"/persona:qa",
-			"/persona:performance",
-		}
-	}
-	
-	return []string{}
-}
\ No newline at end of file
+// handleInitialize starts a session for the client, or resumes the session named in
+// the request's context. The working directory and environment of the context apply
+// to the tools of the session's commands.
+func (s *MCPServer) handleInitialize(conn *connection, req MCPRequest) MCPResponse {
+	var params struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+
+	opened, err := s.openSession(req, params.Name)
+	if err != nil {
+		return sessionError(req.ID, err)
+	}
+	s.bindSession(conn, opened.ID)
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"session_id": opened.ID,
+			"session":    opened,
+			"capabilities": []string{
+				"superclaude.commands",
+				"superclaude.personas",
+				"file.read",
+				"file.write",
+				"bash.execute",
+				"git.operations",
+			},
+			"version": "1.0.0",
+		},
+	}
+}
+
+// openSession creates a session for the request, or resumes the session it names
+// and updates its workspace with the one of the request's context
+func (s *MCPServer) openSession(req MCPRequest, clientName string) (MCPSession, error) {
+	ctx := context.Background()
+	if s.store != nil && req.Context.SessionID == "" {
+		workingDir, err := resolveWorkingDir(req.Context.WorkingDir)
+		if err != nil {
+			return MCPSession{}, err
+		}
+		return s.store.Create(ctx, subjectOf(req.Principal), clientName, workingDir, req.Context.Environment)
+	}
+
+	current, err := s.lookupSession(ctx, req.Context.SessionID)
+	if errors.Is(err, ErrUnknownSession) && s.store == nil {
+		// Sessions kept in memory start on initialize
+		current, err = MCPSession{ID: req.Context.SessionID, Title: "MCP session", WorkingDir: config.WorkingDirectory()}, nil
+	}
+	if err != nil {
+		return MCPSession{}, err
+	}
+
+	updated := current
+	if clientName != "" {
+		updated.ClientName = clientName
+	}
+	if req.Context.WorkingDir != "" {
+		if updated.WorkingDir, err = resolveWorkingDir(req.Context.WorkingDir); err != nil {
+			return MCPSession{}, err
+		}
+	}
+	if req.Context.Environment != nil {
+		updated.Environment = req.Context.Environment
+	}
+	// The shell keeps the old directory and environment, the next command starts a new one
+	if updated.WorkingDir != current.WorkingDir || !maps.Equal(updated.Environment, current.Environment) {
+		shell.CloseWorkspaceShell(updated.ID)
+	}
+
+	if s.store == nil {
+		s.sessions.Store(updated.ID, updated)
+		return updated, nil
+	}
+	return s.store.Update(ctx, updated)
+}
+
+// handleSessionsList returns the persisted sessions of the user
+func (s *MCPServer) handleSessionsList(req MCPRequest) MCPResponse {
+	if s.store == nil {
+		return errorResponse(req.ID, -32601, "Sessions are not persisted")
+	}
+
+	sessions, err := s.store.List(context.Background(), subjectOf(req.Principal))
+	if err != nil {
+		return errorResponse(req.ID, -32603, err.Error())
+	}
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"sessions": sessions,
+		},
+	}
+}
+
+// handleSessionsResume binds the connection to an earlier session of the user, its
+// conversation continues with the next command
+func (s *MCPServer) handleSessionsResume(conn *connection, req MCPRequest) MCPResponse {
+	if s.store == nil {
+		return errorResponse(req.ID, -32601, "Sessions are not persisted")
+	}
+
+	var params struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.SessionID == "" {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+
+	req.Context.SessionID = params.SessionID
+	if err := s.authorizeSession(req); err != nil {
+		return sessionError(req.ID, err)
+	}
+	resumed, err := s.openSession(req, "")
+	if err != nil {
+		return sessionError(req.ID, err)
+	}
+	s.bindSession(conn, resumed.ID)
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"session_id": resumed.ID,
+			"session":    resumed,
+		},
+	}
+}
+
+// handleExecute executes a SuperClaude command. The response only acknowledges the
+// start, the progress and the final message follow as notifications.
+func (s *MCPServer) handleExecute(conn *connection, req MCPRequest) MCPResponse {
+	var params struct {
+		Command string `json:"command"`
+		Input   string `json:"input"`
+	}
+
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+
+	// Plans are returned for approval instead of executing straight away
+	if parsed, err := superclaude.ParseSuperClaudeCommand(params.Command); err == nil && parsed.Flags.Plan {
+		return s.handleExecutePlan(conn, req, params.Command)
+	}
+
+	// Dry runs return the combined diff for review instead of writing to disk
+	if parsed, err := superclaude.ParseSuperClaudeCommand(params.Command); err == nil && parsed.Flags.ValidationOnly {
+		return s.handleExecuteDryRun(conn, req, params.Command)
+	}
+
+	// Execute SuperClaude command
+	handled, err := s.streamProgress(conn, req, func() (bool, error) {
+		return s.handler.HandleCommand(s.commandContext(conn, req), req.Context.SessionID, params.Command)
+	})
+
+	if err != nil {
+		return commandError(req.ID, err)
+	}
+
+	if !handled {
+		return errorResponse(req.ID, -32604, "Not a SuperClaude command")
+	}
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"status":     "started",
+			"request_id": req.ID,
+			"command":    params.Command,
+		},
+	}
+}
+
+// streamProgress starts a command with start and forwards its progress to the
+// client as notifications tagged with the request ID, until the final message
+func (s *MCPServer) streamProgress(conn *connection, req MCPRequest, start func() (bool, error)) (bool, error) {
+	sessionID := req.Context.SessionID
+	ctx, cancel := context.WithCancel(conn.ctx)
+
+	// Watch before starting so no progress is missed
+	progress := s.handler.WatchProgress(ctx, sessionID)
+
+	handled, err := start()
+	if err != nil || !handled {
+		cancel()
+		return handled, err
+	}
+
+	started := conn.track(req.ID, sessionID)
+	go func() {
+		defer cancel()
+		defer conn.untrack(req.ID)
+
+		// Notifications follow the response that acknowledged the request
+		select {
+		case <-started:
+		case <-ctx.Done():
+			return
+		}
+
+		for event := range progress {
+			notification := MCPNotification{
+				Method: "progress",
+				Params: ProgressNotification{RequestID: req.ID, ProgressEvent: event},
+			}
+			if err := conn.write(notification); err != nil {
+				logging.Error("Failed to write progress", "request_id", req.ID, "error", err)
+				return
+			}
+		}
+	}()
+	return true, nil
+}
+
+// handleCancel stops the command started by a request, or the session's command
+// when no request is given. The final progress notification reports the cancellation.
+// agent_session_id stops a single spawned sub-agent by its task session instead.
+func (s *MCPServer) handleCancel(conn *connection, req MCPRequest) MCPResponse {
+	var params struct {
+		RequestID      string `json:"request_id"`
+		AgentSessionID string `json:"agent_session_id"`
+	}
+
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return errorResponse(req.ID, -32602, "Invalid params")
+		}
+	}
+
+	if params.AgentSessionID != "" {
+		parentSessionID, ok := s.handler.SpawnedAgentParent(params.AgentSessionID)
+		if !ok {
+			return errorResponse(req.ID, -32602, fmt.Sprintf("no running sub-agent %s", params.AgentSessionID))
+		}
+		if err := s.authorizeSessionOf(req, parentSessionID); err != nil {
+			return sessionError(req.ID, err)
+		}
+		s.handler.CancelSpawnedAgent(params.AgentSessionID)
+		return MCPResponse{
+			ID: req.ID,
+			Result: map[string]interface{}{
+				"status":           "cancelled",
+				"agent_session_id": params.AgentSessionID,
+			},
+		}
+	}
+
+	sessionID := req.Context.SessionID
+	if params.RequestID != "" {
+		running, ok := conn.sessionOf(params.RequestID)
+		if !ok {
+			return errorResponse(req.ID, -32602, fmt.Sprintf("no running request %s", params.RequestID))
+		}
+		sessionID = running
+	}
+	if sessionID == "" {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+
+	s.handler.Cancel(sessionID)
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"status":     "cancelled",
+			"request_id": params.RequestID,
+		},
+	}
+}
+
+// handleExecutePlan runs the planning phase of a --plan command and returns the
+// proposed plan. The client approves it with plan.approve or rejects it with plan.reject.
+func (s *MCPServer) handleExecutePlan(conn *connection, req MCPRequest, command string) MCPResponse {
+	ctx, cancel := context.WithTimeout(conn.ctx, planProposalTimeout)
+	defer cancel()
+
+	// Subscribe before starting so the proposal cannot be missed
+	events := s.handler.SubscribePlans(ctx)
+
+	handled, err := s.handler.HandleCommand(s.commandContext(conn, req), req.Context.SessionID, command)
+	if err != nil {
+		return commandError(req.ID, err)
+	}
+	if !handled {
+		return errorResponse(req.ID, -32604, "Not a SuperClaude command")
+	}
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// The broker shut down or ctx ended, the result will not arrive
+				s.handler.Cancel(req.Context.SessionID)
+				if ctx.Err() != nil {
+					return errorResponse(req.ID, -32603, "timed out waiting for the plan")
+				}
+				return errorResponse(req.ID, -32603, "plan events closed before the plan was proposed")
+			}
+			if event.Payload.SessionID != req.Context.SessionID {
+				continue
+			}
+			switch event.Payload.Type {
+			case superclaude.PlanEventProposed:
+				return MCPResponse{
+					ID: req.ID,
+					Result: map[string]interface{}{
+						"status":  "awaiting_approval",
+						"command": command,
+						"plan":    event.Payload.Plan,
+					},
+				}
+			case superclaude.PlanEventFailed:
+				return errorResponse(req.ID, -32603, fmt.Sprintf("planning failed: %v", event.Payload.Error))
+			}
+		case <-ctx.Done():
+			s.handler.Cancel(req.Context.SessionID)
+			return errorResponse(req.ID, -32603, "timed out waiting for the plan")
+		}
+	}
+}
+
+// handlePlanApprove approves a proposed plan, optionally with edited steps. The
+// progress of the execution follows as notifications.
+func (s *MCPServer) handlePlanApprove(conn *connection, req MCPRequest) MCPResponse {
+	var params struct {
+		PlanID string   `json:"plan_id"`
+		Steps  []string `json:"steps"`
+	}
+
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.PlanID == "" {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+
+	sessionID, ok := s.handler.PlanSession(params.PlanID)
+	if !ok {
+		return errorResponse(req.ID, -32603, fmt.Sprintf("no pending plan with ID %s", params.PlanID))
+	}
+	if err := s.authorizeSessionOf(req, sessionID); err != nil {
+		return errorResponse(req.ID, -32003, err.Error())
+	}
+
+	// Progress is that of the plan's session, which may not be the request's
+	planReq := req
+	planReq.Context.SessionID = sessionID
+	_, err := s.streamProgress(conn, planReq, func() (bool, error) {
+		return true, s.handler.ApprovePlan(params.PlanID, params.Steps)
+	})
+	if err != nil {
+		return errorResponse(req.ID, -32603, err.Error())
+	}
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"status":     "executing",
+			"plan_id":    params.PlanID,
+			"request_id": req.ID,
+		},
+	}
+}
+
+// handlePlanReject rejects a proposed plan
+func (s *MCPServer) handlePlanReject(req MCPRequest) MCPResponse {
+	var params struct {
+		PlanID string `json:"plan_id"`
+	}
+
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.PlanID == "" {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+	if sessionID, ok := s.handler.PlanSession(params.PlanID); ok {
+		if err := s.authorizeSessionOf(req, sessionID); err != nil {
+			return errorResponse(req.ID, -32003, err.Error())
+		}
+	}
+
+	if err := s.handler.RejectPlan(params.PlanID); err != nil {
+		return errorResponse(req.ID, -32603, err.Error())
+	}
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"status":  "rejected",
+			"plan_id": params.PlanID,
+		},
+	}
+}
+
+// handleExecuteDryRun runs a --validate command and returns its combined diff. The
+// client writes the changes with validate.apply or drops them with validate.discard.
+func (s *MCPServer) handleExecuteDryRun(conn *connection, req MCPRequest, command string) MCPResponse {
+	ctx, cancel := context.WithTimeout(conn.ctx, dryRunTimeout)
+	defer cancel()
+
+	// Subscribe before starting so the result cannot be missed
+	events := s.handler.SubscribeDryRuns(ctx)
+
+	handled, err := s.handler.HandleCommand(s.commandContext(conn, req), req.Context.SessionID, command)
+	if err != nil {
+		return commandError(req.ID, err)
+	}
+	if !handled {
+		return errorResponse(req.ID, -32604, "Not a SuperClaude command")
+	}
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// The broker shut down or ctx ended, the result will not arrive
+				s.handler.Cancel(req.Context.SessionID)
+				if ctx.Err() != nil {
+					return errorResponse(req.ID, -32603, "timed out waiting for the dry run")
+				}
+				return errorResponse(req.ID, -32603, "dry run events closed before the dry run finished")
+			}
+			if event.Payload.SessionID != req.Context.SessionID {
+				continue
+			}
+			switch event.Payload.Type {
+			case superclaude.DryRunEventReady:
+				run := event.Payload.DryRun
+				if len(run.Changes) == 0 {
+					return MCPResponse{
+						ID: req.ID,
+						Result: map[string]interface{}{
+							"status":  "no_changes",
+							"command": command,
+						},
+					}
+				}
+				return MCPResponse{
+					ID: req.ID,
+					Result: map[string]interface{}{
+						"status":     "awaiting_review",
+						"command":    command,
+						"dry_run_id": run.ID,
+						"files":      run.Paths(),
+						"diff":       run.Diff(),
+					},
+				}
+			case superclaude.DryRunEventFailed:
+				return errorResponse(req.ID, -32603, fmt.Sprintf("dry run failed: %v", event.Payload.Error))
+			}
+		case <-ctx.Done():
+			s.handler.Cancel(req.Context.SessionID)
+			return errorResponse(req.ID, -32603, "timed out waiting for the dry run")
+		}
+	}
+}
+
+// handleValidateApply writes the changes of a dry run, all of them or only the given paths
+func (s *MCPServer) handleValidateApply(req MCPRequest) MCPResponse {
+	var params struct {
+		DryRunID string   `json:"dry_run_id"`
+		Paths    []string `json:"paths"`
+	}
+
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.DryRunID == "" {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+	if sessionID, ok := s.handler.DryRunSession(params.DryRunID); ok {
+		if err := s.authorizeSessionOf(req, sessionID); err != nil {
+			return errorResponse(req.ID, -32003, err.Error())
+		}
+	}
+
+	if err := s.handler.ApplyDryRun(params.DryRunID, params.Paths); err != nil {
+		return errorResponse(req.ID, -32603, err.Error())
+	}
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"status":     "applied",
+			"dry_run_id": params.DryRunID,
+		},
+	}
+}
+
+// handleValidateDiscard drops the changes of a dry run
+func (s *MCPServer) handleValidateDiscard(req MCPRequest) MCPResponse {
+	var params struct {
+		DryRunID string `json:"dry_run_id"`
+	}
+
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.DryRunID == "" {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+	if sessionID, ok := s.handler.DryRunSession(params.DryRunID); ok {
+		if err := s.authorizeSessionOf(req, sessionID); err != nil {
+			return errorResponse(req.ID, -32003, err.Error())
+		}
+	}
+
+	if err := s.handler.DiscardDryRun(params.DryRunID); err != nil {
+		return errorResponse(req.ID, -32603, err.Error())
+	}
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"status":     "discarded",
+			"dry_run_id": params.DryRunID,
+		},
+	}
+}
+
+// handleComplete completes the SuperClaude command line at the cursor, with target
+// paths taken from the session's working directory
+func (s *MCPServer) handleComplete(conn *connection, req MCPRequest) MCPResponse {
+	var params struct {
+		Input  string `json:"input"`
+		Cursor *int   `json:"cursor"`
+	}
+
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+
+	// Complete at the end of the input when no cursor is given
+	cursor := len(params.Input)
+	if params.Cursor != nil {
+		cursor = *params.Cursor
+	}
+	if cursor < 0 || cursor > len(params.Input) {
+		return errorResponse(req.ID, -32602, "cursor is outside the input")
+	}
+
+	ctx := s.commandContext(conn, req)
+	completions := superclaude.Complete(params.Input, cursor, tools.WorkingDirectory(ctx))
+	if completions == nil {
+		completions = []superclaude.Completion{}
+	}
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"completions": completions,
+		},
+	}
+}
+
+// handleAnalyze analyzes code or project, streaming progress like execute
+func (s *MCPServer) handleAnalyze(conn *connection, req MCPRequest) MCPResponse {
+	var params struct {
+		Path  string   `json:"path"`
+		Types []string `json:"types"`
+	}
+
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req.ID, -32602, "Invalid params")
+	}
+
+	// Run analysis using SuperClaude
+	command := fmt.Sprintf("/user:analyze %s", params.Path)
+
+	handled, err := s.streamProgress(conn, req, func() (bool, error) {
+		return s.handler.HandleCommand(s.commandContext(conn, req), req.Context.SessionID, command)
+	})
+	if err != nil {
+		return commandError(req.ID, err)
+	}
+
+	if !handled {
+		return errorResponse(req.ID, -32604, "Analysis failed")
+	}
+
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"status":     "analyzing",
+			"path":       params.Path,
+			"request_id": req.ID,
+		},
+	}
+}
+
+// handleCapabilities returns server capabilities
+func (s *MCPServer) handleCapabilities(req MCPRequest) MCPResponse {
+	return MCPResponse{
+		ID: req.ID,
+		Result: map[string]interface{}{
+			"commands": superclaude.GetAvailableCommands(),
+			"personas": superclaude.GetAvailablePersonas(),
+			"flags":    superclaude.GetAvailableFlags(),
+		},
+	}
+}
+
+// connection is a WebSocket client. Requests run concurrently and responses and
+// progress notifications are written from different goroutines, so writes are
+// serialized.
+type connection struct {
+	ctx context.Context
+	ws  *websocket.Conn
+
+	writeMu sync.Mutex
+
+	// slots bounds the requests running at once, inFlight waits for them on teardown
+	slots    chan struct{}
+	inFlight sync.WaitGroup
+
+	// running maps the ID of each request whose command is still running to its session
+	mu      sync.Mutex
+	running map[string]*runningRequest
+
+	// user is the authenticated principal, renewed by auth.refresh
+	user *Principal
+	ip   string
+
+	// sessionID is the session of requests that do not name one
+	sessionID string
+}
+
+type runningRequest struct {
+	sessionID string
+	started   chan struct{}
+}
+
+func newConnection(ctx context.Context, ws *websocket.Conn, user *Principal, ip string, maxInFlight int) *connection {
+	return &connection{
+		ctx:     ctx,
+		ws:      ws,
+		slots:   make(chan struct{}, maxInFlight),
+		running: make(map[string]*runningRequest),
+		user:    user,
+		ip:      ip,
+	}
+}
+
+func (c *connection) principal() Principal {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.user == nil {
+		return Principal{}
+	}
+	return *c.user
+}
+
+func (c *connection) setPrincipal(principal Principal) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.user = &principal
+}
+
+func (c *connection) session() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
+
+// bind sets the session of the connection and returns the previous one
+func (c *connection) bind(sessionID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.sessionID
+	c.sessionID = sessionID
+	return previous
+}
+
+func (c *connection) write(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.ws.WriteJSON(v)
+}
+
+// keepalive pings the client every interval until the connection ends
+func (c *connection) keepalive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// acquire takes an in-flight slot for a request, false when all are taken
+func (c *connection) acquire() bool {
+	select {
+	case c.slots <- struct{}{}:
+		c.inFlight.Add(1)
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *connection) release() {
+	<-c.slots
+	c.inFlight.Done()
+}
+
+// wait blocks until every in-flight request has finished
+func (c *connection) wait() {
+	c.inFlight.Wait()
+}
+
+// runningSessions returns the sessions whose commands the connection started and
+// that are still running
+func (c *connection) runningSessions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var sessions []string
+	for _, request := range c.running {
+		if !slices.Contains(sessions, request.sessionID) {
+			sessions = append(sessions, request.sessionID)
+		}
+	}
+	return sessions
+}
+
+// track records a running request, the returned channel is closed once its response is written
+func (c *connection) track(requestID, sessionID string) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	request := &runningRequest{sessionID: sessionID, started: make(chan struct{})}
+	c.running[requestID] = request
+	return request.started
+}
+
+func (c *connection) untrack(requestID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.running, requestID)
+}
+
+// responded marks the response of a request as written
+func (c *connection) responded(requestID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if request, ok := c.running[requestID]; ok {
+		select {
+		case <-request.started:
+		default:
+			close(request.started)
+		}
+	}
+}
+
+func (c *connection) sessionOf(requestID string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	request, ok := c.running[requestID]
+	if !ok {
+		return "", false
+	}
+	return request.sessionID, true
+}
+
+// Helper functions
+
+func errorResponse(id string, code int, message string) MCPResponse {
+	return MCPResponse{
+		ID: id,
+		Error: &MCPError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// commandError reports a failed command, with a retry-after hint when it was rate
+// limited and the quota when its tenant exceeded one
+func commandError(id string, err error) MCPResponse {
+	var exceeded *config.QuotaExceededError
+	if errors.As(err, &exceeded) {
+		return MCPResponse{
+			ID: id,
+			Error: &MCPError{
+				Code:    -32030,
+				Message: exceeded.Error(),
+				Data: map[string]interface{}{
+					"tenant": exceeded.TenantID,
+					"quota":  exceeded.Quota,
+					"used":   exceeded.Used,
+					"limit":  exceeded.Limit,
+				},
+			},
+		}
+	}
+	if errors.Is(err, tenant.ErrUnavailable) {
+		return errorResponse(id, -32003, err.Error())
+	}
+	var limited *ratelimit.LimitError
+	if errors.As(err, &limited) {
+		return MCPResponse{
+			ID: id,
+			Error: &MCPError{
+				Code:    -32029,
+				Message: limited.Error(),
+				Data: map[string]interface{}{
+					"scope":          limited.Scope,
+					"retry_after_ms": limited.RetryAfter.Milliseconds(),
+				},
+			},
+		}
+	}
+	return errorResponse(id, -32603, err.Error())
+}
+
+// sessionError reports a session that cannot be used
+func sessionError(id string, err error) MCPResponse {
+	switch {
+	case errors.Is(err, ErrUnknownSession):
+		return errorResponse(id, -32004, err.Error())
+	case errors.Is(err, ErrSessionForbidden):
+		return errorResponse(id, -32003, err.Error())
+	case errors.Is(err, ErrInvalidWorkingDir):
+		return errorResponse(id, -32602, err.Error())
+	}
+	return errorResponse(id, -32603, err.Error())
+}
+
+// sessionStatus is the HTTP status of a session that cannot be resumed
+func sessionStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnknownSession):
+		return http.StatusNotFound
+	case errors.Is(err, ErrSessionForbidden):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// subjectOf returns the subject of a principal, empty without authentication
+func subjectOf(user *Principal) string {
+	if user == nil {
+		return ""
+	}
+	return user.Subject
+}
+
+// tenantOf returns the tenant of the user, the default tenant without one
+func tenantOf(user *Principal) string {
+	if user == nil || user.Tenant == "" {
+		return tenant.DefaultTenant
+	}
+	return user.Tenant
+}
+
+// remoteIP returns the client address of a request without its port
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func generateSessionID() string {
+	// Simple session ID generation
+	return fmt.Sprintf("mcp-%d", time.Now().UnixNano())
+}
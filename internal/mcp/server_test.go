@@ -0,0 +1,425 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/ratelimit"
+	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/opencode-ai/opencode/internal/superclaude"
+	"github.com/opencode-ai/opencode/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPServerRateLimit(t *testing.T) {
+	server := NewMCPServer(
+		superclaude.NewSuperClaudeHandler(nil, nil, nil),
+		WithRateLimiter(ratelimit.New("test", config.RateLimitConfig{
+			Enabled: true,
+			PerIP:   config.RateLimitRule{RequestsPerMinute: 1},
+		})),
+	)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	call := func() MCPResponse {
+		require.NoError(t, conn.WriteJSON(MCPRequest{ID: "1", Method: "capabilities"}))
+		var resp MCPResponse
+		require.NoError(t, conn.ReadJSON(&resp))
+		return resp
+	}
+
+	assert.Nil(t, call().Error)
+
+	resp := call()
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, -32029, resp.Error.Code)
+	data := resp.Error.Data.(map[string]interface{})
+	assert.Equal(t, "per_ip", data["scope"])
+	assert.Greater(t, data["retry_after_ms"], 0.0)
+}
+
+func TestMCPServerRefusesPublicHostWithoutAuth(t *testing.T) {
+	server := NewMCPServer(superclaude.NewSuperClaudeHandler(nil, nil, nil))
+	err := server.ListenAndServe(context.Background(), config.ServerConfig{Host: "0.0.0.0", Port: 0}, config.TLSSecurityConfig{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "without authentication")
+
+	assert.True(t, isLoopbackHost("localhost"))
+	assert.True(t, isLoopbackHost("127.0.0.1"))
+	assert.True(t, isLoopbackHost("::1"))
+	assert.False(t, isLoopbackHost(""))
+	assert.False(t, isLoopbackHost("example.com"))
+}
+
+// fakeTenantDB stores no tenants
+type fakeTenantDB struct {
+	db.Querier
+}
+
+func (fakeTenantDB) ListTenants(ctx context.Context) ([]db.Tenant, error) {
+	return nil, nil
+}
+
+func TestMCPServerAdmitsOnlyAgentRequests(t *testing.T) {
+	auth, err := NewAuthenticator(config.AuthConfig{JWTSecret: testSecret})
+	require.NoError(t, err)
+	manager := config.NewMultiTenantConfigManager(&config.SuperClaudeConfig{}, config.IsolationShared)
+	tenants, err := tenant.NewService(fakeTenantDB{}, manager)
+	require.NoError(t, err)
+	server := NewMCPServer(
+		superclaude.NewSuperClaudeHandler(nil, nil, nil),
+		WithAuthenticator(auth),
+		WithTenants(tenants),
+	)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	tokens, err := auth.IssueTenant("alice", "deleted")
+	require.NoError(t, err)
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), header)
+	require.NoError(t, err)
+	defer conn.Close()
+	call := func(req MCPRequest) MCPResponse {
+		require.NoError(t, conn.WriteJSON(req))
+		var resp MCPResponse
+		require.NoError(t, conn.ReadJSON(&resp))
+		return resp
+	}
+
+	assert.Nil(t, call(MCPRequest{ID: "1", Method: "capabilities"}).Error)
+	resp := call(MCPRequest{ID: "2", Method: "execute", Params: json.RawMessage(`{"command":"/user:analyze x"}`)})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, -32003, resp.Error.Code)
+}
+
+func TestMCPServerRoutes(t *testing.T) {
+	server := NewMCPServer(superclaude.NewSuperClaudeHandler(nil, nil, nil), WithMaxConnections(1))
+	server.Handle("/healthz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/healthz")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "ok", string(body), "routes are served next to the WebSocket endpoint")
+
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "connections beyond the maximum are refused")
+
+	server.closeConnections()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "clients are told the server is going away")
+}
+
+// fakeSessionDB keeps opencode and MCP sessions in memory
+type fakeSessionDB struct {
+	session.Service
+
+	mu       sync.Mutex
+	sessions map[string]session.Session
+	rows     map[string]db.McpSession
+}
+
+func newFakeSessionDB() *fakeSessionDB {
+	return &fakeSessionDB{
+		sessions: make(map[string]session.Session),
+		rows:     make(map[string]db.McpSession),
+	}
+}
+
+func (f *fakeSessionDB) Create(ctx context.Context, title string) (session.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	created := session.Session{ID: generateSessionID(), Title: title}
+	f.sessions[created.ID] = created
+	return created, nil
+}
+
+func (f *fakeSessionDB) Get(ctx context.Context, id string) (session.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	found, ok := f.sessions[id]
+	if !ok {
+		return session.Session{}, sql.ErrNoRows
+	}
+	return found, nil
+}
+
+func (f *fakeSessionDB) CreateMCPSession(ctx context.Context, arg db.CreateMCPSessionParams) (db.McpSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	row := db.McpSession{
+		SessionID:   arg.SessionID,
+		Owner:       arg.Owner,
+		ClientName:  arg.ClientName,
+		WorkingDir:  arg.WorkingDir,
+		Environment: arg.Environment,
+	}
+	f.rows[row.SessionID] = row
+	return row, nil
+}
+
+func (f *fakeSessionDB) GetMCPSession(ctx context.Context, sessionID string) (db.McpSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	row, ok := f.rows[sessionID]
+	if !ok {
+		return db.McpSession{}, sql.ErrNoRows
+	}
+	return row, nil
+}
+
+func (f *fakeSessionDB) ListMCPSessionsByOwner(ctx context.Context, owner string) ([]db.McpSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rows := []db.McpSession{}
+	for _, row := range f.rows {
+		if row.Owner == owner {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeSessionDB) UpdateMCPSession(ctx context.Context, arg db.UpdateMCPSessionParams) (db.McpSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	row, ok := f.rows[arg.SessionID]
+	if !ok {
+		return db.McpSession{}, sql.ErrNoRows
+	}
+	row.ClientName = arg.ClientName
+	row.WorkingDir = arg.WorkingDir
+	row.Environment = arg.Environment
+	f.rows[row.SessionID] = row
+	return row, nil
+}
+
+func TestMCPServerSessions(t *testing.T) {
+	auth, err := NewAuthenticator(config.AuthConfig{JWTSecret: testSecret})
+	require.NoError(t, err)
+	fake := newFakeSessionDB()
+	server := NewMCPServer(
+		superclaude.NewSuperClaudeHandler(nil, nil, nil),
+		WithAuthenticator(auth),
+		WithSessionStore(&SessionStore{q: fake, sessions: fake}),
+	)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	dial := func(subject, sessionID string) (*websocket.Conn, *http.Response, error) {
+		tokens, err := auth.Issue(subject)
+		require.NoError(t, err)
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		if sessionID != "" {
+			header.Set("X-Session-ID", sessionID)
+		}
+		return websocket.DefaultDialer.Dial(wsURL, header)
+	}
+	call := func(conn *websocket.Conn, req MCPRequest) MCPResponse {
+		require.NoError(t, conn.WriteJSON(req))
+		var resp MCPResponse
+		require.NoError(t, conn.ReadJSON(&resp))
+		return resp
+	}
+	sessionOf := func(resp MCPResponse) MCPSession {
+		require.Nil(t, resp.Error)
+		data, err := json.Marshal(resp.Result.(map[string]interface{})["session"])
+		require.NoError(t, err)
+		var result MCPSession
+		require.NoError(t, json.Unmarshal(data, &result))
+		return result
+	}
+
+	workingDir := t.TempDir()
+	alice, _, err := dial("alice", "")
+	require.NoError(t, err)
+
+	resp := call(alice, MCPRequest{ID: "1", Method: "initialize", Params: json.RawMessage(`{"name":"ide"}`), Context: MCPContext{
+		WorkingDir: filepath.Join(workingDir, "missing"),
+	}})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, -32602, resp.Error.Code, "working directories must exist")
+
+	created := sessionOf(call(alice, MCPRequest{ID: "2", Method: "initialize", Params: json.RawMessage(`{"name":"ide"}`), Context: MCPContext{
+		WorkingDir:  workingDir,
+		Environment: map[string]string{"GOFLAGS": "-mod=mod"},
+	}}))
+	assert.Equal(t, "MCP: ide", created.Title)
+	assert.Equal(t, workingDir, created.WorkingDir)
+
+	ctx := server.commandContext(newConnection(context.Background(), nil, nil, "", 1), MCPRequest{Context: MCPContext{SessionID: created.ID}})
+	assert.Equal(t, workingDir, tools.WorkingDirectory(ctx), "tools run in the session's working directory")
+	workspace, _ := tools.WorkspaceFromContext(ctx)
+	assert.Equal(t, "-mod=mod", workspace.Environment["GOFLAGS"])
+	alice.Close()
+
+	resumedConn, _, err := dial("alice", created.ID)
+	require.NoError(t, err)
+	defer resumedConn.Close()
+	resumed := sessionOf(call(resumedConn, MCPRequest{ID: "1", Method: "initialize", Params: json.RawMessage(`{}`)}))
+	assert.Equal(t, created.ID, resumed.ID, "X-Session-ID resumes the session")
+	assert.Equal(t, workingDir, resumed.WorkingDir)
+
+	resp = call(resumedConn, MCPRequest{ID: "2", Method: "sessions.list"})
+	require.Nil(t, resp.Error)
+	assert.Len(t, resp.Result.(map[string]interface{})["sessions"], 1)
+
+	resp = call(resumedConn, MCPRequest{ID: "3", Method: "sessions.resume", Params: json.RawMessage(`{"session_id":"missing"}`)})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, -32004, resp.Error.Code)
+
+	_, httpResp, err := dial("alice", "missing")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+
+	_, httpResp, err = dial("bob", created.ID)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode, "sessions of other users cannot be resumed")
+
+	bob, _, err := dial("bob", "")
+	require.NoError(t, err)
+	defer bob.Close()
+	resp = call(bob, MCPRequest{ID: "1", Method: "sessions.list"})
+	require.Nil(t, resp.Error)
+	assert.Empty(t, resp.Result.(map[string]interface{})["sessions"])
+}
+
+func TestMCPServerKeepalive(t *testing.T) {
+	server := NewMCPServer(superclaude.NewSuperClaudeHandler(nil, nil, nil), WithWebSocket(config.WebSocketConfig{
+		PingInterval: 20 * time.Millisecond,
+		PongTimeout:  200 * time.Millisecond,
+	}))
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	// Reading answers pings with pongs
+	alive, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer alive.Close()
+	responses := make(chan MCPResponse)
+	go func() {
+		for {
+			var resp MCPResponse
+			if err := alive.ReadJSON(&resp); err != nil {
+				close(responses)
+				return
+			}
+			responses <- resp
+		}
+	}()
+
+	silent, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer silent.Close()
+	silent.SetPingHandler(func(string) error { return nil })
+
+	time.Sleep(500 * time.Millisecond)
+	require.NoError(t, alive.WriteJSON(MCPRequest{ID: "1", Method: "capabilities"}))
+	resp, ok := <-responses
+	require.True(t, ok, "clients answering pings stay connected")
+	assert.Nil(t, resp.Error)
+
+	silent.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = silent.ReadMessage()
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, os.ErrDeadlineExceeded), "clients that never answer pings are dropped")
+}
+
+func TestConnectionInFlight(t *testing.T) {
+	conn := newConnection(context.Background(), nil, nil, "", 2)
+
+	require.True(t, conn.acquire())
+	require.True(t, conn.acquire())
+	assert.False(t, conn.acquire(), "requests beyond the limit are refused")
+
+	done := make(chan struct{})
+	go func() {
+		conn.wait()
+		close(done)
+	}()
+
+	conn.release()
+	require.True(t, conn.acquire())
+	conn.release()
+	conn.release()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("wait returns once every request finished")
+	}
+}
+
+func TestMCPServerComplete(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	server := NewMCPServer(superclaude.NewSuperClaudeHandler(nil, nil, nil))
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	complete := func(params string) MCPResponse {
+		require.NoError(t, conn.WriteJSON(MCPRequest{ID: "1", Method: "complete", Params: json.RawMessage(params)}))
+		var resp MCPResponse
+		require.NoError(t, conn.ReadJSON(&resp))
+		return resp
+	}
+
+	resp := complete(`{"input": "/user:explain --depth ex --visual", "cursor": 23}`)
+	require.Nil(t, resp.Error)
+	completions := resp.Result.(map[string]interface{})["completions"].([]interface{})
+	require.Len(t, completions, 1)
+	first := completions[0].(map[string]interface{})
+	assert.Equal(t, "expert", first["value"])
+	assert.Equal(t, "value", first["kind"])
+	assert.Equal(t, 22.0, first["start"])
+	assert.Equal(t, 24.0, first["end"], "the whole word under the cursor is replaced")
+
+	resp = complete(`{"input": "/user:sc"}`)
+	require.Nil(t, resp.Error)
+	completions = resp.Result.(map[string]interface{})["completions"].([]interface{})
+	require.Len(t, completions, 1, "the cursor defaults to the end of the input")
+	assert.Equal(t, "/user:scan", completions[0].(map[string]interface{})["value"])
+
+	resp = complete(`{"input": "/user:", "cursor": 42}`)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, -32602, resp.Error.Code)
+}
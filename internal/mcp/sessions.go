@@ -0,0 +1,195 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/session"
+)
+
+var (
+	// ErrUnknownSession is returned for session IDs that were never created
+	ErrUnknownSession = errors.New("unknown session")
+	// ErrSessionForbidden is returned when a user names another user's session
+	ErrSessionForbidden = errors.New("session belongs to another user")
+	// ErrInvalidWorkingDir is returned for working directories that do not exist
+	ErrInvalidWorkingDir = errors.New("invalid working directory")
+)
+
+// MCPSession is the opencode session an MCP client works in, with the directory and
+// environment the tools of its commands run in
+type MCPSession struct {
+	ID           string            `json:"id"`
+	Title        string            `json:"title"`
+	ClientName   string            `json:"client_name,omitempty"`
+	Owner        string            `json:"-"`
+	WorkingDir   string            `json:"working_dir"`
+	Environment  map[string]string `json:"environment,omitempty"`
+	MessageCount int64             `json:"message_count"`
+	CreatedAt    int64             `json:"created_at"`
+	UpdatedAt    int64             `json:"updated_at"`
+}
+
+func (m MCPSession) workspace() tools.Workspace {
+	return tools.Workspace{
+		ID:          m.ID,
+		Dir:         m.WorkingDir,
+		Environment: m.Environment,
+	}
+}
+
+// sessionQuerier is the part of db.Querier the store uses
+type sessionQuerier interface {
+	CreateMCPSession(ctx context.Context, arg db.CreateMCPSessionParams) (db.McpSession, error)
+	GetMCPSession(ctx context.Context, sessionID string) (db.McpSession, error)
+	ListMCPSessionsByOwner(ctx context.Context, owner string) ([]db.McpSession, error)
+	UpdateMCPSession(ctx context.Context, arg db.UpdateMCPSessionParams) (db.McpSession, error)
+}
+
+// SessionStore persists MCP sessions in the opencode database. Every MCP session is
+// an opencode session, so its conversation survives reconnects and restarts and
+// clients can resume it later.
+type SessionStore struct {
+	q        sessionQuerier
+	sessions session.Service
+}
+
+// NewSessionStore creates a store on the opencode database
+func NewSessionStore(q db.Querier, sessions session.Service) *SessionStore {
+	return &SessionStore{q: q, sessions: sessions}
+}
+
+// Create creates an opencode session for a client and records its workspace
+func (s *SessionStore) Create(ctx context.Context, owner, clientName, workingDir string, env map[string]string) (MCPSession, error) {
+	title := "MCP session"
+	if clientName != "" {
+		title = "MCP: " + clientName
+	}
+	created, err := s.sessions.Create(ctx, title)
+	if err != nil {
+		return MCPSession{}, err
+	}
+
+	environment, err := json.Marshal(env)
+	if err != nil {
+		return MCPSession{}, err
+	}
+	item, err := s.q.CreateMCPSession(ctx, db.CreateMCPSessionParams{
+		SessionID:   created.ID,
+		Owner:       owner,
+		ClientName:  clientName,
+		WorkingDir:  workingDir,
+		Environment: string(environment),
+	})
+	if err != nil {
+		return MCPSession{}, err
+	}
+	return s.fromDBItem(item, created)
+}
+
+// Get returns a session, or ErrUnknownSession
+func (s *SessionStore) Get(ctx context.Context, id string) (MCPSession, error) {
+	item, err := s.q.GetMCPSession(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return MCPSession{}, ErrUnknownSession
+	}
+	if err != nil {
+		return MCPSession{}, err
+	}
+	parent, err := s.sessions.Get(ctx, id)
+	if err != nil {
+		return MCPSession{}, err
+	}
+	return s.fromDBItem(item, parent)
+}
+
+// List returns the sessions of a user, most recently used first
+func (s *SessionStore) List(ctx context.Context, owner string) ([]MCPSession, error) {
+	items, err := s.q.ListMCPSessionsByOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]MCPSession, 0, len(items))
+	for _, item := range items {
+		parent, err := s.sessions.Get(ctx, item.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		found, err := s.fromDBItem(item, parent)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, found)
+	}
+	return sessions, nil
+}
+
+// Update saves the client name and workspace of a session
+func (s *SessionStore) Update(ctx context.Context, target MCPSession) (MCPSession, error) {
+	environment, err := json.Marshal(target.Environment)
+	if err != nil {
+		return MCPSession{}, err
+	}
+	item, err := s.q.UpdateMCPSession(ctx, db.UpdateMCPSessionParams{
+		ClientName:  target.ClientName,
+		WorkingDir:  target.WorkingDir,
+		Environment: string(environment),
+		SessionID:   target.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return MCPSession{}, ErrUnknownSession
+	}
+	if err != nil {
+		return MCPSession{}, err
+	}
+	parent, err := s.sessions.Get(ctx, target.ID)
+	if err != nil {
+		return MCPSession{}, err
+	}
+	return s.fromDBItem(item, parent)
+}
+
+func (s *SessionStore) fromDBItem(item db.McpSession, parent session.Session) (MCPSession, error) {
+	var env map[string]string
+	if err := json.Unmarshal([]byte(item.Environment), &env); err != nil {
+		return MCPSession{}, fmt.Errorf("invalid environment of session %s: %w", item.SessionID, err)
+	}
+	return MCPSession{
+		ID:           item.SessionID,
+		Title:        parent.Title,
+		ClientName:   item.ClientName,
+		Owner:        item.Owner,
+		WorkingDir:   item.WorkingDir,
+		Environment:  env,
+		MessageCount: parent.MessageCount,
+		CreatedAt:    item.CreatedAt,
+		UpdatedAt:    item.UpdatedAt,
+	}, nil
+}
+
+// resolveWorkingDir makes a client's working directory absolute against the process
+// working directory, which is also the default, and checks that it exists
+func resolveWorkingDir(dir string) (string, error) {
+	if dir == "" {
+		return config.WorkingDirectory(), nil
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(config.WorkingDirectory(), dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidWorkingDir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%w: %s is not a directory", ErrInvalidWorkingDir, dir)
+	}
+	return filepath.Clean(dir), nil
+}
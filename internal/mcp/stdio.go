@@ -0,0 +1,412 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/history"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/permission"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/opencode-ai/opencode/internal/superclaude"
+	"github.com/opencode-ai/opencode/internal/version"
+)
+
+// ToolServer serves opencode's tools and SuperClaude commands over the standard
+// Model Context Protocol, so any MCP host can drive opencode
+type ToolServer struct {
+	server      *server.MCPServer
+	tools       []tools.BaseTool
+	sessions    session.Service
+	permissions permission.Service
+	messages    message.Service
+	files       history.Service
+
+	// mcp serves the tools of the configured MCP servers, mcpTools names those
+	// registered
+	mcp      *agent.MCPManager
+	mcpMu    sync.Mutex
+	mcpTools []string
+
+	policy       PermissionPolicy
+	sessionTitle string
+
+	sessionMu sync.Mutex
+	sessionID string
+
+	// subscriptions holds the URIs of the resources the client subscribed to
+	subscriptions sync.Map
+}
+
+// ToolServerOption configures a ToolServer
+type ToolServerOption func(*ToolServer)
+
+// WithApprovedTools grants the permission requests of the named tools. Requests of
+// other tools are denied, there is nobody to ask over stdio.
+func WithApprovedTools(names ...string) ToolServerOption {
+	return func(s *ToolServer) {
+		s.policy.ApprovedTools = append(s.policy.ApprovedTools, names...)
+	}
+}
+
+// WithAutoApprove grants every permission request of the MCP session
+func WithAutoApprove() ToolServerOption {
+	return func(s *ToolServer) {
+		s.policy.AutoApprove = true
+	}
+}
+
+// WithSessionTitle sets the title of the session that records the tool calls
+func WithSessionTitle(title string) ToolServerOption {
+	return func(s *ToolServer) {
+		s.sessionTitle = title
+	}
+}
+
+// WithMCPTools also serves the tools of the configured MCP servers, registered again
+// whenever the manager reports a change
+func WithMCPTools(manager *agent.MCPManager) ToolServerOption {
+	return func(s *ToolServer) {
+		s.mcp = manager
+	}
+}
+
+// NewToolServer creates an MCP server exposing the given tools behind the permission service
+func NewToolServer(agentTools []tools.BaseTool, sessions session.Service, permissions permission.Service, opts ...ToolServerOption) *ToolServer {
+	s := &ToolServer{
+		tools:        agentTools,
+		sessions:     sessions,
+		permissions:  permissions,
+		sessionTitle: "MCP session",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.server = server.NewMCPServer(
+		"opencode",
+		version.Version,
+		server.WithToolCapabilities(s.mcp != nil),
+		server.WithPromptCapabilities(false),
+		server.WithResourceCapabilities(s.publishesSessions(), false),
+		server.WithInstructions("Tools operate on the opencode working directory. Prompts expand SuperClaude commands."),
+	)
+	s.registerTools()
+	s.registerPrompts()
+	s.registerResources()
+	if s.publishesSessions() {
+		s.registerSessionResources()
+	}
+	return s
+}
+
+// ServeStdio answers MCP requests read from in on out until ctx is cancelled or in is closed
+func (s *ToolServer) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go AnswerPermissions(s.permissions, s.permissions.Subscribe(ctx), s.policy, s.ownsRequest)
+	if s.mcp != nil {
+		// Subscribe first so no change is missed between the sync and the watch
+		events := s.mcp.Subscribe(ctx)
+		s.syncMCPTools(ctx)
+		go s.watchMCPTools(ctx, events)
+	}
+
+	writer := &lockedWriter{w: out}
+	if s.publishesSessions() {
+		in = s.interceptSubscriptions(in, writer)
+		go s.notifyResourceUpdates(ctx, writer)
+	}
+
+	stdio := server.NewStdioServer(s.server)
+	return stdio.Listen(ctx, in, writer)
+}
+
+// publishesSessions reports whether sessions are published as resources
+func (s *ToolServer) publishesSessions() bool {
+	return s.messages != nil && s.files != nil
+}
+
+// session returns the session tool calls are recorded in, creating it on first use
+func (s *ToolServer) session(ctx context.Context) (string, error) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	if s.sessionID != "" {
+		return s.sessionID, nil
+	}
+	sess, err := s.sessions.Create(ctx, s.sessionTitle)
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	if s.policy.AutoApprove {
+		s.permissions.AutoApproveSession(sess.ID)
+	}
+	s.sessionID = sess.ID
+	logging.Info("Created MCP session", "session_id", sess.ID)
+	return sess.ID, nil
+}
+
+// ownsRequest reports whether a permission request comes from the MCP session
+func (s *ToolServer) ownsRequest(req permission.PermissionRequest) bool {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	return req.SessionID == s.sessionID
+}
+
+// PermissionPolicy decides permission requests when there is nobody to ask
+type PermissionPolicy struct {
+	// ApprovedTools are granted permission, AutoApprove grants every request
+	ApprovedTools []string
+	AutoApprove   bool
+}
+
+// Grants reports whether the policy grants a request
+func (p PermissionPolicy) Grants(req permission.PermissionRequest) bool {
+	return p.AutoApprove || slices.Contains(p.ApprovedTools, req.ToolName)
+}
+
+// AnswerPermissions grants or denies the permission requests read from events by
+// policy until events is closed. Requests for which accept returns false are left
+// for someone else to answer.
+func AnswerPermissions(permissions permission.Service, events <-chan pubsub.Event[permission.PermissionRequest], policy PermissionPolicy, accept func(permission.PermissionRequest) bool) {
+	for event := range events {
+		if event.Type != pubsub.CreatedEvent {
+			continue
+		}
+		req := event.Payload
+		if accept != nil && !accept(req) {
+			continue
+		}
+
+		if policy.Grants(req) {
+			permissions.Grant(req)
+			continue
+		}
+		logging.Info("Denied MCP tool permission", "tool", req.ToolName, "action", req.Action, "path", req.Path)
+		permissions.Deny(req)
+	}
+}
+
+func (s *ToolServer) registerTools() {
+	for _, tool := range s.tools {
+		s.server.AddTools(s.serverTool(tool))
+	}
+}
+
+// serverTool describes a tool to MCP clients
+func (s *ToolServer) serverTool(tool tools.BaseTool) server.ServerTool {
+	info := tool.Info()
+	return server.ServerTool{
+		Tool: mcpgo.Tool{
+			Name:        info.Name,
+			Description: info.Description,
+			InputSchema: mcpgo.ToolInputSchema{
+				Type:       "object",
+				Properties: info.Parameters,
+				Required:   info.Required,
+			},
+		},
+		Handler: s.toolHandler(tool),
+	}
+}
+
+// syncMCPTools registers the current tools of the MCP servers and removes those
+// that are gone
+func (s *ToolServer) syncMCPTools(ctx context.Context) {
+	listed := s.mcp.Tools(ctx)
+
+	s.mcpMu.Lock()
+	defer s.mcpMu.Unlock()
+
+	current := make([]server.ServerTool, 0, len(listed))
+	names := make([]string, 0, len(listed))
+	for _, tool := range listed {
+		current = append(current, s.serverTool(tool))
+		names = append(names, tool.Info().Name)
+	}
+	var removed []string
+	for _, name := range s.mcpTools {
+		if !slices.Contains(names, name) {
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) > 0 {
+		s.server.DeleteTools(removed...)
+	}
+	if len(current) > 0 {
+		s.server.AddTools(current...)
+	}
+	s.mcpTools = names
+}
+
+// watchMCPTools registers the MCP tools again when a server connects, drops out or
+// changes its tool list
+func (s *ToolServer) watchMCPTools(ctx context.Context, events <-chan pubsub.Event[agent.MCPServerState]) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			s.syncMCPTools(ctx)
+		}
+	}
+}
+
+// toolHandler runs a tool call the same way the agent does, with a session and a
+// message ID in the context for permissions and file history
+func (s *ToolServer) toolHandler(tool tools.BaseTool) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		sessionID, err := s.session(ctx)
+		if err != nil {
+			return nil, err
+		}
+		input, err := encodeArguments(request.Params.Arguments)
+		if err != nil {
+			return toolError(err.Error()), nil
+		}
+
+		ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+		ctx = context.WithValue(ctx, tools.MessageIDContextKey, uuid.New().String())
+		response, err := tool.Run(ctx, tools.ToolCall{
+			ID:    uuid.New().String(),
+			Name:  request.Params.Name,
+			Input: input,
+		})
+		if err != nil {
+			if errors.Is(err, permission.ErrorPermissionDenied) {
+				return toolError(fmt.Sprintf("permission denied for %s, start the server with --approve %s or --auto-approve to allow it", request.Params.Name, request.Params.Name)), nil
+			}
+			return toolError(err.Error()), nil
+		}
+
+		if response.Type == tools.ToolResponseTypeImage {
+			return &mcpgo.CallToolResult{
+				Content: []mcpgo.Content{mcpgo.NewImageContent(response.Content, "image/png")},
+				IsError: response.IsError,
+			}, nil
+		}
+		return &mcpgo.CallToolResult{
+			Content: []mcpgo.Content{mcpgo.NewTextContent(response.Content)},
+			IsError: response.IsError,
+		}, nil
+	}
+}
+
+func (s *ToolServer) registerPrompts() {
+	names := superclaude.GetAvailableCommands()
+	sort.Strings(names)
+	for _, name := range names {
+		cmd, ok := superclaude.LookupCommand(name)
+		if !ok {
+			continue
+		}
+		prompt := mcpgo.NewPrompt(name,
+			mcpgo.WithPromptDescription(cmd.Description),
+			mcpgo.WithArgument("target",
+				mcpgo.ArgumentDescription("What the command works on, such as a path or a component"),
+			),
+			mcpgo.WithArgument("flags",
+				mcpgo.ArgumentDescription("SuperClaude flags, for example --persona-architect --think"),
+			),
+		)
+		s.server.AddPrompt(prompt, promptHandler(name, cmd.Description))
+	}
+}
+
+// promptHandler expands a SuperClaude command into the prompt the agent would receive
+func promptHandler(name, description string) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcpgo.GetPromptRequest) (*mcpgo.GetPromptResult, error) {
+		input := strings.Join(strings.Fields(strings.Join([]string{
+			"/user:" + name,
+			request.Params.Arguments["target"],
+			request.Params.Arguments["flags"],
+		}, " ")), " ")
+
+		parsed, err := superclaude.ParseSuperClaudeCommand(input)
+		if err != nil {
+			return nil, err
+		}
+		if err := parsed.Flags.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid flags: %w", err)
+		}
+		prompt, _, err := superclaude.RenderPrompt(parsed)
+		if err != nil {
+			return nil, err
+		}
+
+		return mcpgo.NewGetPromptResult(description, []mcpgo.PromptMessage{
+			mcpgo.NewPromptMessage(mcpgo.RoleUser, mcpgo.NewTextContent(prompt)),
+		}), nil
+	}
+}
+
+// registerResources exposes the project's context files, such as OpenCode.md
+func (s *ToolServer) registerResources() {
+	cfg := config.Get()
+	if cfg == nil {
+		return
+	}
+	workingDir := config.WorkingDirectory()
+	for _, contextPath := range cfg.ContextPaths {
+		path := filepath.Join(workingDir, contextPath)
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		uri := "file://" + filepath.ToSlash(path)
+		resource := mcpgo.NewResource(uri, contextPath,
+			mcpgo.WithResourceDescription("Project context file"),
+			mcpgo.WithMIMEType("text/markdown"),
+		)
+		s.server.AddResource(resource, func(ctx context.Context, request mcpgo.ReadResourceRequest) ([]mcpgo.ResourceContents, error) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			return []mcpgo.ResourceContents{mcpgo.TextResourceContents{
+				URI:      uri,
+				MIMEType: "text/markdown",
+				Text:     string(content),
+			}}, nil
+		})
+	}
+}
+
+func toolError(text string) *mcpgo.CallToolResult {
+	return &mcpgo.CallToolResult{
+		Content: []mcpgo.Content{mcpgo.NewTextContent(text)},
+		IsError: true,
+	}
+}
+
+// encodeArguments turns the arguments of a tool call into the JSON input of a tool
+func encodeArguments(arguments map[string]interface{}) (string, error) {
+	if arguments == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,406 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/history"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/permission"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySessions struct {
+	session.Service
+	created []string
+}
+
+func (m *memorySessions) Create(_ context.Context, title string) (session.Session, error) {
+	m.created = append(m.created, title)
+	return session.Session{ID: "mcp-session", Title: title}, nil
+}
+
+// touchTool asks for permission before answering, like the tools that write files
+type touchTool struct {
+	permissions permission.Service
+}
+
+func (t *touchTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{
+		Name:        "touch",
+		Description: "Touches a file",
+		Parameters:  map[string]any{"path": map[string]any{"type": "string"}},
+		Required:    []string{"path"},
+	}
+}
+
+func (t *touchTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	var params struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return tools.NewTextErrorResponse(err.Error()), nil
+	}
+	sessionID, messageID := tools.GetContextValues(ctx)
+	if messageID == "" {
+		return tools.NewTextErrorResponse("missing message ID"), nil
+	}
+	if !t.permissions.Request(permission.CreatePermissionRequest{
+		SessionID: sessionID,
+		ToolName:  "touch",
+		Action:    "write",
+		Path:      params.Path,
+	}) {
+		return tools.ToolResponse{}, permission.ErrorPermissionDenied
+	}
+	return tools.NewTextResponse("touched " + params.Path), nil
+}
+
+// recordedSessions serves a fixed session with its transcript and file history
+type recordedSessions struct {
+	session.Service
+	sessions *pubsub.Broker[session.Session]
+	messages *recordedMessages
+	files    *recordedFiles
+	recorded session.Session
+}
+
+func (r *recordedSessions) Subscribe(ctx context.Context) <-chan pubsub.Event[session.Session] {
+	return r.sessions.Subscribe(ctx)
+}
+
+func (r *recordedSessions) List(context.Context) ([]session.Session, error) {
+	return []session.Session{r.recorded}, nil
+}
+
+func (r *recordedSessions) Get(_ context.Context, id string) (session.Session, error) {
+	if id != r.recorded.ID {
+		return session.Session{}, errors.New("not found")
+	}
+	return r.recorded, nil
+}
+
+type recordedMessages struct {
+	message.Service
+	*pubsub.Broker[message.Message]
+	list []message.Message
+}
+
+func (r *recordedMessages) Subscribe(ctx context.Context) <-chan pubsub.Event[message.Message] {
+	return r.Broker.Subscribe(ctx)
+}
+
+func (r *recordedMessages) List(context.Context, string) ([]message.Message, error) {
+	return r.list, nil
+}
+
+type recordedFiles struct {
+	history.Service
+	*pubsub.Broker[history.File]
+	list []history.File
+}
+
+func (r *recordedFiles) Subscribe(ctx context.Context) <-chan pubsub.Event[history.File] {
+	return r.Broker.Subscribe(ctx)
+}
+
+func (r *recordedFiles) ListBySession(context.Context, string) ([]history.File, error) {
+	return r.list, nil
+}
+
+// fakeMCPClient is a connected MCP server whose tool list can change
+type fakeMCPClient struct {
+	mu     sync.Mutex
+	tools  []mcpgo.Tool
+	notify func(mcpgo.JSONRPCNotification)
+}
+
+func (c *fakeMCPClient) Initialize(context.Context, mcpgo.InitializeRequest) (*mcpgo.InitializeResult, error) {
+	return &mcpgo.InitializeResult{}, nil
+}
+
+func (c *fakeMCPClient) Ping(context.Context) error { return nil }
+
+func (c *fakeMCPClient) ListTools(context.Context, mcpgo.ListToolsRequest) (*mcpgo.ListToolsResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &mcpgo.ListToolsResult{Tools: c.tools}, nil
+}
+
+func (c *fakeMCPClient) CallTool(context.Context, mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+	return &mcpgo.CallToolResult{}, nil
+}
+
+func (c *fakeMCPClient) OnNotification(handler func(mcpgo.JSONRPCNotification)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = handler
+}
+
+func (c *fakeMCPClient) Close() error { return nil }
+
+// setTools replaces the tool list and announces the change
+func (c *fakeMCPClient) setTools(tools ...mcpgo.Tool) {
+	c.mu.Lock()
+	c.tools = tools
+	notify := c.notify
+	c.mu.Unlock()
+	notify(mcpgo.JSONRPCNotification{Notification: mcpgo.Notification{Method: "notifications/tools/list_changed"}})
+}
+
+type stdioClient struct {
+	t   *testing.T
+	in  *io.PipeWriter
+	out *bufio.Scanner
+	id  int
+}
+
+func (c *stdioClient) call(method string, params any) map[string]any {
+	c.id++
+	request, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": c.id, "method": method, "params": params})
+	require.NoError(c.t, err)
+	_, err = c.in.Write(append(request, '\n'))
+	require.NoError(c.t, err)
+
+	// Skip the notifications sent before the response
+	for {
+		require.True(c.t, c.out.Scan(), "no response to %s", method)
+		var response map[string]any
+		require.NoError(c.t, json.Unmarshal(c.out.Bytes(), &response))
+		if _, ok := response["id"]; ok {
+			return response
+		}
+	}
+}
+
+func TestToolServer(t *testing.T) {
+	run := func(t *testing.T, opts ...ToolServerOption) (*stdioClient, *memorySessions) {
+		permissions := permission.NewPermissionService()
+		sessions := &memorySessions{}
+		server := NewToolServer([]tools.BaseTool{&touchTool{permissions: permissions}}, sessions, permissions, opts...)
+
+		inReader, inWriter := io.Pipe()
+		outReader, outWriter := io.Pipe()
+		ctx, cancel := context.WithCancel(context.Background())
+		go server.ServeStdio(ctx, inReader, outWriter)
+		t.Cleanup(func() {
+			cancel()
+			inWriter.Close()
+			outReader.Close()
+		})
+
+		client := &stdioClient{t: t, in: inWriter, out: bufio.NewScanner(outReader)}
+		response := client.call("initialize", map[string]any{
+			"protocolVersion": "2024-11-05",
+			"clientInfo":      map[string]any{"name": "test", "version": "1"},
+			"capabilities":    map[string]any{},
+		})
+		require.Contains(t, response, "result")
+		return client, sessions
+	}
+
+	t.Run("lists tools with their schema", func(t *testing.T) {
+		client, sessions := run(t)
+		result := client.call("tools/list", map[string]any{})["result"].(map[string]any)
+		toolList := result["tools"].([]any)
+		require.Len(t, toolList, 1)
+		tool := toolList[0].(map[string]any)
+		assert.Equal(t, "touch", tool["name"])
+		assert.Equal(t, []any{"path"}, tool["inputSchema"].(map[string]any)["required"])
+		assert.Empty(t, sessions.created, "listing tools does not create a session")
+	})
+
+	t.Run("denies permission requests by default", func(t *testing.T) {
+		client, _ := run(t)
+		result := client.call("tools/call", map[string]any{"name": "touch", "arguments": map[string]any{"path": "/tmp/a"}})["result"].(map[string]any)
+		assert.Equal(t, true, result["isError"])
+		assert.Contains(t, result["content"].([]any)[0].(map[string]any)["text"], "permission denied")
+	})
+
+	t.Run("grants approved tools", func(t *testing.T) {
+		client, sessions := run(t, WithApprovedTools("touch"))
+		result := client.call("tools/call", map[string]any{"name": "touch", "arguments": map[string]any{"path": "/tmp/a"}})["result"].(map[string]any)
+		assert.NotEqual(t, true, result["isError"])
+		assert.Equal(t, "touched /tmp/a", result["content"].([]any)[0].(map[string]any)["text"])
+		assert.Len(t, sessions.created, 1)
+	})
+
+	t.Run("expands SuperClaude commands as prompts", func(t *testing.T) {
+		client, _ := run(t)
+		result := client.call("prompts/list", map[string]any{})["result"].(map[string]any)
+		var names []string
+		for _, prompt := range result["prompts"].([]any) {
+			names = append(names, prompt.(map[string]any)["name"].(string))
+		}
+		assert.Contains(t, names, "analyze")
+
+		result = client.call("prompts/get", map[string]any{
+			"name":      "analyze",
+			"arguments": map[string]any{"target": "internal/mcp", "flags": "--persona-security"},
+		})["result"].(map[string]any)
+		message := result["messages"].([]any)[0].(map[string]any)
+		assert.Equal(t, "user", message["role"])
+		assert.Contains(t, message["content"].(map[string]any)["text"], "Analyze internal/mcp")
+	})
+
+	t.Run("lists resources", func(t *testing.T) {
+		client, _ := run(t)
+		response := client.call("resources/list", map[string]any{})
+		assert.Contains(t, response, "result")
+	})
+}
+
+func TestToolServerSessionResources(t *testing.T) {
+	workingDir := t.TempDir()
+	_, err := config.Load(workingDir, false)
+	require.NoError(t, err)
+	path := filepath.Join(config.WorkingDirectory(), "main.go")
+
+	reply := message.Message{ID: "msg-1", SessionID: "s1", Role: message.Assistant, CreatedAt: 1}
+	reply.AppendContent("Done")
+	reply.AddToolCall(message.ToolCall{ID: "call-1", Name: "edit", Input: `{}`, Finished: true})
+	recorded := &recordedSessions{
+		sessions: pubsub.NewBroker[session.Session](),
+		recorded: session.Session{ID: "s1", Title: "Refactor", MessageCount: 1},
+		messages: &recordedMessages{Broker: pubsub.NewBroker[message.Message](), list: []message.Message{reply}},
+		files: &recordedFiles{Broker: pubsub.NewBroker[history.File](), list: []history.File{
+			{ID: "f2", SessionID: "s1", Path: path, Content: "package main\n\nfunc main() {}\n", Version: "v1", CreatedAt: 2},
+			{ID: "f1", SessionID: "s1", Path: path, Content: "package main\n", Version: history.InitialVersion, CreatedAt: 1},
+		}},
+	}
+	permissions := permission.NewPermissionService()
+	server := NewToolServer(nil, recorded, permissions, WithSessionResources(recorded.messages, recorded.files))
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	go server.ServeStdio(ctx, inReader, outWriter)
+	t.Cleanup(func() {
+		cancel()
+		inWriter.Close()
+		outReader.Close()
+	})
+	client := &stdioClient{t: t, in: inWriter, out: bufio.NewScanner(outReader)}
+	response := client.call("initialize", map[string]any{
+		"protocolVersion": "2024-11-05",
+		"clientInfo":      map[string]any{"name": "test", "version": "1"},
+		"capabilities":    map[string]any{},
+	})
+	capabilities := response["result"].(map[string]any)["capabilities"].(map[string]any)
+	assert.Equal(t, true, capabilities["resources"].(map[string]any)["subscribe"])
+
+	read := func(uri string) string {
+		response := client.call("resources/read", map[string]any{"uri": uri})
+		require.Contains(t, response, "result", "reading %s", uri)
+		return response["result"].(map[string]any)["contents"].([]any)[0].(map[string]any)["text"].(string)
+	}
+
+	var sessions []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(read("opencode://sessions")), &sessions))
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "Refactor", sessions[0]["title"])
+
+	var transcript []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(read("opencode://sessions/s1/messages")), &transcript))
+	require.Len(t, transcript, 1)
+	assert.Equal(t, "Done", transcript[0]["content"])
+	assert.Equal(t, "edit", transcript[0]["tool_calls"].([]any)[0].(map[string]any)["name"])
+
+	var files []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(read("opencode://sessions/s1/files")), &files))
+	require.Len(t, files, 1)
+	versions := files[0]["versions"].([]any)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "opencode://sessions/s1/files/main.go@initial", versions[0].(map[string]any)["uri"])
+	assert.Equal(t, "opencode://sessions/s1/files/main.go@v1", files[0]["uri"], "the file links its latest version")
+
+	assert.Equal(t, "package main\n", read("opencode://sessions/s1/files/main.go@initial"))
+	assert.Contains(t, client.call("resources/read", map[string]any{"uri": "opencode://sessions/s1/files/main.go@v9"}), "error")
+
+	// Updates are sent for subscribed resources only
+	response = client.call("resources/subscribe", map[string]any{"uri": "opencode://sessions/s1/messages"})
+	assert.Contains(t, response, "result")
+	response = client.call("resources/subscribe", map[string]any{"uri": "https://example.com"})
+	assert.Contains(t, response, "error")
+
+	recorded.files.Publish(pubsub.CreatedEvent, history.File{SessionID: "s1", Path: path, Version: "v2"})
+	reply.AppendContent(" and tested")
+	recorded.messages.Publish(pubsub.UpdatedEvent, reply)
+	recorded.messages.Publish(pubsub.UpdatedEvent, reply)
+
+	require.True(t, client.out.Scan())
+	var notification map[string]any
+	require.NoError(t, json.Unmarshal(client.out.Bytes(), &notification))
+	assert.Equal(t, "notifications/resources/updated", notification["method"])
+	assert.Equal(t, "opencode://sessions/s1/messages", notification["params"].(map[string]any)["uri"])
+
+	response = client.call("resources/unsubscribe", map[string]any{"uri": "opencode://sessions/s1/messages"})
+	assert.Contains(t, response, "result", "updates of the same resource are coalesced")
+}
+
+func TestToolServerFollowsMCPTools(t *testing.T) {
+	client := &fakeMCPClient{tools: []mcpgo.Tool{mcpgo.NewTool("lookup")}}
+	manager := agent.NewMCPManager(
+		map[string]config.MCPServer{"docs": {Type: config.MCPStdio, Command: "docs"}},
+		permission.NewPermissionService(),
+		agent.WithMCPClientFactory(func(context.Context, config.MCPServer) (agent.MCPClient, error) {
+			return client, nil
+		}),
+	)
+	t.Cleanup(manager.Close)
+
+	permissions := permission.NewPermissionService()
+	server := NewToolServer([]tools.BaseTool{&touchTool{permissions: permissions}}, &memorySessions{}, permissions, WithMCPTools(manager))
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	go server.ServeStdio(ctx, inReader, outWriter)
+	t.Cleanup(func() {
+		cancel()
+		inWriter.Close()
+		outReader.Close()
+	})
+
+	stdio := &stdioClient{t: t, in: inWriter, out: bufio.NewScanner(outReader)}
+	stdio.call("initialize", map[string]any{
+		"protocolVersion": "2024-11-05",
+		"clientInfo":      map[string]any{"name": "test", "version": "1"},
+		"capabilities":    map[string]any{},
+	})
+	listTools := func() []string {
+		result := stdio.call("tools/list", map[string]any{})["result"].(map[string]any)
+		var names []string
+		for _, tool := range result["tools"].([]any) {
+			names = append(names, tool.(map[string]any)["name"].(string))
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"touch", "docs_lookup"}, listTools())
+
+	client.setTools(mcpgo.NewTool("search"))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		names := listTools()
+		slices.Sort(names)
+		if slices.Equal([]string{"docs_search", "touch"}, names) {
+			break
+		}
+		require.True(t, time.Now().Before(deadline), "tools not re-registered: %v", names)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -108,6 +108,33 @@ type Finish struct {
 
 func (Finish) isPart() {}
 
+// EvidenceSource is something the agent read during a turn: a file line range or a fetched URL
+type EvidenceSource struct {
+	Tool      string `json:"tool"`
+	Path      string `json:"path,omitempty"`
+	StartLine int    `json:"start_line,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// EvidenceCitation is a file:line citation found in an answer
+type EvidenceCitation struct {
+	Text      string `json:"text"`
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Verified  bool   `json:"verified"`
+}
+
+// EvidenceContent is the evidence ledger of a turn and the verdict on the answer's citations
+type EvidenceContent struct {
+	Sources   []EvidenceSource   `json:"sources"`
+	Citations []EvidenceCitation `json:"citations"`
+	Stripped  int                `json:"stripped"`
+}
+
+func (EvidenceContent) isPart() {}
+
 type Message struct {
 	ID        string
 	Role      MessageRole
@@ -176,6 +203,35 @@ func (m *Message) ToolResults() []ToolResult {
 	return toolResults
 }
 
+func (m *Message) Evidence() (EvidenceContent, bool) {
+	for _, part := range m.Parts {
+		if c, ok := part.(EvidenceContent); ok {
+			return c, true
+		}
+	}
+	return EvidenceContent{}, false
+}
+
+func (m *Message) SetEvidence(evidence EvidenceContent) {
+	for i, part := range m.Parts {
+		if _, ok := part.(EvidenceContent); ok {
+			m.Parts[i] = evidence
+			return
+		}
+	}
+	m.Parts = append(m.Parts, evidence)
+}
+
+func (m *Message) SetContent(text string) {
+	for i, part := range m.Parts {
+		if _, ok := part.(TextContent); ok {
+			m.Parts[i] = TextContent{Text: text}
+			return
+		}
+	}
+	m.Parts = append(m.Parts, TextContent{Text: text})
+}
+
 func (m *Message) IsFinished() bool {
 	for _, part := range m.Parts {
 		if _, ok := part.(Finish); ok {
@@ -170,6 +170,7 @@ const (
 	toolCallType   partType = "tool_call"
 	toolResultType partType = "tool_result"
 	finishType     partType = "finish"
+	evidenceType   partType = "evidence"
 )
 
 type partWrapper struct {
@@ -198,6 +199,8 @@ func marshallParts(parts []ContentPart) ([]byte, error) {
 			typ = toolResultType
 		case Finish:
 			typ = finishType
+		case EvidenceContent:
+			typ = evidenceType
 		default:
 			return nil, fmt.Errorf("unknown part type: %T", part)
 		}
@@ -271,6 +274,12 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 				return nil, err
 			}
 			parts = append(parts, part)
+		case evidenceType:
+			part := EvidenceContent{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
 		default:
 			return nil, fmt.Errorf("unknown part type: %s", wrapper.Type)
 		}
@@ -0,0 +1,316 @@
+// Package ratelimit enforces the rules of RateLimitConfig with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Scope is the rule a request was limited by
+type Scope string
+
+const (
+	ScopeGlobal  Scope = "global"
+	ScopeSession Scope = "per_session"
+	ScopeIP      Scope = "per_ip"
+)
+
+// pruneInterval is how often idle per-session and per-IP buckets are dropped
+const pruneInterval = time.Minute
+
+var (
+	rejectedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "opencode_rate_limit_rejected_total",
+		Help: "Requests rejected by a rate limit",
+	}, []string{"limiter", "scope"})
+	globalTokens = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "opencode_rate_limit_global_tokens",
+		Help: "Tokens left in the global bucket",
+	}, []string{"limiter"})
+	trackedBuckets = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "opencode_rate_limit_buckets",
+		Help: "Per-session and per-IP buckets currently tracked",
+	}, []string{"limiter", "scope"})
+	bucketWaits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "opencode_rate_limit_waits_total",
+		Help: "Calls delayed by a client-side rate limit",
+	}, []string{"limiter"})
+)
+
+// LimitError is returned when a request exceeds a rule
+type LimitError struct {
+	Scope      Scope
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded (%s), retry after %s", e.Scope, e.RetryAfter.Round(time.Millisecond))
+}
+
+// Bucket is a token bucket refilled at a steady rate up to its burst. A nil Bucket
+// is unlimited.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewBucket creates a full bucket for the rule. Rules without requests per minute
+// are unlimited and return nil. The burst defaults to one minute of requests.
+func NewBucket(rule config.RateLimitRule) *Bucket {
+	return newBucket(rule, time.Now)
+}
+
+func newBucket(rule config.RateLimitRule, now func() time.Time) *Bucket {
+	if rule.RequestsPerMinute <= 0 {
+		return nil
+	}
+	burst := rule.Burst
+	if burst <= 0 {
+		burst = rule.RequestsPerMinute
+	}
+	return &Bucket{
+		rate:   float64(rule.RequestsPerMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now(),
+		now:    now,
+	}
+}
+
+// Take takes a token. Without one left it returns false and how long until the
+// next token is available.
+func (b *Bucket) Take() (time.Duration, bool) {
+	if b == nil {
+		return 0, true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	missing := 1 - b.tokens
+	return time.Duration(math.Ceil(missing / b.rate * float64(time.Second))), false
+}
+
+// Wait blocks until a token is taken or ctx is done
+func (b *Bucket) Wait(ctx context.Context) error {
+	for {
+		retryAfter, ok := b.Take()
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Tokens returns the tokens currently available
+func (b *Bucket) Tokens() float64 {
+	if b == nil {
+		return math.Inf(1)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	return b.tokens
+}
+
+// full reports whether the bucket has refilled completely, so dropping it changes nothing
+func (b *Bucket) full() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	return b.tokens >= b.burst
+}
+
+// refund returns a token taken for a request that was rejected by another rule
+func (b *Bucket) refund() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+func (b *Bucket) refill() {
+	now := b.now()
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+	}
+	b.last = now
+}
+
+// Key identifies who a request is counted against
+type Key struct {
+	SessionID string
+	IP        string
+}
+
+// Limiter applies the global, per-session and per-IP rules of a RateLimitConfig. A
+// nil Limiter allows everything.
+type Limiter struct {
+	name string
+	cfg  config.RateLimitConfig
+	now  func() time.Time
+
+	global *Bucket
+
+	mu        sync.Mutex
+	sessions  map[string]*Bucket
+	ips       map[string]*Bucket
+	lastPrune time.Time
+}
+
+// New creates a limiter for the config, or nil when rate limiting is disabled. The
+// name labels the limiter's metrics.
+func New(name string, cfg config.RateLimitConfig) *Limiter {
+	return newLimiter(name, cfg, time.Now)
+}
+
+func newLimiter(name string, cfg config.RateLimitConfig, now func() time.Time) *Limiter {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Limiter{
+		name:      name,
+		cfg:       cfg,
+		now:       now,
+		global:    newBucket(cfg.Global, now),
+		sessions:  make(map[string]*Bucket),
+		ips:       make(map[string]*Bucket),
+		lastPrune: now(),
+	}
+}
+
+// Allow counts a request against the per-IP, per-session and global rules. It
+// returns a *LimitError for the first rule that is exceeded, without counting the
+// request against the others.
+func (l *Limiter) Allow(key Key) error {
+	if l == nil {
+		return nil
+	}
+
+	buckets := []struct {
+		scope  Scope
+		bucket *Bucket
+	}{
+		{ScopeIP, l.bucketFor(ScopeIP, key.IP)},
+		{ScopeSession, l.bucketFor(ScopeSession, key.SessionID)},
+		{ScopeGlobal, l.global},
+	}
+
+	for i, b := range buckets {
+		retryAfter, ok := b.bucket.Take()
+		if ok {
+			continue
+		}
+		for _, taken := range buckets[:i] {
+			taken.bucket.refund()
+		}
+		rejectedRequests.WithLabelValues(l.name, string(b.scope)).Inc()
+		l.recordState()
+		return &LimitError{Scope: b.scope, RetryAfter: retryAfter}
+	}
+	l.recordState()
+	return nil
+}
+
+// bucketFor returns the bucket of a session or IP, creating it on first use
+func (l *Limiter) bucketFor(scope Scope, key string) *Bucket {
+	rule, buckets := l.cfg.PerSession, l.sessions
+	if scope == ScopeIP {
+		rule, buckets = l.cfg.PerIP, l.ips
+	}
+	if key == "" || rule.RequestsPerMinute <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune()
+	bucket, ok := buckets[key]
+	if !ok {
+		bucket = newBucket(rule, l.now)
+		buckets[key] = bucket
+	}
+	return bucket
+}
+
+// prune drops buckets that have refilled, they would be recreated full anyway
+func (l *Limiter) prune() {
+	now := l.now()
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+	for _, buckets := range []map[string]*Bucket{l.sessions, l.ips} {
+		for key, bucket := range buckets {
+			if bucket.full() {
+				delete(buckets, key)
+			}
+		}
+	}
+}
+
+func (l *Limiter) recordState() {
+	if l.global != nil {
+		globalTokens.WithLabelValues(l.name).Set(l.global.Tokens())
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	trackedBuckets.WithLabelValues(l.name, string(ScopeSession)).Set(float64(len(l.sessions)))
+	trackedBuckets.WithLabelValues(l.name, string(ScopeIP)).Set(float64(len(l.ips)))
+}
+
+var (
+	sharedMu      sync.Mutex
+	sharedBuckets = map[string]*sharedBucket{}
+)
+
+type sharedBucket struct {
+	rule   config.RateLimitRule
+	bucket *Bucket
+}
+
+// Shared returns the bucket shared by every caller using the same name, such as all
+// clients of a provider. The bucket is replaced when the rule changes.
+func Shared(name string, rule config.RateLimitRule) *Bucket {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if shared, ok := sharedBuckets[name]; ok && shared.rule == rule {
+		return shared.bucket
+	}
+	bucket := NewBucket(rule)
+	sharedBuckets[name] = &sharedBucket{rule: rule, bucket: bucket}
+	return bucket
+}
+
+// WaitShared waits for a token of a shared bucket and counts the delay in the metrics
+func WaitShared(ctx context.Context, name string, bucket *Bucket) error {
+	if _, ok := bucket.Take(); ok {
+		return nil
+	}
+	bucketWaits.WithLabelValues(name).Inc()
+	return bucket.Wait(ctx)
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestBucket(t *testing.T) {
+	assert.Nil(t, NewBucket(config.RateLimitRule{}), "rules without a rate are unlimited")
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	bucket := newBucket(config.RateLimitRule{RequestsPerMinute: 60, Burst: 2}, clock.Now)
+
+	for i := 0; i < 2; i++ {
+		_, ok := bucket.Take()
+		require.True(t, ok, "the burst is available at once")
+	}
+	retryAfter, ok := bucket.Take()
+	require.False(t, ok)
+	assert.Equal(t, time.Second, retryAfter)
+
+	clock.Advance(500 * time.Millisecond)
+	retryAfter, ok = bucket.Take()
+	require.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, 2.0, bucket.Tokens(), "tokens never exceed the burst")
+}
+
+func TestBucketWait(t *testing.T) {
+	bucket := NewBucket(config.RateLimitRule{RequestsPerMinute: 600, Burst: 1})
+	require.NoError(t, bucket.Wait(context.Background()))
+
+	start := time.Now()
+	require.NoError(t, bucket.Wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "the second call waits for a token")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, bucket.Wait(ctx), context.Canceled)
+}
+
+func TestLimiter(t *testing.T) {
+	assert.NoError(t, New("test", config.RateLimitConfig{}).Allow(Key{}), "a disabled limiter allows everything")
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	limiter := newLimiter("test", config.RateLimitConfig{
+		Enabled:    true,
+		Global:     config.RateLimitRule{RequestsPerMinute: 60, Burst: 3},
+		PerSession: config.RateLimitRule{RequestsPerMinute: 60, Burst: 1},
+	}, clock.Now)
+
+	require.NoError(t, limiter.Allow(Key{SessionID: "a", IP: "10.0.0.1"}))
+
+	err := limiter.Allow(Key{SessionID: "a", IP: "10.0.0.1"})
+	var limited *LimitError
+	require.ErrorAs(t, err, &limited)
+	assert.Equal(t, ScopeSession, limited.Scope)
+	assert.Equal(t, time.Second, limited.RetryAfter)
+
+	require.NoError(t, limiter.Allow(Key{SessionID: "b"}), "sessions have their own buckets")
+	require.NoError(t, limiter.Allow(Key{SessionID: "c"}))
+
+	err = limiter.Allow(Key{SessionID: "d"})
+	require.ErrorAs(t, err, &limited)
+	assert.Equal(t, ScopeGlobal, limited.Scope)
+
+	clock.Advance(2 * time.Minute)
+	require.NoError(t, limiter.Allow(Key{SessionID: "e"}))
+	limiter.mu.Lock()
+	assert.Len(t, limiter.sessions, 1, "idle buckets are pruned")
+	limiter.mu.Unlock()
+}
+
+func TestShared(t *testing.T) {
+	rule := config.RateLimitRule{RequestsPerMinute: 10}
+	bucket := Shared("test-provider", rule)
+	assert.Same(t, bucket, Shared("test-provider", rule))
+	assert.NotSame(t, bucket, Shared("test-provider", config.RateLimitRule{RequestsPerMinute: 20}))
+	assert.Nil(t, Shared("test-unlimited", config.RateLimitRule{}))
+}
@@ -0,0 +1,310 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+)
+
+const (
+	// turnRefPrefix holds one ref per user message, pointing at the tree before its turn
+	turnRefPrefix = "refs/turns"
+	// rewindRefPrefix holds the tree as it was right before a rewind, so rewinds can be undone
+	rewindRefPrefix = "refs/rewinds"
+)
+
+// Snapshot is the state of the working tree before an agent turn
+type Snapshot struct {
+	SessionID string
+	MessageID string
+	Commit    string
+	CreatedAt int64
+}
+
+// RewindResult describes a finished rewind
+type RewindResult struct {
+	// Target is the snapshot the working tree was restored to
+	Target Snapshot
+	// Backup is the commit holding the working tree as it was before the rewind
+	Backup string
+	// DeletedMessages is the number of messages removed from the session
+	DeletedMessages int
+}
+
+// Service snapshots the working tree into a shadow git repository in the data
+// directory. The user's own repository and refs are never touched.
+type Service interface {
+	// Snapshot records the working tree before the turn started by a user message
+	Snapshot(ctx context.Context, sessionID, messageID string) (Snapshot, error)
+	// List returns the snapshots of a session, oldest first
+	List(ctx context.Context, sessionID string) ([]Snapshot, error)
+	// Rewind restores the working tree to the snapshot taken before a user message and
+	// drops that message and everything after it from the session
+	Rewind(ctx context.Context, sessionID, messageID string) (RewindResult, error)
+	// DeleteSession removes the snapshots of a session
+	DeleteSession(ctx context.Context, sessionID string) error
+}
+
+type service struct {
+	messages message.Service
+
+	gitDir     string
+	workingDir string
+
+	mu          sync.Mutex
+	initialized bool
+}
+
+// NewService creates a snapshot service for workingDir that keeps its shadow
+// repository in the snapshots directory of dataDir
+func NewService(messages message.Service, workingDir, dataDir string) Service {
+	workingDir, _ = filepath.Abs(workingDir)
+	dataDir, _ = filepath.Abs(dataDir)
+	return &service{
+		messages:   messages,
+		gitDir:     filepath.Join(dataDir, "snapshots"),
+		workingDir: workingDir,
+	}
+}
+
+func (s *service) Snapshot(ctx context.Context, sessionID, messageID string) (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	commit, err := s.commitWorkingTree(ctx, fmt.Sprintf("Before turn %s of session %s", messageID, sessionID))
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if _, err := s.git(ctx, "update-ref", turnRef(sessionID, messageID), commit); err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{
+		SessionID: sessionID,
+		MessageID: messageID,
+		Commit:    commit,
+		CreatedAt: time.Now().Unix(),
+	}, nil
+}
+
+func (s *service) List(ctx context.Context, sessionID string) ([]Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+	out, err := s.git(ctx, "for-each-ref", "--sort=committerdate",
+		"--format=%(refname) %(objectname) %(committerdate:unix)",
+		turnRefPrefix+"/"+sessionID+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		createdAt, _ := strconv.ParseInt(fields[2], 10, 64)
+		snapshots = append(snapshots, Snapshot{
+			SessionID: sessionID,
+			MessageID: filepath.Base(fields[0]),
+			Commit:    fields[1],
+			CreatedAt: createdAt,
+		})
+	}
+	return snapshots, nil
+}
+
+func (s *service) Rewind(ctx context.Context, sessionID, messageID string) (RewindResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.init(ctx); err != nil {
+		return RewindResult{}, err
+	}
+	out, err := s.git(ctx, "rev-parse", "--verify", "--quiet", turnRef(sessionID, messageID)+"^{commit}")
+	if err != nil {
+		return RewindResult{}, fmt.Errorf("no snapshot for message %s in session %s", messageID, sessionID)
+	}
+	target := Snapshot{SessionID: sessionID, MessageID: messageID, Commit: strings.TrimSpace(out)}
+
+	msgs, err := s.messages.List(ctx, sessionID)
+	if err != nil {
+		return RewindResult{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	from := -1
+	for i, msg := range msgs {
+		if msg.ID == messageID {
+			from = i
+			break
+		}
+	}
+	if from == -1 {
+		return RewindResult{}, fmt.Errorf("message %s not found in session %s", messageID, sessionID)
+	}
+
+	// Keep the current state first so the rewind itself can be undone. Committing
+	// also stages the working tree, which lets read-tree remove files created since.
+	backup, err := s.commitWorkingTree(ctx, fmt.Sprintf("Before rewinding session %s to %s", sessionID, messageID))
+	if err != nil {
+		return RewindResult{}, err
+	}
+	if _, err := s.git(ctx, "update-ref", fmt.Sprintf("%s/%s/%d", rewindRefPrefix, sessionID, time.Now().UnixNano()), backup); err != nil {
+		return RewindResult{}, err
+	}
+	if _, err := s.git(ctx, "read-tree", "-u", "--reset", target.Commit); err != nil {
+		return RewindResult{}, fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+
+	// The snapshots of the dropped turns now point past the restored state
+	deleted := 0
+	for _, msg := range msgs[from:] {
+		if err := s.messages.Delete(ctx, msg.ID); err != nil {
+			return RewindResult{Target: target, Backup: backup, DeletedMessages: deleted}, fmt.Errorf("failed to delete message %s: %w", msg.ID, err)
+		}
+		deleted++
+		if msg.Role == message.User {
+			if _, err := s.git(ctx, "update-ref", "-d", turnRef(sessionID, msg.ID)); err != nil {
+				logging.Warn("Failed to drop snapshot", "message", msg.ID, "error", err)
+			}
+		}
+	}
+
+	logging.Info("Rewound session", "session", sessionID, "message", messageID, "backup", backup, "messages", deleted)
+	return RewindResult{Target: target, Backup: backup, DeletedMessages: deleted}, nil
+}
+
+func (s *service) DeleteSession(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.init(ctx); err != nil {
+		return err
+	}
+	for _, prefix := range []string{turnRefPrefix, rewindRefPrefix} {
+		out, err := s.git(ctx, "for-each-ref", "--format=%(refname)", prefix+"/"+sessionID+"/")
+		if err != nil {
+			return err
+		}
+		for _, ref := range strings.Fields(out) {
+			if _, err := s.git(ctx, "update-ref", "-d", ref); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// commitWorkingTree stages the whole working tree into the shadow index and commits it
+func (s *service) commitWorkingTree(ctx context.Context, subject string) (string, error) {
+	if err := s.init(ctx); err != nil {
+		return "", err
+	}
+	if _, err := s.git(ctx, "add", "--all", "."); err != nil {
+		return "", fmt.Errorf("failed to stage working tree: %w", err)
+	}
+	tree, err := s.git(ctx, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to write tree: %w", err)
+	}
+	commit, err := s.git(ctx, "commit-tree", strings.TrimSpace(tree), "-m", subject)
+	if err != nil {
+		return "", fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// init creates the shadow repository and keeps the data directory out of it
+func (s *service) init(ctx context.Context) error {
+	if s.initialized {
+		return nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("snapshots require git: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.gitDir, "HEAD")); os.IsNotExist(err) {
+		if err := os.MkdirAll(s.gitDir, 0o700); err != nil {
+			return fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+		if _, err := s.git(ctx, "init", "--quiet"); err != nil {
+			return fmt.Errorf("failed to initialize snapshot repository: %w", err)
+		}
+	}
+
+	// Keep the data directory out of the snapshots, it holds the database and the
+	// shadow repository itself
+	exclude := []string{".git/"}
+	excluded := s.gitDir
+	if dataDir := filepath.Dir(s.gitDir); dataDir != s.workingDir {
+		excluded = dataDir
+	}
+	if rel, err := filepath.Rel(s.workingDir, excluded); err == nil && !strings.HasPrefix(rel, "..") {
+		exclude = append(exclude, "/"+filepath.ToSlash(rel)+"/")
+	}
+	infoDir := filepath.Join(s.gitDir, "info")
+	if err := os.MkdirAll(infoDir, 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(infoDir, "exclude"), []byte(strings.Join(exclude, "\n")+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to write snapshot excludes: %w", err)
+	}
+
+	s.initialized = true
+	return nil
+}
+
+// git runs a git command against the shadow repository and the working tree
+func (s *service) git(ctx context.Context, args ...string) (string, error) {
+	name := args[0]
+	args = append([]string{
+		"-c", "core.autocrlf=false",
+		"-c", "core.safecrlf=false",
+		"-c", "core.quotepath=false",
+		"-c", "gc.auto=0",
+	}, args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.workingDir
+	cmd.Env = append(cleanGitEnv(os.Environ()),
+		"GIT_DIR="+s.gitDir,
+		"GIT_WORK_TREE="+s.workingDir,
+		"GIT_AUTHOR_NAME=opencode",
+		"GIT_AUTHOR_EMAIL=snapshots@opencode.local",
+		"GIT_COMMITTER_NAME=opencode",
+		"GIT_COMMITTER_EMAIL=snapshots@opencode.local",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// cleanGitEnv drops inherited GIT_* variables so the user's repository settings
+// cannot redirect the shadow repository
+func cleanGitEnv(env []string) []string {
+	cleaned := env[:0:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, "GIT_") {
+			cleaned = append(cleaned, kv)
+		}
+	}
+	return cleaned
+}
+
+func turnRef(sessionID, messageID string) string {
+	return turnRefPrefix + "/" + sessionID + "/" + messageID
+}
@@ -0,0 +1,134 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryMessages struct {
+	*pubsub.Broker[message.Message]
+	msgs []message.Message
+}
+
+func (m *memoryMessages) Create(_ context.Context, sessionID string, params message.CreateMessageParams) (message.Message, error) {
+	msg := message.Message{ID: params.Parts[0].(message.TextContent).Text, SessionID: sessionID, Role: params.Role, Parts: params.Parts}
+	m.msgs = append(m.msgs, msg)
+	return msg, nil
+}
+
+func (m *memoryMessages) Update(context.Context, message.Message) error { return nil }
+
+func (m *memoryMessages) Get(_ context.Context, id string) (message.Message, error) {
+	for _, msg := range m.msgs {
+		if msg.ID == id {
+			return msg, nil
+		}
+	}
+	return message.Message{}, os.ErrNotExist
+}
+
+func (m *memoryMessages) List(_ context.Context, sessionID string) ([]message.Message, error) {
+	var msgs []message.Message
+	for _, msg := range m.msgs {
+		if msg.SessionID == sessionID {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+func (m *memoryMessages) Delete(_ context.Context, id string) error {
+	for i, msg := range m.msgs {
+		if msg.ID == id {
+			m.msgs = append(m.msgs[:i], m.msgs[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memoryMessages) DeleteSessionMessages(context.Context, string) error { return nil }
+
+func TestRewind(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	ctx := context.Background()
+	workingDir := t.TempDir()
+	dataDir := filepath.Join(workingDir, ".opencode")
+	messages := &memoryMessages{Broker: pubsub.NewBroker[message.Message]()}
+	snapshots := NewService(messages, workingDir, dataDir)
+
+	write := func(name, content string) {
+		path := filepath.Join(workingDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	read := func(name string) (string, bool) {
+		content, err := os.ReadFile(filepath.Join(workingDir, name))
+		return string(content), err == nil
+	}
+	turn := func(id string) {
+		_, err := messages.Create(ctx, "session", message.CreateMessageParams{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: id}}})
+		require.NoError(t, err)
+		_, err = snapshots.Snapshot(ctx, "session", id)
+		require.NoError(t, err)
+		_, err = messages.Create(ctx, "session", message.CreateMessageParams{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: id + "-reply"}}})
+		require.NoError(t, err)
+	}
+
+	write("main.go", "package main\n")
+	write("old.go", "package old\n")
+	write(".opencode/opencode.db", "database")
+	turn("first")
+
+	// What a shell command would do: edit, delete and create files behind the tools' back
+	write("main.go", "package main\n\nfunc main() {}\n")
+	require.NoError(t, os.Remove(filepath.Join(workingDir, "old.go")))
+	write("gen/generated.go", "package gen\n")
+	turn("second")
+	write("main.go", "package changed\n")
+
+	list, err := snapshots.List(ctx, "session")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	result, err := snapshots.Rewind(ctx, "session", "first")
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.DeletedMessages)
+	assert.NotEmpty(t, result.Backup)
+
+	content, ok := read("main.go")
+	assert.True(t, ok)
+	assert.Equal(t, "package main\n", content)
+	content, ok = read("old.go")
+	assert.True(t, ok)
+	assert.Equal(t, "package old\n", content)
+	_, ok = read("gen/generated.go")
+	assert.False(t, ok, "files created after the snapshot are removed")
+	content, ok = read(".opencode/opencode.db")
+	assert.True(t, ok, "the data directory is never snapshotted")
+	assert.Equal(t, "database", content)
+
+	remaining, err := messages.List(ctx, "session")
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+	list, err = snapshots.List(ctx, "session")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	_, err = snapshots.Rewind(ctx, "session", "second")
+	assert.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(workingDir, ".git"))
+	assert.True(t, os.IsNotExist(err), "the working tree gets no repository of its own")
+}
@@ -0,0 +1,275 @@
+package superclaude
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opencode-ai/opencode/internal/fileutil"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+)
+
+// maxHashedFiles bounds how many files are hashed for a single target
+const maxHashedFiles = 2000
+
+// cacheableCommands are read-only commands whose output only depends on their inputs.
+// Commands that modify the tree always run.
+var cacheableCommands = map[string]bool{
+	"analyze":  true,
+	"estimate": true,
+	"explain":  true,
+	"load":     true,
+	"review":   true,
+	"scan":     true,
+}
+
+// CacheKeyInput holds everything that determines the output of a command
+type CacheKeyInput struct {
+	Command    string
+	Target     string
+	Persona    string
+	Model      string
+	Flags      *Flags
+	WorkingDir string
+}
+
+// ComputeCacheKey returns a content-addressed key for a command together with the
+// files and directories referenced by its target
+func ComputeCacheKey(input CacheKeyInput) (string, []string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "command=%s\x00target=%s\x00persona=%s\x00model=%s\x00",
+		input.Command, input.Target, input.Persona, input.Model)
+	if input.Flags != nil {
+		fmt.Fprintf(hash, "flags=%s\x00", canonicalFlags(input.Flags))
+	}
+
+	var dependencies []string
+	var files []string
+	for _, field := range strings.Fields(input.Target) {
+		path := field
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(input.WorkingDir, path)
+		}
+		path = filepath.Clean(path)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// Not a path, the text is already part of the key
+			continue
+		}
+		dependencies = append(dependencies, path)
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if p != path && fileutil.SkipHidden(d.Name()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			files = append(files, p)
+			if len(files) > maxHashedFiles {
+				return fmt.Errorf("target references more than %d files", maxHashedFiles)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	sort.Strings(files)
+	for _, file := range files {
+		sum, err := hashFile(file)
+		if err != nil {
+			return "", nil, err
+		}
+		fmt.Fprintf(hash, "file=%s:%s\x00", file, sum)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), dependencies, nil
+}
+
+// canonicalFlags renders flags in a stable order
+func canonicalFlags(flags *Flags) string {
+	parts := []string{
+		"persona=" + flags.Persona,
+		"think=" + flags.Think,
+		fmt.Sprintf("uc=%t", flags.UltraCompressed),
+		fmt.Sprintf("plan=%t", flags.Plan),
+		fmt.Sprintf("evidence=%t", flags.Evidence),
+		fmt.Sprintf("validate=%t", flags.ValidationOnly),
+		fmt.Sprintf("seq=%t", flags.Sequential),
+		fmt.Sprintf("allmcp=%t", flags.AllMCP),
+	}
+
+	additional := make([]string, 0, len(flags.Additional))
+	for k, v := range flags.Additional {
+		additional = append(additional, k+"="+v)
+	}
+	sort.Strings(additional)
+
+	return strings.Join(append(parts, additional...), ",")
+}
+
+// hashFile returns the sha256 of a file's content
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// executeOptimized runs a command through the optimizer, answering from the cache
+// when the command, flags, persona, model and referenced files are unchanged
+func (h *SuperClaudeHandler) executeOptimized(ctx context.Context, sessionID string, runner agent.Service, parsed *ParsedCommand, persona Persona, prompt string) error {
+	req := &OptimizedRequest{
+		Command:   parsed.RawInput,
+		SessionID: sessionID,
+	}
+
+	if cacheableCommands[parsed.Command] {
+		key, dependencies, err := ComputeCacheKey(CacheKeyInput{
+			Command:    parsed.Command,
+			Target:     parsed.Target,
+			Persona:    persona.Name,
+			Model:      string(runner.Model().ID),
+			Flags:      parsed.Flags,
+			WorkingDir: tools.WorkingDirectory(ctx),
+		})
+		if err != nil {
+			logging.Debug("SuperClaude cache bypassed", "command", parsed.Command, "error", err)
+		} else {
+			req.Key = key
+			req.Dependencies = dependencies
+			if persona.Source != "" {
+				req.Dependencies = append(req.Dependencies, persona.Source)
+			}
+		}
+	}
+
+	// Only keyed requests can be cached or batched with identical ones
+	if req.Key == "" {
+		go func() {
+			defer logging.RecoverPanic("superclaude.prompt", nil)
+
+			output, err := runPrompt(ctx, runner, sessionID, prompt)
+			if err != nil {
+				logging.ErrorPersist(fmt.Sprintf("SuperClaude command %s failed: %v", parsed.Command, err))
+				h.publishResult(CommandResult{SessionID: sessionID, Error: err})
+				return
+			}
+			h.publishResult(CommandResult{SessionID: sessionID, Content: output})
+		}()
+		return nil
+	}
+
+	// Serve unchanged results straight from the cache
+	if entry, ok := h.optimizer.Lookup(req.Key); ok {
+		logging.Info("Serving SuperClaude command from cache",
+			"command", parsed.Command,
+			"persona", persona.Name,
+			"target", parsed.Target)
+		if err := h.replayCached(ctx, sessionID, parsed, entry.Data); err != nil {
+			return err
+		}
+		output, _ := entry.Data.(string)
+		h.publishResult(CommandResult{SessionID: sessionID, Content: output})
+		return nil
+	}
+
+	executed := false
+	req.Execute = func(ctx context.Context) (interface{}, error) {
+		executed = true
+		return runPrompt(ctx, runner, sessionID, prompt)
+	}
+
+	go func() {
+		defer logging.RecoverPanic("superclaude.optimizer.request", nil)
+
+		resp, err := h.optimizer.OptimizeCommand(ctx, req)
+		if err == nil {
+			err = resp.Error
+		}
+		if err != nil {
+			logging.ErrorPersist(fmt.Sprintf("SuperClaude command %s failed: %v", parsed.Command, err))
+			h.publishResult(CommandResult{SessionID: sessionID, Error: err})
+			return
+		}
+
+		// Identical requests batched together only execute once
+		if !executed {
+			if err := h.replayCached(context.Background(), sessionID, parsed, resp.Result); err != nil {
+				logging.ErrorPersist(fmt.Sprintf("failed to write cached result: %v", err))
+				h.publishResult(CommandResult{SessionID: sessionID, Error: err})
+				return
+			}
+		}
+		output, _ := resp.Result.(string)
+		h.publishResult(CommandResult{SessionID: sessionID, Content: output})
+	}()
+
+	return nil
+}
+
+// runPrompt runs the prompt through the agent and returns the final response text
+func runPrompt(ctx context.Context, runner agent.Service, sessionID, prompt string) (string, error) {
+	events, err := runner.Run(ctx, sessionID, prompt)
+	if err != nil {
+		return "", err
+	}
+	result := <-events
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return result.Message.Content().String(), nil
+}
+
+// replayCached writes the request and a previously computed result to the session
+func (h *SuperClaudeHandler) replayCached(ctx context.Context, sessionID string, parsed *ParsedCommand, data interface{}) error {
+	output, ok := data.(string)
+	if !ok {
+		return fmt.Errorf("unexpected cached result type %T", data)
+	}
+
+	if _, err := h.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: parsed.RawInput}},
+	}); err != nil {
+		return fmt.Errorf("failed to create user message: %w", err)
+	}
+
+	return h.writeReport(ctx, sessionID, output, 0)
+}
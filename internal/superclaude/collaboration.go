@@ -0,0 +1,230 @@
+package superclaude
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+)
+
+// CollaborationStep holds the outcome of a single persona within a collaboration
+type CollaborationStep struct {
+	Persona   string
+	SessionID string
+	Output    string
+	Error     error
+	Cost      float64
+}
+
+// ParseCollaborationTarget splits a collab target into the pattern name and the real target
+func ParseCollaborationTarget(target string) (CollaborationPattern, string, error) {
+	parts := strings.SplitN(strings.TrimSpace(target), " ", 2)
+	if len(parts) == 0 || parts[0] == "" {
+		return CollaborationPattern{}, "", fmt.Errorf("collaboration pattern is required (available: %s)",
+			strings.Join(GetAvailableCollaborationPatterns(), ", "))
+	}
+
+	pattern, exists := CollaborationPatterns[parts[0]]
+	if !exists {
+		return CollaborationPattern{}, "", fmt.Errorf("unknown collaboration pattern: %s", parts[0])
+	}
+
+	rest := ""
+	if len(parts) > 1 {
+		rest = strings.TrimSpace(parts[1])
+	}
+	return pattern, rest, nil
+}
+
+// handleCollaboration runs a multi-persona workflow and writes the merged report to the parent session
+func (h *SuperClaudeHandler) handleCollaboration(ctx context.Context, sessionID string, parsed *ParsedCommand) error {
+	if h.sessions == nil || h.messages == nil {
+		return fmt.Errorf("collaboration requires session and message services")
+	}
+
+	pattern, target, err := ParseCollaborationTarget(parsed.Target)
+	if err != nil {
+		return err
+	}
+
+	// Record the original request in the parent session
+	if _, err := h.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: parsed.RawInput}},
+	}); err != nil {
+		return fmt.Errorf("failed to create user message: %w", err)
+	}
+
+	logging.Info("Starting SuperClaude collaboration",
+		"pattern", pattern.Name,
+		"personas", strings.Join(pattern.Personas, ","),
+		"sequence", pattern.Sequence,
+		"target", target)
+
+	go func() {
+		defer logging.RecoverPanic("superclaude.collaboration", nil)
+
+		steps := h.runCollaboration(ctx, sessionID, pattern, target, parsed)
+		if err := h.finishCollaboration(context.Background(), sessionID, pattern, target, steps); err != nil {
+			logging.ErrorPersist(fmt.Sprintf("failed to write collaboration report: %v", err))
+			h.publishResult(CommandResult{SessionID: sessionID, Error: err})
+			return
+		}
+		h.publishResult(CommandResult{SessionID: sessionID, Content: MergeCollaborationReport(pattern, target, steps)})
+	}()
+
+	return nil
+}
+
+// runCollaboration executes every persona of the pattern in the declared order
+func (h *SuperClaudeHandler) runCollaboration(ctx context.Context, parentSessionID string, pattern CollaborationPattern, target string, parsed *ParsedCommand) []CollaborationStep {
+	steps := make([]CollaborationStep, len(pattern.Personas))
+
+	if pattern.Sequence == "parallel" {
+		var wg sync.WaitGroup
+		for i, personaName := range pattern.Personas {
+			wg.Add(1)
+			go func(i int, personaName string) {
+				defer wg.Done()
+				defer logging.RecoverPanic("superclaude.collaboration.step", func() {
+					steps[i] = CollaborationStep{Persona: personaName, Error: fmt.Errorf("panic while running persona")}
+				})
+				steps[i] = h.runCollaborationStep(ctx, parentSessionID, pattern, personaName, target, "", parsed)
+			}(i, personaName)
+		}
+		wg.Wait()
+		return steps
+	}
+
+	previous := ""
+	for i, personaName := range pattern.Personas {
+		if ctx.Err() != nil {
+			steps[i] = CollaborationStep{Persona: personaName, Error: ctx.Err()}
+			continue
+		}
+		steps[i] = h.runCollaborationStep(ctx, parentSessionID, pattern, personaName, target, previous, parsed)
+		if steps[i].Error == nil {
+			previous = steps[i].Output
+		}
+	}
+	return steps
+}
+
+// runCollaborationStep runs one persona in its own task session
+func (h *SuperClaudeHandler) runCollaborationStep(ctx context.Context, parentSessionID string, pattern CollaborationPattern, personaName, target, previous string, parsed *ParsedCommand) CollaborationStep {
+	step := CollaborationStep{Persona: personaName}
+
+	persona := GetPersona(personaName)
+	flags := MergeFlags(parsed.Flags, &Flags{
+		Persona: persona.Name,
+		Additional: map[string]string{
+			"pattern":  pattern.Name,
+			"previous": previous,
+		},
+	})
+	applyPersonaDefaults(flags, persona)
+
+	runner, err := h.agentFor(persona)
+	if err != nil {
+		step.Error = err
+		return step
+	}
+
+	cmd := Commands["collab"]
+	prompt, err := cmd.BuildPrompt(persona, flags, target, parsed.RawInput)
+	if err != nil {
+		step.Error = fmt.Errorf("failed to build prompt: %w", err)
+		return step
+	}
+	if flags.Think != "" {
+		prompt = applyThinkingMode(prompt, flags.Think)
+	}
+	if flags.UltraCompressed {
+		prompt = applyUltraCompressed(prompt)
+	}
+
+	title := fmt.Sprintf("%s: %s", pattern.Name, persona.Name)
+	session, err := h.sessions.CreateTaskSession(ctx, uuid.New().String(), parentSessionID, title)
+	if err != nil {
+		step.Error = fmt.Errorf("failed to create task session: %w", err)
+		return step
+	}
+	step.SessionID = session.ID
+
+	events, err := runner.Run(h.withThinking(ctx, runner.Model(), "collab", flags), session.ID, prompt)
+	if err != nil {
+		step.Error = err
+		return step
+	}
+	result := <-events
+	if result.Error != nil {
+		step.Error = result.Error
+		return step
+	}
+	step.Output = result.Message.Content().String()
+
+	if updated, err := h.sessions.Get(ctx, session.ID); err == nil {
+		step.Cost = updated.Cost
+	}
+
+	return step
+}
+
+// finishCollaboration writes the merged report to the parent session and rolls up the cost
+func (h *SuperClaudeHandler) finishCollaboration(ctx context.Context, sessionID string, pattern CollaborationPattern, target string, steps []CollaborationStep) error {
+	var cost float64
+	for _, step := range steps {
+		cost += step.Cost
+	}
+	return h.writeReport(ctx, sessionID, MergeCollaborationReport(pattern, target, steps), cost)
+}
+
+// MergeCollaborationReport combines the persona outputs into a single markdown report
+func MergeCollaborationReport(pattern CollaborationPattern, target string, steps []CollaborationStep) string {
+	var report strings.Builder
+
+	report.WriteString(fmt.Sprintf("# Collaboration: %s\n\n", pattern.Name))
+	report.WriteString(fmt.Sprintf("%s\n\n", pattern.Description))
+	if target != "" {
+		report.WriteString(fmt.Sprintf("- Target: %s\n", target))
+	}
+	report.WriteString(fmt.Sprintf("- Sequence: %s\n", pattern.Sequence))
+	report.WriteString(fmt.Sprintf("- Personas: %s\n", strings.Join(pattern.Personas, " → ")))
+
+	failed := 0
+	for _, step := range steps {
+		report.WriteString(fmt.Sprintf("\n## %s\n\n", step.Persona))
+		if step.Error != nil {
+			failed++
+			report.WriteString(fmt.Sprintf("_Failed: %v_\n", step.Error))
+			continue
+		}
+		output := strings.TrimSpace(step.Output)
+		if output == "" {
+			output = "_No output_"
+		}
+		report.WriteString(output)
+		report.WriteString("\n")
+	}
+
+	if failed > 0 {
+		report.WriteString(fmt.Sprintf("\n---\n%d of %d personas failed\n", failed, len(steps)))
+	}
+
+	return report.String()
+}
+
+// GetAvailableCollaborationPatterns returns all collaboration pattern names, sorted
+func GetAvailableCollaborationPatterns() []string {
+	patterns := make([]string, 0, len(CollaborationPatterns))
+	for name := range CollaborationPatterns {
+		patterns = append(patterns, name)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
@@ -0,0 +1,182 @@
+package superclaude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+var commandNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// commandFile is the on-disk representation of a file-based command
+type commandFile struct {
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description"`
+	Persona     string     `yaml:"persona"`
+	Flags       []FlagSpec `yaml:"flags"`
+	Template    string     `yaml:"template"`
+}
+
+// CommandLoader loads file-based command definitions and keeps them up to date
+type CommandLoader struct {
+	dirs []string
+	mu   sync.Mutex
+}
+
+// CommandDirs returns the user and project command definition directories, in load order
+func CommandDirs(workingDir string) []string {
+	return definitionDirs("superclaude-commands", workingDir)
+}
+
+// NewCommandLoader creates a loader for the given directories. Later directories
+// override commands of the same name from earlier ones.
+func NewCommandLoader(dirs ...string) *CommandLoader {
+	return &CommandLoader{dirs: dirs}
+}
+
+// Load reads every command file and replaces the registered file-based commands.
+// Invalid files are skipped and reported in the returned error.
+func (l *CommandLoader) Load() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	commands := make(map[string]SuperClaudeCommand)
+	var errs []error
+	for _, dir := range l.dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to read command directory %s: %w", dir, err))
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !isDefinitionFile(entry.Name()) {
+				continue
+			}
+			cmd, err := LoadCommandFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			commands[cmd.Name] = cmd
+		}
+	}
+
+	SetCustomCommands(commands)
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	logging.Debug("Loaded file-based commands", "commands", strings.Join(names, ","))
+
+	return errors.Join(errs...)
+}
+
+// Watch reloads the commands whenever a command directory changes
+func (l *CommandLoader) Watch(ctx context.Context) {
+	watchDefinitions(ctx, "commands", l.dirs, func() {
+		if err := l.Load(); err != nil {
+			logging.WarnPersist(fmt.Sprintf("Some SuperClaude commands failed to load: %v", err))
+			return
+		}
+		logging.InfoPersist("SuperClaude commands reloaded")
+	})
+}
+
+// LoadCommandFile parses and validates a command definition. YAML files carry the
+// template in a "template" field, Markdown files use the body after the front matter.
+func LoadCommandFile(path string) (SuperClaudeCommand, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SuperClaudeCommand{}, fmt.Errorf("failed to read command %s: %w", path, err)
+	}
+
+	var file commandFile
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		frontMatter, body, splitErr := splitFrontMatter(data)
+		if splitErr != nil {
+			return SuperClaudeCommand{}, fmt.Errorf("invalid command %s: %w", path, splitErr)
+		}
+		if frontMatter != nil {
+			err = decodeStrictYAML(frontMatter, &file)
+		}
+		if file.Template == "" {
+			file.Template = body
+		}
+	} else {
+		err = decodeStrictYAML(data, &file)
+	}
+	if err != nil {
+		return SuperClaudeCommand{}, fmt.Errorf("invalid command %s: %w", path, err)
+	}
+
+	if file.Name == "" {
+		file.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	for i := range file.Flags {
+		if file.Flags[i].Type == "" {
+			file.Flags[i].Type = FlagTypeString
+		}
+	}
+
+	cmd := SuperClaudeCommand{
+		Name:        file.Name,
+		Persona:     file.Persona,
+		Template:    strings.TrimSpace(file.Template),
+		Description: file.Description,
+		Schema:      file.Flags,
+		Source:      path,
+	}
+
+	if err := ValidateCommand(cmd); err != nil {
+		return SuperClaudeCommand{}, fmt.Errorf("invalid command %s: %w", path, err)
+	}
+	return cmd, nil
+}
+
+// ValidateCommand checks a file-based command before it is registered
+func ValidateCommand(cmd SuperClaudeCommand) error {
+	if !commandNamePattern.MatchString(cmd.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits and dashes", cmd.Name)
+	}
+	if _, exists := Commands[cmd.Name]; exists {
+		return fmt.Errorf("name %q conflicts with a built-in command", cmd.Name)
+	}
+	if cmd.Template == "" {
+		return fmt.Errorf("template is required")
+	}
+	if _, err := template.New(cmd.Name).Parse(cmd.Template); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	if cmd.Persona != "" {
+		if _, exists := LookupPersona(cmd.Persona); !exists {
+			return fmt.Errorf("unknown persona: %s", cmd.Persona)
+		}
+	}
+
+	seen := make(map[string]bool, len(cmd.Schema))
+	for _, spec := range cmd.Schema {
+		if seen[spec.Name] {
+			return fmt.Errorf("flag --%s is declared twice", spec.Name)
+		}
+		seen[spec.Name] = true
+		if err := spec.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
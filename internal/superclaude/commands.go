@@ -3,6 +3,7 @@ package superclaude
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"text/template"
 )
 
@@ -13,6 +14,11 @@ type SuperClaudeCommand struct {
 	Flags       map[string]string
 	Template    string
 	Description string
+
+	// Schema declares the command-specific flags, commands without one accept any flag
+	Schema []FlagSpec
+	// Source is the file the command was loaded from, empty for built-ins
+	Source string
 }
 
 // CommandTemplate holds the template structure for commands
@@ -33,16 +39,31 @@ var Commands = map[string]SuperClaudeCommand{
 	"build": {
 		Name:        "build",
 		Description: "Build a component or system with specified technology",
-		Template: `You are a {{.Persona}} specialist. Build {{.Target}} using {{.Flags}}.
+		Template: `You are a {{.Persona}} specialist. Build {{.Target}}.
+{{if .Flags.react}}Stack: React{{if .Flags.typescript}} with TypeScript{{end}}{{end}}
+{{if .Flags.api}}API: Service endpoints{{if .Flags.openapi}} with an OpenAPI specification{{end}}{{end}}
+{{if .Flags.component}}Component: {{.Flags.component}}{{end}}
+{{if .Flags.magic}}UI Generation: Scaffold components from the design system{{end}}
         
 CRITICAL RULES:
 - Evidence-based decisions only (cite sources)
 - {{if .UltraCompressed}}Use 70% fewer tokens{{end}}
 - {{if .Think}}Provide {{.ThinkLevel}} analysis depth{{end}}
-- Follow TDD practices if --tdd flag is set
+- {{if .Flags.tdd}}Follow TDD practices: write failing tests first{{end}}
 - Include comprehensive error handling
+{{if .Flags.watch}}Watch Mode: Rebuild on change{{end}}
         
 Execute: {{.Command}}`,
+		Schema: []FlagSpec{
+			{Name: "react", Type: FlagTypeBool, Description: "Build with React"},
+			{Name: "typescript", Type: FlagTypeBool, Description: "Use TypeScript"},
+			{Name: "api", Type: FlagTypeBool, Description: "Build an API"},
+			{Name: "openapi", Type: FlagTypeBool, Description: "Write an OpenAPI specification"},
+			{Name: "component", Type: FlagTypeString, Description: "Component to build"},
+			{Name: "magic", Type: FlagTypeBool, Description: "Scaffold UI components"},
+			{Name: "tdd", Type: FlagTypeBool, Description: "Write tests first"},
+			{Name: "watch", Type: FlagTypeBool, Description: "Rebuild on change"},
+		},
 	},
 
 	"analyze": {
@@ -50,10 +71,18 @@ Execute: {{.Command}}`,
 		Description: "Analyze code, architecture, or system components",
 		Template: `Analyze {{.Target}} as {{.Persona}}.
         
-Focus: {{.AnalysisType}}
-Depth: {{.ThinkLevel}}
+Focus: {{.AnalysisType}}{{if .Flags.code}} | code quality{{end}}{{if .Flags.architecture}} | architecture{{end}}{{if .Flags.performance}} | performance{{end}}{{if .Flags.security}} | security{{end}}
+Depth: {{if .ThinkLevel}}{{.ThinkLevel}}{{else}}standard{{end}}{{if .Flags.deep}} | trace every dependency{{end}}
 Output: Evidence-based findings with citations
 {{if .Evidence}}Include: External documentation references{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "type", Type: FlagTypeString, Default: "general", Description: "Kind of analysis"},
+			{Name: "code", Type: FlagTypeBool, Description: "Focus on code quality"},
+			{Name: "architecture", Type: FlagTypeBool, Description: "Focus on architecture"},
+			{Name: "performance", Type: FlagTypeBool, Description: "Focus on performance"},
+			{Name: "security", Type: FlagTypeBool, Description: "Focus on security"},
+			{Name: "deep", Type: FlagTypeBool, Description: "Trace every dependency"},
+		},
 	},
 
 	"test": {
@@ -61,10 +90,22 @@ Output: Evidence-based findings with citations
 		Description: "Run tests with specified coverage and frameworks",
 		Template: `Execute {{.Target}} tests as {{.Persona}}.
 		
-Test Type: {{.Flags.type}}
-Coverage Target: {{.Flags.coverage}}
+Test Type: {{.Flags.type}}{{if .Flags.unit}} | unit{{end}}{{if .Flags.integration}} | integration{{end}}{{if .Flags.e2e}} | e2e{{end}}
+Coverage Target: {{.Flags.coverage}}%
+{{if .Flags.pup}}Browser Automation: Puppeteer{{end}}
 {{if .Flags.watch}}Watch Mode: Enabled{{end}}
+{{if index .Flags "dry-run"}}Dry Run: List the tests without running them{{end}}
 Report Format: Detailed with metrics`,
+		Schema: []FlagSpec{
+			{Name: "type", Type: FlagTypeString, Default: "all", Enum: []string{"all", "unit", "integration", "e2e"}, Description: "Test suite to run"},
+			{Name: "coverage", Type: FlagTypeInt, Default: "80", Description: "Coverage target in percent"},
+			{Name: "unit", Type: FlagTypeBool, Description: "Include unit tests"},
+			{Name: "integration", Type: FlagTypeBool, Description: "Include integration tests"},
+			{Name: "e2e", Type: FlagTypeBool, Description: "Include end-to-end tests"},
+			{Name: "pup", Type: FlagTypeBool, Description: "Drive browser tests with Puppeteer"},
+			{Name: "watch", Type: FlagTypeBool, Description: "Re-run tests on change"},
+			{Name: "dry-run", Type: FlagTypeBool, Description: "List tests without running them"},
+		},
 	},
 
 	"improve": {
@@ -74,7 +115,8 @@ Report Format: Detailed with metrics`,
 
 IMPROVEMENT FOCUS:
 {{if .Flags.quality}}Code Quality: Naming clarity | Function extraction | Duplication removal | Complexity reduction{{end}}
-{{if .Flags.perf}}Performance: Algorithm optimization | Query optimization | Caching strategies | Memory efficiency{{end}}
+{{if or .Flags.perf .Flags.performance}}Performance: Algorithm optimization | Query optimization | Caching strategies | Memory efficiency{{end}}
+{{if .Flags.cache}}Caching: Identify repeated work and cache it safely{{end}}
 {{if .Flags.arch}}Architecture: Design patterns | Dependency injection | Layer separation | Scalability patterns{{end}}
 {{if .Flags.refactor}}Refactoring: Safe changes preserving behavior{{end}}
 
@@ -86,10 +128,23 @@ PROCESS:
 
 {{if .Flags.threshold}}Quality Threshold: {{.Flags.threshold}}{{end}}
 {{if .Flags.iterate}}Iterative Mode: Continue until threshold met{{end}}
-{{if .Flags.metrics}}Metrics: Show before/after measurements{{end}}
+{{if or .Flags.metrics .Flags.benchmark}}Metrics: Show before/after measurements{{end}}
 {{if .Flags.safe}}Safe Mode: Conservative changes only{{end}}
 
 Validation: Evidence-based improvements with measurable impact`,
+		Schema: []FlagSpec{
+			{Name: "quality", Type: FlagTypeBool, Description: "Improve code quality"},
+			{Name: "perf", Type: FlagTypeBool, Description: "Improve performance"},
+			{Name: "performance", Type: FlagTypeBool, Description: "Improve performance, same as --perf"},
+			{Name: "cache", Type: FlagTypeBool, Description: "Add caching"},
+			{Name: "arch", Type: FlagTypeBool, Description: "Improve architecture"},
+			{Name: "refactor", Type: FlagTypeBool, Description: "Refactor without changing behavior"},
+			{Name: "threshold", Type: FlagTypeString, Description: "Quality threshold to reach, such as 95% or high"},
+			{Name: "iterate", Type: FlagTypeBool, Description: "Repeat until the threshold is met"},
+			{Name: "metrics", Type: FlagTypeBool, Description: "Show before/after measurements"},
+			{Name: "benchmark", Type: FlagTypeBool, Description: "Benchmark before and after, same as --metrics"},
+			{Name: "safe", Type: FlagTypeBool, Description: "Make conservative changes only"},
+		},
 	},
 
 	"troubleshoot": {
@@ -99,7 +154,12 @@ Validation: Evidence-based improvements with measurable impact`,
 		
 Method: {{.Flags.method}}
 {{if .Flags.investigate}}Deep Investigation: Enabled{{end}}
-{{if .Flags.fiveWhys}}Five Whys Analysis: Enabled{{end}}`,
+{{if index .Flags "five-whys"}}Five Whys Analysis: Enabled{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "method", Type: FlagTypeString, Default: "systematic", Description: "Debugging method"},
+			{Name: "investigate", Type: FlagTypeBool, Description: "Investigate in depth"},
+			{Name: "five-whys", Type: FlagTypeBool, Description: "Apply the five whys"},
+		},
 	},
 
 	"design": {
@@ -107,9 +167,20 @@ Method: {{.Flags.method}}
 		Description: "Design systems, APIs, or architectures",
 		Template: `Design {{.Target}} as {{.Persona}}.
 		
-Patterns: {{.Flags.patterns}}
-Architecture: {{.Flags.architecture}}
+{{if .Flags.patterns}}Patterns: {{.Flags.patterns}}{{end}}
+{{if .Flags.architecture}}Architecture: {{.Flags.architecture}}{{end}}
+{{if .Flags.api}}API: Resource model | Endpoints | Error contract{{if .Flags.openapi}} | OpenAPI specification{{end}}{{end}}
+{{if .Flags.ddd}}Domain-Driven Design: Bounded contexts | Aggregates | Ubiquitous language{{end}}
+{{if .Flags.microservices}}Microservices: Service boundaries | Communication | Data ownership{{end}}
 {{if .Evidence}}Documentation: Include references{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "patterns", Type: FlagTypeString, Description: "Design patterns to apply"},
+			{Name: "architecture", Type: FlagTypeString, Description: "Architecture style"},
+			{Name: "api", Type: FlagTypeBool, Description: "Design an API"},
+			{Name: "openapi", Type: FlagTypeBool, Description: "Write an OpenAPI specification"},
+			{Name: "ddd", Type: FlagTypeBool, Description: "Apply domain-driven design"},
+			{Name: "microservices", Type: FlagTypeBool, Description: "Design as microservices"},
+		},
 	},
 
 	"deploy": {
@@ -118,8 +189,13 @@ Architecture: {{.Flags.architecture}}
 		Template: `Deploy {{.Target}} to {{.Flags.env}} as {{.Persona}}.
 		
 Environment: {{.Flags.env}}
-{{if .Flags.dryRun}}Dry Run Mode: Enabled{{end}}
+{{if index .Flags "dry-run"}}Dry Run Mode: Enabled{{end}}
 {{if .Flags.rollback}}Rollback Ready: Yes{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "env", Type: FlagTypeString, Required: true, Description: "Target environment"},
+			{Name: "dry-run", Type: FlagTypeBool, Description: "Show the deployment steps without executing them"},
+			{Name: "rollback", Type: FlagTypeBool, Description: "Prepare a rollback plan"},
+		},
 	},
 
 	"scan": {
@@ -128,8 +204,18 @@ Environment: {{.Flags.env}}
 		Template: `Scan {{.Target}} for {{.Flags.type}} as {{.Persona}}.
 		
 Scan Type: {{.Flags.type}}
+{{if .Flags.security}}Security: Vulnerabilities | Secrets | Unsafe defaults{{end}}
 {{if .Flags.owasp}}OWASP Standards: Applied{{end}}
+{{if .Flags.deps}}Dependencies: Known vulnerabilities and outdated versions{{end}}
+{{if .Flags.penetration}}Penetration: Probe exploitable paths{{end}}
 {{if .Flags.validate}}Validation: Strict{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "type", Type: FlagTypeString, Default: "security", Description: "Kind of issues to scan for"},
+			{Name: "security", Type: FlagTypeBool, Description: "Scan for security issues"},
+			{Name: "owasp", Type: FlagTypeBool, Description: "Apply OWASP standards"},
+			{Name: "deps", Type: FlagTypeBool, Description: "Scan dependencies"},
+			{Name: "penetration", Type: FlagTypeBool, Description: "Probe exploitable paths"},
+		},
 	},
 
 	"document": {
@@ -139,7 +225,14 @@ Scan Type: {{.Flags.type}}
 		
 Type: {{.Flags.type}}
 Format: {{.Flags.format}}
+{{if .Flags.api}}API Reference: Every endpoint with examples{{end}}
 {{if .Flags.interactive}}Interactive Mode: Enabled{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "type", Type: FlagTypeString, Default: "reference", Description: "Kind of documentation"},
+			{Name: "format", Type: FlagTypeString, Default: "markdown", Description: "Output format"},
+			{Name: "api", Type: FlagTypeBool, Description: "Document the API"},
+			{Name: "interactive", Type: FlagTypeBool, Description: "Write interactive documentation"},
+		},
 	},
 
 	"review": {
@@ -147,9 +240,13 @@ Format: {{.Flags.format}}
 		Description: "Review code, architecture, or documentation",
 		Template: `Review {{.Target}} as {{.Persona}}.
 		
-Focus: {{.Flags.focus}}
+Focus: {{.Flags.focus}}{{if .Flags.quality}} | code quality{{end}}
 {{if .Evidence}}Evidence Required: Yes{{end}}
 Standards: Comprehensive analysis`,
+		Schema: []FlagSpec{
+			{Name: "focus", Type: FlagTypeString, Default: "correctness", Description: "What the review concentrates on"},
+			{Name: "quality", Type: FlagTypeBool, Description: "Review code quality"},
+		},
 	},
 
 	"migrate": {
@@ -157,9 +254,13 @@ Standards: Comprehensive analysis`,
 		Description: "Migrate data, code, or systems",
 		Template: `Migrate {{.Target}} as {{.Persona}}.
 		
-{{if .Flags.dryRun}}Dry Run: Enabled{{end}}
+{{if index .Flags "dry-run"}}Dry Run: Enabled{{end}}
 {{if .Flags.validate}}Validation: Enabled{{end}}
 {{if .Flags.rollback}}Rollback Strategy: Prepared{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "dry-run", Type: FlagTypeBool, Description: "Show the migration steps without executing them"},
+			{Name: "rollback", Type: FlagTypeBool, Description: "Prepare a rollback strategy"},
+		},
 	},
 
 	"cleanup": {
@@ -167,9 +268,14 @@ Standards: Comprehensive analysis`,
 		Description: "Clean up code, dependencies, or resources",
 		Template: `Cleanup {{.Target}} as {{.Persona}}.
 		
-Scope: {{.Flags.scope}}
+Scope: {{if .Flags.all}}all{{else}}{{.Flags.scope}}{{end}}
 {{if .Flags.optimize}}Optimization: Enabled{{end}}
 {{if .Flags.validate}}Validation: Post-cleanup{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "scope", Type: FlagTypeString, Default: "code", Description: "What to clean up"},
+			{Name: "all", Type: FlagTypeBool, Description: "Clean up everything"},
+			{Name: "optimize", Type: FlagTypeBool, Description: "Optimize while cleaning up"},
+		},
 	},
 
 	"explain": {
@@ -180,6 +286,11 @@ Scope: {{.Flags.scope}}
 Depth: {{.Flags.depth}}
 {{if .Flags.visual}}Visual Aids: Include diagrams{{end}}
 {{if .Flags.examples}}Examples: Provide practical examples{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "depth", Type: FlagTypeString, Default: "intermediate", Enum: []string{"basic", "intermediate", "expert"}, Description: "Level of detail"},
+			{Name: "visual", Type: FlagTypeBool, Description: "Include diagrams"},
+			{Name: "examples", Type: FlagTypeBool, Description: "Include practical examples"},
+		},
 	},
 
 	"estimate": {
@@ -188,8 +299,13 @@ Depth: {{.Flags.depth}}
 		Template: `Estimate {{.Target}} as {{.Persona}}.
 		
 Type: {{.Flags.type}}
+{{if .Flags.detailed}}Breakdown: Per task with ranges{{end}}
 Confidence: Evidence-based
 Include: Risk factors and assumptions`,
+		Schema: []FlagSpec{
+			{Name: "type", Type: FlagTypeString, Default: "time", Description: "What to estimate"},
+			{Name: "detailed", Type: FlagTypeBool, Description: "Break the estimate down per task"},
+		},
 	},
 
 	"dev-setup": {
@@ -197,9 +313,14 @@ Include: Risk factors and assumptions`,
 		Description: "Set up development environment",
 		Template: `Setup development environment for {{.Target}} as {{.Persona}}.
 		
-Components: {{.Flags.components}}
+{{if .Flags.components}}Components: {{.Flags.components}}{{end}}
 {{if .Flags.ci}}CI/CD: Configure{{end}}
 {{if .Flags.automation}}Automation: Enable{{end}}`,
+		Schema: []FlagSpec{
+			{Name: "components", Type: FlagTypeString, Description: "Components to set up"},
+			{Name: "ci", Type: FlagTypeBool, Description: "Configure CI/CD"},
+			{Name: "automation", Type: FlagTypeBool, Description: "Enable automation"},
+		},
 	},
 
 	"load": {
@@ -210,6 +331,9 @@ Components: {{.Flags.components}}
 Depth: {{.Flags.depth}}
 {{if .Flags.seq}}Sequential Processing: Enabled{{end}}
 Analysis: Comprehensive understanding`,
+		Schema: []FlagSpec{
+			{Name: "depth", Type: FlagTypeString, Default: "normal", Enum: []string{"shallow", "normal", "deep"}, Description: "How far to follow dependencies"},
+		},
 	},
 
 	"git": {
@@ -220,19 +344,82 @@ Analysis: Comprehensive understanding`,
 Operation: {{.Target}}
 {{if .Flags.checkpoint}}Checkpoint: Create{{end}}
 Safety: Validate before execution`,
+		Schema: []FlagSpec{
+			{Name: "checkpoint", Type: FlagTypeBool, Description: "Create a checkpoint first"},
+		},
 	},
 
 	"spawn": {
 		Name:        "spawn",
 		Description: "Spawn multi-agent tasks",
-		Template: `Spawn multi-agent task {{.Target}} as {{.Persona}}.
-		
-Agents: {{.Flags.agents}}
-{{if .Flags.parallel}}Parallel Execution: Enabled{{end}}
-Coordination: Managed workflow`,
+		Template: `{{if .Flags.index}}Execute sub-task {{.Flags.index}} of {{.Flags.total}} as {{.Persona}}: {{.Target}}
+
+Overall goal: {{.Flags.goal}}{{else}}Execute as {{.Persona}}: {{.Target}}
+{{end}}
+Coordination: Other agents handle the remaining sub-tasks, stay within your scope
+Report: Concise summary of the changes made and any follow-ups`,
+		Schema: []FlagSpec{
+			{Name: "agents", Type: FlagTypeInt, Default: "3", Description: "Number of sub-agents"},
+			{Name: "parallel", Type: FlagTypeString, Default: "true", Description: "Sub-agents to run at once, true runs them all"},
+			{Name: "task", Type: FlagTypeString, Description: "Name of the overall task"},
+		},
+	},
+
+	"collab": {
+		Name:        "collab",
+		Description: "Run a multi-persona collaboration pattern",
+		Template: `Collaborate on {{.Target}} as {{.Persona}}.
+
+Workflow: {{.Flags.pattern}}
+{{if .Flags.previous}}Findings from the previous persona:
+{{.Flags.previous}}
+
+Build on these findings from your own perspective.{{end}}
+Output: Your persona's findings only, they will be merged into a combined report`,
+		Schema: []FlagSpec{
+			{Name: "pattern", Type: FlagTypeString, Default: "collaboration", Description: "Collaboration pattern, set for each persona"},
+			{Name: "previous", Type: FlagTypeString, Description: "Findings of the previous persona, set for each persona"},
+		},
 	},
 }
 
+// customCommands holds the file-based commands, replaced as a whole on reload
+var customCommands = struct {
+	sync.RWMutex
+	commands map[string]SuperClaudeCommand
+}{commands: map[string]SuperClaudeCommand{}}
+
+// SetCustomCommands replaces the set of file-based commands
+func SetCustomCommands(commands map[string]SuperClaudeCommand) {
+	customCommands.Lock()
+	defer customCommands.Unlock()
+	customCommands.commands = commands
+}
+
+// GetCustomCommands returns a snapshot of the file-based commands
+func GetCustomCommands() map[string]SuperClaudeCommand {
+	customCommands.RLock()
+	defer customCommands.RUnlock()
+
+	result := make(map[string]SuperClaudeCommand, len(customCommands.commands))
+	for name, cmd := range customCommands.commands {
+		result[name] = cmd
+	}
+	return result
+}
+
+// LookupCommand returns a built-in or file-based command by name
+func LookupCommand(name string) (SuperClaudeCommand, bool) {
+	if cmd, exists := Commands[name]; exists {
+		return cmd, true
+	}
+
+	customCommands.RLock()
+	defer customCommands.RUnlock()
+	cmd, exists := customCommands.commands[name]
+	return cmd, exists
+}
+
 // BuildPrompt generates the final prompt from a command and context
 func (cmd *SuperClaudeCommand) BuildPrompt(persona Persona, flags *Flags, target string, rawCommand string) (string, error) {
 	tmpl, err := template.New("command").Parse(cmd.Template)
@@ -251,7 +438,8 @@ func (cmd *SuperClaudeCommand) BuildPrompt(persona Persona, flags *Flags, target
 		Evidence:        flags.Evidence,
 	}
 
-	if analysisType, ok := flags.Additional["type"]; ok {
+	cmd.templateFlags(data.Flags)
+	if analysisType, ok := data.Flags["type"].(string); ok {
 		data.AnalysisType = analysisType
 	}
 
@@ -296,6 +484,9 @@ func convertFlagsToMap(flags *Flags) map[string]interface{} {
 	if flags.ValidationOnly {
 		result["validate"] = true
 	}
+	if flags.Sequential {
+		result["seq"] = true
+	}
 
 	return result
 }
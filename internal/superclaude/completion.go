@@ -0,0 +1,333 @@
+package superclaude
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/opencode-ai/opencode/internal/fileutil"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// CompletionKind is the kind of token a completion inserts
+type CompletionKind string
+
+const (
+	CompletionCommand CompletionKind = "command"
+	CompletionPersona CompletionKind = "persona"
+	CompletionFlag    CompletionKind = "flag"
+	CompletionValue   CompletionKind = "value"
+	CompletionPattern CompletionKind = "pattern"
+	CompletionPath    CompletionKind = "path"
+)
+
+// maxPathCompletions bounds the file suggestions for a target
+const maxPathCompletions = 20
+
+// Completion is a suggestion for the token under the cursor. Applying it replaces
+// the input between Start and End, both byte offsets, with Value.
+type Completion struct {
+	Value       string         `json:"value"`
+	Kind        CompletionKind `json:"kind"`
+	Description string         `json:"description,omitempty"`
+	Start       int            `json:"start"`
+	End         int            `json:"end"`
+}
+
+// Apply returns input with the completion inserted
+func (c Completion) Apply(input string) string {
+	return input[:c.Start] + c.Value + input[c.End:]
+}
+
+// coreFlag is a flag understood by every command
+type coreFlag struct {
+	Name        string
+	Description string
+}
+
+// coreFlags are the flags ParseSuperClaudeCommand handles itself, in help order
+var coreFlags = []coreFlag{
+	{"uc", "Ultra-compressed responses"},
+	{"ultracompressed", "Ultra-compressed responses"},
+	{"think", "Standard analysis mode"},
+	{"think-hard", "Deep analysis mode"},
+	{"ultrathink", "Maximum analysis"},
+	{"plan", "Propose a read-only plan and wait for approval"},
+	{"evidence", "Verify file:line citations"},
+	{"c7", "Verify file:line citations"},
+	{"validate", "Preview every file change before writing"},
+	{"validation-only", "Preview every file change before writing"},
+	{"seq", "Step-by-step execution"},
+	{"sequential", "Step-by-step execution"},
+	{"all-mcp", "Use all available MCP tools"},
+}
+
+// token is a whitespace separated word of the input
+type token struct {
+	text       string
+	start, end int
+}
+
+// Complete suggests completions for the token at cursor, a byte offset into input.
+// Commands, personas and flags come from the loaded registries, target paths are
+// matched against the files below workingDir.
+func Complete(input string, cursor int, workingDir string) []Completion {
+	if cursor < 0 || cursor > len(input) {
+		cursor = len(input)
+	}
+	for cursor > 0 && cursor < len(input) && !utf8.RuneStart(input[cursor]) {
+		cursor--
+	}
+
+	words := tokenize(input[:cursor])
+	current := token{start: cursor, end: cursor}
+	if len(words) > 0 && words[len(words)-1].end == cursor {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	// Replace the whole word under the cursor, not just the part before it
+	for current.end < len(input) {
+		r, size := utf8.DecodeRuneInString(input[current.end:])
+		if unicode.IsSpace(r) {
+			break
+		}
+		current.end += size
+	}
+	prefix := input[current.start:cursor]
+
+	var suggestions []Completion
+	switch {
+	case len(words) == 0:
+		suggestions = completeFirst(prefix)
+	case strings.HasPrefix(words[0].text, "/user:"):
+		suggestions = completeArguments(strings.TrimPrefix(words[0].text, "/user:"), words[1:], prefix, workingDir)
+	case strings.HasPrefix(words[0].text, "/persona:"):
+		rest := words[1:]
+		if len(rest) > 0 && (rest[0].text == "→" || rest[0].text == "->") {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			suggestions = completeCommands(prefix)
+		} else if strings.HasPrefix(rest[0].text, "/user:") {
+			suggestions = completeArguments(strings.TrimPrefix(rest[0].text, "/user:"), rest[1:], prefix, workingDir)
+		}
+	}
+
+	for i := range suggestions {
+		suggestions[i].Start = current.start
+		suggestions[i].End = current.end
+	}
+	return suggestions
+}
+
+// tokenize splits input into words and records where each one starts and ends
+func tokenize(input string) []token {
+	var words []token
+	start := -1
+	for i, r := range input {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				words = append(words, token{text: input[start:i], start: start, end: i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, token{text: input[start:], start: start, end: len(input)})
+	}
+	return words
+}
+
+// completeFirst completes the leading /user: command or /persona: prefix
+func completeFirst(prefix string) []Completion {
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		return nil
+	}
+	return append(completeCommands(prefix), completePersonas("/persona:", prefix)...)
+}
+
+func completeCommands(prefix string) []Completion {
+	names := GetAvailableCommands()
+	sort.Strings(names)
+
+	var suggestions []Completion
+	for _, name := range names {
+		value := "/user:" + name
+		if !strings.HasPrefix(value, prefix) {
+			continue
+		}
+		cmd, _ := LookupCommand(name)
+		suggestions = append(suggestions, Completion{
+			Value:       value,
+			Kind:        CompletionCommand,
+			Description: cmd.Description,
+		})
+	}
+	return suggestions
+}
+
+// completePersonas completes persona names written as <form><name>
+func completePersonas(form, prefix string) []Completion {
+	names := GetAvailablePersonas()
+	sort.Strings(names)
+
+	var suggestions []Completion
+	for _, name := range names {
+		value := form + name
+		if !strings.HasPrefix(value, prefix) {
+			continue
+		}
+		persona, _ := LookupPersona(name)
+		suggestions = append(suggestions, Completion{
+			Value:       value,
+			Kind:        CompletionPersona,
+			Description: persona.Identity,
+		})
+	}
+	return suggestions
+}
+
+// completeArguments completes the flags and target of a command, given the words
+// between the command and the cursor
+func completeArguments(name string, args []token, prefix, workingDir string) []Completion {
+	spec, _ := LookupCommand(name)
+
+	// Walk the arguments like ParseSuperClaudeCommand to learn which flags are
+	// set and whether the word at the cursor is the value of a flag
+	used := map[string]bool{}
+	var pending *FlagSpec
+	targets := 0
+	for _, arg := range args {
+		if strings.HasPrefix(arg.text, "--") {
+			flagName := strings.TrimPrefix(arg.text, "--")
+			used[flagName] = true
+			pending = nil
+			if flagSpec, ok := spec.FlagSpec(flagName); ok && flagSpec.Type != FlagTypeBool {
+				pending = &flagSpec
+			}
+			continue
+		}
+		if pending == nil {
+			targets++
+		}
+		pending = nil
+	}
+
+	if strings.HasPrefix(prefix, "-") {
+		return completeFlags(spec, used, prefix)
+	}
+	if pending != nil {
+		return completeFlagValue(*pending, prefix)
+	}
+	if name == "collab" && targets == 0 {
+		return completePatterns(prefix)
+	}
+	return completePaths(prefix, workingDir)
+}
+
+// completeFlags suggests the command's own flags first, then the core and persona
+// flags, leaving out flags that are already set
+func completeFlags(spec SuperClaudeCommand, used map[string]bool, prefix string) []Completion {
+	var suggestions []Completion
+	add := func(name, description string) {
+		value := "--" + name
+		if used[name] || !strings.HasPrefix(value, prefix) {
+			return
+		}
+		suggestions = append(suggestions, Completion{
+			Value:       value,
+			Kind:        CompletionFlag,
+			Description: description,
+		})
+	}
+
+	for _, flagSpec := range spec.Schema {
+		description := flagSpec.Description
+		if flagSpec.Required {
+			description += " (required)"
+		}
+		add(flagSpec.Name, description)
+	}
+	for _, flag := range coreFlags {
+		add(flag.Name, flag.Description)
+	}
+
+	for _, persona := range completePersonas("--persona-", prefix) {
+		if !used[strings.TrimPrefix(persona.Value, "--")] {
+			persona.Kind = CompletionFlag
+			suggestions = append(suggestions, persona)
+		}
+	}
+	return suggestions
+}
+
+// completeFlagValue suggests the allowed values of a flag, or its default
+func completeFlagValue(flagSpec FlagSpec, prefix string) []Completion {
+	values := flagSpec.Enum
+	if len(values) == 0 && flagSpec.Default != "" {
+		values = []string{flagSpec.Default}
+	}
+
+	var suggestions []Completion
+	for _, value := range values {
+		if !strings.HasPrefix(value, prefix) {
+			continue
+		}
+		description := ""
+		if value == flagSpec.Default {
+			description = "default"
+		}
+		suggestions = append(suggestions, Completion{
+			Value:       value,
+			Kind:        CompletionValue,
+			Description: description,
+		})
+	}
+	return suggestions
+}
+
+func completePatterns(prefix string) []Completion {
+	names := GetAvailableCollaborationPatterns()
+
+	var suggestions []Completion
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		suggestions = append(suggestions, Completion{
+			Value:       name,
+			Kind:        CompletionPattern,
+			Description: CollaborationPatterns[name].Description,
+		})
+	}
+	return suggestions
+}
+
+// completePaths fuzzy matches the target against the files of the working directory
+func completePaths(prefix, workingDir string) []Completion {
+	if workingDir == "" {
+		return nil
+	}
+	matches, err := fileutil.FindFiles(workingDir, prefix)
+	if err != nil {
+		logging.Debug("SuperClaude path completion failed", "error", err)
+		return nil
+	}
+	if len(matches) > maxPathCompletions {
+		matches = matches[:maxPathCompletions]
+	}
+
+	suggestions := make([]Completion, 0, len(matches))
+	for _, match := range matches {
+		suggestions = append(suggestions, Completion{
+			Value: match,
+			Kind:  CompletionPath,
+		})
+	}
+	return suggestions
+}
@@ -0,0 +1,225 @@
+package superclaude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+)
+
+// EvidencePolicy decides what happens to claims whose citation is not backed by the ledger
+type EvidencePolicy string
+
+const (
+	// EvidencePolicyFlag marks unverified citations in the answer
+	EvidencePolicyFlag EvidencePolicy = "flag"
+	// EvidencePolicyStrip removes the sentences that carry unverified citations
+	EvidencePolicyStrip EvidencePolicy = "strip"
+)
+
+const unverifiedMarker = " [unverified]"
+
+var (
+	// citationPattern matches file:line and file:start-end citations
+	citationPattern = regexp.MustCompile(`([\w./-]*\w\.[A-Za-z0-9]+):(\d+)(?:-(\d+))?`)
+	// urlPattern matches URLs, whose host:port must not be read as a citation
+	urlPattern = regexp.MustCompile(`https?://\S+`)
+)
+
+// ParseEvidencePolicy converts a configured policy name, defaulting to flag
+func ParseEvidencePolicy(value string) (EvidencePolicy, error) {
+	switch EvidencePolicy(strings.ToLower(strings.TrimSpace(value))) {
+	case "", EvidencePolicyFlag:
+		return EvidencePolicyFlag, nil
+	case EvidencePolicyStrip:
+		return EvidencePolicyStrip, nil
+	default:
+		return "", fmt.Errorf("unknown evidence policy %q (use flag or strip)", value)
+	}
+}
+
+// FindCitations returns the file:line citations in text, in order of appearance
+func FindCitations(text string) []message.EvidenceCitation {
+	urls := urlPattern.FindAllStringIndex(text, -1)
+	insideURL := func(start int) bool {
+		for _, span := range urls {
+			if start >= span[0] && start < span[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var citations []message.EvidenceCitation
+	for _, match := range citationPattern.FindAllStringSubmatchIndex(text, -1) {
+		if insideURL(match[0]) {
+			continue
+		}
+		start, err := strconv.Atoi(text[match[4]:match[5]])
+		if err != nil || start == 0 {
+			continue
+		}
+		end := start
+		if match[6] >= 0 {
+			if n, err := strconv.Atoi(text[match[6]:match[7]]); err == nil && n >= start {
+				end = n
+			}
+		}
+		citations = append(citations, message.EvidenceCitation{
+			Text:      text[match[0]:match[1]],
+			Path:      text[match[2]:match[3]],
+			StartLine: start,
+			EndLine:   end,
+		})
+	}
+	return citations
+}
+
+// VerifyCitations checks every citation in text against the lines recorded in the ledger
+func VerifyCitations(text string, sources []message.EvidenceSource) []message.EvidenceCitation {
+	citations := FindCitations(text)
+	for i, citation := range citations {
+		citations[i].Verified = tools.EvidenceCovers(sources, citation.Path, citation.StartLine, citation.EndLine)
+	}
+	return citations
+}
+
+// applyEvidencePolicy flags or strips the claims carrying unverified citations and
+// returns the rewritten text with the number of stripped sentences
+func applyEvidencePolicy(text string, citations []message.EvidenceCitation, policy EvidencePolicy) (string, int) {
+	unverified := make(map[string]bool)
+	for _, citation := range citations {
+		if !citation.Verified {
+			unverified[citation.Text] = true
+		}
+	}
+	if len(unverified) == 0 {
+		return text, 0
+	}
+
+	if policy != EvidencePolicyStrip {
+		return citationPattern.ReplaceAllStringFunc(text, func(citation string) string {
+			if unverified[citation] {
+				return citation + unverifiedMarker
+			}
+			return citation
+		}), 0
+	}
+
+	stripped := 0
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		sentences := splitSentences(line)
+		var keep []string
+		for _, sentence := range sentences {
+			if hasUnverifiedCitation(sentence, unverified) {
+				stripped++
+				continue
+			}
+			keep = append(keep, sentence)
+		}
+		if len(keep) == 0 && len(sentences) > 0 {
+			continue
+		}
+		kept = append(kept, strings.Join(keep, " "))
+	}
+	return strings.Join(kept, "\n"), stripped
+}
+
+func hasUnverifiedCitation(sentence string, unverified map[string]bool) bool {
+	for _, citation := range FindCitations(sentence) {
+		if unverified[citation.Text] {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSentences splits a line after sentence punctuation followed by a space. Blank
+// lines yield no sentences so they survive stripping.
+func splitSentences(line string) []string {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	var sentences []string
+	start := 0
+	for i := 0; i < len(line)-1; i++ {
+		if strings.ContainsRune(".!?", rune(line[i])) && line[i+1] == ' ' {
+			sentences = append(sentences, line[start:i+1])
+			start = i + 2
+		}
+	}
+	return append(sentences, line[start:])
+}
+
+// attachEvidence verifies the citations of an answer against the turn's ledger, applies
+// the evidence policy and stores the ledger on the message
+func (h *SuperClaudeHandler) attachEvidence(ctx context.Context, msg message.Message, sources []message.EvidenceSource) (message.Message, error) {
+	text := msg.Content().String()
+	citations := VerifyCitations(text, sources)
+	text, stripped := applyEvidencePolicy(text, citations, h.evidencePolicy)
+
+	msg.SetContent(text)
+	msg.SetEvidence(message.EvidenceContent{
+		Sources:   sources,
+		Citations: citations,
+		Stripped:  stripped,
+	})
+	return msg, h.messages.Update(ctx, msg)
+}
+
+// ExportEvidence writes the evidence ledgers of a session to a JSON file in the data
+// directory and returns its path
+func (h *SuperClaudeHandler) ExportEvidence(ctx context.Context, sessionID string) (string, error) {
+	if h.messages == nil {
+		return "", fmt.Errorf("exporting evidence requires a message service")
+	}
+	msgs, err := h.messages.List(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	type exportedEvidence struct {
+		MessageID string `json:"message_id"`
+		CreatedAt int64  `json:"created_at"`
+		message.EvidenceContent
+	}
+	var ledgers []exportedEvidence
+	for _, msg := range msgs {
+		if evidence, ok := msg.Evidence(); ok {
+			ledgers = append(ledgers, exportedEvidence{MessageID: msg.ID, CreatedAt: msg.CreatedAt, EvidenceContent: evidence})
+		}
+	}
+	if len(ledgers) == 0 {
+		return "", fmt.Errorf("no evidence recorded in this session, run a command with --evidence first")
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"session_id": sessionID,
+		"exported":   time.Now().Format(time.RFC3339),
+		"evidence":   ledgers,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(config.Get().Data.Directory, "evidence")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create evidence directory: %w", err)
+	}
+	path := filepath.Join(dir, sessionID+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write evidence: %w", err)
+	}
+	return path, nil
+}
@@ -1,10 +1,14 @@
 package superclaude
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
+// ErrNotSuperClaudeCommand is returned for input that should be handled as a regular message
+var ErrNotSuperClaudeCommand = errors.New("not a SuperClaude command")
+
 // Flags represents all possible SuperClaude flags
 type Flags struct {
 	// Core flags
@@ -35,7 +39,7 @@ func ParseSuperClaudeCommand(input string) (*ParsedCommand, error) {
 
 	// Check if it's a SuperClaude command
 	if !strings.HasPrefix(input, "/user:") && !strings.HasPrefix(input, "/persona:") {
-		return nil, fmt.Errorf("not a SuperClaude command")
+		return nil, ErrNotSuperClaudeCommand
 	}
 
 	// Extract persona if specified with /persona:
@@ -72,6 +76,8 @@ func ParseSuperClaudeCommand(input string) (*ParsedCommand, error) {
 
 	// The command is the first part after /user:
 	command = commandPart
+	spec, hasSpec := LookupCommand(command)
+	strict := hasSpec && len(spec.Schema) > 0
 
 	// Parse flags and extract target
 	flags := &Flags{
@@ -116,6 +122,8 @@ func ParseSuperClaudeCommand(input string) (*ParsedCommand, error) {
 				// Check for persona flags
 				if strings.HasPrefix(flagName, "persona-") {
 					flags.Persona = strings.TrimPrefix(flagName, "persona-")
+				} else if strict {
+					flags.Additional[flagName] = parseDeclaredFlagValue(spec, flagName, parts, &i)
 				} else {
 					// Check if next part is a value
 					if i+1 < len(parts) && !strings.HasPrefix(parts[i+1], "--") {
@@ -135,7 +143,17 @@ func ParseSuperClaudeCommand(input string) (*ParsedCommand, error) {
 
 	target = strings.Join(targetParts, " ")
 
+	// Check command-specific flags against the declared schema
+	if strict {
+		if err := spec.ValidateFlags(flags); err != nil {
+			return nil, err
+		}
+	}
+
 	// If no persona specified, use the default for the command
+	if flags.Persona == "" && hasSpec && spec.Persona != "" {
+		flags.Persona = spec.Persona
+	}
 	if flags.Persona == "" {
 		// Check if specific flags should influence persona selection
 		if command == "build" && flags.Additional["react"] == "true" {
@@ -153,6 +171,30 @@ func ParseSuperClaudeCommand(input string) (*ParsedCommand, error) {
 	}, nil
 }
 
+// parseDeclaredFlagValue reads the value of a flag declared in the command schema.
+// Boolean flags only consume an explicit true/false, other flags consume the next
+// token unless it is another flag. A missing value is left empty for the default.
+func parseDeclaredFlagValue(cmd SuperClaudeCommand, name string, parts []string, i *int) string {
+	next := ""
+	if *i+1 < len(parts) && !strings.HasPrefix(parts[*i+1], "--") {
+		next = parts[*i+1]
+	}
+
+	if flagSpec, ok := cmd.FlagSpec(name); ok && flagSpec.Type == FlagTypeBool {
+		if next == "true" || next == "false" {
+			*i++
+			return next
+		}
+		return "true"
+	}
+
+	if next == "" {
+		return ""
+	}
+	*i++
+	return next
+}
+
 // GetThinkingTokens returns the max tokens based on thinking mode
 func GetThinkingTokens(thinkMode string) int {
 	switch thinkMode {
@@ -171,7 +213,7 @@ func GetThinkingTokens(thinkMode string) int {
 func (f *Flags) Validate() error {
 	// Validate persona
 	if f.Persona != "" {
-		if _, exists := Personas[f.Persona]; !exists {
+		if _, exists := LookupPersona(f.Persona); !exists {
 			return fmt.Errorf("unknown persona: %s", f.Persona)
 		}
 	}
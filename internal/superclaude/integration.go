@@ -2,55 +2,335 @@ package superclaude
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/provider"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
 	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/opencode-ai/opencode/internal/ratelimit"
+	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/opencode-ai/opencode/internal/snapshot"
 )
 
-// GetAvailableCommands returns all available SuperClaude commands
+// GetAvailableCommands returns all available SuperClaude commands, including file-based ones
 func GetAvailableCommands() []string {
-	commands := make([]string, 0, len(Commands))
+	custom := GetCustomCommands()
+	commands := make([]string, 0, len(Commands)+len(custom))
 	for cmd := range Commands {
 		commands = append(commands, cmd)
 	}
+	for cmd := range custom {
+		commands = append(commands, cmd)
+	}
 	return commands
 }
 
-// GetAvailablePersonas returns all available personas
+// GetAvailablePersonas returns all available personas, including user-defined ones
 func GetAvailablePersonas() []string {
-	personas := make([]string, 0, len(Personas))
+	custom := GetCustomPersonas()
+	personas := make([]string, 0, len(Personas)+len(custom))
 	for name := range Personas {
 		personas = append(personas, name)
 	}
+	for name := range custom {
+		personas = append(personas, name)
+	}
 	return personas
 }
 
 // GetAvailableFlags returns all available flags
 func GetAvailableFlags() []string {
-	return []string{
-		"--uc", "--ultracompressed",
-		"--think", "--think-hard", "--ultrathink",
-		"--plan",
-		"--evidence", "--c7",
-		"--validate", "--validation-only",
-		"--seq", "--sequential",
-		"--all-mcp",
-		"--persona-<name>",
+	flags := make([]string, 0, len(coreFlags)+1)
+	for _, flag := range coreFlags {
+		flags = append(flags, "--"+flag.Name)
 	}
+	return append(flags, "--persona-<name>")
 }
 
 // SuperClaudeHandler handles SuperClaude commands within OpenCode
 type SuperClaudeHandler struct {
-	agent agent.Service
+	*pubsub.Broker[SpawnEvent]
+	agent    agent.Service
+	sessions session.Service
+	messages message.Service
+
+	spawnTools func() []tools.BaseTool
+	spawnRuns  sync.Map
+	// spawnAgent creates the agent running one sub-task of a spawn
+	spawnAgent func(persona Persona) (agent.Service, error)
+	// spawnedAgents holds the running sub-agents by task session ID
+	spawnedAgents sync.Map
+	mcp           *agent.MCPManager
+
+	optimizer *Optimizer
+
+	personaAgents sync.Map
+
+	thinkingTokens config.ThinkingTokensConfig
+
+	plans        *pubsub.Broker[PlanEvent]
+	planTools    func() []tools.BaseTool
+	planRuns     sync.Map
+	pendingPlans sync.Map
+
+	dryRuns        *pubsub.Broker[DryRunEvent]
+	pendingDryRuns sync.Map
+
+	evidencePolicy EvidencePolicy
+
+	snapshots snapshot.Service
+
+	results *pubsub.Broker[CommandResult]
+
+	limiter *ratelimit.Limiter
+
+	quotas agent.QuotaEnforcer
+}
+
+// HandlerOption configures a SuperClaudeHandler
+type HandlerOption func(*SuperClaudeHandler)
+
+// WithSpawnTools sets the tool factory used for agents started by /user:spawn
+func WithSpawnTools(factory func() []tools.BaseTool) HandlerOption {
+	return func(h *SuperClaudeHandler) {
+		h.spawnTools = factory
+	}
+}
+
+// WithMCPTools offers the tools of the MCP servers to persona and spawned agents,
+// looked up on every turn like the main agent does
+func WithMCPTools(manager *agent.MCPManager) HandlerOption {
+	return func(h *SuperClaudeHandler) {
+		h.mcp = manager
+	}
+}
+
+// WithPlanTools sets the read-only tool factory used to propose --plan plans
+func WithPlanTools(factory func() []tools.BaseTool) HandlerOption {
+	return func(h *SuperClaudeHandler) {
+		h.planTools = factory
+	}
+}
+
+// WithOptimizer routes commands through the optimizer and its result cache
+func WithOptimizer(optimizer *Optimizer) HandlerOption {
+	return func(h *SuperClaudeHandler) {
+		h.optimizer = optimizer
+	}
+}
+
+// WithThinkingTokens sets the reasoning budgets used for --think, --think-hard and
+// --ultrathink. Zero values fall back to GetThinkingTokens.
+func WithThinkingTokens(tokens config.ThinkingTokensConfig) HandlerOption {
+	return func(h *SuperClaudeHandler) {
+		h.thinkingTokens = tokens
+	}
+}
+
+// WithEvidencePolicy sets how --evidence treats claims with unverified citations
+func WithEvidencePolicy(policy EvidencePolicy) HandlerOption {
+	return func(h *SuperClaudeHandler) {
+		h.evidencePolicy = policy
+	}
+}
+
+// WithSnapshots snapshots the working tree before the turns of persona agents, the
+// same way the main agent does
+func WithSnapshots(snapshots snapshot.Service) HandlerOption {
+	return func(h *SuperClaudeHandler) {
+		h.snapshots = snapshots
+	}
+}
+
+// WithRateLimiter limits the commands run per session and in total
+func WithRateLimiter(limiter *ratelimit.Limiter) HandlerOption {
+	return func(h *SuperClaudeHandler) {
+		h.limiter = limiter
+	}
+}
+
+// WithQuotas checks the runs of the agents the handler creates, such as persona and
+// spawned agents, against quotas
+func WithQuotas(quotas agent.QuotaEnforcer) HandlerOption {
+	return func(h *SuperClaudeHandler) {
+		h.quotas = quotas
+	}
 }
 
 // NewSuperClaudeHandler creates a new SuperClaude handler
-func NewSuperClaudeHandler(agent agent.Service) *SuperClaudeHandler {
-	return &SuperClaudeHandler{
-		agent: agent,
+func NewSuperClaudeHandler(agent agent.Service, sessions session.Service, messages message.Service, opts ...HandlerOption) *SuperClaudeHandler {
+	h := &SuperClaudeHandler{
+		Broker:  pubsub.NewBroker[SpawnEvent](),
+		plans:   pubsub.NewBroker[PlanEvent](),
+		dryRuns: pubsub.NewBroker[DryRunEvent](),
+		results: pubsub.NewBroker[CommandResult](),
+
+		evidencePolicy: EvidencePolicyFlag,
+		agent:          agent,
+		sessions:       sessions,
+		messages:       messages,
+	}
+	h.spawnAgent = h.newSpawnedAgent
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Cancel stops any spawned sub-agents, pending plans and the agent run for the
+// session. The task session of a sub-agent stops that sub-agent alone.
+func (h *SuperClaudeHandler) Cancel(sessionID string) {
+	h.CancelSpawnedAgent(sessionID)
+	if cancelFunc, exists := h.spawnRuns.LoadAndDelete(sessionID); exists {
+		if cancel, ok := cancelFunc.(context.CancelFunc); ok {
+			logging.InfoPersist(fmt.Sprintf("Spawn cancellation initiated for session: %s", sessionID))
+			cancel()
+		}
+	}
+	if cancelFunc, exists := h.planRuns.LoadAndDelete(sessionID); exists {
+		if cancel, ok := cancelFunc.(context.CancelFunc); ok {
+			logging.InfoPersist(fmt.Sprintf("Plan cancellation initiated for session: %s", sessionID))
+			cancel()
+		}
+	}
+	h.agent.Cancel(sessionID)
+	h.personaAgents.Range(func(_, value interface{}) bool {
+		value.(agent.Service).Cancel(sessionID)
+		return true
+	})
+}
+
+// agentOptions returns opts with the options every agent of the handler shares
+func (h *SuperClaudeHandler) agentOptions(opts ...agent.Option) []agent.Option {
+	if h.quotas != nil {
+		opts = append(opts, agent.WithQuotas(h.quotas))
+	}
+	return opts
+}
+
+// toolOptions returns the options that offer the MCP tools a persona may use
+func (h *SuperClaudeHandler) toolOptions(persona Persona) []agent.Option {
+	if h.mcp == nil {
+		return nil
 	}
+	return []agent.Option{agent.WithMCPTools(h.mcp, persona.AllowedTools...)}
+}
+
+// agentFor returns the agent that runs commands for a persona. Personas that set
+// their own model or restrict the tools get a dedicated agent.
+func (h *SuperClaudeHandler) agentFor(persona Persona) (agent.Service, error) {
+	if persona.Model == "" && len(persona.AllowedTools) == 0 {
+		return h.agent, nil
+	}
+
+	key := fmt.Sprintf("%s|%s|%s", persona.Name, persona.Model, strings.Join(persona.AllowedTools, ","))
+	if existing, ok := h.personaAgents.Load(key); ok {
+		return existing.(agent.Service), nil
+	}
+
+	if h.spawnTools == nil {
+		return nil, fmt.Errorf("persona %s requires a tool factory", persona.Name)
+	}
+	agentOpts := h.toolOptions(persona)
+	if h.snapshots != nil {
+		agentOpts = append(agentOpts, agent.WithSnapshots(h.snapshots))
+	}
+	agentOpts = h.agentOptions(agentOpts...)
+	personaAgent, err := agent.NewAgentWithModel(
+		config.AgentCoder,
+		models.ModelID(persona.Model),
+		h.sessions,
+		h.messages,
+		filterTools(h.spawnTools(), persona.AllowedTools),
+		agentOpts...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating agent for persona %s: %w", persona.Name, err)
+	}
+
+	actual, _ := h.personaAgents.LoadOrStore(key, personaAgent)
+	return actual.(agent.Service), nil
+}
+
+// EvictPersonaAgents drops the cached agents of personas, so they are created again
+// from the current persona definitions. Agents that are running are kept until the
+// next eviction.
+func (h *SuperClaudeHandler) EvictPersonaAgents() {
+	h.personaAgents.Range(func(key, value interface{}) bool {
+		if !value.(agent.Service).IsBusy() {
+			h.personaAgents.Delete(key)
+		}
+		return true
+	})
+}
+
+// applyPersonaDefaults fills flags the user left unset from the persona
+func applyPersonaDefaults(flags *Flags, persona Persona) {
+	if flags.Think == "" && persona.Thinking != "" {
+		flags.Think = persona.Thinking
+	}
+}
+
+// filterTools keeps only the allowed tools, an empty allow list keeps everything
+func filterTools(all []tools.BaseTool, allowed []string) []tools.BaseTool {
+	if len(allowed) == 0 {
+		return all
+	}
+
+	allow := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		allow[name] = true
+	}
+
+	var filtered []tools.BaseTool
+	for _, tool := range all {
+		if allow[tool.Info().Name] {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
+}
+
+// IsSpawnRunning reports whether sub-agents are running for the session
+func (h *SuperClaudeHandler) IsSpawnRunning(sessionID string) bool {
+	_, running := h.spawnRuns.Load(sessionID)
+	return running
+}
+
+// IsSessionBusy reports whether any agent of the handler is working in the session:
+// the main agent, a persona agent, spawned sub-agents or an approved plan
+func (h *SuperClaudeHandler) IsSessionBusy(sessionID string) bool {
+	if h.agent != nil && h.agent.IsSessionBusy(sessionID) {
+		return true
+	}
+	if h.IsSpawnRunning(sessionID) {
+		return true
+	}
+	if _, running := h.planRuns.Load(sessionID); running {
+		return true
+	}
+	busy := false
+	h.spawnedAgents.Range(func(_, value interface{}) bool {
+		busy = value.(*spawnedAgent).parentSessionID == sessionID
+		return !busy
+	})
+	if busy {
+		return true
+	}
+	h.personaAgents.Range(func(_, value interface{}) bool {
+		busy = value.(agent.Service).IsSessionBusy(sessionID)
+		return !busy
+	})
+	return busy
 }
 
 // HandleCommand processes a potential SuperClaude command
@@ -59,42 +339,50 @@ func (h *SuperClaudeHandler) HandleCommand(ctx context.Context, sessionID string
 	if sessionID == "" {
 		return false, fmt.Errorf("session ID is required")
 	}
-	
+
 	// Try to parse as SuperClaude command
 	parsed, err := ParseSuperClaudeCommand(input)
-	if err != nil {
+	if errors.Is(err, ErrNotSuperClaudeCommand) {
 		// Not a SuperClaude command, let OpenCode handle it normally
 		return false, nil
 	}
+	if err != nil {
+		return true, err
+	}
 
 	// Validate flags
 	if err := parsed.Flags.Validate(); err != nil {
 		return true, fmt.Errorf("invalid flags: %w", err)
 	}
 
-	// Get the command
-	cmd, exists := Commands[parsed.Command]
-	if !exists {
-		return true, fmt.Errorf("unknown command: %s", parsed.Command)
+	if err := h.limiter.Allow(ratelimit.Key{SessionID: sessionID}); err != nil {
+		return true, err
 	}
 
-	// Get the persona
-	persona := GetPersona(parsed.Flags.Persona)
+	// Sub-agent fan-out cannot be collected into a single dry run
+	if parsed.Flags.ValidationOnly && (parsed.Command == "collab" || parsed.Command == "spawn") {
+		return true, fmt.Errorf("--validate is not supported for /user:%s", parsed.Command)
+	}
 
-	// Build the enhanced prompt
-	prompt, err := cmd.BuildPrompt(persona, parsed.Flags, parsed.Target, parsed.RawInput)
-	if err != nil {
-		return true, fmt.Errorf("failed to build prompt: %w", err)
+	// Collaboration patterns fan out to one sub-session per persona
+	if parsed.Command == "collab" {
+		return true, h.handleCollaboration(ctx, sessionID, parsed)
 	}
 
-	// Apply thinking mode by adjusting context
-	if parsed.Flags.Think != "" {
-		prompt = applyThinkingMode(prompt, parsed.Flags.Think)
+	// Spawn decomposes the target across independent sub-agents
+	if parsed.Command == "spawn" {
+		return true, h.handleSpawn(ctx, sessionID, parsed)
 	}
 
-	// Apply ultra-compressed mode
-	if parsed.Flags.UltraCompressed {
-		prompt = applyUltraCompressed(prompt)
+	// Build the enhanced prompt
+	prompt, persona, err := RenderPrompt(parsed)
+	if err != nil {
+		return true, err
+	}
+
+	runner, err := h.agentFor(persona)
+	if err != nil {
+		return true, err
 	}
 
 	// Log the SuperClaude command execution
@@ -104,19 +392,158 @@ func (h *SuperClaudeHandler) HandleCommand(ctx context.Context, sessionID string
 		"target", parsed.Target,
 		"flags", formatFlags(parsed.Flags))
 
+	ctx = h.withThinking(ctx, runner.Model(), parsed.Command, parsed.Flags)
+
+	// Plan mode proposes a read-only plan and waits for approval before executing
+	if parsed.Flags.Plan {
+		return true, h.handlePlan(ctx, sessionID, parsed, persona, runner, prompt)
+	}
+
+	// Validate mode runs against an in-memory overlay and reports the combined diff
+	if parsed.Flags.ValidationOnly {
+		return true, h.handleDryRun(ctx, sessionID, parsed, runner, prompt)
+	}
+
+	// Route through the optimizer so unchanged targets are answered from the cache.
+	// Evidence is verified against the ledger of a live turn, so it is never cached.
+	if h.optimizer != nil && h.messages != nil && !parsed.Flags.Evidence {
+		return true, h.executeOptimized(ctx, sessionID, runner, parsed, persona, prompt)
+	}
+
 	// Execute through the agent with the enhanced prompt
-	events, err := h.agent.Run(ctx, sessionID, prompt)
+	events, err := runner.Run(ctx, sessionID, prompt)
 	if err != nil {
 		return true, err
 	}
 
 	// Handle the response events
-	go h.handleAgentEvents(events, parsed)
+	go h.handleAgentEvents(events, sessionID, parsed)
 
 	return true, nil
 }
 
-// applyThinkingMode enhances the prompt for different thinking levels
+// RenderPrompt expands a parsed command into the prompt sent to the agent. Persona
+// defaults are applied to the flags, followed by the thinking and compression modes.
+func RenderPrompt(parsed *ParsedCommand) (string, Persona, error) {
+	cmd, exists := LookupCommand(parsed.Command)
+	if !exists {
+		return "", Persona{}, fmt.Errorf("unknown command: %s", parsed.Command)
+	}
+
+	persona := GetPersona(parsed.Flags.Persona)
+	applyPersonaDefaults(parsed.Flags, persona)
+
+	prompt, err := cmd.BuildPrompt(persona, parsed.Flags, parsed.Target, parsed.RawInput)
+	if err != nil {
+		return "", Persona{}, fmt.Errorf("failed to build prompt: %w", err)
+	}
+
+	// Apply thinking mode by adjusting context
+	if parsed.Flags.Think != "" {
+		prompt = applyThinkingMode(prompt, parsed.Flags.Think)
+	}
+
+	// Apply ultra-compressed mode
+	if parsed.Flags.UltraCompressed {
+		prompt = applyUltraCompressed(prompt)
+	}
+	return prompt, persona, nil
+}
+
+// writeReport stores an aggregated report as an assistant message and rolls the cost up to the session
+func (h *SuperClaudeHandler) writeReport(ctx context.Context, sessionID, report string, cost float64) error {
+	_, err := h.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: report},
+			message.Finish{
+				Reason: message.FinishReasonEndTurn,
+				Time:   time.Now().Unix(),
+			},
+		},
+		Model: h.agent.Model().ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cost == 0 {
+		return nil
+	}
+
+	parent, err := h.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	parent.Cost += cost
+	_, err = h.sessions.Save(ctx, parent)
+	return err
+}
+
+// thinkingBudget returns the configured reasoning budget for a thinking level
+func (h *SuperClaudeHandler) thinkingBudget(thinkMode string) int {
+	var configured int
+	switch thinkMode {
+	case "ultra":
+		configured = h.thinkingTokens.Ultra
+	case "deep":
+		configured = h.thinkingTokens.Deep
+	case "standard":
+		configured = h.thinkingTokens.Standard
+	}
+	if configured > 0 {
+		return configured
+	}
+	return GetThinkingTokens(thinkMode)
+}
+
+// reasoningEffort maps a thinking level to an OpenAI reasoning effort
+func reasoningEffort(thinkMode string) string {
+	switch thinkMode {
+	case "ultra":
+		return "high"
+	case "deep":
+		return "medium"
+	case "standard":
+		return "low"
+	default:
+		return ""
+	}
+}
+
+// thinkingOptions returns the provider options for a command's thinking level. The
+// output tokens are only raised for models that reason, up to the model's limit.
+func (h *SuperClaudeHandler) thinkingOptions(model models.Model, command string, flags *Flags) []provider.CallOption {
+	if flags.Think == "" {
+		return nil
+	}
+	budget := h.thinkingBudget(flags.Think)
+	opts := []provider.CallOption{
+		provider.WithThinkingBudget(int64(budget)),
+		provider.WithCallReasoningEffort(reasoningEffort(flags.Think)),
+	}
+	if model.CanReason {
+		maxTokens := int64(maxTokensForCommand(command, flags, budget))
+		if model.DefaultMaxTokens > 0 && maxTokens > model.DefaultMaxTokens {
+			maxTokens = model.DefaultMaxTokens
+		}
+		opts = append(opts, provider.WithCallMaxTokens(maxTokens))
+	}
+	return opts
+}
+
+// withThinking passes the native reasoning options for the flags to runs of the
+// given model using ctx
+func (h *SuperClaudeHandler) withThinking(ctx context.Context, model models.Model, command string, flags *Flags) context.Context {
+	opts := h.thinkingOptions(model, command, flags)
+	if len(opts) == 0 {
+		return ctx
+	}
+	return agent.WithCallOptions(ctx, opts...)
+}
+
+// applyThinkingMode enhances the prompt for different thinking levels. The native
+// reasoning budget is set by withThinking, the prompt guides models without one.
 func applyThinkingMode(prompt string, thinkMode string) string {
 	prefix := ""
 
@@ -186,31 +613,46 @@ func formatFlags(flags *Flags) string {
 }
 
 // handleAgentEvents processes events from the agent
-func (h *SuperClaudeHandler) handleAgentEvents(events <-chan agent.AgentEvent, parsed *ParsedCommand) {
+func (h *SuperClaudeHandler) handleAgentEvents(events <-chan agent.AgentEvent, sessionID string, parsed *ParsedCommand) {
 	for event := range events {
 		switch event.Type {
 		case agent.AgentEventTypeResponse:
-			// Response handled by OpenCode's UI
-			continue
+			// Response handled by OpenCode's UI, --evidence verifies its citations first
+			msg := event.Message
+			if parsed.Flags.Evidence && h.messages != nil {
+				updated, err := h.attachEvidence(context.Background(), msg, event.Evidence)
+				if err != nil {
+					logging.ErrorPersist(fmt.Sprintf("failed to attach evidence: %v", err))
+				} else {
+					msg = updated
+				}
+			}
+			h.publishResult(CommandResult{SessionID: sessionID, Content: msg.Content().String()})
 		case agent.AgentEventTypeError:
 			logging.Error("SuperClaude command error",
 				"command", parsed.Command,
 				"error", event.Error)
+			h.publishResult(CommandResult{SessionID: sessionID, Error: event.Error})
 		}
 	}
 }
 
 // GetMaxTokensForCommand returns appropriate token limit based on command and flags
 func GetMaxTokensForCommand(parsed *ParsedCommand) int {
+	return maxTokensForCommand(parsed.Command, parsed.Flags, GetThinkingTokens(parsed.Flags.Think))
+}
+
+// maxTokensForCommand scales the thinking budget by how verbose the command's output is
+func maxTokensForCommand(command string, flags *Flags, thinkingTokens int) int {
 	baseTokens := 4096
 
 	// Adjust based on thinking mode
-	if parsed.Flags.Think != "" {
-		baseTokens = GetThinkingTokens(parsed.Flags.Think)
+	if flags.Think != "" {
+		baseTokens = thinkingTokens
 	}
 
 	// Adjust based on command type
-	switch parsed.Command {
+	switch command {
 	case "analyze", "design", "explain":
 		baseTokens = int(float64(baseTokens) * 1.5)
 	case "build", "improve", "refactor":
@@ -218,7 +660,7 @@ func GetMaxTokensForCommand(parsed *ParsedCommand) int {
 	}
 
 	// Reduce for ultra-compressed mode
-	if parsed.Flags.UltraCompressed {
+	if flags.UltraCompressed {
 		baseTokens = int(float64(baseTokens) * 0.7)
 	}
 
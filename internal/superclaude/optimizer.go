@@ -3,29 +3,37 @@ package superclaude
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/opencode-ai/opencode/internal/history"
 	"github.com/opencode-ai/opencode/internal/logging"
 )
 
 // Optimizer provides performance optimizations for SuperClaude
 type Optimizer struct {
 	// Response caching
-	cache      sync.Map
-	cacheSize  int
-	cacheTTL   time.Duration
-	
+	cache     sync.Map
+	cacheSize int
+	cacheTTL  time.Duration
+
 	// Request batching
 	batchQueue chan *OptimizedRequest
 	batchSize  int
 	batchDelay time.Duration
-	
+
 	// Resource pooling
 	workerPool *WorkerPool
-	
+
+	// Filesystem invalidation
+	watcherMu sync.Mutex
+	watcher   *fsnotify.Watcher
+	watched   map[string]bool
+
 	// Metrics
 	metrics *Metrics
 }
@@ -37,6 +45,13 @@ type OptimizedRequest struct {
 	Context   context.Context
 	Response  chan *OptimizedResponse
 	Timestamp time.Time
+
+	// Key is the content-addressed cache key, requests without a key are never cached
+	Key string
+	// Dependencies are the files and directories the cached result depends on
+	Dependencies []string
+	// Execute produces the result on a cache miss
+	Execute func(ctx context.Context) (interface{}, error)
 }
 
 // OptimizedResponse contains the response and metrics
@@ -60,6 +75,7 @@ type Metrics struct {
 	mu            sync.RWMutex
 	totalRequests int64
 	cacheHits     int64
+	invalidations int64
 	avgDuration   time.Duration
 	peakMemory    uint64
 }
@@ -72,22 +88,23 @@ func NewOptimizer() *Optimizer {
 		batchSize:  10,
 		batchDelay: 100 * time.Millisecond,
 		batchQueue: make(chan *OptimizedRequest, 100),
+		watched:    make(map[string]bool),
 		metrics:    &Metrics{},
 	}
-	
+
 	// Initialize worker pool based on CPU cores
 	numWorkers := runtime.NumCPU() * 2
 	opt.workerPool = NewWorkerPool(numWorkers)
-	
+
 	// Start batch processor
 	go opt.processBatches()
-	
+
 	// Start cache cleaner
 	go opt.cleanCache()
-	
+
 	// Start metrics collector
 	go opt.collectMetrics()
-	
+
 	return opt
 }
 
@@ -97,12 +114,12 @@ func NewWorkerPool(workers int) *WorkerPool {
 		workers:   workers,
 		taskQueue: make(chan func(), workers*2),
 	}
-	
+
 	// Start workers
 	for i := 0; i < workers; i++ {
 		go wp.worker()
 	}
-	
+
 	return wp
 }
 
@@ -127,55 +144,65 @@ func (wp *WorkerPool) Wait() {
 	wp.wg.Wait()
 }
 
-// OptimizeCommand optimizes a SuperClaude command execution
-func (opt *Optimizer) OptimizeCommand(ctx context.Context, sessionID, command string) (*OptimizedResponse, error) {
+// Lookup returns the cached entry for a key if it is still valid
+func (opt *Optimizer) Lookup(key string) (*CacheEntry, bool) {
+	if key == "" {
+		return nil, false
+	}
+	cached, ok := opt.cache.Load(key)
+	if !ok {
+		return nil, false
+	}
+	entry, ok := cached.(*CacheEntry)
+	if !ok || entry.IsExpired() {
+		opt.cache.Delete(key)
+		return nil, false
+	}
+
+	opt.recordCacheHit()
+	opt.recordRequest(0)
+	return entry, true
+}
+
+// OptimizeCommand runs a request through the cache and the batch queue.
+// Identical requests that land in the same batch share a single execution.
+func (opt *Optimizer) OptimizeCommand(ctx context.Context, req *OptimizedRequest) (*OptimizedResponse, error) {
 	start := time.Now()
-	
-	// Check cache first
-	cacheKey := fmt.Sprintf("%s:%s", sessionID, command)
-	if cached, ok := opt.cache.Load(cacheKey); ok {
-		if entry, ok := cached.(*CacheEntry); ok && !entry.IsExpired() {
-			opt.recordCacheHit()
-			return &OptimizedResponse{
-				Result:   entry.Data,
-				CacheHit: true,
-				Duration: time.Since(start),
-			}, nil
-		}
+
+	if req.Execute == nil {
+		return nil, fmt.Errorf("request for %q has nothing to execute", req.Command)
 	}
-	
-	// Create optimized request
-	req := &OptimizedRequest{
-		Command:   command,
-		SessionID: sessionID,
-		Context:   ctx,
-		Response:  make(chan *OptimizedResponse, 1),
-		Timestamp: time.Now(),
+
+	// Check cache first
+	if entry, ok := opt.Lookup(req.Key); ok {
+		return &OptimizedResponse{
+			Result:   entry.Data,
+			CacheHit: true,
+			Duration: time.Since(start),
+		}, nil
 	}
-	
+
+	req.Context = ctx
+	req.Response = make(chan *OptimizedResponse, 1)
+	req.Timestamp = start
+
 	// Try to batch with other requests
 	select {
 	case opt.batchQueue <- req:
 		// Added to batch queue
 	case <-time.After(opt.batchDelay):
 		// Process immediately if queue is full
-		opt.processSingleRequest(req)
+		opt.workerPool.Submit(func() {
+			opt.processSingleRequest(req)
+		})
 	}
-	
+
 	// Wait for response
 	select {
 	case resp := <-req.Response:
-		// Cache successful responses
-		if resp.Error == nil && !resp.CacheHit {
-			opt.cache.Store(cacheKey, &CacheEntry{
-				Data:      resp.Result,
-				Timestamp: time.Now(),
-			})
-		}
-		
 		opt.recordRequest(time.Since(start))
 		return resp, nil
-		
+
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
@@ -185,144 +212,259 @@ func (opt *Optimizer) OptimizeCommand(ctx context.Context, sessionID, command st
 func (opt *Optimizer) processBatches() {
 	ticker := time.NewTicker(opt.batchDelay)
 	defer ticker.Stop()
-	
+
 	batch := make([]*OptimizedRequest, 0, opt.batchSize)
-	
+
 	for {
 		select {
 		case req := <-opt.batchQueue:
 			batch = append(batch, req)
-			
+
 			// Process batch if full
 			if len(batch) >= opt.batchSize {
 				opt.processBatch(batch)
-				batch = batch[:0]
+				batch = make([]*OptimizedRequest, 0, opt.batchSize)
 			}
-			
+
 		case <-ticker.C:
 			// Process partial batch
 			if len(batch) > 0 {
 				opt.processBatch(batch)
-				batch = batch[:0]
+				batch = make([]*OptimizedRequest, 0, opt.batchSize)
 			}
 		}
 	}
 }
 
-// processBatch processes a batch of requests in parallel
+// processBatch hands a batch to the worker pool, grouping requests with the same cache key
 func (opt *Optimizer) processBatch(batch []*OptimizedRequest) {
 	logging.Debug("Processing batch", "size", len(batch))
-	
-	// Group by command type for better batching
+
 	groups := make(map[string][]*OptimizedRequest)
-	for _, req := range batch {
-		cmdType := extractCommandType(req.Command)
-		groups[cmdType] = append(groups[cmdType], req)
-	}
-	
-	// Process each group in parallel
-	var wg sync.WaitGroup
-	for cmdType, reqs := range groups {
-		wg.Add(1)
-		go func(ct string, requests []*OptimizedRequest) {
-			defer wg.Done()
-			opt.processCommandGroup(ct, requests)
-		}(cmdType, reqs)
+	var order []string
+	for i, req := range batch {
+		key := req.Key
+		if key == "" {
+			// Uncached requests always run on their own
+			key = fmt.Sprintf("uncached:%d", i)
+		}
+		if _, exists := groups[key]; !exists {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], req)
 	}
-	
-	wg.Wait()
-}
 
-// processCommandGroup processes a group of similar commands
-func (opt *Optimizer) processCommandGroup(cmdType string, requests []*OptimizedRequest) {
-	// Special optimization for certain command types
-	switch cmdType {
-	case "analyze":
-		// Combine analyze requests for the same directory
-		opt.combineAnalyzeRequests(requests)
-	case "test":
-		// Run tests in parallel
-		opt.parallelTestRequests(requests)
-	default:
-		// Process individually
-		for _, req := range requests {
-			opt.processSingleRequest(req)
-		}
+	for _, key := range order {
+		requests := groups[key]
+		opt.workerPool.Submit(func() {
+			opt.processGroup(requests)
+		})
 	}
 }
 
 // processSingleRequest processes a single request
 func (opt *Optimizer) processSingleRequest(req *OptimizedRequest) {
-	// This would call the actual SuperClaude handler
-	// For now, we'll simulate it
-	result := fmt.Sprintf("Processed: %s", req.Command)
-	
-	req.Response <- &OptimizedResponse{
-		Result:    result,
-		Error:     nil,
-		CacheHit:  false,
-		BatchSize: 1,
-		Duration:  time.Since(req.Timestamp),
-	}
+	opt.processGroup([]*OptimizedRequest{req})
 }
 
-// combineAnalyzeRequests combines multiple analyze requests
-func (opt *Optimizer) combineAnalyzeRequests(requests []*OptimizedRequest) {
-	// Group by target directory
-	targets := make(map[string][]*OptimizedRequest)
-	for _, req := range requests {
-		target := extractTarget(req.Command)
-		targets[target] = append(targets[target], req)
-	}
-	
-	// Analyze each target once and share results
-	for target, reqs := range targets {
-		result := fmt.Sprintf("Combined analysis of %s for %d requests", target, len(reqs))
-		
-		// Send result to all requests
-		for _, req := range reqs {
+// processGroup executes the first request of a group and shares the result with the rest
+func (opt *Optimizer) processGroup(requests []*OptimizedRequest) {
+	leader := requests[0]
+
+	// An identical request may have completed while this one was queued
+	if entry, ok := opt.Lookup(leader.Key); ok {
+		for _, req := range requests {
 			req.Response <- &OptimizedResponse{
-				Result:    result,
-				Error:     nil,
-				CacheHit:  false,
-				BatchSize: len(reqs),
+				Result:    entry.Data,
+				CacheHit:  true,
+				BatchSize: len(requests),
 				Duration:  time.Since(req.Timestamp),
 			}
 		}
+		return
 	}
-}
 
-// parallelTestRequests runs test requests in parallel
-func (opt *Optimizer) parallelTestRequests(requests []*OptimizedRequest) {
-	var wg sync.WaitGroup
-	
-	for _, req := range requests {
-		wg.Add(1)
-		opt.workerPool.Submit(func() {
-			defer wg.Done()
-			opt.processSingleRequest(req)
+	var (
+		result interface{}
+		err    error
+	)
+	func() {
+		defer logging.RecoverPanic("superclaude.optimizer", func() {
+			err = fmt.Errorf("panic while executing %q", leader.Command)
 		})
+		result, err = leader.Execute(leader.Context)
+	}()
+
+	if err == nil && leader.Key != "" {
+		opt.Store(leader.Key, leader.Dependencies, result)
+	}
+
+	for _, req := range requests {
+		req.Response <- &OptimizedResponse{
+			Result:    result,
+			Error:     err,
+			BatchSize: len(requests),
+			Duration:  time.Since(req.Timestamp),
+		}
 	}
-	
-	wg.Wait()
 }
 
 // CacheEntry represents a cached response
 type CacheEntry struct {
-	Data      interface{}
-	Timestamp time.Time
+	Data         interface{}
+	Timestamp    time.Time
+	Dependencies []string
+	TTL          time.Duration
 }
 
 // IsExpired checks if the cache entry is expired
 func (ce *CacheEntry) IsExpired() bool {
-	return time.Since(ce.Timestamp) > 15*time.Minute
+	ttl := ce.TTL
+	if ttl == 0 {
+		ttl = 15 * time.Minute
+	}
+	return time.Since(ce.Timestamp) > ttl
+}
+
+// dependsOn reports whether a change to path affects the entry
+func (ce *CacheEntry) dependsOn(path string) bool {
+	for _, dep := range ce.Dependencies {
+		if dep == path || strings.HasPrefix(path, dep+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store caches a result, evicting the oldest entry once the cache is full
+func (opt *Optimizer) Store(key string, dependencies []string, data interface{}) {
+	count := 0
+	var oldestKey interface{}
+	var oldest time.Time
+	opt.cache.Range(func(k, value interface{}) bool {
+		count++
+		if entry, ok := value.(*CacheEntry); ok && (oldestKey == nil || entry.Timestamp.Before(oldest)) {
+			oldestKey, oldest = k, entry.Timestamp
+		}
+		return true
+	})
+	if count >= opt.cacheSize && oldestKey != nil {
+		opt.cache.Delete(oldestKey)
+	}
+
+	opt.cache.Store(key, &CacheEntry{
+		Data:         data,
+		Timestamp:    time.Now(),
+		Dependencies: dependencies,
+		TTL:          opt.cacheTTL,
+	})
+	opt.watchDependencies(dependencies)
+}
+
+// Invalidate drops every cached entry that depends on the given path
+func (opt *Optimizer) Invalidate(path string) int {
+	path = filepath.Clean(path)
+
+	count := 0
+	opt.cache.Range(func(key, value interface{}) bool {
+		if entry, ok := value.(*CacheEntry); ok && entry.dependsOn(path) {
+			opt.cache.Delete(key)
+			count++
+		}
+		return true
+	})
+
+	if count > 0 {
+		opt.metrics.mu.Lock()
+		opt.metrics.invalidations += int64(count)
+		opt.metrics.mu.Unlock()
+		logging.Debug("Invalidated cache entries", "path", path, "count", count)
+	}
+	return count
+}
+
+// WatchHistory invalidates cached results whenever the agent records a file change
+func (opt *Optimizer) WatchHistory(ctx context.Context, files history.Service) {
+	for event := range files.Subscribe(ctx) {
+		path := event.Payload.Path
+		if !filepath.IsAbs(path) {
+			continue
+		}
+		opt.Invalidate(path)
+	}
+}
+
+// WatchFiles invalidates cached results when their files change on disk.
+// Only directories referenced by cached entries are watched.
+func (opt *Optimizer) WatchFiles(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logging.Error("Error creating cache watcher", "error", err)
+		return
+	}
+	defer watcher.Close()
+
+	opt.watcherMu.Lock()
+	opt.watcher = watcher
+	opt.watcherMu.Unlock()
+
+	defer func() {
+		opt.watcherMu.Lock()
+		opt.watcher = nil
+		opt.watched = make(map[string]bool)
+		opt.watcherMu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+				opt.Invalidate(event.Name)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logging.Error("Error watching cached file", "error", err)
+		}
+	}
+}
+
+// watchDependencies adds the directories of a cache entry to the file watcher
+func (opt *Optimizer) watchDependencies(dependencies []string) {
+	opt.watcherMu.Lock()
+	defer opt.watcherMu.Unlock()
+
+	if opt.watcher == nil {
+		return
+	}
+
+	for _, dep := range dependencies {
+		dir := dep
+		if !isDir(dep) {
+			dir = filepath.Dir(dep)
+		}
+		if opt.watched[dir] {
+			continue
+		}
+		if err := opt.watcher.Add(dir); err != nil {
+			logging.Debug("Error watching cached directory", "path", dir, "error", err)
+			continue
+		}
+		opt.watched[dir] = true
+	}
 }
 
 // cleanCache periodically cleans expired cache entries
 func (opt *Optimizer) cleanCache() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
-	
+
 	for range ticker.C {
 		count := 0
 		opt.cache.Range(func(key, value interface{}) bool {
@@ -332,7 +474,7 @@ func (opt *Optimizer) cleanCache() {
 			}
 			return true
 		})
-		
+
 		if count > 0 {
 			logging.Debug("Cleaned cache entries", "count", count)
 		}
@@ -343,22 +485,27 @@ func (opt *Optimizer) cleanCache() {
 func (opt *Optimizer) collectMetrics() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
-	
+
 	for range ticker.C {
 		var m runtime.MemStats
 		runtime.ReadMemStats(&m)
-		
+
 		opt.metrics.mu.Lock()
 		if m.Alloc > opt.metrics.peakMemory {
 			opt.metrics.peakMemory = m.Alloc
 		}
+		totalRequests := opt.metrics.totalRequests
+		cacheHits := opt.metrics.cacheHits
+		invalidations := opt.metrics.invalidations
+		avgDuration := opt.metrics.avgDuration
 		opt.metrics.mu.Unlock()
-		
+
 		logging.Debug("Performance metrics",
-			"total_requests", opt.metrics.totalRequests,
-			"cache_hits", opt.metrics.cacheHits,
+			"total_requests", totalRequests,
+			"cache_hits", cacheHits,
 			"cache_hit_rate", opt.getCacheHitRate(),
-			"avg_duration", opt.metrics.avgDuration,
+			"invalidations", invalidations,
+			"avg_duration", avgDuration,
 			"memory_mb", m.Alloc/1024/1024,
 			"goroutines", runtime.NumGoroutine(),
 		)
@@ -370,9 +517,9 @@ func (opt *Optimizer) collectMetrics() {
 func (opt *Optimizer) recordRequest(duration time.Duration) {
 	opt.metrics.mu.Lock()
 	defer opt.metrics.mu.Unlock()
-	
+
 	opt.metrics.totalRequests++
-	
+
 	// Update average duration
 	if opt.metrics.avgDuration == 0 {
 		opt.metrics.avgDuration = duration
@@ -390,26 +537,10 @@ func (opt *Optimizer) recordCacheHit() {
 func (opt *Optimizer) getCacheHitRate() float64 {
 	opt.metrics.mu.RLock()
 	defer opt.metrics.mu.RUnlock()
-	
+
 	if opt.metrics.totalRequests == 0 {
 		return 0
 	}
-	
-	return float64(opt.metrics.cacheHits) / float64(opt.metrics.totalRequests)
-}
 
-func extractCommandType(command string) string {
-	parts := strings.Split(command, " ")
-	if len(parts) > 0 && strings.HasPrefix(parts[0], "/user:") {
-		return strings.TrimPrefix(parts[0], "/user:")
-	}
-	return "unknown"
+	return float64(opt.metrics.cacheHits) / float64(opt.metrics.totalRequests)
 }
-
-func extractTarget(command string) string {
-	parts := strings.Split(command, " ")
-	if len(parts) > 1 {
-		return parts[1]
-	}
-	return "."
-}
\ No newline at end of file
@@ -0,0 +1,277 @@
+package superclaude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+var personaNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// builtinToolNames are the tools a persona may allow, MCP tools are matched by their
+// "<server>_<tool>" name
+var builtinToolNames = map[string]bool{
+	agent.AgentToolName:       true,
+	tools.BashToolName:        true,
+	tools.DiagnosticsToolName: true,
+	tools.EditToolName:        true,
+	tools.FetchToolName:       true,
+	tools.GlobToolName:        true,
+	tools.GrepToolName:        true,
+	tools.LSToolName:          true,
+	tools.PatchToolName:       true,
+	tools.SourcegraphToolName: true,
+	tools.ViewToolName:        true,
+	tools.WriteToolName:       true,
+}
+
+// personaFile is the on-disk representation of a user-defined persona
+type personaFile struct {
+	Name               string   `yaml:"name"`
+	Identity           string   `yaml:"identity"`
+	CoreBelief         string   `yaml:"core_belief"`
+	DecisionFramework  string   `yaml:"decision_framework"`
+	CommunicationStyle string   `yaml:"communication_style"`
+	Model              string   `yaml:"model"`
+	Thinking           string   `yaml:"thinking"`
+	Tools              []string `yaml:"tools"`
+	ToolPreferences    []string `yaml:"tool_preferences"`
+	Specializations    []string `yaml:"specializations"`
+}
+
+// PersonaLoader loads user-defined personas from disk and keeps them up to date
+type PersonaLoader struct {
+	dirs        []string
+	allowCustom bool
+	mu          sync.Mutex
+
+	// onLoad is called after every load, with mu held
+	onLoad func()
+}
+
+// PersonaDirs returns the user and project persona directories, in load order
+func PersonaDirs(workingDir string) []string {
+	return definitionDirs("personas", workingDir)
+}
+
+// NewPersonaLoader creates a loader for the given directories. Later directories
+// override personas of the same name from earlier ones.
+func NewPersonaLoader(allowCustom bool, dirs ...string) *PersonaLoader {
+	return &PersonaLoader{
+		dirs:        dirs,
+		allowCustom: allowCustom,
+	}
+}
+
+// OnLoad calls fn after every load of the personas, such as the reloads of Watch
+func (l *PersonaLoader) OnLoad(fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onLoad = fn
+}
+
+// Load reads every persona file and replaces the registered custom personas.
+// Invalid files are skipped and reported in the returned error.
+func (l *PersonaLoader) Load() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.onLoad != nil {
+		defer l.onLoad()
+	}
+
+	if !l.allowCustom {
+		SetCustomPersonas(map[string]Persona{})
+		return nil
+	}
+
+	personas := make(map[string]Persona)
+	var errs []error
+	for _, dir := range l.dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to read persona directory %s: %w", dir, err))
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !isDefinitionFile(entry.Name()) {
+				continue
+			}
+			persona, err := LoadPersonaFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			personas[persona.Name] = persona
+		}
+	}
+
+	SetCustomPersonas(personas)
+
+	names := make([]string, 0, len(personas))
+	for name := range personas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	logging.Debug("Loaded custom personas", "personas", strings.Join(names, ","))
+
+	return errors.Join(errs...)
+}
+
+// Watch reloads the personas whenever a persona directory changes
+func (l *PersonaLoader) Watch(ctx context.Context) {
+	if !l.allowCustom {
+		return
+	}
+
+	watchDefinitions(ctx, "personas", l.dirs, func() {
+		if err := l.Load(); err != nil {
+			logging.WarnPersist(fmt.Sprintf("Some personas failed to load: %v", err))
+			return
+		}
+		logging.InfoPersist("Personas reloaded")
+	})
+}
+
+// LoadPersonaFile parses and validates a YAML or Markdown persona definition
+func LoadPersonaFile(path string) (Persona, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Persona{}, fmt.Errorf("failed to read persona %s: %w", path, err)
+	}
+
+	var file personaFile
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		err = parseMarkdownPersona(data, &file)
+	} else {
+		err = decodeStrictYAML(data, &file)
+	}
+	if err != nil {
+		return Persona{}, fmt.Errorf("invalid persona %s: %w", path, err)
+	}
+
+	if file.Name == "" {
+		file.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	persona := Persona{
+		Name:               file.Name,
+		Identity:           strings.TrimSpace(file.Identity),
+		CoreBelief:         strings.TrimSpace(file.CoreBelief),
+		DecisionFramework:  strings.TrimSpace(file.DecisionFramework),
+		CommunicationStyle: strings.TrimSpace(file.CommunicationStyle),
+		ToolPreferences:    file.ToolPreferences,
+		Specializations:    file.Specializations,
+		Model:              file.Model,
+		Thinking:           file.Thinking,
+		AllowedTools:       file.Tools,
+		Source:             path,
+	}
+
+	if err := ValidatePersona(persona); err != nil {
+		return Persona{}, fmt.Errorf("invalid persona %s: %w", path, err)
+	}
+	return persona, nil
+}
+
+// ValidatePersona checks a user-defined persona before it is registered
+func ValidatePersona(p Persona) error {
+	if !personaNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits and dashes", p.Name)
+	}
+	if _, exists := Personas[p.Name]; exists {
+		return fmt.Errorf("name %q conflicts with a built-in persona", p.Name)
+	}
+
+	required := []struct {
+		field string
+		value string
+	}{
+		{"identity", p.Identity},
+		{"core_belief", p.CoreBelief},
+		{"decision_framework", p.DecisionFramework},
+		{"communication_style", p.CommunicationStyle},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return fmt.Errorf("%s is required", r.field)
+		}
+	}
+
+	if p.Model != "" {
+		if _, ok := models.SupportedModels[models.ModelID(p.Model)]; !ok {
+			return fmt.Errorf("unsupported model: %s", p.Model)
+		}
+	}
+
+	switch p.Thinking {
+	case "", "standard", "deep", "ultra":
+	default:
+		return fmt.Errorf("thinking must be one of standard, deep, ultra, got %q", p.Thinking)
+	}
+
+	for _, tool := range p.AllowedTools {
+		if !builtinToolNames[tool] && !strings.Contains(tool, "_") {
+			return fmt.Errorf("unknown tool: %s", tool)
+		}
+	}
+
+	return nil
+}
+
+// parseMarkdownPersona reads YAML front matter and "## Section" headings. Sections
+// fill any of the four persona fields left empty by the front matter.
+func parseMarkdownPersona(data []byte, file *personaFile) error {
+	frontMatter, content, err := splitFrontMatter(data)
+	if err != nil {
+		return err
+	}
+	if frontMatter != nil {
+		if err := decodeStrictYAML(frontMatter, file); err != nil {
+			return err
+		}
+	}
+
+	sections := map[string]*string{
+		"identity":            &file.Identity,
+		"core belief":         &file.CoreBelief,
+		"decision framework":  &file.DecisionFramework,
+		"communication style": &file.CommunicationStyle,
+	}
+
+	var current *string
+	var body strings.Builder
+	flush := func() {
+		if current != nil && *current == "" {
+			*current = strings.TrimSpace(body.String())
+		}
+		body.Reset()
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if heading, ok := strings.CutPrefix(line, "## "); ok {
+			flush()
+			current = sections[strings.ToLower(strings.TrimSpace(heading))]
+			continue
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+
+	return nil
+}
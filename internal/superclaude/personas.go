@@ -1,5 +1,7 @@
 package superclaude
 
+import "sync"
+
 // Persona represents a cognitive archetype with specific characteristics
 type Persona struct {
 	Name               string
@@ -9,6 +11,49 @@ type Persona struct {
 	CommunicationStyle string
 	ToolPreferences    []string
 	Specializations    []string
+
+	// Optional defaults, set by user-defined personas
+	Model        string   // Model used instead of the coder agent's model
+	Thinking     string   // Thinking level applied when no --think flag is given
+	AllowedTools []string // Restricts the tools available to the agent
+	Source       string   // File the persona was loaded from, empty for built-ins
+}
+
+// customPersonas holds the user-defined personas, replaced as a whole on reload
+var customPersonas = struct {
+	sync.RWMutex
+	personas map[string]Persona
+}{personas: map[string]Persona{}}
+
+// SetCustomPersonas replaces the set of user-defined personas
+func SetCustomPersonas(personas map[string]Persona) {
+	customPersonas.Lock()
+	defer customPersonas.Unlock()
+	customPersonas.personas = personas
+}
+
+// GetCustomPersonas returns a snapshot of the user-defined personas
+func GetCustomPersonas() map[string]Persona {
+	customPersonas.RLock()
+	defer customPersonas.RUnlock()
+
+	result := make(map[string]Persona, len(customPersonas.personas))
+	for name, persona := range customPersonas.personas {
+		result[name] = persona
+	}
+	return result
+}
+
+// LookupPersona returns a built-in or user-defined persona by name
+func LookupPersona(name string) (Persona, bool) {
+	if persona, exists := Personas[name]; exists {
+		return persona, true
+	}
+
+	customPersonas.RLock()
+	defer customPersonas.RUnlock()
+	persona, exists := customPersonas.personas[name]
+	return persona, exists
 }
 
 // Personas defines all available cognitive archetypes
@@ -106,7 +151,7 @@ var Personas = map[string]Persona{
 
 // GetPersona returns a persona by name with a default fallback
 func GetPersona(name string) Persona {
-	if persona, exists := Personas[name]; exists {
+	if persona, exists := LookupPersona(name); exists {
 		return persona
 	}
 	// Default to architect if persona not found
@@ -126,7 +171,7 @@ func GetPersonaForCommand(command string) string {
 		"deploy":       "backend",
 		"document":     "mentor",
 		"review":       "analyzer",
-		"analyze":      "analyzer",  // Added analyze mapping
+		"analyze":      "analyzer", // Added analyze mapping
 		"migrate":      "backend",
 		"cleanup":      "refactorer",
 		"explain":      "mentor",
@@ -0,0 +1,414 @@
+package superclaude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// ErrPlanRejected is returned when a plan is rejected instead of approved
+var ErrPlanRejected = errors.New("plan rejected")
+
+// stepDonePattern matches the completion markers the agent writes during execution
+var stepDonePattern = regexp.MustCompile(`(?im)^\W*step\s+(\d+)\s*[:.-]?\s*(?:done|complete|completed)\b`)
+
+// PlanStatus is the lifecycle state of a plan
+type PlanStatus string
+
+const (
+	PlanStatusPending   PlanStatus = "pending"
+	PlanStatusApproved  PlanStatus = "approved"
+	PlanStatusRejected  PlanStatus = "rejected"
+	PlanStatusCompleted PlanStatus = "completed"
+	PlanStatusFailed    PlanStatus = "failed"
+)
+
+// PlanStep is a single step of a plan
+type PlanStep struct {
+	Index       int    `json:"index"`
+	Description string `json:"description"`
+	Done        bool   `json:"done"`
+}
+
+// Plan is the read-only proposal produced by phase one of --plan
+type Plan struct {
+	ID        string     `json:"id"`
+	SessionID string     `json:"session_id"`
+	Command   string     `json:"command"`
+	Steps     []PlanStep `json:"steps"`
+	Status    PlanStatus `json:"status"`
+}
+
+// NewPlanSteps numbers the given step descriptions, skipping blank ones
+func NewPlanSteps(descriptions []string) []PlanStep {
+	steps := make([]PlanStep, 0, len(descriptions))
+	for _, description := range descriptions {
+		description = strings.TrimSpace(description)
+		if description == "" {
+			continue
+		}
+		steps = append(steps, PlanStep{Index: len(steps) + 1, Description: description})
+	}
+	return steps
+}
+
+// Descriptions returns the step descriptions in order
+func (p Plan) Descriptions() []string {
+	descriptions := make([]string, len(p.Steps))
+	for i, step := range p.Steps {
+		descriptions[i] = step.Description
+	}
+	return descriptions
+}
+
+// String renders the plan as a numbered list
+func (p Plan) String() string {
+	var sb strings.Builder
+	for _, step := range p.Steps {
+		mark := " "
+		if step.Done {
+			mark = "x"
+		}
+		fmt.Fprintf(&sb, "%d. [%s] %s\n", step.Index, mark, step.Description)
+	}
+	return sb.String()
+}
+
+// markDone marks a step as completed and reports whether it changed
+func (p *Plan) markDone(index int) bool {
+	if index < 1 || index > len(p.Steps) || p.Steps[index-1].Done {
+		return false
+	}
+	p.Steps[index-1].Done = true
+	return true
+}
+
+// CompletedPlanSteps returns the step numbers the agent reported as done
+func CompletedPlanSteps(content string) []int {
+	var steps []int
+	for _, match := range stepDonePattern.FindAllStringSubmatch(content, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			steps = append(steps, n)
+		}
+	}
+	return steps
+}
+
+// PlanEventType is the type of a plan lifecycle event
+type PlanEventType string
+
+const (
+	PlanEventProposed      PlanEventType = "proposed"
+	PlanEventApproved      PlanEventType = "approved"
+	PlanEventRejected      PlanEventType = "rejected"
+	PlanEventStepCompleted PlanEventType = "step_completed"
+	PlanEventCompleted     PlanEventType = "completed"
+	PlanEventFailed        PlanEventType = "failed"
+)
+
+// PlanEvent reports progress of a --plan run
+type PlanEvent struct {
+	Type      PlanEventType
+	SessionID string
+	Plan      Plan
+	Step      int
+	Error     error
+}
+
+// planDecision is the reviewer's answer to a proposed plan
+type planDecision struct {
+	approved bool
+	steps    []string
+}
+
+// pendingPlan is a proposed plan waiting for approval
+type pendingPlan struct {
+	plan     Plan
+	decision chan planDecision
+}
+
+// SubscribePlans returns plan lifecycle events
+func (h *SuperClaudeHandler) SubscribePlans(ctx context.Context) <-chan pubsub.Event[PlanEvent] {
+	return h.plans.Subscribe(ctx)
+}
+
+func (h *SuperClaudeHandler) publishPlan(event PlanEvent) {
+	h.plans.Publish(pubsub.UpdatedEvent, event)
+}
+
+// PendingPlan returns the plan awaiting approval in a session
+func (h *SuperClaudeHandler) PendingPlan(sessionID string) (Plan, bool) {
+	var found *pendingPlan
+	h.pendingPlans.Range(func(_, value interface{}) bool {
+		pending := value.(*pendingPlan)
+		if pending.plan.SessionID == sessionID {
+			found = pending
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return Plan{}, false
+	}
+	return found.plan, true
+}
+
+// PlanSession returns the session of a plan awaiting approval
+func (h *SuperClaudeHandler) PlanSession(planID string) (string, bool) {
+	value, ok := h.pendingPlans.Load(planID)
+	if !ok {
+		return "", false
+	}
+	return value.(*pendingPlan).plan.SessionID, true
+}
+
+// ApprovePlan approves a pending plan. Non-empty steps replace the proposed ones.
+func (h *SuperClaudeHandler) ApprovePlan(planID string, steps []string) error {
+	if steps != nil && len(NewPlanSteps(steps)) == 0 {
+		return fmt.Errorf("an approved plan needs at least one step")
+	}
+	return h.decidePlan(planID, planDecision{approved: true, steps: steps})
+}
+
+// RejectPlan rejects a pending plan, nothing is executed
+func (h *SuperClaudeHandler) RejectPlan(planID string) error {
+	return h.decidePlan(planID, planDecision{approved: false})
+}
+
+func (h *SuperClaudeHandler) decidePlan(planID string, decision planDecision) error {
+	value, ok := h.pendingPlans.Load(planID)
+	if !ok {
+		return fmt.Errorf("no pending plan with ID %s", planID)
+	}
+	select {
+	case value.(*pendingPlan).decision <- decision:
+		return nil
+	default:
+		return fmt.Errorf("plan %s has already been decided", planID)
+	}
+}
+
+// handlePlan runs a command in two phases: a read-only agent proposes a plan, and
+// only once the plan is approved does the command run with the plan pinned in its prompt
+func (h *SuperClaudeHandler) handlePlan(ctx context.Context, sessionID string, parsed *ParsedCommand, persona Persona, runner agent.Service, prompt string) error {
+	if h.sessions == nil || h.messages == nil {
+		return fmt.Errorf("--plan requires session and message services")
+	}
+	if _, running := h.planRuns.Load(sessionID); running {
+		return fmt.Errorf("a plan is already running in this session")
+	}
+
+	if _, err := h.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: parsed.RawInput}},
+	}); err != nil {
+		return fmt.Errorf("failed to create user message: %w", err)
+	}
+
+	planCtx, cancel := context.WithCancel(ctx)
+	h.planRuns.Store(sessionID, cancel)
+
+	go func() {
+		defer logging.RecoverPanic("superclaude.plan", nil)
+		defer h.planRuns.Delete(sessionID)
+		defer cancel()
+
+		plan, err := h.proposePlan(planCtx, sessionID, parsed, persona, prompt)
+		if err != nil {
+			h.publishPlan(PlanEvent{Type: PlanEventFailed, SessionID: sessionID, Plan: plan, Error: err})
+			logging.ErrorPersist(fmt.Sprintf("Planning %s failed: %v", parsed.Command, err))
+			return
+		}
+
+		if err := h.writeReport(context.Background(), sessionID, "# Proposed plan\n\n"+plan.String(), 0); err != nil {
+			logging.ErrorPersist(fmt.Sprintf("failed to write plan: %v", err))
+		}
+
+		plan, err = h.awaitPlanDecision(planCtx, plan)
+		if err != nil {
+			if errors.Is(err, ErrPlanRejected) {
+				h.publishPlan(PlanEvent{Type: PlanEventRejected, SessionID: sessionID, Plan: plan})
+				logging.InfoPersist("Plan rejected, nothing was executed")
+				return
+			}
+			h.publishPlan(PlanEvent{Type: PlanEventFailed, SessionID: sessionID, Plan: plan, Error: err})
+			return
+		}
+
+		h.executePlan(planCtx, sessionID, runner, plan, prompt, parsed.Flags.Evidence)
+	}()
+
+	return nil
+}
+
+// proposePlan runs phase one with a read-only agent in a task session
+func (h *SuperClaudeHandler) proposePlan(ctx context.Context, sessionID string, parsed *ParsedCommand, persona Persona, prompt string) (Plan, error) {
+	plan := Plan{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		Command:   parsed.RawInput,
+		Status:    PlanStatusPending,
+	}
+
+	planner, err := agent.NewAgentWithModel(
+		config.AgentTask,
+		models.ModelID(persona.Model),
+		h.sessions,
+		h.messages,
+		h.readOnlyTools(),
+		h.agentOptions()...,
+	)
+	if err != nil {
+		return plan, fmt.Errorf("error creating planner agent: %w", err)
+	}
+
+	session, err := h.sessions.CreateTaskSession(ctx, uuid.New().String(), sessionID, "plan: "+parsed.Command)
+	if err != nil {
+		return plan, fmt.Errorf("error creating planner session: %w", err)
+	}
+
+	output, err := runPrompt(ctx, planner, session.ID, buildPlanningPrompt(prompt))
+	if err != nil {
+		return plan, err
+	}
+
+	plan.Steps = NewPlanSteps(ParseNumberedList(output))
+	if len(plan.Steps) == 0 {
+		return plan, fmt.Errorf("planner returned no steps")
+	}
+	return plan, nil
+}
+
+// readOnlyTools returns the tools available while planning
+func (h *SuperClaudeHandler) readOnlyTools() []tools.BaseTool {
+	if h.planTools == nil {
+		return nil
+	}
+	return h.planTools()
+}
+
+// awaitPlanDecision publishes the plan and blocks until it is approved, rejected or cancelled
+func (h *SuperClaudeHandler) awaitPlanDecision(ctx context.Context, plan Plan) (Plan, error) {
+	pending := &pendingPlan{
+		plan:     plan,
+		decision: make(chan planDecision, 1),
+	}
+	h.pendingPlans.Store(plan.ID, pending)
+	defer h.pendingPlans.Delete(plan.ID)
+
+	h.publishPlan(PlanEvent{Type: PlanEventProposed, SessionID: plan.SessionID, Plan: plan})
+
+	select {
+	case decision := <-pending.decision:
+		if !decision.approved {
+			plan.Status = PlanStatusRejected
+			return plan, ErrPlanRejected
+		}
+		if decision.steps != nil {
+			plan.Steps = NewPlanSteps(decision.steps)
+		}
+		plan.Status = PlanStatusApproved
+		return plan, nil
+	case <-ctx.Done():
+		plan.Status = PlanStatusFailed
+		return plan, ctx.Err()
+	}
+}
+
+// executePlan runs phase two and tracks the steps the agent reports as done
+func (h *SuperClaudeHandler) executePlan(ctx context.Context, sessionID string, runner agent.Service, plan Plan, prompt string, evidence bool) {
+	h.publishPlan(PlanEvent{Type: PlanEventApproved, SessionID: sessionID, Plan: plan})
+
+	trackCtx, stopTracking := context.WithCancel(ctx)
+	defer stopTracking()
+	updates := h.messages.Subscribe(trackCtx)
+
+	events, err := runner.Run(ctx, sessionID, buildExecutionPrompt(prompt, plan))
+	if err != nil {
+		plan.Status = PlanStatusFailed
+		h.publishPlan(PlanEvent{Type: PlanEventFailed, SessionID: sessionID, Plan: plan, Error: err})
+		logging.ErrorPersist(fmt.Sprintf("Executing plan failed: %v", err))
+		h.publishResult(CommandResult{SessionID: sessionID, Error: err})
+		return
+	}
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				updates = nil
+				continue
+			}
+			msg := update.Payload
+			if msg.SessionID == sessionID && msg.Role == message.Assistant {
+				h.trackPlanSteps(&plan, msg.Content().String())
+			}
+		case result := <-events:
+			if result.Error != nil {
+				plan.Status = PlanStatusFailed
+				h.publishPlan(PlanEvent{Type: PlanEventFailed, SessionID: sessionID, Plan: plan, Error: result.Error})
+				h.publishResult(CommandResult{SessionID: sessionID, Error: result.Error})
+				return
+			}
+			msg := result.Message
+			h.trackPlanSteps(&plan, msg.Content().String())
+			if evidence {
+				updated, err := h.attachEvidence(context.Background(), msg, result.Evidence)
+				if err != nil {
+					logging.ErrorPersist(fmt.Sprintf("failed to attach evidence: %v", err))
+				} else {
+					msg = updated
+				}
+			}
+			plan.Status = PlanStatusCompleted
+			h.publishPlan(PlanEvent{Type: PlanEventCompleted, SessionID: sessionID, Plan: plan})
+			h.publishResult(CommandResult{SessionID: sessionID, Content: msg.Content().String()})
+			return
+		}
+	}
+}
+
+// trackPlanSteps publishes a step event for each newly completed step
+func (h *SuperClaudeHandler) trackPlanSteps(plan *Plan, content string) {
+	for _, index := range CompletedPlanSteps(content) {
+		if plan.markDone(index) {
+			h.publishPlan(PlanEvent{Type: PlanEventStepCompleted, SessionID: plan.SessionID, Plan: *plan, Step: index})
+		}
+	}
+}
+
+// buildPlanningPrompt turns a command prompt into a read-only planning request
+func buildPlanningPrompt(prompt string) string {
+	return prompt + `
+
+PLANNING PHASE:
+- You are in read-only mode: investigate with the available tools but do not modify anything
+- Respond ONLY with a numbered list of concrete steps, one step per line
+- The plan is reviewed before any step is executed`
+}
+
+// buildExecutionPrompt pins the approved plan to the command prompt
+func buildExecutionPrompt(prompt string, plan Plan) string {
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\nAPPROVED PLAN:\n")
+	for _, step := range plan.Steps {
+		fmt.Fprintf(&sb, "%d. %s\n", step.Index, step.Description)
+	}
+	sb.WriteString(`
+Execute the approved plan in order and do not go beyond it.
+After finishing each step, write a line "Step N: done" with the step number.`)
+	return sb.String()
+}
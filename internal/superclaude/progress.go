@@ -0,0 +1,175 @@
+package superclaude
+
+import (
+	"context"
+	"strings"
+
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// CommandResult is published when a command has finished in a session, with the
+// final answer or the error that stopped it
+type CommandResult struct {
+	SessionID string
+	Content   string
+	Error     error
+}
+
+// ProgressEventType is the type of a progress event
+type ProgressEventType string
+
+const (
+	ProgressContentDelta     ProgressEventType = "content_delta"
+	ProgressReasoningDelta   ProgressEventType = "reasoning_delta"
+	ProgressToolCallStarted  ProgressEventType = "tool_call_started"
+	ProgressToolCallFinished ProgressEventType = "tool_call_finished"
+	ProgressMessage          ProgressEventType = "message"
+	ProgressError            ProgressEventType = "error"
+)
+
+// ProgressEvent is one step of a command running in a session. Message and error
+// events are final, no events follow them.
+type ProgressEvent struct {
+	Type      ProgressEventType   `json:"type"`
+	SessionID string              `json:"session_id"`
+	MessageID string              `json:"message_id,omitempty"`
+	Delta     string              `json:"delta,omitempty"`
+	ToolCall  *message.ToolCall   `json:"tool_call,omitempty"`
+	Result    *message.ToolResult `json:"tool_result,omitempty"`
+	Content   string              `json:"content,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// SubscribeResults returns the results of finished commands
+func (h *SuperClaudeHandler) SubscribeResults(ctx context.Context) <-chan pubsub.Event[CommandResult] {
+	return h.results.Subscribe(ctx)
+}
+
+func (h *SuperClaudeHandler) publishResult(result CommandResult) {
+	h.results.Publish(pubsub.CreatedEvent, result)
+}
+
+// WatchProgress streams the progress of the next command in a session: text and
+// reasoning deltas, tool calls as they start and finish, and the final message or
+// error. Call it before starting the command so no event is missed. The channel is
+// closed after the final event or when ctx is done.
+func (h *SuperClaudeHandler) WatchProgress(ctx context.Context, sessionID string) <-chan ProgressEvent {
+	ctx, cancel := context.WithCancel(ctx)
+	messages := h.messages.Subscribe(ctx)
+	results := h.results.Subscribe(ctx)
+	progress := make(chan ProgressEvent, 64)
+
+	go func() {
+		defer cancel()
+		defer close(progress)
+
+		tracker := newProgressTracker(sessionID)
+		send := func(event ProgressEvent) bool {
+			select {
+			case progress <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-messages:
+				if !ok {
+					return
+				}
+				if event.Payload.SessionID != sessionID {
+					continue
+				}
+				for _, progressEvent := range tracker.update(event.Payload) {
+					if !send(progressEvent) {
+						return
+					}
+				}
+			case event, ok := <-results:
+				if !ok {
+					return
+				}
+				result := event.Payload
+				if result.SessionID != sessionID {
+					continue
+				}
+				final := ProgressEvent{Type: ProgressMessage, SessionID: sessionID, Content: result.Content}
+				if result.Error != nil {
+					final = ProgressEvent{Type: ProgressError, SessionID: sessionID, Error: result.Error.Error()}
+				}
+				send(final)
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return progress
+}
+
+// progressTracker turns successive versions of the session's messages into deltas
+type progressTracker struct {
+	sessionID    string
+	content      map[string]string
+	reasoning    map[string]string
+	startedCalls map[string]bool
+	doneCalls    map[string]bool
+}
+
+func newProgressTracker(sessionID string) *progressTracker {
+	return &progressTracker{
+		sessionID:    sessionID,
+		content:      make(map[string]string),
+		reasoning:    make(map[string]string),
+		startedCalls: make(map[string]bool),
+		doneCalls:    make(map[string]bool),
+	}
+}
+
+// update returns the progress made by a new version of a message
+func (t *progressTracker) update(msg message.Message) []ProgressEvent {
+	var events []ProgressEvent
+	switch msg.Role {
+	case message.Assistant:
+		if delta := t.delta(t.reasoning, msg.ID, msg.ReasoningContent().Thinking); delta != "" {
+			events = append(events, ProgressEvent{Type: ProgressReasoningDelta, SessionID: t.sessionID, MessageID: msg.ID, Delta: delta})
+		}
+		if delta := t.delta(t.content, msg.ID, msg.Content().String()); delta != "" {
+			events = append(events, ProgressEvent{Type: ProgressContentDelta, SessionID: t.sessionID, MessageID: msg.ID, Delta: delta})
+		}
+		// A call starts running once its input has been streamed completely
+		for _, call := range msg.ToolCalls() {
+			if !call.Finished || t.startedCalls[call.ID] {
+				continue
+			}
+			t.startedCalls[call.ID] = true
+			call := call
+			events = append(events, ProgressEvent{Type: ProgressToolCallStarted, SessionID: t.sessionID, MessageID: msg.ID, ToolCall: &call})
+		}
+	case message.Tool:
+		for _, result := range msg.ToolResults() {
+			if t.doneCalls[result.ToolCallID] {
+				continue
+			}
+			t.doneCalls[result.ToolCallID] = true
+			result := result
+			events = append(events, ProgressEvent{Type: ProgressToolCallFinished, SessionID: t.sessionID, MessageID: msg.ID, Result: &result})
+		}
+	}
+	return events
+}
+
+// delta returns the text added since the last version of a message. Text that was
+// rewritten rather than appended, such as stripped evidence, is not replayed.
+func (t *progressTracker) delta(seen map[string]string, id, text string) string {
+	previous := seen[id]
+	seen[id] = text
+	if len(text) <= len(previous) || !strings.HasPrefix(text, previous) {
+		return ""
+	}
+	return text[len(previous):]
+}
@@ -0,0 +1,208 @@
+package superclaude
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FlagType is the value type of a declared command flag
+type FlagType string
+
+const (
+	FlagTypeString FlagType = "string"
+	FlagTypeInt    FlagType = "int"
+	FlagTypeFloat  FlagType = "float"
+	FlagTypeBool   FlagType = "bool"
+)
+
+var flagNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// reservedFlags are parsed by ParseSuperClaudeCommand itself and cannot be declared
+var reservedFlags = map[string]bool{
+	"think":           true,
+	"think-hard":      true,
+	"ultrathink":      true,
+	"uc":              true,
+	"ultracompressed": true,
+	"plan":            true,
+	"evidence":        true,
+	"c7":              true,
+	"validate":        true,
+	"validation-only": true,
+	"seq":             true,
+	"sequential":      true,
+	"all-mcp":         true,
+}
+
+// FlagSpec declares a flag accepted by a command
+type FlagSpec struct {
+	Name        string   `yaml:"name"`
+	Type        FlagType `yaml:"type"`
+	Default     string   `yaml:"default"`
+	Enum        []string `yaml:"enum"`
+	Required    bool     `yaml:"required"`
+	Description string   `yaml:"description"`
+}
+
+// Validate checks that the spec itself is well formed
+func (s FlagSpec) Validate() error {
+	if !flagNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("flag name %q must be lowercase letters, digits and dashes", s.Name)
+	}
+	if reservedFlags[s.Name] || strings.HasPrefix(s.Name, "persona-") {
+		return fmt.Errorf("flag --%s is reserved", s.Name)
+	}
+
+	switch s.Type {
+	case FlagTypeString, FlagTypeInt, FlagTypeFloat, FlagTypeBool:
+	default:
+		return fmt.Errorf("flag --%s has unknown type %q (use string, int, float or bool)", s.Name, s.Type)
+	}
+
+	if len(s.Enum) > 0 && s.Type != FlagTypeString {
+		return fmt.Errorf("flag --%s: enum is only supported for string flags", s.Name)
+	}
+	if s.Default != "" {
+		if _, err := s.parse(s.Default); err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+	return nil
+}
+
+// parse converts a raw value to the flag's type
+func (s FlagSpec) parse(value string) (interface{}, error) {
+	switch s.Type {
+	case FlagTypeInt:
+		n, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil {
+			return nil, fmt.Errorf("flag --%s expects an integer, got %q", s.Name, value)
+		}
+		return n, nil
+	case FlagTypeFloat:
+		f, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("flag --%s expects a number, got %q", s.Name, value)
+		}
+		return f, nil
+	case FlagTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("flag --%s expects true or false, got %q", s.Name, value)
+		}
+		return b, nil
+	default:
+		if len(s.Enum) > 0 && !contains(s.Enum, value) {
+			return nil, fmt.Errorf("flag --%s must be one of %s, got %q", s.Name, strings.Join(s.Enum, ", "), value)
+		}
+		return value, nil
+	}
+}
+
+// zero returns the template value of an unset flag
+func (s FlagSpec) zero() interface{} {
+	switch s.Type {
+	case FlagTypeInt:
+		return 0
+	case FlagTypeFloat:
+		return 0.0
+	case FlagTypeBool:
+		return false
+	default:
+		return ""
+	}
+}
+
+// FlagSpec returns the declared flag with the given name
+func (cmd *SuperClaudeCommand) FlagSpec(name string) (FlagSpec, bool) {
+	for _, spec := range cmd.Schema {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return FlagSpec{}, false
+}
+
+// ValidateFlags checks the command-specific flags against the schema and fills in
+// defaults. Commands without a schema accept any flag.
+func (cmd *SuperClaudeCommand) ValidateFlags(flags *Flags) error {
+	if len(cmd.Schema) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(flags.Additional))
+	for name := range flags.Additional {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		spec, ok := cmd.FlagSpec(name)
+		if !ok {
+			return fmt.Errorf("unknown flag --%s for /user:%s (available: %s)", name, cmd.Name, cmd.flagList())
+		}
+
+		value := flags.Additional[name]
+		if value == "" {
+			if spec.Default == "" {
+				return fmt.Errorf("flag --%s for /user:%s requires a value", name, cmd.Name)
+			}
+			flags.Additional[name] = spec.Default
+			continue
+		}
+		if _, err := spec.parse(value); err != nil {
+			return fmt.Errorf("/user:%s: %w", cmd.Name, err)
+		}
+	}
+
+	for _, spec := range cmd.Schema {
+		if _, set := flags.Additional[spec.Name]; set {
+			continue
+		}
+		if spec.Required {
+			return fmt.Errorf("/user:%s requires --%s", cmd.Name, spec.Name)
+		}
+	}
+
+	return nil
+}
+
+// templateFlags converts declared flags to typed template values, unset flags
+// render as their default or zero value
+func (cmd *SuperClaudeCommand) templateFlags(values map[string]interface{}) {
+	for _, spec := range cmd.Schema {
+		raw, set := values[spec.Name].(string)
+		if !set {
+			raw = spec.Default
+		}
+		if raw == "" {
+			values[spec.Name] = spec.zero()
+			continue
+		}
+		if typed, err := spec.parse(raw); err == nil {
+			values[spec.Name] = typed
+		}
+	}
+}
+
+// flagList formats the declared flags for error messages
+func (cmd *SuperClaudeCommand) flagList() string {
+	names := make([]string, len(cmd.Schema))
+	for i, spec := range cmd.Schema {
+		names[i] = "--" + spec.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
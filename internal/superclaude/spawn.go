@@ -0,0 +1,418 @@
+package superclaude
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+const (
+	defaultSpawnAgents = 3
+	maxSpawnAgents     = 10
+)
+
+type SpawnEventType string
+
+const (
+	SpawnEventPlanned   SpawnEventType = "planned"
+	SpawnEventStarted   SpawnEventType = "started"
+	SpawnEventCompleted SpawnEventType = "completed"
+	SpawnEventFailed    SpawnEventType = "failed"
+	SpawnEventFinished  SpawnEventType = "finished"
+)
+
+// SpawnEvent reports the progress of a spawned sub-agent
+type SpawnEvent struct {
+	Type            SpawnEventType
+	ParentSessionID string
+	SessionID       string
+	Index           int
+	Total           int
+	Task            string
+	Error           error
+}
+
+// SpawnResult holds the outcome of a single spawned sub-agent
+type SpawnResult struct {
+	Index     int
+	Task      string
+	SessionID string
+	Output    string
+	Error     error
+	Cost      float64
+}
+
+// spawnedAgent is a running sub-agent of a spawn
+type spawnedAgent struct {
+	parentSessionID string
+	index           int
+	cancel          context.CancelFunc
+}
+
+// spawnOptions holds the parsed spawn flags
+type spawnOptions struct {
+	agents   int
+	parallel int
+}
+
+var numberedItemPattern = regexp.MustCompile(`^\s*(?:\d+[.)]|[-*])\s+(.+)$`)
+
+// parseSpawnOptions reads --agents and --parallel from the command flags
+func parseSpawnOptions(flags *Flags) (spawnOptions, error) {
+	opts := spawnOptions{
+		agents:   defaultSpawnAgents,
+		parallel: 1,
+	}
+
+	if value, ok := flags.Additional["agents"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return opts, fmt.Errorf("--agents must be a positive number, got %q", value)
+		}
+		if n > maxSpawnAgents {
+			return opts, fmt.Errorf("--agents cannot exceed %d", maxSpawnAgents)
+		}
+		opts.agents = n
+	}
+
+	if value, ok := flags.Additional["parallel"]; ok {
+		if value == "true" {
+			opts.parallel = opts.agents
+		} else {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return opts, fmt.Errorf("--parallel must be a positive number, got %q", value)
+			}
+			opts.parallel = n
+		}
+	}
+
+	return opts, nil
+}
+
+// SplitSpawnTasks returns explicitly listed sub-tasks separated by ";" or newlines
+func SplitSpawnTasks(target string) []string {
+	if !strings.ContainsAny(target, ";\n") {
+		return nil
+	}
+
+	var tasks []string
+	for _, part := range strings.FieldsFunc(target, func(r rune) bool { return r == ';' || r == '\n' }) {
+		if task := strings.TrimSpace(part); task != "" {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// ParseNumberedList extracts the items of a numbered or bulleted list
+func ParseNumberedList(content string) []string {
+	var items []string
+	for _, line := range strings.Split(content, "\n") {
+		if match := numberedItemPattern.FindStringSubmatch(line); match != nil {
+			items = append(items, strings.TrimSpace(match[1]))
+		}
+	}
+	return items
+}
+
+// handleSpawn decomposes the target and runs each sub-task in its own agent
+func (h *SuperClaudeHandler) handleSpawn(ctx context.Context, sessionID string, parsed *ParsedCommand) error {
+	if h.sessions == nil || h.messages == nil {
+		return fmt.Errorf("spawn requires session and message services")
+	}
+	if h.spawnTools == nil {
+		return fmt.Errorf("spawn is not available: no tools configured for sub-agents")
+	}
+	if strings.TrimSpace(parsed.Target) == "" {
+		return fmt.Errorf("spawn requires a target to decompose")
+	}
+
+	opts, err := parseSpawnOptions(parsed.Flags)
+	if err != nil {
+		return err
+	}
+	// Listed tasks are never dropped, each of them needs its own agent
+	if tasks := SplitSpawnTasks(parsed.Target); len(tasks) > opts.agents {
+		return fmt.Errorf("%d tasks are listed but --agents is %d, raise --agents or list fewer tasks", len(tasks), opts.agents)
+	}
+
+	if _, running := h.spawnRuns.Load(sessionID); running {
+		return fmt.Errorf("a spawn is already running in this session")
+	}
+
+	if _, err := h.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: parsed.RawInput}},
+	}); err != nil {
+		return fmt.Errorf("failed to create user message: %w", err)
+	}
+
+	spawnCtx, cancel := context.WithCancel(ctx)
+	h.spawnRuns.Store(sessionID, cancel)
+
+	go func() {
+		defer logging.RecoverPanic("superclaude.spawn", nil)
+		defer h.spawnRuns.Delete(sessionID)
+		defer cancel()
+
+		report, cost := h.runSpawn(spawnCtx, sessionID, parsed, opts)
+		if err := h.writeReport(context.Background(), sessionID, report, cost); err != nil {
+			logging.ErrorPersist(fmt.Sprintf("failed to write spawn report: %v", err))
+			h.publishResult(CommandResult{SessionID: sessionID, Error: err})
+			return
+		}
+		h.publishResult(CommandResult{SessionID: sessionID, Content: report})
+	}()
+
+	return nil
+}
+
+// runSpawn plans the sub-tasks, runs them with bounded concurrency and aggregates the results
+func (h *SuperClaudeHandler) runSpawn(ctx context.Context, parentSessionID string, parsed *ParsedCommand, opts spawnOptions) (string, float64) {
+	tasks := SplitSpawnTasks(parsed.Target)
+	if len(tasks) == 0 {
+		planned, err := h.planSpawnTasks(ctx, parentSessionID, parsed.Target, opts.agents)
+		if err != nil {
+			h.publishSpawn(SpawnEvent{Type: SpawnEventFailed, ParentSessionID: parentSessionID, Error: err})
+			return fmt.Sprintf("# Spawn: %s\n\n_Failed to decompose target: %v_\n", parsed.Target, err), 0
+		}
+		tasks = planned
+		if len(tasks) > opts.agents {
+			tasks = tasks[:opts.agents]
+		}
+	}
+
+	h.publishSpawn(SpawnEvent{Type: SpawnEventPlanned, ParentSessionID: parentSessionID, Total: len(tasks)})
+
+	results := make([]SpawnResult, len(tasks))
+	semaphore := make(chan struct{}, opts.parallel)
+	var wg sync.WaitGroup
+
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task string) {
+			defer wg.Done()
+			defer logging.RecoverPanic("superclaude.spawn.agent", func() {
+				results[i] = SpawnResult{Index: i + 1, Task: task, Error: fmt.Errorf("panic while running sub-agent")}
+			})
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				results[i] = SpawnResult{Index: i + 1, Task: task, Error: ctx.Err()}
+				return
+			}
+
+			results[i] = h.runSpawnedAgent(ctx, parentSessionID, parsed, task, i+1, len(tasks))
+		}(i, task)
+	}
+	wg.Wait()
+
+	h.publishSpawn(SpawnEvent{Type: SpawnEventFinished, ParentSessionID: parentSessionID, Total: len(tasks)})
+
+	var cost float64
+	for _, result := range results {
+		cost += result.Cost
+	}
+	return MergeSpawnReport(parsed.Target, results), cost
+}
+
+// planSpawnTasks asks a tool-less task agent to split the target into sub-tasks
+func (h *SuperClaudeHandler) planSpawnTasks(ctx context.Context, parentSessionID, target string, count int) ([]string, error) {
+	planner, err := agent.NewAgent(config.AgentTask, h.sessions, h.messages, nil, h.agentOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating planner agent: %w", err)
+	}
+
+	session, err := h.sessions.CreateTaskSession(ctx, uuid.New().String(), parentSessionID, "spawn: plan")
+	if err != nil {
+		return nil, fmt.Errorf("error creating planner session: %w", err)
+	}
+
+	prompt := fmt.Sprintf(`Decompose the following task into at most %d independent sub-tasks that separate agents can execute in parallel.
+Respond ONLY with a numbered list, one sub-task per line, each self-contained and specific.
+
+Task: %s`, count, target)
+
+	events, err := planner.Run(ctx, session.ID, prompt)
+	if err != nil {
+		return nil, err
+	}
+	result := <-events
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	tasks := ParseNumberedList(result.Message.Content().String())
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("planner returned no sub-tasks")
+	}
+	return tasks, nil
+}
+
+// runSpawnedAgent runs one sub-task through its own agent instance and task session
+func (h *SuperClaudeHandler) runSpawnedAgent(ctx context.Context, parentSessionID string, parsed *ParsedCommand, task string, index, total int) SpawnResult {
+	result := SpawnResult{Index: index, Task: task}
+
+	persona := GetPersona(parsed.Flags.Persona)
+	flags := MergeFlags(parsed.Flags, &Flags{
+		Additional: map[string]string{
+			"goal":  parsed.Target,
+			"index": strconv.Itoa(index),
+			"total": strconv.Itoa(total),
+		},
+	})
+	applyPersonaDefaults(flags, persona)
+
+	cmd := Commands["spawn"]
+	prompt, err := cmd.BuildPrompt(persona, flags, task, parsed.RawInput)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to build prompt: %w", err)
+		return result
+	}
+	if flags.Think != "" {
+		prompt = applyThinkingMode(prompt, flags.Think)
+	}
+	if flags.UltraCompressed {
+		prompt = applyUltraCompressed(prompt)
+	}
+
+	subAgent, err := h.spawnAgent(persona)
+	if err != nil {
+		result.Error = fmt.Errorf("error creating agent: %w", err)
+		return result
+	}
+
+	title := fmt.Sprintf("spawn %d/%d: %s", index, total, task)
+	session, err := h.sessions.CreateTaskSession(ctx, uuid.New().String(), parentSessionID, title)
+	if err != nil {
+		result.Error = fmt.Errorf("error creating session: %w", err)
+		return result
+	}
+	result.SessionID = session.ID
+
+	agentCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	h.spawnedAgents.Store(session.ID, &spawnedAgent{parentSessionID: parentSessionID, index: index, cancel: cancel})
+	defer h.spawnedAgents.Delete(session.ID)
+
+	h.publishSpawn(SpawnEvent{
+		Type:            SpawnEventStarted,
+		ParentSessionID: parentSessionID,
+		SessionID:       session.ID,
+		Index:           index,
+		Total:           total,
+		Task:            task,
+	})
+
+	events, err := subAgent.Run(h.withThinking(agentCtx, subAgent.Model(), "spawn", flags), session.ID, prompt)
+	if err == nil {
+		event := <-events
+		err = event.Error
+		result.Output = event.Message.Content().String()
+	}
+
+	if updated, getErr := h.sessions.Get(context.Background(), session.ID); getErr == nil {
+		result.Cost = updated.Cost
+	}
+
+	eventType := SpawnEventCompleted
+	if err != nil {
+		result.Error = err
+		eventType = SpawnEventFailed
+	}
+	h.publishSpawn(SpawnEvent{
+		Type:            eventType,
+		ParentSessionID: parentSessionID,
+		SessionID:       session.ID,
+		Index:           index,
+		Total:           total,
+		Task:            task,
+		Error:           err,
+	})
+
+	return result
+}
+
+// newSpawnedAgent creates a task agent for a sub-task with the persona's model and tools
+func (h *SuperClaudeHandler) newSpawnedAgent(persona Persona) (agent.Service, error) {
+	return agent.NewAgentWithModel(
+		config.AgentTask,
+		models.ModelID(persona.Model),
+		h.sessions,
+		h.messages,
+		filterTools(h.spawnTools(), persona.AllowedTools),
+		h.agentOptions(h.toolOptions(persona)...)...,
+	)
+}
+
+// SpawnedAgentParent returns the session that spawned the running sub-agent with
+// the task session sessionID
+func (h *SuperClaudeHandler) SpawnedAgentParent(sessionID string) (string, bool) {
+	value, ok := h.spawnedAgents.Load(sessionID)
+	if !ok {
+		return "", false
+	}
+	return value.(*spawnedAgent).parentSessionID, true
+}
+
+// CancelSpawnedAgent stops the running sub-agent with the task session sessionID,
+// leaving its siblings running. The spawn reports it as failed.
+func (h *SuperClaudeHandler) CancelSpawnedAgent(sessionID string) bool {
+	value, ok := h.spawnedAgents.Load(sessionID)
+	if !ok {
+		return false
+	}
+	running := value.(*spawnedAgent)
+	logging.InfoPersist(fmt.Sprintf("Sub-agent %d cancellation initiated for session: %s", running.index, running.parentSessionID))
+	running.cancel()
+	return true
+}
+
+// publishSpawn publishes a spawn progress event
+func (h *SuperClaudeHandler) publishSpawn(event SpawnEvent) {
+	h.Publish(pubsub.CreatedEvent, event)
+}
+
+// MergeSpawnReport aggregates sub-agent results into a single markdown report
+func MergeSpawnReport(goal string, results []SpawnResult) string {
+	var report strings.Builder
+
+	report.WriteString(fmt.Sprintf("# Spawn: %s\n\n", goal))
+
+	failed := 0
+	for _, result := range results {
+		report.WriteString(fmt.Sprintf("## %d. %s\n\n", result.Index, result.Task))
+		if result.SessionID != "" {
+			report.WriteString(fmt.Sprintf("Session: `%s`\n\n", result.SessionID))
+		}
+		if result.Error != nil {
+			failed++
+			report.WriteString(fmt.Sprintf("_Failed: %v_\n\n", result.Error))
+			continue
+		}
+		output := strings.TrimSpace(result.Output)
+		if output == "" {
+			output = "_No output_"
+		}
+		report.WriteString(output)
+		report.WriteString("\n\n")
+	}
+
+	report.WriteString(fmt.Sprintf("---\n%d of %d sub-agents succeeded\n", len(results)-failed, len(results)))
+
+	return report.String()
+}
@@ -1,7 +1,24 @@
 package superclaude
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/opencode-ai/opencode/internal/session"
 )
 
 func TestParseSuperClaudeCommand(t *testing.T) {
@@ -209,3 +226,1157 @@ func TestGetThinkingTokens(t *testing.T) {
 		})
 	}
 }
+
+func TestThinkingBudget(t *testing.T) {
+	h := NewSuperClaudeHandler(nil, nil, nil, WithThinkingTokens(config.ThinkingTokensConfig{
+		Deep:  20000,
+		Ultra: 40000,
+	}))
+
+	tests := []struct {
+		mode       string
+		wantBudget int
+		wantEffort string
+	}{
+		{"standard", 8000, "low"},
+		{"deep", 20000, "medium"},
+		{"ultra", 40000, "high"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			if budget := h.thinkingBudget(tt.mode); budget != tt.wantBudget {
+				t.Errorf("thinkingBudget(%s) = %v, want %v", tt.mode, budget, tt.wantBudget)
+			}
+			if effort := reasoningEffort(tt.mode); effort != tt.wantEffort {
+				t.Errorf("reasoningEffort(%s) = %q, want %q", tt.mode, effort, tt.wantEffort)
+			}
+		})
+	}
+
+	if opts := h.thinkingOptions(models.Model{CanReason: true}, "analyze", &Flags{}); opts != nil {
+		t.Errorf("expected no provider options without a thinking flag, got %d", len(opts))
+	}
+	if opts := h.thinkingOptions(models.Model{}, "analyze", &Flags{Think: "deep"}); len(opts) != 2 {
+		t.Errorf("expected no max tokens override for a model without reasoning, got %d options", len(opts))
+	}
+	if opts := h.thinkingOptions(models.Model{CanReason: true}, "analyze", &Flags{Think: "deep"}); len(opts) != 3 {
+		t.Errorf("expected a max tokens override for a reasoning model, got %d options", len(opts))
+	}
+	if got := maxTokensForCommand("analyze", &Flags{Think: "deep"}, 20000); got != 30000 {
+		t.Errorf("maxTokensForCommand(analyze, deep) = %v, want 30000", got)
+	}
+}
+
+func TestParseCollaborationTarget(t *testing.T) {
+	tests := []struct {
+		target      string
+		wantPattern string
+		wantTarget  string
+		wantErr     bool
+	}{
+		{"security-review ./internal/mcp", "security-review", "./internal/mcp", false},
+		{"full-stack-build", "full-stack-build", "", false},
+		{"unknown-pattern ./src", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			pattern, target, err := ParseCollaborationTarget(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCollaborationTarget(%q) error = %v, wantErr %v", tt.target, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if pattern.Name != tt.wantPattern {
+				t.Errorf("Pattern = %v, want %v", pattern.Name, tt.wantPattern)
+			}
+			if target != tt.wantTarget {
+				t.Errorf("Target = %v, want %v", target, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestMergeCollaborationReport(t *testing.T) {
+	pattern := CollaborationPatterns["security-review"]
+	steps := []CollaborationStep{
+		{Persona: "security", Output: "No injection points found"},
+		{Persona: "analyzer", Error: context.Canceled},
+		{Persona: "qa", Output: "Coverage is 80%"},
+	}
+
+	report := MergeCollaborationReport(pattern, "./internal/mcp", steps)
+
+	for _, want := range []string{
+		"# Collaboration: security-review",
+		"## security",
+		"No injection points found",
+		"_Failed: context canceled_",
+		"Coverage is 80%",
+		"1 of 3 personas failed",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestAvailableCollaborationPatternsSorted(t *testing.T) {
+	patterns := GetAvailableCollaborationPatterns()
+	if len(patterns) != len(CollaborationPatterns) {
+		t.Fatalf("got %d patterns, want %d", len(patterns), len(CollaborationPatterns))
+	}
+	if !sort.StringsAreSorted(patterns) {
+		t.Errorf("patterns are not sorted: %v", patterns)
+	}
+}
+
+func TestParseSpawnOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		additional   map[string]string
+		wantAgents   int
+		wantParallel int
+		wantErr      bool
+	}{
+		{"Defaults", nil, defaultSpawnAgents, 1, false},
+		{"Agents", map[string]string{"agents": "5"}, 5, 1, false},
+		{"Parallel all", map[string]string{"agents": "4", "parallel": "true"}, 4, 4, false},
+		{"Parallel bound", map[string]string{"agents": "4", "parallel": "2"}, 4, 2, false},
+		{"Too many agents", map[string]string{"agents": "50"}, 0, 0, true},
+		{"Invalid parallel", map[string]string{"parallel": "many"}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseSpawnOptions(&Flags{Additional: tt.additional})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSpawnOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if opts.agents != tt.wantAgents || opts.parallel != tt.wantParallel {
+				t.Errorf("parseSpawnOptions() = %+v, want agents %d parallel %d", opts, tt.wantAgents, tt.wantParallel)
+			}
+		})
+	}
+}
+
+func TestSpawnTaskParsing(t *testing.T) {
+	if tasks := SplitSpawnTasks("build the api"); tasks != nil {
+		t.Errorf("SplitSpawnTasks() = %v, want nil for a single goal", tasks)
+	}
+
+	tasks := SplitSpawnTasks("add endpoint; write tests ;\nupdate docs")
+	if len(tasks) != 3 || tasks[1] != "write tests" {
+		t.Errorf("SplitSpawnTasks() = %v", tasks)
+	}
+
+	items := ParseNumberedList("Plan:\n1. Add endpoint\n2) Write tests\n- Update docs\nDone")
+	if len(items) != 3 || items[0] != "Add endpoint" || items[2] != "Update docs" {
+		t.Errorf("ParseNumberedList() = %v", items)
+	}
+}
+
+func TestSpawnRefusesMoreTasksThanAgents(t *testing.T) {
+	h := NewSuperClaudeHandler(nil, &fakeSessions{}, &brokerMessages{},
+		WithSpawnTools(func() []tools.BaseTool { return nil }))
+	parsed := &ParsedCommand{
+		Command:  "spawn",
+		Target:   "task a; task b; task c",
+		Flags:    &Flags{Additional: map[string]string{"agents": "2"}},
+		RawInput: "/user:spawn task a; task b; task c --agents 2",
+	}
+
+	err := h.handleSpawn(context.Background(), "parent", parsed)
+	if err == nil || !strings.Contains(err.Error(), "3 tasks are listed but --agents is 2") {
+		t.Errorf("handleSpawn() error = %v, want the task count against --agents", err)
+	}
+}
+
+func TestMergeSpawnReport(t *testing.T) {
+	report := MergeSpawnReport("ship feature", []SpawnResult{
+		{Index: 1, Task: "add endpoint", SessionID: "s1", Output: "Endpoint added"},
+		{Index: 2, Task: "write tests", Error: context.Canceled},
+	})
+
+	for _, want := range []string{
+		"# Spawn: ship feature",
+		"## 1. add endpoint",
+		"Session: `s1`",
+		"_Failed: context canceled_",
+		"1 of 2 sub-agents succeeded",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestComputeCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "lsp")
+	if err := os.MkdirAll(pkg, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(pkg, "client.go")
+	if err := os.WriteFile(file, []byte("package lsp"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := CacheKeyInput{
+		Command:    "analyze",
+		Target:     "./lsp",
+		Persona:    "analyzer",
+		Model:      "claude-3.7-sonnet",
+		Flags:      &Flags{Think: "deep", Additional: map[string]string{"focus": "perf"}},
+		WorkingDir: dir,
+	}
+
+	key, deps, err := ComputeCacheKey(input)
+	if err != nil {
+		t.Fatalf("ComputeCacheKey() error = %v", err)
+	}
+	if len(deps) != 1 || deps[0] != pkg {
+		t.Errorf("dependencies = %v, want [%s]", deps, pkg)
+	}
+
+	again, _, _ := ComputeCacheKey(input)
+	if again != key {
+		t.Error("key is not stable for unchanged input")
+	}
+
+	input.Model = "gpt-4o"
+	if other, _, _ := ComputeCacheKey(input); other == key {
+		t.Error("key did not change with the model")
+	}
+	input.Model = "claude-3.7-sonnet"
+
+	if err := os.WriteFile(file, []byte("package lsp // changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if other, _, _ := ComputeCacheKey(input); other == key {
+		t.Error("key did not change with the file content")
+	}
+}
+
+func TestOptimizerCache(t *testing.T) {
+	opt := NewOptimizer()
+	runs := 0
+	req := func() *OptimizedRequest {
+		return &OptimizedRequest{
+			Command:      "/user:analyze ./lsp",
+			Key:          "key",
+			Dependencies: []string{"/project/lsp"},
+			Execute: func(ctx context.Context) (interface{}, error) {
+				runs++
+				return "analysis", nil
+			},
+		}
+	}
+
+	first, err := opt.OptimizeCommand(context.Background(), req())
+	if err != nil || first.Result != "analysis" || first.CacheHit {
+		t.Fatalf("first run = %+v, %v", first, err)
+	}
+
+	second, err := opt.OptimizeCommand(context.Background(), req())
+	if err != nil || !second.CacheHit || runs != 1 {
+		t.Fatalf("second run = %+v, %v (runs %d), want cache hit", second, err, runs)
+	}
+
+	if n := opt.Invalidate("/project/lsp/client.go"); n != 1 {
+		t.Errorf("Invalidate() = %d, want 1", n)
+	}
+	if _, ok := opt.Lookup("key"); ok {
+		t.Error("entry still cached after invalidation")
+	}
+}
+
+// fakeAgent answers every prompt with the result of run
+type fakeAgent struct {
+	*pubsub.Broker[agent.AgentEvent]
+	run func(ctx context.Context, sessionID, prompt string) (string, error)
+}
+
+func newFakeAgent(run func(ctx context.Context, sessionID, prompt string) (string, error)) *fakeAgent {
+	return &fakeAgent{Broker: pubsub.NewBroker[agent.AgentEvent](), run: run}
+}
+
+func (a *fakeAgent) Model() models.Model { return models.Model{} }
+
+func (a *fakeAgent) Run(ctx context.Context, sessionID string, content string, _ ...message.Attachment) (<-chan agent.AgentEvent, error) {
+	events := make(chan agent.AgentEvent, 1)
+	go func() {
+		output, err := a.run(ctx, sessionID, content)
+		if err != nil {
+			events <- agent.AgentEvent{Type: agent.AgentEventTypeError, Error: err}
+			return
+		}
+		events <- agent.AgentEvent{
+			Type:    agent.AgentEventTypeResponse,
+			Message: message.Message{Parts: []message.ContentPart{message.TextContent{Text: output}}},
+		}
+	}()
+	return events, nil
+}
+
+func (a *fakeAgent) Cancel(string)             {}
+func (a *fakeAgent) IsSessionBusy(string) bool { return false }
+func (a *fakeAgent) IsBusy() bool              { return false }
+func (a *fakeAgent) Update(config.AgentName, models.ModelID) (models.Model, error) {
+	return models.Model{}, nil
+}
+func (a *fakeAgent) Summarize(context.Context, string) error { return nil }
+
+func TestExecuteOptimizedRunsUncacheableCommands(t *testing.T) {
+	opt := NewOptimizer()
+	h := NewSuperClaudeHandler(nil, nil, nil, WithOptimizer(opt))
+	runner := newFakeAgent(func(ctx context.Context, sessionID, prompt string) (string, error) {
+		return "implemented", nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := h.SubscribeResults(ctx)
+
+	parsed := &ParsedCommand{Command: "implement", Target: "auth", Flags: &Flags{}, RawInput: "/user:implement auth"}
+	if err := h.executeOptimized(ctx, "session-1", runner, parsed, Persona{}, "implement auth"); err != nil {
+		t.Fatalf("executeOptimized() error = %v", err)
+	}
+
+	select {
+	case event := <-results:
+		if event.Payload.Error != nil || event.Payload.Content != "implemented" {
+			t.Errorf("result = %+v, want the agent's output", event.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result published")
+	}
+
+	opt.metrics.mu.RLock()
+	defer opt.metrics.mu.RUnlock()
+	if opt.metrics.totalRequests != 0 {
+		t.Errorf("optimizer saw %d requests, want none for a command that is not cached", opt.metrics.totalRequests)
+	}
+}
+
+// fakeSessions creates task sessions in memory
+type fakeSessions struct {
+	session.Service
+	mu    sync.Mutex
+	count int
+}
+
+func (s *fakeSessions) CreateTaskSession(_ context.Context, _, parentSessionID, title string) (session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	return session.Session{ID: fmt.Sprintf("task-%d", s.count), ParentSessionID: parentSessionID, Title: title}, nil
+}
+
+func (s *fakeSessions) Get(_ context.Context, id string) (session.Session, error) {
+	return session.Session{ID: id}, nil
+}
+
+func TestCancelSpawnedAgent(t *testing.T) {
+	h := NewSuperClaudeHandler(nil, &fakeSessions{}, nil)
+	release := make(chan struct{})
+	h.spawnAgent = func(Persona) (agent.Service, error) {
+		return newFakeAgent(func(ctx context.Context, sessionID, prompt string) (string, error) {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-release:
+				return "done in " + sessionID, nil
+			}
+		}), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := h.Subscribe(ctx)
+
+	parsed := &ParsedCommand{Command: "spawn", Target: "task a; task b; task c", Flags: &Flags{}, RawInput: "/user:spawn task a; task b; task c"}
+	done := make(chan string, 1)
+	go func() {
+		report, _ := h.runSpawn(ctx, "parent", parsed, spawnOptions{agents: 3, parallel: 3})
+		done <- report
+	}()
+
+	started := make(map[int]string)
+	for len(started) < 3 {
+		select {
+		case event := <-events:
+			if event.Payload.Type == SpawnEventStarted {
+				started[event.Payload.Index] = event.Payload.SessionID
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d sub-agents started", len(started))
+		}
+	}
+
+	if parent, ok := h.SpawnedAgentParent(started[2]); !ok || parent != "parent" {
+		t.Errorf("SpawnedAgentParent() = %q, %v, want the spawning session", parent, ok)
+	}
+	if !h.CancelSpawnedAgent(started[2]) {
+		t.Fatal("CancelSpawnedAgent() = false for a running sub-agent")
+	}
+	for failed := false; !failed; {
+		select {
+		case event := <-events:
+			failed = event.Payload.Type == SpawnEventFailed && event.Payload.Index == 2
+		case <-time.After(5 * time.Second):
+			t.Fatal("the cancelled sub-agent did not fail")
+		}
+	}
+	// The siblings only finish once the cancelled sub-agent has stopped
+	close(release)
+
+	var report string
+	select {
+	case report = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("spawn did not finish")
+	}
+	for _, want := range []string{
+		"done in " + started[1],
+		"_Failed: context canceled_",
+		"done in " + started[3],
+		"2 of 3 sub-agents succeeded",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+	if h.CancelSpawnedAgent(started[1]) {
+		t.Error("CancelSpawnedAgent() = true for a finished sub-agent")
+	}
+}
+
+// busyAgent is working in one session
+type busyAgent struct {
+	*fakeAgent
+	sessionID string
+}
+
+func (a busyAgent) IsSessionBusy(sessionID string) bool { return sessionID == a.sessionID }
+
+func TestIsSessionBusy(t *testing.T) {
+	h := NewSuperClaudeHandler(busyAgent{newFakeAgent(nil), "coder"}, nil, nil)
+	h.personaAgents.Store("architect", busyAgent{newFakeAgent(nil), "persona"})
+	h.spawnRuns.Store("spawn", context.CancelFunc(func() {}))
+	h.spawnedAgents.Store("task-1", &spawnedAgent{parentSessionID: "cancelled-spawn", index: 1, cancel: func() {}})
+
+	for _, sessionID := range []string{"coder", "persona", "spawn", "cancelled-spawn"} {
+		if !h.IsSessionBusy(sessionID) {
+			t.Errorf("IsSessionBusy(%q) = false, want true", sessionID)
+		}
+	}
+	if h.IsSessionBusy("idle") {
+		t.Error("IsSessionBusy() = true for a session no agent works in")
+	}
+}
+
+type runningAgent struct {
+	*fakeAgent
+}
+
+func (runningAgent) IsBusy() bool { return true }
+
+func TestEvictPersonaAgentsOnLoad(t *testing.T) {
+	h := NewSuperClaudeHandler(newFakeAgent(nil), nil, nil)
+	h.personaAgents.Store("idle", newFakeAgent(nil))
+	h.personaAgents.Store("running", runningAgent{newFakeAgent(nil)})
+
+	loader := NewPersonaLoader(false, t.TempDir())
+	loader.OnLoad(h.EvictPersonaAgents)
+	if err := loader.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := h.personaAgents.Load("idle"); ok {
+		t.Error("idle persona agent kept after the personas were reloaded")
+	}
+	if _, ok := h.personaAgents.Load("running"); !ok {
+		t.Error("running persona agent evicted")
+	}
+}
+
+func TestPersonaLoader(t *testing.T) {
+	userDir := t.TempDir()
+	projectDir := t.TempDir()
+
+	files := map[string]string{
+		filepath.Join(userDir, "sre.yaml"): `name: sre
+identity: "Site reliability engineer"
+core_belief: "Reliability is a feature"
+decision_framework: "Error budgets > Gut feeling"
+communication_style: "SLOs | Runbooks"
+thinking: deep
+tools: [view, grep]
+`,
+		filepath.Join(projectDir, "dba.md"): `---
+tools: [view]
+---
+## Identity
+Database administrator
+
+## Core Belief
+Data outlives code
+
+## Decision Framework
+Consistency > Latency
+
+## Communication Style
+Query plans
+`,
+		filepath.Join(projectDir, "broken.yaml"): `name: broken
+identty: "typo"
+`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { SetCustomPersonas(map[string]Persona{}) })
+
+	err := NewPersonaLoader(true, userDir, projectDir).Load()
+	if err == nil || !strings.Contains(err.Error(), "broken.yaml") {
+		t.Errorf("Load() error = %v, want error for broken.yaml", err)
+	}
+
+	sre, ok := LookupPersona("sre")
+	if !ok || sre.Thinking != "deep" || len(sre.AllowedTools) != 2 {
+		t.Errorf("sre persona = %+v, %v", sre, ok)
+	}
+	dba, ok := LookupPersona("dba")
+	if !ok || dba.CoreBelief != "Data outlives code" || dba.CommunicationStyle != "Query plans" {
+		t.Errorf("dba persona = %+v, %v", dba, ok)
+	}
+	if err := (&Flags{Persona: "sre"}).Validate(); err != nil {
+		t.Errorf("Validate() with custom persona error = %v", err)
+	}
+
+	if err := NewPersonaLoader(false, userDir, projectDir).Load(); err != nil {
+		t.Fatalf("Load() with custom personas disabled error = %v", err)
+	}
+	if _, ok := LookupPersona("sre"); ok {
+		t.Error("custom persona loaded while allow_custom is false")
+	}
+}
+
+func TestValidatePersona(t *testing.T) {
+	valid := Persona{
+		Name:               "sre",
+		Identity:           "Site reliability engineer",
+		CoreBelief:         "Reliability is a feature",
+		DecisionFramework:  "Error budgets > Gut feeling",
+		CommunicationStyle: "SLOs",
+	}
+	if err := ValidatePersona(valid); err != nil {
+		t.Fatalf("ValidatePersona() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(p *Persona)
+	}{
+		{"Built-in name", func(p *Persona) { p.Name = "architect" }},
+		{"Invalid name", func(p *Persona) { p.Name = "SRE Team" }},
+		{"Missing identity", func(p *Persona) { p.Identity = "" }},
+		{"Unknown model", func(p *Persona) { p.Model = "gpt-17" }},
+		{"Invalid thinking", func(p *Persona) { p.Thinking = "extreme" }},
+		{"Unknown tool", func(p *Persona) { p.AllowedTools = []string{"rm"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			tt.mutate(&p)
+			if err := ValidatePersona(p); err == nil {
+				t.Error("ValidatePersona() expected error")
+			}
+		})
+	}
+}
+
+func TestFlagSchemaValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+		check   func(t *testing.T, parsed *ParsedCommand)
+	}{
+		{
+			name:    "Mistyped value",
+			input:   "/user:test --coverage abc",
+			wantErr: `flag --coverage expects an integer, got "abc"`,
+		},
+		{
+			name:    "Unknown flag",
+			input:   "/user:test --covrage 90",
+			wantErr: "unknown flag --covrage for /user:test",
+		},
+		{
+			name:    "Enum value",
+			input:   "/user:explain hooks --depth guru",
+			wantErr: "flag --depth must be one of basic, intermediate, expert",
+		},
+		{
+			name:    "Required flag",
+			input:   "/user:deploy api --dry-run",
+			wantErr: "/user:deploy requires --env",
+		},
+		{
+			name:  "Bare flag uses default",
+			input: "/user:test --coverage --e2e",
+			check: func(t *testing.T, parsed *ParsedCommand) {
+				if parsed.Flags.Additional["coverage"] != "80" {
+					t.Errorf("coverage = %q, want default 80", parsed.Flags.Additional["coverage"])
+				}
+			},
+		},
+		{
+			name:  "Bool flag does not consume target",
+			input: "/user:test --watch ./pkg",
+			check: func(t *testing.T, parsed *ParsedCommand) {
+				if parsed.Target != "./pkg" || parsed.Flags.Additional["watch"] != "true" {
+					t.Errorf("target = %q, watch = %q", parsed.Target, parsed.Flags.Additional["watch"])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseSuperClaudeCommand(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSuperClaudeCommand() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSuperClaudeCommand() error = %v", err)
+			}
+			tt.check(t, parsed)
+		})
+	}
+}
+
+func TestBuildPromptRendersDefaults(t *testing.T) {
+	parsed, err := ParseSuperClaudeCommand("/user:test ./pkg --e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := Commands["test"]
+	prompt, err := cmd.BuildPrompt(GetPersona(parsed.Flags.Persona), parsed.Flags, parsed.Target, parsed.RawInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(prompt, "<no value>") {
+		t.Errorf("prompt contains <no value>:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "Coverage Target: 80%") || strings.Contains(prompt, "Watch Mode") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+}
+
+func TestBuiltinCommandsDeclareTheirFlags(t *testing.T) {
+	for name, cmd := range Commands {
+		t.Run(name, func(t *testing.T) {
+			if len(cmd.Schema) == 0 {
+				t.Fatal("built-in command has no flag schema")
+			}
+			for _, spec := range cmd.Schema {
+				if err := spec.Validate(); err != nil {
+					t.Errorf("flag --%s: %v", spec.Name, err)
+				}
+			}
+
+			flags := &Flags{Additional: map[string]string{}}
+			prompt, err := cmd.BuildPrompt(GetPersona(GetPersonaForCommand(name)), flags, "target", "/user:"+name+" target")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(prompt, "<no value>") {
+				t.Errorf("prompt without flags contains <no value>:\n%s", prompt)
+			}
+		})
+	}
+}
+
+func TestCommandLoader(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"audit.md": `---
+description: Audit dependencies
+persona: security
+flags:
+  - name: level
+    enum: [low, high]
+    default: low
+  - name: max-age
+    type: int
+---
+Audit {{.Target}} at {{.Flags.level}} level{{if index .Flags "max-age"}}, max age {{index .Flags "max-age"}} days{{end}}
+`,
+		"bad.yaml": `name: bad
+template: "{{.Target"
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { SetCustomCommands(map[string]SuperClaudeCommand{}) })
+
+	err := NewCommandLoader(dir).Load()
+	if err == nil || !strings.Contains(err.Error(), "bad.yaml") {
+		t.Errorf("Load() error = %v, want error for bad.yaml", err)
+	}
+
+	parsed, err := ParseSuperClaudeCommand("/user:audit go.mod --max-age 30")
+	if err != nil {
+		t.Fatalf("ParseSuperClaudeCommand() error = %v", err)
+	}
+	if parsed.Flags.Persona != "security" {
+		t.Errorf("Persona = %q, want security", parsed.Flags.Persona)
+	}
+
+	cmd, _ := LookupCommand("audit")
+	prompt, err := cmd.BuildPrompt(GetPersona(parsed.Flags.Persona), parsed.Flags, parsed.Target, parsed.RawInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "Audit go.mod at low level, max age 30 days") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+
+	if _, err := ParseSuperClaudeCommand("/user:audit go.mod --level extreme"); err == nil {
+		t.Error("expected enum error for --level extreme")
+	}
+}
+
+func TestWatchDefinitionsPicksUpNewDirs(t *testing.T) {
+	workingDir := t.TempDir()
+	dir := filepath.Join(workingDir, ".opencode", "commands")
+	reloads := make(chan struct{}, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchDefinitions(ctx, "commands", []string{dir}, func() { reloads <- struct{}{} })
+	time.Sleep(100 * time.Millisecond)
+
+	// Files next to the commands dir are not definitions
+	if err := os.WriteFile(filepath.Join(workingDir, "notes.md"), []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloads:
+		t.Fatal("reloaded for a file outside the commands dir")
+	case <-time.After(2 * reloadDelay):
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "audit.md"), []byte("Audit {{.Target}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after the commands dir was created")
+	}
+
+	// Once the dir exists, its own files trigger reloads
+	time.Sleep(2 * reloadDelay)
+	for len(reloads) > 0 {
+		<-reloads
+	}
+	if err := os.WriteFile(filepath.Join(dir, "review.md"), []byte("Review {{.Target}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after a command was added")
+	}
+}
+
+func TestCompletedPlanSteps(t *testing.T) {
+	content := `Updated the handler.
+Step 1: done
+- Step 2 completed
+Step 3 is next, not done yet
+**Step 4: Done**`
+
+	got := CompletedPlanSteps(content)
+	want := []int{1, 2, 4}
+	if len(got) != len(want) {
+		t.Fatalf("CompletedPlanSteps() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("CompletedPlanSteps()[%d] = %d, want %d", i, got[i], want[i])
+		}
+	}
+
+	plan := Plan{Steps: NewPlanSteps([]string{"inspect", "", "change", "test"})}
+	if len(plan.Steps) != 3 || plan.Steps[2].Index != 3 {
+		t.Fatalf("NewPlanSteps() = %+v, want 3 numbered steps", plan.Steps)
+	}
+	if !plan.markDone(2) || plan.markDone(2) || plan.markDone(7) {
+		t.Error("markDone should only report newly completed, existing steps")
+	}
+}
+
+func TestPlanApproval(t *testing.T) {
+	h := NewSuperClaudeHandler(nil, nil, nil)
+	proposed := Plan{
+		ID:        "plan-1",
+		SessionID: "session-1",
+		Steps:     NewPlanSteps([]string{"read the code", "change it"}),
+	}
+
+	if err := h.ApprovePlan(proposed.ID, nil); err == nil {
+		t.Error("expected an error approving a plan that is not pending")
+	}
+
+	type outcome struct {
+		plan Plan
+		err  error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		plan, err := h.awaitPlanDecision(context.Background(), proposed)
+		done <- outcome{plan, err}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if _, ok := h.PendingPlan("session-1"); ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := h.ApprovePlan(proposed.ID, []string{" "}); err == nil {
+		t.Error("expected an error approving a plan without steps")
+	}
+	if err := h.ApprovePlan(proposed.ID, []string{"read the code", "change it", "run the tests"}); err != nil {
+		t.Fatalf("ApprovePlan() error = %v", err)
+	}
+
+	result := <-done
+	if result.err != nil {
+		t.Fatalf("awaitPlanDecision() error = %v", result.err)
+	}
+	if result.plan.Status != PlanStatusApproved || len(result.plan.Steps) != 3 {
+		t.Errorf("approved plan = %+v, want 3 approved steps", result.plan)
+	}
+	if !strings.Contains(buildExecutionPrompt("do it", result.plan), "3. run the tests") {
+		t.Error("execution prompt should pin the approved steps")
+	}
+
+	go func() {
+		plan, err := h.awaitPlanDecision(context.Background(), proposed)
+		done <- outcome{plan, err}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, ok := h.PendingPlan("session-1"); ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := h.RejectPlan(proposed.ID); err != nil {
+		t.Fatalf("RejectPlan() error = %v", err)
+	}
+	if result := <-done; !errors.Is(result.err, ErrPlanRejected) {
+		t.Errorf("awaitPlanDecision() error = %v, want ErrPlanRejected", result.err)
+	}
+}
+
+func TestDryRunReview(t *testing.T) {
+	h := NewSuperClaudeHandler(nil, nil, nil)
+	store := func(id string) {
+		h.pendingDryRuns.Store(id, &pendingDryRun{
+			run: DryRun{
+				ID:        id,
+				SessionID: "session-1",
+				Changes:   []tools.FileChange{{Path: "/repo/a.go"}, {Path: "/repo/b.go", Created: true}},
+			},
+			overlay: tools.NewOverlay(),
+		})
+	}
+
+	store("run-1")
+	if run, ok := h.PendingDryRun("session-1"); !ok || len(run.Paths()) != 2 {
+		t.Fatalf("PendingDryRun() = %+v, %v", run, ok)
+	}
+	if err := h.ApplyDryRun("run-1", []string{"/repo/c.go"}); err == nil {
+		t.Error("expected an error applying a file the dry run did not change")
+	}
+	if _, ok := h.PendingDryRun("session-1"); !ok {
+		t.Fatal("a rejected apply should keep the dry run pending")
+	}
+	if err := h.ApplyDryRun("run-1", []string{"/repo/b.go"}); err != nil {
+		t.Fatalf("ApplyDryRun() error = %v", err)
+	}
+	if err := h.ApplyDryRun("run-1", nil); err == nil {
+		t.Error("expected an error applying a dry run twice")
+	}
+
+	store("run-2")
+	if err := h.DiscardDryRun("run-2"); err != nil {
+		t.Fatalf("DiscardDryRun() error = %v", err)
+	}
+	if _, ok := h.PendingDryRun("session-1"); ok {
+		t.Error("a discarded dry run should no longer be pending")
+	}
+}
+
+func TestVerifyCitations(t *testing.T) {
+	sources := []message.EvidenceSource{
+		{Tool: "view", Path: "/repo/internal/app/app.go", StartLine: 1, EndLine: 120},
+		{Tool: "grep", Path: "/repo/cmd/root.go", StartLine: 42, EndLine: 42},
+		{Tool: "fetch", URL: "https://pkg.go.dev/context"},
+	}
+	text := "The app is wired in internal/app/app.go:80-95. The root command starts at cmd/root.go:42.\n" +
+		"Config is loaded in internal/config/config.go:10. See http://localhost:8080/docs.go:3 for details."
+
+	citations := VerifyCitations(text, sources)
+	if len(citations) != 3 {
+		t.Fatalf("VerifyCitations() found %d citations, want 3: %+v", len(citations), citations)
+	}
+	want := []struct {
+		path       string
+		start, end int
+		verified   bool
+	}{
+		{"internal/app/app.go", 80, 95, true},
+		{"cmd/root.go", 42, 42, true},
+		{"internal/config/config.go", 10, 10, false},
+	}
+	for i, w := range want {
+		c := citations[i]
+		if c.Path != w.path || c.StartLine != w.start || c.EndLine != w.end || c.Verified != w.verified {
+			t.Errorf("citation %d = %+v, want %+v", i, c, w)
+		}
+	}
+
+	if VerifyCitations("app.go:110-130", sources)[0].Verified {
+		t.Error("a range reaching past the lines read should not verify")
+	}
+
+	flagged, stripped := applyEvidencePolicy(text, citations, EvidencePolicyFlag)
+	if stripped != 0 || !strings.Contains(flagged, "internal/config/config.go:10 [unverified]") ||
+		strings.Contains(flagged, "cmd/root.go:42 [unverified]") {
+		t.Errorf("flag policy = %q", flagged)
+	}
+
+	cleaned, stripped := applyEvidencePolicy(text, citations, EvidencePolicyStrip)
+	if stripped != 1 || strings.Contains(cleaned, "Config is loaded") || !strings.Contains(cleaned, "See http://localhost:8080") {
+		t.Errorf("strip policy = %q (%d stripped)", cleaned, stripped)
+	}
+
+	if _, err := ParseEvidencePolicy("ignore"); err == nil {
+		t.Error("expected an error for an unknown evidence policy")
+	}
+}
+
+func TestProgressTracker(t *testing.T) {
+	tracker := newProgressTracker("session-1")
+	msg := message.Message{ID: "msg-1", SessionID: "session-1", Role: message.Assistant}
+
+	msg.AppendReasoningContent("Looking")
+	msg.AppendContent("Hello")
+	events := tracker.update(msg)
+	if len(events) != 2 || events[0].Type != ProgressReasoningDelta || events[1].Delta != "Hello" {
+		t.Fatalf("update() = %+v, want a reasoning and a content delta", events)
+	}
+
+	msg.AppendContent(", world")
+	msg.AddToolCall(message.ToolCall{ID: "call-1", Name: "view"})
+	events = tracker.update(msg)
+	if len(events) != 1 || events[0].Delta != ", world" {
+		t.Fatalf("update() = %+v, want only the new text while the call streams", events)
+	}
+
+	msg.FinishToolCall("call-1")
+	events = tracker.update(msg)
+	if len(events) != 1 || events[0].Type != ProgressToolCallStarted || events[0].ToolCall.ID != "call-1" {
+		t.Fatalf("update() = %+v, want the call to start", events)
+	}
+	if events := tracker.update(msg); len(events) != 0 {
+		t.Errorf("update() of an unchanged message = %+v, want no events", events)
+	}
+
+	results := message.Message{ID: "msg-2", SessionID: "session-1", Role: message.Tool}
+	results.AddToolResult(message.ToolResult{ToolCallID: "call-1", Content: "package main"})
+	events = tracker.update(results)
+	if len(events) != 1 || events[0].Type != ProgressToolCallFinished || events[0].Result.Content != "package main" {
+		t.Fatalf("update() = %+v, want the call to finish", events)
+	}
+
+	msg.SetContent("rewritten")
+	if events := tracker.update(msg); len(events) != 0 {
+		t.Errorf("update() of rewritten text = %+v, want no events", events)
+	}
+}
+
+type brokerMessages struct {
+	message.Service
+	broker *pubsub.Broker[message.Message]
+}
+
+func (m *brokerMessages) Subscribe(ctx context.Context) <-chan pubsub.Event[message.Message] {
+	return m.broker.Subscribe(ctx)
+}
+
+func TestWatchProgress(t *testing.T) {
+	messages := &brokerMessages{broker: pubsub.NewBroker[message.Message]()}
+	h := NewSuperClaudeHandler(nil, nil, messages)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	progress := h.WatchProgress(ctx, "session-1")
+
+	other := message.Message{ID: "other", SessionID: "session-2", Role: message.Assistant}
+	other.AppendContent("not ours")
+	messages.broker.Publish(pubsub.UpdatedEvent, other)
+
+	msg := message.Message{ID: "msg-1", SessionID: "session-1", Role: message.Assistant}
+	msg.AppendContent("Done")
+	messages.broker.Publish(pubsub.UpdatedEvent, msg)
+
+	event := <-progress
+	if event.Type != ProgressContentDelta || event.Delta != "Done" {
+		t.Fatalf("first event = %+v, want the content delta of the session", event)
+	}
+
+	h.publishResult(CommandResult{SessionID: "session-2", Error: errors.New("not ours")})
+	h.publishResult(CommandResult{SessionID: "session-1", Content: "Done"})
+	event = <-progress
+	if event.Type != ProgressMessage || event.Content != "Done" {
+		t.Fatalf("final event = %+v, want the message", event)
+	}
+	if _, open := <-progress; open {
+		t.Error("progress should be closed after the final event")
+	}
+}
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "internal"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "internal", "server.go"), []byte("package internal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	values := func(completions []Completion) []string {
+		var out []string
+		for _, c := range completions {
+			out = append(out, c.Value)
+		}
+		return out
+	}
+	has := func(completions []Completion, value string) bool {
+		for _, c := range completions {
+			if c.Value == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("commands", func(t *testing.T) {
+		got := Complete("/user:s", len("/user:s"), dir)
+		if !has(got, "/user:scan") || !has(got, "/user:spawn") || has(got, "/user:build") {
+			t.Fatalf("completions = %v, want the commands starting with s", values(got))
+		}
+		if got[0].Description == "" || got[0].Kind != CompletionCommand {
+			t.Errorf("completion = %+v, want a described command", got[0])
+		}
+		if got[0].Start != 0 || got[0].End != len("/user:s") {
+			t.Errorf("range = %d-%d, want the whole word", got[0].Start, got[0].End)
+		}
+	})
+
+	t.Run("uses the cursor", func(t *testing.T) {
+		input := "/user:an src/"
+		got := Complete(input, len("/user:an"), dir)
+		if len(got) != 1 || got[0].Value != "/user:analyze" {
+			t.Fatalf("completions = %v, want /user:analyze", values(got))
+		}
+		if applied := got[0].Apply(input); applied != "/user:analyze src/" {
+			t.Errorf("applied = %q", applied)
+		}
+	})
+
+	t.Run("personas", func(t *testing.T) {
+		got := Complete("/persona:arch", len("/persona:arch"), dir)
+		if len(got) != 1 || got[0].Value != "/persona:architect" || got[0].Description == "" {
+			t.Fatalf("completions = %+v, want the architect persona with its identity", got)
+		}
+		got = Complete("/persona:architect → /user:bu", len("/persona:architect → /user:bu"), dir)
+		if len(got) != 1 || got[0].Value != "/user:build" {
+			t.Errorf("completions = %v, want the command after the persona", values(got))
+		}
+	})
+
+	t.Run("command flags", func(t *testing.T) {
+		input := "/user:explain --visual --"
+		got := Complete(input, len(input), dir)
+		if got[0].Value != "--depth" {
+			t.Errorf("first completion = %q, want the command's own flags first", got[0].Value)
+		}
+		if has(got, "--visual") {
+			t.Error("flags that are already set should not be suggested")
+		}
+		if !has(got, "--ultrathink") || !has(got, "--persona-security") {
+			t.Errorf("completions = %v, want the core and persona flags", values(got))
+		}
+	})
+
+	t.Run("flag values", func(t *testing.T) {
+		input := "/user:test --type "
+		got := Complete(input, len(input), dir)
+		want := []string{"all", "unit", "integration", "e2e"}
+		if strings.Join(values(got), ",") != strings.Join(want, ",") {
+			t.Errorf("completions = %v, want %v", values(got), want)
+		}
+		input = "/user:test --unit "
+		if got := Complete(input, len(input), dir); has(got, "true") {
+			t.Errorf("boolean flags take no value, got %v", values(got))
+		}
+	})
+
+	t.Run("collaboration patterns", func(t *testing.T) {
+		got := Complete("/user:collab sec", len("/user:collab sec"), dir)
+		if len(got) != 1 || got[0].Value != "security-review" || got[0].Kind != CompletionPattern {
+			t.Errorf("completions = %+v, want the security-review pattern", got)
+		}
+	})
+
+	t.Run("target paths", func(t *testing.T) {
+		input := "/user:analyze --think serv"
+		got := Complete(input, len(input), dir)
+		if !has(got, filepath.Join("internal", "server.go")) {
+			t.Errorf("completions = %v, want the matching file", values(got))
+		}
+	})
+
+	t.Run("regular messages", func(t *testing.T) {
+		if got := Complete("hello there", len("hello there"), dir); len(got) != 0 {
+			t.Errorf("completions = %v, want none", values(got))
+		}
+	})
+}
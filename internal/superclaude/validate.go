@@ -0,0 +1,226 @@
+package superclaude
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// DryRun holds the file changes of a --validate run until they are applied or discarded
+type DryRun struct {
+	ID        string             `json:"id"`
+	SessionID string             `json:"session_id"`
+	Command   string             `json:"command"`
+	Changes   []tools.FileChange `json:"changes"`
+}
+
+// Diff returns one unified diff covering every changed file
+func (d DryRun) Diff() string {
+	var sb strings.Builder
+	for _, change := range d.Changes {
+		sb.WriteString(change.Diff())
+	}
+	return sb.String()
+}
+
+// Paths returns the changed file paths
+func (d DryRun) Paths() []string {
+	paths := make([]string, len(d.Changes))
+	for i, change := range d.Changes {
+		paths[i] = change.Path
+	}
+	return paths
+}
+
+// DryRunEventType is the type of a dry run lifecycle event
+type DryRunEventType string
+
+const (
+	DryRunEventReady     DryRunEventType = "ready"
+	DryRunEventApplied   DryRunEventType = "applied"
+	DryRunEventDiscarded DryRunEventType = "discarded"
+	DryRunEventFailed    DryRunEventType = "failed"
+)
+
+// DryRunEvent reports progress of a --validate run. A ready event without changes
+// means the run finished without touching any file.
+type DryRunEvent struct {
+	Type      DryRunEventType
+	SessionID string
+	DryRun    DryRun
+	// Applied lists the files written to disk by an applied event
+	Applied []string
+	Error   error
+}
+
+// pendingDryRun is a finished dry run waiting for review
+type pendingDryRun struct {
+	run     DryRun
+	overlay *tools.Overlay
+}
+
+// SubscribeDryRuns returns dry run lifecycle events
+func (h *SuperClaudeHandler) SubscribeDryRuns(ctx context.Context) <-chan pubsub.Event[DryRunEvent] {
+	return h.dryRuns.Subscribe(ctx)
+}
+
+func (h *SuperClaudeHandler) publishDryRun(event DryRunEvent) {
+	h.dryRuns.Publish(pubsub.UpdatedEvent, event)
+}
+
+// PendingDryRun returns the dry run awaiting review in a session
+func (h *SuperClaudeHandler) PendingDryRun(sessionID string) (DryRun, bool) {
+	var found *pendingDryRun
+	h.pendingDryRuns.Range(func(_, value interface{}) bool {
+		pending := value.(*pendingDryRun)
+		if pending.run.SessionID == sessionID {
+			found = pending
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return DryRun{}, false
+	}
+	return found.run, true
+}
+
+// DryRunSession returns the session of a dry run awaiting review
+func (h *SuperClaudeHandler) DryRunSession(dryRunID string) (string, bool) {
+	value, ok := h.pendingDryRuns.Load(dryRunID)
+	if !ok {
+		return "", false
+	}
+	return value.(*pendingDryRun).run.SessionID, true
+}
+
+// ApplyDryRun writes the changes of a dry run to disk. An empty paths list applies
+// every change, otherwise only the listed files are written and the rest is dropped.
+func (h *SuperClaudeHandler) ApplyDryRun(dryRunID string, paths []string) error {
+	value, ok := h.pendingDryRuns.Load(dryRunID)
+	if !ok {
+		return fmt.Errorf("no pending dry run with ID %s", dryRunID)
+	}
+	pending := value.(*pendingDryRun)
+
+	changed := pending.run.Paths()
+	for _, path := range paths {
+		if !contains(changed, path) {
+			return fmt.Errorf("%s is not changed by dry run %s", path, dryRunID)
+		}
+	}
+	if _, loaded := h.pendingDryRuns.LoadAndDelete(dryRunID); !loaded {
+		return fmt.Errorf("dry run %s has already been decided", dryRunID)
+	}
+
+	applied := paths
+	if len(applied) == 0 {
+		applied = changed
+	}
+	if err := pending.overlay.Apply(applied...); err != nil {
+		h.publishDryRun(DryRunEvent{Type: DryRunEventFailed, SessionID: pending.run.SessionID, DryRun: pending.run, Error: err})
+		return err
+	}
+
+	h.publishDryRun(DryRunEvent{Type: DryRunEventApplied, SessionID: pending.run.SessionID, DryRun: pending.run, Applied: applied})
+	logging.Info("Applied dry run", "id", dryRunID, "files", len(applied), "changed", len(changed))
+	return nil
+}
+
+// DiscardDryRun drops the changes of a dry run, nothing is written to disk
+func (h *SuperClaudeHandler) DiscardDryRun(dryRunID string) error {
+	value, ok := h.pendingDryRuns.LoadAndDelete(dryRunID)
+	if !ok {
+		return fmt.Errorf("no pending dry run with ID %s", dryRunID)
+	}
+	pending := value.(*pendingDryRun)
+	h.publishDryRun(DryRunEvent{Type: DryRunEventDiscarded, SessionID: pending.run.SessionID, DryRun: pending.run})
+	return nil
+}
+
+// handleDryRun runs a command with its file changes redirected to an in-memory overlay.
+// Once the agent is done the combined diff is reported for review.
+func (h *SuperClaudeHandler) handleDryRun(ctx context.Context, sessionID string, parsed *ParsedCommand, runner agent.Service, prompt string) error {
+	if h.messages == nil {
+		return fmt.Errorf("--validate requires a message service")
+	}
+
+	overlay := tools.NewOverlay()
+	events, err := runner.Run(tools.WithOverlay(ctx, overlay), sessionID, buildDryRunPrompt(prompt))
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer logging.RecoverPanic("superclaude.validate", nil)
+
+		run := DryRun{
+			ID:        uuid.New().String(),
+			SessionID: sessionID,
+			Command:   parsed.RawInput,
+		}
+
+		result := <-events
+		if result.Error != nil {
+			h.publishDryRun(DryRunEvent{Type: DryRunEventFailed, SessionID: sessionID, DryRun: run, Error: result.Error})
+			logging.ErrorPersist(fmt.Sprintf("Dry run of %s failed: %v", parsed.Command, result.Error))
+			return
+		}
+
+		if parsed.Flags.Evidence {
+			if _, err := h.attachEvidence(context.Background(), result.Message, result.Evidence); err != nil {
+				logging.ErrorPersist(fmt.Sprintf("failed to attach evidence: %v", err))
+			}
+		}
+
+		run.Changes = overlay.Changes()
+		if len(run.Changes) == 0 {
+			h.publishDryRun(DryRunEvent{Type: DryRunEventReady, SessionID: sessionID, DryRun: run})
+			return
+		}
+
+		h.pendingDryRuns.Store(run.ID, &pendingDryRun{run: run, overlay: overlay})
+		if err := h.writeReport(context.Background(), sessionID, formatDryRunReport(run), 0); err != nil {
+			logging.ErrorPersist(fmt.Sprintf("failed to write dry run report: %v", err))
+		}
+		h.publishDryRun(DryRunEvent{Type: DryRunEventReady, SessionID: sessionID, DryRun: run})
+	}()
+
+	return nil
+}
+
+// formatDryRunReport renders the combined diff of a dry run as a session message
+func formatDryRunReport(run DryRun) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Dry run: %d files changed\n\n", len(run.Changes))
+	for _, change := range run.Changes {
+		switch {
+		case change.Created:
+			fmt.Fprintf(&sb, "- created %s\n", change.Path)
+		case change.Deleted:
+			fmt.Fprintf(&sb, "- deleted %s\n", change.Path)
+		default:
+			fmt.Fprintf(&sb, "- modified %s\n", change.Path)
+		}
+	}
+	sb.WriteString("\n```diff\n")
+	sb.WriteString(run.Diff())
+	sb.WriteString("```\n\nNothing has been written to disk yet.")
+	return sb.String()
+}
+
+// buildDryRunPrompt tells the agent its changes are collected for review
+func buildDryRunPrompt(prompt string) string {
+	return prompt + `
+
+DRY RUN:
+- File changes made with the edit, write and patch tools are collected for review instead of written to disk
+- Bash is limited to read-only commands without pipes or redirects
+- Make every change the task needs, the user decides afterwards which files to apply`
+}
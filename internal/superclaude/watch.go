@@ -0,0 +1,170 @@
+package superclaude
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// reloadDelay debounces bursts of file events into a single reload
+const reloadDelay = 250 * time.Millisecond
+
+var errUnterminatedFrontMatter = errors.New("unterminated front matter")
+
+// definitionDirs returns the user and project directories for a kind of definition
+// file, in load order
+func definitionDirs(kind, workingDir string) []string {
+	var dirs []string
+
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configDir = filepath.Join(home, ".config")
+		}
+	}
+	if configDir != "" {
+		dirs = append(dirs, filepath.Join(configDir, "opencode", kind))
+	}
+
+	if workingDir != "" {
+		dirs = append(dirs, filepath.Join(workingDir, ".opencode", kind))
+	}
+	return dirs
+}
+
+// isDefinitionFile reports whether a file holds a YAML or Markdown definition
+func isDefinitionFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".md":
+		return true
+	}
+	return false
+}
+
+// existingDir returns dir, or else its closest parent that exists
+func existingDir(dir string) string {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// watchDefinitions calls reload whenever a definition file in dirs changes. A dir
+// that does not exist yet is picked up when it is created: its closest existing
+// parent is watched until then.
+func watchDefinitions(ctx context.Context, kind string, dirs []string, reload func()) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logging.Error("Error creating watcher", "kind", kind, "error", err)
+		return
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]bool)
+	// watch watches each dir or its closest existing parent, dropping the watches no
+	// longer needed. It reports whether a dir was created or removed since last time.
+	watch := func() bool {
+		needed := make(map[string]bool)
+		for _, dir := range dirs {
+			if path := existingDir(dir); path != "" {
+				needed[path] = true
+			}
+		}
+		changed := false
+		for _, dir := range dirs {
+			if needed[dir] != watched[dir] {
+				changed = true
+			}
+		}
+		for path := range watched {
+			if !needed[path] {
+				// The watch of a removed dir is already gone
+				_ = watcher.Remove(path)
+				delete(watched, path)
+			}
+		}
+		for path := range needed {
+			if watched[path] {
+				continue
+			}
+			if err := watcher.Add(path); err != nil {
+				logging.Warn("Error watching definitions", "kind", kind, "path", path, "error", err)
+				continue
+			}
+			watched[path] = true
+		}
+		return changed
+	}
+	watch()
+	if len(watched) == 0 {
+		return
+	}
+
+	var timer *time.Timer
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			changed := false
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				changed = watch()
+			}
+			if !changed && !(isDefinitionFile(event.Name) && slices.Contains(dirs, filepath.Dir(event.Name))) {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(reloadDelay, reload)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logging.Error("Error watching definitions", "kind", kind, "error", err)
+		}
+	}
+}
+
+// splitFrontMatter separates YAML front matter from a Markdown body
+func splitFrontMatter(data []byte) (frontMatter []byte, body string, err error) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return nil, content, nil
+	}
+
+	end := strings.Index(content[4:], "\n---")
+	if end < 0 {
+		return nil, "", errUnterminatedFrontMatter
+	}
+	body = strings.TrimPrefix(content[4+end+4:], "\n")
+	return []byte(content[4 : 4+end]), body, nil
+}
+
+// decodeStrictYAML decodes YAML strictly so typos in field names are reported
+func decodeStrictYAML(data []byte, out interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	return decoder.Decode(out)
+}
@@ -0,0 +1,31 @@
+package tenant
+
+import "context"
+
+type contextKey struct{}
+
+// scope is the tenant work in a context is done for
+type scope struct {
+	tenantID string
+	// admitted is set once the request was counted against the tenant's quotas
+	admitted bool
+	// running is set within an agent run, whose nested runs share its admission
+	running bool
+}
+
+// WithTenant returns a context whose agent runs belong to tenantID
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{tenantID: tenantID})
+}
+
+// WithRequest returns a context for a request of tenantID that Admit accepted. The
+// agent runs of the request are not counted as requests again.
+func WithRequest(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{tenantID: tenantID, admitted: true})
+}
+
+// FromContext returns the tenant work in ctx is done for
+func FromContext(ctx context.Context) (string, bool) {
+	current, ok := ctx.Value(contextKey{}).(scope)
+	return current.tenantID, ok && current.tenantID != ""
+}
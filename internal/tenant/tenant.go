@@ -0,0 +1,438 @@
+// Package tenant persists the tenants of the multi-tenant configuration manager and
+// enforces their quotas on agent runs and MCP requests.
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/llm/provider"
+)
+
+// DefaultTenant owns the sessions and requests that name no tenant. It has no
+// quotas until it is created.
+const DefaultTenant = "default"
+
+// reloadInterval is how often tenants are reloaded from the database, picking up
+// the changes made with superclaude-config
+const reloadInterval = 30 * time.Second
+
+// ErrUnavailable is returned for requests of tenants that do not exist or are not
+// active
+var ErrUnavailable = errors.New("tenant unavailable")
+
+// MonthlyUsage is the tokens and cost of a tenant in a month
+type MonthlyUsage struct {
+	Period           string    `json:"period"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	Cost             float64   `json:"cost"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Service stores tenants in the database and admits the requests and agent runs of
+// tenants within their quotas. Tokens, sessions and storage are measured in the
+// database; requests per minute and concurrent runs are counted per process.
+type Service struct {
+	q       db.Querier
+	manager *config.MultiTenantConfigManager
+	now     func() time.Time
+
+	mu       sync.Mutex
+	requests map[string][]time.Time
+	running  map[string]int
+	reloaded time.Time
+}
+
+// NewService stores the tenants of manager in the database, loading those already
+// stored
+func NewService(q db.Querier, manager *config.MultiTenantConfigManager) (*Service, error) {
+	s := &Service{
+		q:        q,
+		manager:  manager,
+		now:      time.Now,
+		requests: make(map[string][]time.Time),
+		running:  make(map[string]int),
+	}
+	if err := manager.SetTenantStore(s); err != nil {
+		return nil, err
+	}
+	s.reloaded = s.now()
+	return s, nil
+}
+
+// Manager returns the configuration manager of the tenants
+func (s *Service) Manager() *config.MultiTenantConfigManager {
+	return s.manager
+}
+
+// Admit counts a request of tenantID, such as an MCP request, and refuses it when
+// the tenant is unavailable or over a quota. The agent runs of an admitted request
+// should use a context from WithRequest, so they are not counted again.
+func (s *Service) Admit(ctx context.Context, tenantID string) error {
+	if err := s.available(tenantID); err != nil {
+		return err
+	}
+	usage, err := s.measure(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	window := s.window(tenantID)
+	usage.RequestsPerMinute = len(window) + 1
+	usage.ConcurrentRequests = s.running[tenantID]
+	if err := s.manager.ValidateTenantQuotas(tenantID, usage); err != nil {
+		return err
+	}
+	s.requests[tenantID] = append(window, s.now())
+	return nil
+}
+
+// Acquire admits an agent run in sessionID for the tenant of ctx, or else the
+// tenant the session, or its parent, belongs to. Sessions of no tenant are bound
+// to the default tenant once a run is admitted. Sessions of a deleted tenant stay
+// bound to it and are refused. The run is counted as a request unless ctx is an
+// admitted request, and holds one of the tenant's concurrent requests until release
+// is called. Runs started within the run share its admission.
+func (s *Service) Acquire(ctx context.Context, sessionID string) (context.Context, func(), error) {
+	current, _ := ctx.Value(contextKey{}).(scope)
+	tenantID := current.tenantID
+	if tenantID == "" {
+		var err error
+		if tenantID, err = s.sessionTenant(ctx, sessionID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !current.running {
+		if err := s.available(tenantID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if current.running {
+		if err := s.bind(ctx, sessionID, tenantID); err != nil {
+			return nil, nil, err
+		}
+		return ctx, func() {}, nil
+	}
+
+	usage, err := s.measure(ctx, tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if active, err := s.isActiveSession(ctx, tenantID, sessionID); err != nil {
+		return nil, nil, err
+	} else if !active {
+		usage.ActiveSessions++
+	}
+	// A run uses at least one token, so a tenant at its token quota is refused
+	usage.TokensThisMonth++
+
+	s.mu.Lock()
+	window := s.window(tenantID)
+	usage.RequestsPerMinute = len(window)
+	if !current.admitted {
+		usage.RequestsPerMinute++
+	}
+	usage.ConcurrentRequests = s.running[tenantID] + 1
+	if err := s.manager.ValidateTenantQuotas(tenantID, usage); err != nil {
+		s.mu.Unlock()
+		return nil, nil, err
+	}
+	if !current.admitted {
+		s.requests[tenantID] = append(window, s.now())
+	}
+	s.running[tenantID]++
+	s.mu.Unlock()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.running[tenantID]--; s.running[tenantID] <= 0 {
+				delete(s.running, tenantID)
+			}
+		})
+	}
+	// A refused run leaves the session unbound, so it is not held to the tenant
+	if err := s.bind(ctx, sessionID, tenantID); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return context.WithValue(ctx, contextKey{}, scope{tenantID: tenantID, admitted: true, running: true}), release, nil
+}
+
+// TrackUsage adds the tokens and cost of a model response in sessionID to the
+// usage of its tenant this month
+func (s *Service) TrackUsage(ctx context.Context, sessionID string, usage provider.TokenUsage, cost float64) error {
+	tenantID, ok := FromContext(ctx)
+	if !ok {
+		var err error
+		if tenantID, err = s.sessionTenant(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+	return s.q.AddTenantUsage(ctx, db.AddTenantUsageParams{
+		TenantID:         tenantID,
+		Period:           period(s.now()),
+		PromptTokens:     usage.InputTokens + usage.CacheCreationTokens,
+		CompletionTokens: usage.OutputTokens + usage.CacheReadTokens,
+		Cost:             cost,
+	})
+}
+
+// UsageHistory returns the monthly usage of tenantID, newest first
+func (s *Service) UsageHistory(ctx context.Context, tenantID string) ([]MonthlyUsage, error) {
+	rows, err := s.q.ListTenantUsage(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]MonthlyUsage, 0, len(rows))
+	for _, row := range rows {
+		history = append(history, fromDBUsage(row))
+	}
+	return history, nil
+}
+
+// LoadTenants implements config.TenantStore
+func (s *Service) LoadTenants(ctx context.Context) ([]*config.TenantConfig, error) {
+	rows, err := s.q.ListTenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenants := make([]*config.TenantConfig, 0, len(rows))
+	for _, row := range rows {
+		tenant, err := fromDBTenant(row)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+// SaveTenant implements config.TenantStore
+func (s *Service) SaveTenant(ctx context.Context, tenant *config.TenantConfig) error {
+	params := db.UpsertTenantParams{
+		ID:     tenant.ID,
+		Name:   tenant.Name,
+		Status: int64(tenant.Status),
+	}
+	fields := []struct {
+		target *string
+		value  interface{}
+	}{
+		{&params.Quotas, tenant.Quotas},
+		{&params.Features, tenant.Features},
+		{&params.Overrides, tenant.Overrides},
+		{&params.Metadata, tenant.Metadata},
+	}
+	for _, field := range fields {
+		data, err := json.Marshal(field.value)
+		if err != nil {
+			return err
+		}
+		*field.target = string(data)
+	}
+	_, err := s.q.UpsertTenant(ctx, params)
+	return err
+}
+
+// DeleteTenant implements config.TenantStore. The usage of the tenant is removed.
+// Its sessions are kept and stay bound to it, so their runs are refused instead of
+// falling back to the default tenant.
+func (s *Service) DeleteTenant(ctx context.Context, tenantID string) error {
+	return s.q.DeleteTenant(ctx, tenantID)
+}
+
+// TenantUsage implements config.TenantStore
+func (s *Service) TenantUsage(ctx context.Context, tenantID string) (*config.TenantUsage, error) {
+	usage, err := s.measure(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage.RequestsPerMinute = len(s.window(tenantID))
+	usage.ConcurrentRequests = s.running[tenantID]
+	return usage, nil
+}
+
+// available reloads the tenants when they are stale and checks that tenantID
+// exists and is active
+func (s *Service) available(tenantID string) error {
+	s.mu.Lock()
+	stale := s.now().Sub(s.reloaded) >= reloadInterval
+	if stale {
+		s.reloaded = s.now()
+	}
+	s.mu.Unlock()
+	if stale {
+		if err := s.manager.Reload(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.manager.GetTenantConfig(tenantID); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// measure returns the tokens used this month, active sessions and storage of
+// tenantID
+func (s *Service) measure(ctx context.Context, tenantID string) (*config.TenantUsage, error) {
+	usage := &config.TenantUsage{}
+
+	monthly, err := s.q.GetTenantUsage(ctx, db.GetTenantUsageParams{TenantID: tenantID, Period: period(s.now())})
+	switch {
+	case err == nil:
+		usage.TokensThisMonth = monthly.PromptTokens + monthly.CompletionTokens
+		usage.LastActivity = time.Unix(monthly.UpdatedAt, 0)
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to get the usage of tenant %s: %w", tenantID, err)
+	}
+
+	active, err := s.q.CountActiveTenantSessions(ctx, db.CountActiveTenantSessionsParams{
+		TenantID:  tenantID,
+		UpdatedAt: s.activeSince(tenantID).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count the sessions of tenant %s: %w", tenantID, err)
+	}
+	usage.ActiveSessions = int(active)
+
+	bytes, err := s.q.GetTenantStorage(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure the storage of tenant %s: %w", tenantID, err)
+	}
+	usage.StorageUsedMB = int(math.Ceil(float64(bytes) / (1 << 20)))
+	return usage, nil
+}
+
+// activeSince is when a session must have last been updated to count as active:
+// within the session timeout of the tenant, any time without one
+func (s *Service) activeSince(tenantID string) time.Time {
+	tenant, err := s.manager.GetTenant(tenantID)
+	if err != nil || tenant.Quotas == nil || tenant.Quotas.SessionTimeout <= 0 {
+		return time.Unix(0, 0)
+	}
+	return s.now().Add(-tenant.Quotas.SessionTimeout)
+}
+
+// isActiveSession reports whether sessionID is already counted among the active
+// sessions of tenantID. Task sessions never count.
+func (s *Service) isActiveSession(ctx context.Context, tenantID, sessionID string) (bool, error) {
+	session, err := s.q.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+	if session.ParentSessionID.Valid {
+		return true, nil
+	}
+	bound, err := s.q.GetSessionTenant(ctx, sessionID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to get the tenant of session %s: %w", sessionID, err)
+	}
+	return bound == tenantID && session.UpdatedAt >= s.activeSince(tenantID).Unix(), nil
+}
+
+// bind binds sessionID to tenantID unless it already belongs to a tenant
+func (s *Service) bind(ctx context.Context, sessionID, tenantID string) error {
+	if err := s.q.BindTenantSession(ctx, db.BindTenantSessionParams{SessionID: sessionID, TenantID: tenantID}); err != nil {
+		return fmt.Errorf("failed to bind session %s to tenant %s: %w", sessionID, tenantID, err)
+	}
+	return nil
+}
+
+// sessionTenant returns the tenant sessionID, or else its parent, is bound to, or
+// the default tenant
+func (s *Service) sessionTenant(ctx context.Context, sessionID string) (string, error) {
+	for id := sessionID; id != ""; {
+		tenantID, err := s.q.GetSessionTenant(ctx, id)
+		if err == nil {
+			return tenantID, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("failed to get the tenant of session %s: %w", id, err)
+		}
+		session, err := s.q.GetSessionByID(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to get session %s: %w", id, err)
+		}
+		id = session.ParentSessionID.String
+	}
+	return DefaultTenant, nil
+}
+
+// window returns the requests of tenantID within the last minute. The service must
+// be locked.
+func (s *Service) window(tenantID string) []time.Time {
+	cutoff := s.now().Add(-time.Minute)
+	requests := s.requests[tenantID]
+	keep := 0
+	for keep < len(requests) && !requests[keep].After(cutoff) {
+		keep++
+	}
+	requests = requests[keep:]
+	if len(requests) == 0 {
+		delete(s.requests, tenantID)
+		return nil
+	}
+	s.requests[tenantID] = requests
+	return requests
+}
+
+// period is the month of t that usage is added up in
+func period(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+func fromDBTenant(row db.Tenant) (*config.TenantConfig, error) {
+	tenant := &config.TenantConfig{
+		ID:        row.ID,
+		Name:      row.Name,
+		Status:    config.TenantStatus(row.Status),
+		CreatedAt: time.Unix(row.CreatedAt, 0),
+		UpdatedAt: time.Unix(row.UpdatedAt, 0),
+	}
+	fields := []struct {
+		name   string
+		data   string
+		target interface{}
+	}{
+		{"quotas", row.Quotas, &tenant.Quotas},
+		{"features", row.Features, &tenant.Features},
+		{"overrides", row.Overrides, &tenant.Overrides},
+		{"metadata", row.Metadata, &tenant.Metadata},
+	}
+	for _, field := range fields {
+		if err := json.Unmarshal([]byte(field.data), field.target); err != nil {
+			return nil, fmt.Errorf("tenant %s has invalid %s: %w", row.ID, field.name, err)
+		}
+	}
+	return tenant, nil
+}
+
+func fromDBUsage(row db.TenantUsage) MonthlyUsage {
+	return MonthlyUsage{
+		Period:           row.Period,
+		PromptTokens:     row.PromptTokens,
+		CompletionTokens: row.CompletionTokens,
+		Cost:             row.Cost,
+		UpdatedAt:        time.Unix(row.UpdatedAt, 0),
+	}
+}
@@ -0,0 +1,208 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/llm/provider"
+	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB returns a migrated database in a temporary directory
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "opencode.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	_, err = conn.Exec("PRAGMA foreign_keys = ON;")
+	require.NoError(t, err)
+
+	goose.SetBaseFS(db.FS)
+	require.NoError(t, goose.SetDialect("sqlite3"))
+	goose.SetLogger(goose.NopLogger())
+	require.NoError(t, goose.Up(conn, "migrations"))
+	return conn
+}
+
+func newTestService(t *testing.T, conn *sql.DB) *Service {
+	t.Helper()
+	manager := config.NewMultiTenantConfigManager(&config.SuperClaudeConfig{}, config.IsolationShared)
+	s, err := NewService(db.New(conn), manager)
+	require.NoError(t, err)
+	return s
+}
+
+// testClock is a time that tests move forward
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func requireQuotaExceeded(t *testing.T, err error, quota string) {
+	t.Helper()
+	var exceeded *config.QuotaExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, quota, exceeded.Quota)
+}
+
+func TestServicePersistsTenants(t *testing.T) {
+	conn := newTestDB(t)
+	s := newTestService(t, conn)
+	_, err := s.Manager().CreateTenant("acme", "ACME", &config.TenantQuotas{MaxSessions: 3}, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Manager().UpdateTenantConfig("acme", map[string]interface{}{"performance.batch_size": 25}))
+	require.NoError(t, s.Manager().SetTenantStatus("acme", config.TenantSuspended))
+	_, err = s.Manager().CreateTenant("globex", "Globex", nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Manager().DeleteTenant("globex"))
+
+	// A new process loads the tenants back, with their overrides applied
+	reloaded := newTestService(t, conn)
+	tenants := reloaded.Manager().ListTenants()
+	require.Len(t, tenants, 1)
+	tenant := tenants[0]
+	assert.Equal(t, "ACME", tenant.Name)
+	assert.Equal(t, config.TenantSuspended, tenant.Status)
+	assert.Equal(t, 3, tenant.Quotas.MaxSessions)
+	assert.Equal(t, 25, tenant.Config.Performance.BatchSize)
+	assert.Equal(t, "acme", tenant.Config.Logging.StructuredFields["tenant_id"])
+}
+
+func TestAcquireEnforcesQuotas(t *testing.T) {
+	conn := newTestDB(t)
+	s := newTestService(t, conn)
+	clock := &testClock{now: time.Now()}
+	s.now = clock.Now
+	sessions := session.NewService(db.New(conn))
+	ctx := context.Background()
+
+	_, err := s.Manager().CreateTenant("acme", "ACME", &config.TenantQuotas{
+		MaxRequestsPerMinute:  2,
+		MaxConcurrentRequests: 1,
+		MaxTokensPerMonth:     100,
+	}, nil)
+	require.NoError(t, err)
+	sess, err := sessions.Create(ctx, "work")
+	require.NoError(t, err)
+
+	runCtx, release, err := s.Acquire(WithTenant(ctx, "acme"), sess.ID)
+	require.NoError(t, err)
+	tenantID, ok := FromContext(runCtx)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenantID)
+
+	_, _, err = s.Acquire(WithTenant(ctx, "acme"), sess.ID)
+	requireQuotaExceeded(t, err, "max_concurrent_requests")
+
+	// Runs started by the run, such as task agents, share its admission
+	task, err := sessions.CreateTaskSession(ctx, "task-1", sess.ID, "task")
+	require.NoError(t, err)
+	_, releaseTask, err := s.Acquire(runCtx, task.ID)
+	require.NoError(t, err)
+	releaseTask()
+
+	release()
+	release() // releasing twice is harmless
+	_, release, err = s.Acquire(ctx, sess.ID)
+	require.NoError(t, err, "the session stays bound to its tenant")
+	release()
+
+	_, _, err = s.Acquire(ctx, sess.ID)
+	requireQuotaExceeded(t, err, "max_requests_per_minute")
+
+	clock.now = clock.now.Add(time.Minute)
+	require.NoError(t, s.TrackUsage(ctx, task.ID, provider.TokenUsage{InputTokens: 60, OutputTokens: 40}, 0.5))
+	usage, err := s.Manager().GetTenantUsage("acme")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), usage.TokensThisMonth, "task sessions count for the tenant of their parent")
+	assert.Equal(t, 1, usage.ActiveSessions)
+
+	_, _, err = s.Acquire(ctx, sess.ID)
+	requireQuotaExceeded(t, err, "max_tokens_per_month")
+
+	history, err := s.UsageHistory(ctx, "acme")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, period(clock.now), history[0].Period)
+	assert.Equal(t, 0.5, history[0].Cost)
+}
+
+func TestAcquireCountsSessions(t *testing.T) {
+	conn := newTestDB(t)
+	s := newTestService(t, conn)
+	sessions := session.NewService(db.New(conn))
+	ctx := WithTenant(context.Background(), "acme")
+
+	_, err := s.Manager().CreateTenant("acme", "ACME", &config.TenantQuotas{MaxSessions: 1}, nil)
+	require.NoError(t, err)
+	first, err := sessions.Create(ctx, "first")
+	require.NoError(t, err)
+	second, err := sessions.Create(ctx, "second")
+	require.NoError(t, err)
+
+	_, release, err := s.Acquire(ctx, first.ID)
+	require.NoError(t, err)
+	release()
+	_, _, err = s.Acquire(ctx, second.ID)
+	requireQuotaExceeded(t, err, "max_sessions")
+
+	// The refused session was not bound to the tenant
+	runCtx, release, err := s.Acquire(context.Background(), second.ID)
+	require.NoError(t, err)
+	release()
+	tenantID, _ := FromContext(runCtx)
+	assert.Equal(t, DefaultTenant, tenantID)
+}
+
+func TestAcquireRefusesSessionsOfDeletedTenants(t *testing.T) {
+	conn := newTestDB(t)
+	s := newTestService(t, conn)
+	sessions := session.NewService(db.New(conn))
+	ctx := context.Background()
+
+	_, err := s.Manager().CreateTenant("acme", "ACME", nil, nil)
+	require.NoError(t, err)
+	sess, err := sessions.Create(ctx, "work")
+	require.NoError(t, err)
+	_, release, err := s.Acquire(WithTenant(ctx, "acme"), sess.ID)
+	require.NoError(t, err)
+	release()
+
+	require.NoError(t, s.Manager().DeleteTenant("acme"))
+	_, _, err = s.Acquire(ctx, sess.ID)
+	assert.ErrorIs(t, err, ErrUnavailable, "the session does not fall back to the default tenant")
+}
+
+func TestAdmit(t *testing.T) {
+	conn := newTestDB(t)
+	s := newTestService(t, conn)
+	sessions := session.NewService(db.New(conn))
+	ctx := context.Background()
+
+	assert.NoError(t, s.Admit(ctx, DefaultTenant), "the default tenant has no quotas until it is created")
+	assert.ErrorIs(t, s.Admit(ctx, "unknown"), ErrUnavailable)
+
+	_, err := s.Manager().CreateTenant("acme", "ACME", &config.TenantQuotas{MaxRequestsPerMinute: 1}, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Admit(ctx, "acme"))
+	requireQuotaExceeded(t, s.Admit(ctx, "acme"), "max_requests_per_minute")
+
+	// The agent runs of an admitted request are not counted again
+	sess, err := sessions.Create(ctx, "work")
+	require.NoError(t, err)
+	_, release, err := s.Acquire(WithRequest(ctx, "acme"), sess.ID)
+	require.NoError(t, err)
+	release()
+
+	require.NoError(t, s.Manager().SetTenantStatus("acme", config.TenantSuspended))
+	assert.ErrorIs(t, s.Admit(ctx, "acme"), ErrUnavailable)
+}
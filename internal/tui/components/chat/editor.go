@@ -7,15 +7,18 @@ import (
 	"slices"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/opencode-ai/opencode/internal/app"
+	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/opencode-ai/opencode/internal/message"
 	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/opencode-ai/opencode/internal/superclaude"
 	"github.com/opencode-ai/opencode/internal/tui/components/dialog"
 	"github.com/opencode-ai/opencode/internal/tui/layout"
 	"github.com/opencode-ai/opencode/internal/tui/styles"
@@ -36,6 +39,7 @@ type editorCmp struct {
 type EditorKeyMaps struct {
 	Send       key.Binding
 	OpenEditor key.Binding
+	Complete   key.Binding
 }
 
 type bluredEditorKeyMaps struct {
@@ -58,6 +62,10 @@ var editorMaps = EditorKeyMaps{
 		key.WithKeys("ctrl+e"),
 		key.WithHelp("ctrl+e", "open editor"),
 	),
+	Complete: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "complete command"),
+	),
 }
 
 var DeleteKeyMaps = DeleteAttachmentKeyMaps{
@@ -77,6 +85,9 @@ var DeleteKeyMaps = DeleteAttachmentKeyMaps{
 
 const (
 	maxAttachments = 5
+
+	// maxListedCompletions bounds the candidates shown for an ambiguous completion
+	maxListedCompletions = 8
 )
 
 func (m *editorCmp) openEditor() tea.Cmd {
@@ -140,6 +151,73 @@ func (m *editorCmp) send() tea.Cmd {
 	)
 }
 
+// complete completes the SuperClaude command line at the cursor. A single candidate
+// is inserted, several are narrowed to their common prefix and listed.
+func (m *editorCmp) complete() tea.Cmd {
+	value := m.textarea.Value()
+	candidates := superclaude.Complete(value, m.cursorOffset(), config.WorkingDirectory())
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if len(candidates) == 1 {
+		candidate := candidates[0]
+		if !strings.HasPrefix(value[candidate.End:], " ") {
+			candidate.Value += " "
+		}
+		m.setValue(candidate.Apply(value), candidate.Start+len(candidate.Value))
+		return nil
+	}
+
+	common := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate.Value, common.Value) {
+			_, size := utf8.DecodeLastRuneInString(common.Value)
+			common.Value = common.Value[:len(common.Value)-size]
+		}
+	}
+	// Fuzzy path matches need not extend what was typed
+	typed := value[common.Start:common.End]
+	if len(common.Value) > len(typed) && strings.HasPrefix(common.Value, typed) {
+		m.setValue(common.Apply(value), common.Start+len(common.Value))
+	}
+
+	listed := make([]string, 0, maxListedCompletions)
+	for _, candidate := range candidates {
+		if len(listed) == maxListedCompletions {
+			listed = append(listed, fmt.Sprintf("+%d more", len(candidates)-maxListedCompletions))
+			break
+		}
+		listed = append(listed, candidate.Value)
+	}
+	return util.ReportInfo(strings.Join(listed, "  "))
+}
+
+// cursorOffset returns the byte offset of the cursor in the value of the textarea
+func (m *editorCmp) cursorOffset() int {
+	lines := strings.Split(m.textarea.Value(), "\n")
+	row := min(m.textarea.Line(), len(lines)-1)
+	offset := 0
+	for _, line := range lines[:row] {
+		offset += len(line) + 1
+	}
+	info := m.textarea.LineInfo()
+	runes := []rune(lines[row])
+	return offset + len(string(runes[:min(info.StartColumn+info.ColumnOffset, len(runes))]))
+}
+
+// setValue replaces the value of the textarea, leaving the cursor at the byte
+// offset cursor
+func (m *editorCmp) setValue(value string, cursor int) {
+	m.textarea.SetValue(value[:cursor])
+	row, info := m.textarea.Line(), m.textarea.LineInfo()
+	m.textarea.InsertString(value[cursor:])
+	for m.textarea.Line() > row {
+		m.textarea.CursorUp()
+	}
+	m.textarea.SetCursor(info.StartColumn + info.ColumnOffset)
+}
+
 func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	switch msg := msg.(type) {
@@ -198,6 +276,9 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.deleteMode = false
 			return m, nil
 		}
+		if m.textarea.Focused() && key.Matches(msg, editorMaps.Complete) && strings.HasPrefix(m.textarea.Value(), "/") {
+			return m, m.complete()
+		}
 		// Hanlde Enter key
 		if m.textarea.Focused() && key.Matches(msg, editorMaps.Send) {
 			value := m.textarea.Value()
@@ -150,6 +150,22 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					break
 				}
 			}
+		} else if msg.Type == pubsub.DeletedEvent && msg.Payload.SessionID == m.session.ID {
+			// Rewinding a session removes its latest messages
+			for i, v := range m.messages {
+				if v.ID == msg.Payload.ID {
+					m.messages = append(m.messages[:i], m.messages[i+1:]...)
+					delete(m.cachedContent, msg.Payload.ID)
+					if len(m.messages) > 0 {
+						m.currentMsgID = m.messages[len(m.messages)-1].ID
+						delete(m.cachedContent, m.currentMsgID)
+					} else {
+						m.currentMsgID = ""
+					}
+					needsRerender = true
+					break
+				}
+			}
 		}
 		if needsRerender {
 			m.renderView()
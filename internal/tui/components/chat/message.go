@@ -114,6 +114,36 @@ func renderUserMessage(msg message.Message, isFocused bool, width int, position
 }
 
 // Returns multiple uiMessages because of the tool calls
+// renderEvidenceInfo summarizes the evidence ledger of a message and lists its unverified citations
+func renderEvidenceInfo(evidence message.EvidenceContent, width int) []string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	verified := 0
+	var unverified []string
+	for _, citation := range evidence.Citations {
+		if citation.Verified {
+			verified++
+		} else {
+			unverified = append(unverified, citation.Text)
+		}
+	}
+
+	summary := fmt.Sprintf(" evidence: %d sources read, %d/%d citations verified", len(evidence.Sources), verified, len(evidence.Citations))
+	if evidence.Stripped > 0 {
+		summary += fmt.Sprintf(", %d unsupported claims removed", evidence.Stripped)
+	}
+	info := []string{baseStyle.Width(width - 1).Foreground(t.TextMuted()).Render(summary)}
+	if len(unverified) > 0 {
+		info = append(info, baseStyle.
+			Width(width-1).
+			Foreground(t.Warning()).
+			Render(" unverified: "+strings.Join(unverified, ", ")),
+		)
+	}
+	return info
+}
+
 func renderAssistantMessage(
 	msg message.Message,
 	msgIndex int,
@@ -172,6 +202,9 @@ func renderAssistantMessage(
 		if isSummary {
 			info = append(info, baseStyle.Width(width-1).Foreground(t.TextMuted()).Render(" (summary)"))
 		}
+		if evidence, ok := msg.Evidence(); ok {
+			info = append(info, renderEvidenceInfo(evidence, width)...)
+		}
 
 		content = renderMessage(content, false, true, width, info...)
 		messages = append(messages, uiMessage{
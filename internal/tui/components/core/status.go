@@ -8,6 +8,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
 	"github.com/opencode-ai/opencode/internal/llm/models"
 	"github.com/opencode-ai/opencode/internal/lsp"
 	"github.com/opencode-ai/opencode/internal/lsp/protocol"
@@ -28,6 +29,7 @@ type statusCmp struct {
 	width      int
 	messageTTL time.Duration
 	lspClients map[string]*lsp.Client
+	mcpServers []agent.MCPServerState
 	session    session.Session
 }
 
@@ -57,6 +59,12 @@ func (m statusCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.session = msg.Payload
 			}
 		}
+	case pubsub.Event[agent.MCPServerState]:
+		for i, server := range m.mcpServers {
+			if server.Name == msg.Payload.Name {
+				m.mcpServers[i] = msg.Payload
+			}
+		}
 	case util.InfoMsg:
 		m.info = msg
 		ttl := msg.TTL
@@ -143,7 +151,14 @@ func (m statusCmp) View() string {
 		Background(t.BackgroundDarker()).
 		Render(m.projectDiagnostics())
 
-	availableWidht := max(0, m.width-lipgloss.Width(helpWidget)-lipgloss.Width(m.model())-lipgloss.Width(diagnostics)-tokenInfoWidth)
+	mcpStatus := ""
+	if len(m.mcpServers) > 0 {
+		mcpStatus = styles.Padded().
+			Background(t.BackgroundDarker()).
+			Render(m.mcpStatus())
+	}
+
+	availableWidht := max(0, m.width-lipgloss.Width(helpWidget)-lipgloss.Width(m.model())-lipgloss.Width(diagnostics)-lipgloss.Width(mcpStatus)-tokenInfoWidth)
 
 	if m.info.Msg != "" {
 		infoStyle := styles.Padded().
@@ -174,6 +189,7 @@ func (m statusCmp) View() string {
 			Render("")
 	}
 
+	status += mcpStatus
 	status += diagnostics
 	status += m.model()
 	return status
@@ -258,6 +274,42 @@ func (m *statusCmp) projectDiagnostics() string {
 	return strings.Join(diagnostics, " ")
 }
 
+// mcpStatus shows how many of the configured MCP servers are connected, colored
+// by the worst status among them
+func (m statusCmp) mcpStatus() string {
+	t := theme.CurrentTheme()
+
+	connected := 0
+	failed, pending, idle := false, false, false
+	for _, server := range m.mcpServers {
+		switch server.Status {
+		case agent.MCPStatusConnected:
+			connected++
+		case agent.MCPStatusFailed:
+			failed = true
+		case agent.MCPStatusConnecting, agent.MCPStatusReconnecting:
+			pending = true
+		case agent.MCPStatusIdle:
+			idle = true
+		}
+	}
+
+	icon, color := styles.CheckIcon, t.Success()
+	switch {
+	case failed:
+		icon, color = styles.ErrorIcon, t.Error()
+	case pending:
+		icon, color = styles.LoadingIcon, t.Warning()
+	case idle:
+		color = t.TextMuted()
+	}
+
+	return lipgloss.NewStyle().
+		Background(t.BackgroundDarker()).
+		Foreground(color).
+		Render(fmt.Sprintf("%s MCP %d/%d", icon, connected, len(m.mcpServers)))
+}
+
 func (m statusCmp) availableFooterMsgWidth(diagnostics, tokenInfo string) int {
 	tokensWidth := 0
 	if m.session.ID != "" {
@@ -283,11 +335,12 @@ func (m statusCmp) model() string {
 		Render(model.Name)
 }
 
-func NewStatusCmp(lspClients map[string]*lsp.Client) StatusCmp {
+func NewStatusCmp(lspClients map[string]*lsp.Client, mcpServers []agent.MCPServerState) StatusCmp {
 	helpWidget = getHelpWidget()
 
 	return &statusCmp{
 		messageTTL: 10 * time.Second,
 		lspClients: lspClients,
+		mcpServers: mcpServers,
 	}
 }
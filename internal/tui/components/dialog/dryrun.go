@@ -0,0 +1,265 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/opencode-ai/opencode/internal/diff"
+	"github.com/opencode-ai/opencode/internal/superclaude"
+	"github.com/opencode-ai/opencode/internal/tui/layout"
+	"github.com/opencode-ai/opencode/internal/tui/styles"
+	"github.com/opencode-ai/opencode/internal/tui/theme"
+	"github.com/opencode-ai/opencode/internal/tui/util"
+)
+
+// DryRunResponseMsg is sent when the user applies or discards a --validate dry run
+type DryRunResponseMsg struct {
+	DryRunID string
+	Apply    bool
+	// Paths holds the files to apply, nil applies every change
+	Paths []string
+}
+
+// DryRunDialogCmp shows the combined diff of a --validate dry run for review
+type DryRunDialogCmp interface {
+	tea.Model
+	layout.Bindings
+	SetDryRun(run superclaude.DryRun) tea.Cmd
+}
+
+type dryRunMapping struct {
+	Up            key.Binding
+	Down          key.Binding
+	Toggle        key.Binding
+	ApplyAll      key.Binding
+	ApplySelected key.Binding
+	Discard       key.Binding
+}
+
+var dryRunKeys = dryRunMapping{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "previous file"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "next file"),
+	),
+	Toggle: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "select file"),
+	),
+	ApplyAll: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "apply all"),
+	),
+	ApplySelected: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "apply selected"),
+	),
+	Discard: key.NewBinding(
+		key.WithKeys("d", "esc"),
+		key.WithHelp("d/esc", "discard"),
+	),
+}
+
+type dryRunDialogCmp struct {
+	windowSize    tea.WindowSizeMsg
+	width, height int
+	run           superclaude.DryRun
+	selected      []bool
+	cursor        int
+	offsets       []int
+	renderedWidth int
+	viewport      viewport.Model
+}
+
+func (d *dryRunDialogCmp) Init() tea.Cmd {
+	return d.viewport.Init()
+}
+
+func (d *dryRunDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		d.windowSize = msg
+		d.setSize()
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, dryRunKeys.Up):
+			d.moveCursor(-1)
+			return d, nil
+		case key.Matches(msg, dryRunKeys.Down):
+			d.moveCursor(1)
+			return d, nil
+		case key.Matches(msg, dryRunKeys.Toggle):
+			if d.cursor < len(d.selected) {
+				d.selected[d.cursor] = !d.selected[d.cursor]
+			}
+			return d, nil
+		case key.Matches(msg, dryRunKeys.ApplyAll):
+			return d, util.CmdHandler(DryRunResponseMsg{DryRunID: d.run.ID, Apply: true})
+		case key.Matches(msg, dryRunKeys.ApplySelected):
+			paths := d.selectedPaths()
+			if len(paths) == 0 {
+				return d, util.ReportWarn("No files selected, use space to select files or a to apply all")
+			}
+			return d, util.CmdHandler(DryRunResponseMsg{DryRunID: d.run.ID, Apply: true, Paths: paths})
+		case key.Matches(msg, dryRunKeys.Discard):
+			return d, util.CmdHandler(DryRunResponseMsg{DryRunID: d.run.ID, Apply: false})
+		default:
+			// Pass other keys to the viewport so the diff can be paged
+			vp, cmd := d.viewport.Update(msg)
+			d.viewport = vp
+			return d, cmd
+		}
+	}
+	return d, nil
+}
+
+func (d *dryRunDialogCmp) moveCursor(delta int) {
+	if len(d.run.Changes) == 0 {
+		return
+	}
+	d.cursor = (d.cursor + delta + len(d.run.Changes)) % len(d.run.Changes)
+	if d.cursor < len(d.offsets) {
+		d.viewport.SetYOffset(d.offsets[d.cursor])
+	}
+}
+
+func (d *dryRunDialogCmp) selectedPaths() []string {
+	var paths []string
+	for i, change := range d.run.Changes {
+		if d.selected[i] {
+			paths = append(paths, change.Path)
+		}
+	}
+	return paths
+}
+
+func (d *dryRunDialogCmp) setSize() {
+	d.width = int(float64(d.windowSize.Width) * 0.8)
+	d.height = int(float64(d.windowSize.Height) * 0.8)
+}
+
+// renderDiffs renders every file diff side by side and remembers where each one starts
+func (d *dryRunDialogCmp) renderDiffs(width int) {
+	if d.renderedWidth == width {
+		return
+	}
+	d.renderedWidth = width
+
+	t := theme.CurrentTheme()
+	header := lipgloss.NewStyle().Bold(true).Foreground(t.Primary()).Background(t.Background())
+
+	var sections []string
+	d.offsets = make([]int, len(d.run.Changes))
+	line := 0
+	for i, change := range d.run.Changes {
+		rendered, err := diff.FormatDiff(change.Diff(), diff.WithTotalWidth(width))
+		if err != nil {
+			rendered = fmt.Sprintf("Error formatting diff: %v", err)
+		}
+		section := header.Render(change.Path) + "\n" + rendered
+		d.offsets[i] = line
+		line += lipgloss.Height(section)
+		sections = append(sections, section)
+	}
+	d.viewport.SetContent(strings.Join(sections, "\n"))
+}
+
+func (d *dryRunDialogCmp) renderFiles(width int) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	lines := make([]string, len(d.run.Changes))
+	for i, change := range d.run.Changes {
+		mark := "[ ]"
+		if d.selected[i] {
+			mark = "[x]"
+		}
+		kind := "M"
+		if change.Created {
+			kind = "A"
+		} else if change.Deleted {
+			kind = "D"
+		}
+		style := baseStyle.Width(width).Foreground(t.Text())
+		if i == d.cursor {
+			style = style.Foreground(t.Primary()).Bold(true)
+		}
+		lines[i] = style.Render(fmt.Sprintf("%s %s %s", mark, kind, change.Path))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (d *dryRunDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	width := max(d.width-4, 40)
+
+	title := baseStyle.
+		Bold(true).
+		Width(width).
+		Foreground(t.Primary()).
+		Render(fmt.Sprintf("Dry run: %d files changed", len(d.run.Changes)))
+
+	command := baseStyle.
+		Width(width).
+		Foreground(t.TextMuted()).
+		Render(d.run.Command)
+
+	files := d.renderFiles(width)
+
+	footer := baseStyle.
+		Width(width).
+		Foreground(t.TextMuted()).
+		Render("space select · enter apply selected · a apply all · d discard")
+
+	d.renderDiffs(width)
+	d.viewport.Width = width
+	d.viewport.Height = max(d.height-lipgloss.Height(title)-lipgloss.Height(command)-lipgloss.Height(files)-lipgloss.Height(footer)-6, 3)
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		command,
+		baseStyle.Width(width).Render(""),
+		files,
+		baseStyle.Width(width).Render(""),
+		d.viewport.View(),
+		baseStyle.Width(width).Render(""),
+		footer,
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+func (d *dryRunDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(dryRunKeys)
+}
+
+func (d *dryRunDialogCmp) SetDryRun(run superclaude.DryRun) tea.Cmd {
+	d.run = run
+	d.selected = make([]bool, len(run.Changes))
+	d.cursor = 0
+	d.renderedWidth = 0
+	d.viewport.GotoTop()
+	d.setSize()
+	return nil
+}
+
+// NewDryRunDialogCmp creates the dry run review dialog
+func NewDryRunDialogCmp() DryRunDialogCmp {
+	return &dryRunDialogCmp{
+		viewport: viewport.New(0, 0),
+	}
+}
@@ -0,0 +1,224 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/opencode-ai/opencode/internal/superclaude"
+	"github.com/opencode-ai/opencode/internal/tui/layout"
+	"github.com/opencode-ai/opencode/internal/tui/styles"
+	"github.com/opencode-ai/opencode/internal/tui/theme"
+	"github.com/opencode-ai/opencode/internal/tui/util"
+)
+
+// PlanResponseMsg is sent when the user approves or rejects a proposed plan
+type PlanResponseMsg struct {
+	PlanID   string
+	Approved bool
+	// Steps holds the edited steps, nil when the plan was approved unchanged
+	Steps []string
+}
+
+// PlanDialogCmp shows a proposed --plan plan for approval or editing
+type PlanDialogCmp interface {
+	tea.Model
+	layout.Bindings
+	SetPlan(plan superclaude.Plan) tea.Cmd
+}
+
+type planMapping struct {
+	Approve key.Binding
+	Edit    key.Binding
+	Reject  key.Binding
+	Save    key.Binding
+	Cancel  key.Binding
+}
+
+var planKeys = planMapping{
+	Approve: key.NewBinding(
+		key.WithKeys("a", "enter"),
+		key.WithHelp("a/enter", "approve"),
+	),
+	Edit: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "edit steps"),
+	),
+	Reject: key.NewBinding(
+		key.WithKeys("r", "esc"),
+		key.WithHelp("r/esc", "reject"),
+	),
+	Save: key.NewBinding(
+		key.WithKeys("ctrl+s"),
+		key.WithHelp("ctrl+s", "save steps"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "discard edits"),
+	),
+}
+
+type planDialogCmp struct {
+	width, height int
+	plan          superclaude.Plan
+	steps         []string
+	edited        bool
+	editing       bool
+	editor        textarea.Model
+}
+
+func (p *planDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (p *planDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width = min(int(float64(msg.Width)*0.7), 100)
+		p.height = msg.Height
+		p.editor.SetWidth(p.width - 4)
+	case tea.KeyMsg:
+		if p.editing {
+			switch {
+			case key.Matches(msg, planKeys.Save):
+				p.steps = splitSteps(p.editor.Value())
+				p.edited = true
+				p.editing = false
+				p.editor.Blur()
+				return p, nil
+			case key.Matches(msg, planKeys.Cancel):
+				p.editing = false
+				p.editor.Blur()
+				return p, nil
+			}
+			var cmd tea.Cmd
+			p.editor, cmd = p.editor.Update(msg)
+			return p, cmd
+		}
+
+		switch {
+		case key.Matches(msg, planKeys.Approve):
+			if len(p.steps) == 0 {
+				return p, util.ReportWarn("The plan has no steps, edit it or reject it")
+			}
+			response := PlanResponseMsg{PlanID: p.plan.ID, Approved: true}
+			if p.edited {
+				response.Steps = p.steps
+			}
+			return p, util.CmdHandler(response)
+		case key.Matches(msg, planKeys.Edit):
+			p.editing = true
+			p.editor.SetValue(strings.Join(p.steps, "\n"))
+			return p, p.editor.Focus()
+		case key.Matches(msg, planKeys.Reject):
+			return p, util.CmdHandler(PlanResponseMsg{PlanID: p.plan.ID, Approved: false})
+		}
+	}
+	return p, nil
+}
+
+// splitSteps reads one step per line, ignoring any numbering the user kept
+func splitSteps(value string) []string {
+	var steps []string
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if items := superclaude.ParseNumberedList(line); len(items) == 1 {
+			line = items[0]
+		}
+		steps = append(steps, line)
+	}
+	return steps
+}
+
+func (p *planDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	width := max(p.width, 40)
+
+	title := baseStyle.
+		Bold(true).
+		Width(width).
+		Foreground(t.Primary()).
+		Render("Approve plan")
+
+	command := baseStyle.
+		Width(width).
+		Foreground(t.TextMuted()).
+		Render(p.plan.Command)
+
+	var body string
+	if p.editing {
+		body = p.editor.View()
+	} else {
+		lines := make([]string, len(p.steps))
+		for i, step := range p.steps {
+			lines[i] = fmt.Sprintf("%d. %s", i+1, step)
+		}
+		body = baseStyle.
+			Width(width).
+			Foreground(t.Text()).
+			Render(strings.Join(lines, "\n"))
+	}
+
+	help := "a approve · e edit · r reject"
+	if p.editing {
+		help = "one step per line · ctrl+s save · esc discard"
+	}
+	footer := baseStyle.
+		Width(width).
+		Foreground(t.TextMuted()).
+		Render(help)
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		command,
+		baseStyle.Width(width).Render(""),
+		body,
+		baseStyle.Width(width).Render(""),
+		footer,
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+func (p *planDialogCmp) BindingKeys() []key.Binding {
+	if p.editing {
+		return []key.Binding{planKeys.Save, planKeys.Cancel}
+	}
+	return []key.Binding{planKeys.Approve, planKeys.Edit, planKeys.Reject}
+}
+
+func (p *planDialogCmp) SetPlan(plan superclaude.Plan) tea.Cmd {
+	p.plan = plan
+	p.steps = plan.Descriptions()
+	p.edited = false
+	p.editing = false
+	p.editor.Blur()
+	return nil
+}
+
+// NewPlanDialogCmp creates the plan approval dialog
+func NewPlanDialogCmp() PlanDialogCmp {
+	editor := textarea.New()
+	editor.ShowLineNumbers = true
+	editor.Prompt = ""
+	editor.SetHeight(10)
+	editor.CharLimit = -1
+
+	return &planDialogCmp{
+		width:  60,
+		editor: editor,
+	}
+}
@@ -0,0 +1,182 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/opencode-ai/opencode/internal/tui/layout"
+	"github.com/opencode-ai/opencode/internal/tui/styles"
+	"github.com/opencode-ai/opencode/internal/tui/theme"
+	"github.com/opencode-ai/opencode/internal/tui/util"
+)
+
+// RewindPoint is a user message the session can be rewound to
+type RewindPoint struct {
+	MessageID string
+	Prompt    string
+	CreatedAt int64
+}
+
+// RewindSelectedMsg is sent when the user picks the message to rewind to
+type RewindSelectedMsg struct {
+	MessageID string
+}
+
+// CloseRewindDialogMsg is sent when the rewind dialog is closed
+type CloseRewindDialogMsg struct{}
+
+// RewindDialog interface for the session rewind dialog
+type RewindDialog interface {
+	tea.Model
+	layout.Bindings
+	SetPoints(points []RewindPoint)
+}
+
+type rewindDialogCmp struct {
+	points      []RewindPoint
+	selectedIdx int
+	width       int
+	height      int
+}
+
+type rewindKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Enter  key.Binding
+	Escape key.Binding
+}
+
+var rewindKeys = rewindKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "previous message"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "next message"),
+	),
+	Enter: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "rewind to before message"),
+	),
+	Escape: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "close"),
+	),
+}
+
+func (r *rewindDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (r *rewindDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, rewindKeys.Up):
+			if r.selectedIdx > 0 {
+				r.selectedIdx--
+			}
+			return r, nil
+		case key.Matches(msg, rewindKeys.Down):
+			if r.selectedIdx < len(r.points)-1 {
+				r.selectedIdx++
+			}
+			return r, nil
+		case key.Matches(msg, rewindKeys.Enter):
+			if len(r.points) > 0 {
+				return r, util.CmdHandler(RewindSelectedMsg{
+					MessageID: r.points[r.selectedIdx].MessageID,
+				})
+			}
+		case key.Matches(msg, rewindKeys.Escape):
+			return r, util.CmdHandler(CloseRewindDialogMsg{})
+		}
+	case tea.WindowSizeMsg:
+		r.width = msg.Width
+		r.height = msg.Height
+	}
+	return r, nil
+}
+
+func (r *rewindDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	maxWidth := max(40, min(80, r.width-15))
+	maxVisible := min(10, len(r.points))
+
+	// Keep the selected message in view, newest messages are at the bottom
+	startIdx := 0
+	if len(r.points) > maxVisible {
+		startIdx = min(max(0, r.selectedIdx-maxVisible/2), len(r.points)-maxVisible)
+	}
+	endIdx := min(startIdx+maxVisible, len(r.points))
+
+	items := make([]string, 0, maxVisible)
+	for i := startIdx; i < endIdx; i++ {
+		point := r.points[i]
+		prompt := strings.Join(strings.Fields(point.Prompt), " ")
+		label := fmt.Sprintf("%s  %s", time.Unix(point.CreatedAt, 0).Format("15:04"), prompt)
+		if len(label) > maxWidth-2 {
+			label = label[:maxWidth-5] + "..."
+		}
+
+		itemStyle := baseStyle.Width(maxWidth)
+		if i == r.selectedIdx {
+			itemStyle = itemStyle.
+				Background(t.Primary()).
+				Foreground(t.Background()).
+				Bold(true)
+		}
+		items = append(items, itemStyle.Padding(0, 1).Render(label))
+	}
+
+	title := baseStyle.
+		Foreground(t.Primary()).
+		Bold(true).
+		Width(maxWidth).
+		Padding(0, 1).
+		Render("Rewind Session")
+
+	hint := baseStyle.
+		Foreground(t.TextMuted()).
+		Width(maxWidth).
+		Padding(0, 1).
+		Render("Files are restored to before the selected message, which is removed with everything after it.")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		baseStyle.Width(maxWidth).Render(""),
+		baseStyle.Width(maxWidth).Render(lipgloss.JoinVertical(lipgloss.Left, items...)),
+		baseStyle.Width(maxWidth).Render(""),
+		hint,
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+func (r *rewindDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(rewindKeys)
+}
+
+// SetPoints sets the messages to choose from and selects the latest one
+func (r *rewindDialogCmp) SetPoints(points []RewindPoint) {
+	r.points = points
+	r.selectedIdx = max(0, len(points)-1)
+}
+
+// NewRewindDialogCmp creates a new session rewind dialog
+func NewRewindDialogCmp() RewindDialog {
+	return &rewindDialogCmp{}
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/opencode-ai/opencode/internal/app"
 	"github.com/opencode-ai/opencode/internal/completions"
 	"github.com/opencode-ai/opencode/internal/message"
-	"github.com/opencode-ai/opencode/internal/superclaude"
 	"github.com/opencode-ai/opencode/internal/session"
 	"github.com/opencode-ai/opencode/internal/tui/components/chat"
 	"github.com/opencode-ai/opencode/internal/tui/components/dialog"
@@ -77,7 +76,7 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if p.app.CoderAgent.IsBusy() {
 			return p, util.ReportWarn("Agent is busy, please wait before executing a command...")
 		}
-		
+
 		// Process the command content with arguments if any
 		content := msg.Content
 		if msg.Args != nil {
@@ -87,7 +86,7 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				content = strings.ReplaceAll(content, placeholder, value)
 			}
 		}
-		
+
 		// Handle custom command execution
 		cmd := p.sendMessage(content, nil)
 		if cmd != nil {
@@ -115,8 +114,9 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, keyMap.Cancel):
 			if p.session.ID != "" {
 				// Cancel the current session's generation process
-				// This allows users to interrupt long-running operations
-				p.app.CoderAgent.Cancel(p.session.ID)
+				// This allows users to interrupt long-running operations,
+				// including any sub-agents spawned from this session
+				p.app.SuperClaude.Cancel(p.session.ID)
 				return p, nil
 			}
 		}
@@ -161,14 +161,13 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 			return util.ReportError(err)
 		}
 		p.session = session
-		
+
 		// Now check if it's a SuperClaude command with the new session
-		superHandler := superclaude.NewSuperClaudeHandler(p.app.CoderAgent)
-		handled, err := superHandler.HandleCommand(context.Background(), p.session.ID, text)
+		handled, err := p.app.SuperClaude.HandleCommand(context.Background(), p.session.ID, text)
 		if err != nil {
 			return util.ReportError(err)
 		}
-		
+
 		if handled {
 			// SuperClaude handled it, update sidebar
 			cmd := p.setSidebar()
@@ -177,7 +176,7 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 			}
 			return util.CmdHandler(chat.SessionSelectedMsg(session))
 		}
-		
+
 		// Not a SuperClaude command, continue with normal flow
 		var cmds []tea.Cmd
 		cmd := p.setSidebar()
@@ -191,18 +190,17 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 		}
 		return tea.Batch(cmds...)
 	}
-	
+
 	// We have a session, check SuperClaude
-	superHandler := superclaude.NewSuperClaudeHandler(p.app.CoderAgent)
-	handled, err := superHandler.HandleCommand(context.Background(), p.session.ID, text)
+	handled, err := p.app.SuperClaude.HandleCommand(context.Background(), p.session.ID, text)
 	if err != nil {
 		return util.ReportError(err)
 	}
-	
+
 	if handled {
 		return nil
 	}
-	
+
 	var cmds []tea.Cmd
 	if p.session.ID == "" {
 		session, err := p.app.Sessions.Create(context.Background(), "New Session")
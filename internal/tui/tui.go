@@ -15,6 +15,7 @@ import (
 	"github.com/opencode-ai/opencode/internal/permission"
 	"github.com/opencode-ai/opencode/internal/pubsub"
 	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/opencode-ai/opencode/internal/superclaude"
 	"github.com/opencode-ai/opencode/internal/tui/components/chat"
 	"github.com/opencode-ai/opencode/internal/tui/components/core"
 	"github.com/opencode-ai/opencode/internal/tui/components/dialog"
@@ -37,6 +38,10 @@ type keyMap struct {
 
 type startCompactSessionMsg struct{}
 
+type exportEvidenceMsg struct{}
+
+type showRewindDialogMsg struct{}
+
 const (
 	quitKey = "q"
 )
@@ -108,6 +113,12 @@ type appModel struct {
 	showPermissions bool
 	permissions     dialog.PermissionDialogCmp
 
+	showPlanDialog bool
+	planDialog     dialog.PlanDialogCmp
+
+	showDryRunDialog bool
+	dryRunDialog     dialog.DryRunDialogCmp
+
 	showHelp bool
 	help     dialog.HelpCmp
 
@@ -117,6 +128,9 @@ type appModel struct {
 	showSessionDialog bool
 	sessionDialog     dialog.SessionDialog
 
+	showRewindDialog bool
+	rewindDialog     dialog.RewindDialog
+
 	showCommandDialog bool
 	commandDialog     dialog.CommandDialog
 	commands          []dialog.Command
@@ -196,6 +210,14 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.permissions = prm.(dialog.PermissionDialogCmp)
 		cmds = append(cmds, permCmd)
 
+		plan, planCmd := a.planDialog.Update(msg)
+		a.planDialog = plan.(dialog.PlanDialogCmp)
+		cmds = append(cmds, planCmd)
+
+		dryRun, dryRunCmd := a.dryRunDialog.Update(msg)
+		a.dryRunDialog = dryRun.(dialog.DryRunDialogCmp)
+		cmds = append(cmds, dryRunCmd)
+
 		help, helpCmd := a.help.Update(msg)
 		a.help = help.(dialog.HelpCmp)
 		cmds = append(cmds, helpCmd)
@@ -204,6 +226,10 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.sessionDialog = session.(dialog.SessionDialog)
 		cmds = append(cmds, sessionCmd)
 
+		rewind, rewindCmd := a.rewindDialog.Update(msg)
+		a.rewindDialog = rewind.(dialog.RewindDialog)
+		cmds = append(cmds, rewindCmd)
+
 		command, commandCmd := a.commandDialog.Update(msg)
 		a.commandDialog = command.(dialog.CommandDialog)
 		cmds = append(cmds, commandCmd)
@@ -288,6 +314,34 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.showPermissions = false
 		return a, cmd
 
+	// Plan approval
+	case dialog.PlanResponseMsg:
+		a.showPlanDialog = false
+		if msg.Approved {
+			if err := a.app.SuperClaude.ApprovePlan(msg.PlanID, msg.Steps); err != nil {
+				return a, util.ReportError(err)
+			}
+			return a, util.ReportInfo("Plan approved, executing")
+		}
+		if err := a.app.SuperClaude.RejectPlan(msg.PlanID); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, nil
+
+	// Dry run review
+	case dialog.DryRunResponseMsg:
+		a.showDryRunDialog = false
+		if msg.Apply {
+			if err := a.app.SuperClaude.ApplyDryRun(msg.DryRunID, msg.Paths); err != nil {
+				return a, util.ReportError(err)
+			}
+			return a, nil
+		}
+		if err := a.app.SuperClaude.DiscardDryRun(msg.DryRunID); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, nil
+
 	case page.PageChangeMsg:
 		return a, a.moveToPage(msg.ID)
 
@@ -299,6 +353,10 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.showSessionDialog = false
 		return a, nil
 
+	case dialog.CloseRewindDialogMsg:
+		a.showRewindDialog = false
+		return a, nil
+
 	case dialog.CloseCommandDialogMsg:
 		a.showCommandDialog = false
 		return a, nil
@@ -320,6 +378,67 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return nil
 		}
 
+	case exportEvidenceMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session to export evidence from")
+		}
+		sessionID := a.selectedSession.ID
+		return a, func() tea.Msg {
+			path, err := a.app.SuperClaude.ExportEvidence(context.Background(), sessionID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Evidence exported to " + path}
+		}
+
+	case showRewindDialogMsg:
+		if a.app.Snapshots == nil {
+			return a, util.ReportWarn("Snapshots are disabled in the configuration")
+		}
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session to rewind")
+		}
+		ctx := context.Background()
+		snapshots, err := a.app.Snapshots.List(ctx, a.selectedSession.ID)
+		if err != nil {
+			return a, util.ReportError(err)
+		}
+		points := make([]dialog.RewindPoint, 0, len(snapshots))
+		for _, snap := range snapshots {
+			msg, err := a.app.Messages.Get(ctx, snap.MessageID)
+			if err != nil {
+				continue
+			}
+			points = append(points, dialog.RewindPoint{
+				MessageID: snap.MessageID,
+				Prompt:    msg.Content().String(),
+				CreatedAt: msg.CreatedAt,
+			})
+		}
+		if len(points) == 0 {
+			return a, util.ReportWarn("No snapshots recorded for this session")
+		}
+		a.rewindDialog.SetPoints(points)
+		a.showRewindDialog = true
+		return a, nil
+
+	case dialog.RewindSelectedMsg:
+		a.showRewindDialog = false
+		sessionID := a.selectedSession.ID
+		if a.app.SuperClaude.IsSessionBusy(sessionID) {
+			return a, util.ReportWarn("Agent is busy, cancel the current request before rewinding")
+		}
+		return a, func() tea.Msg {
+			result, err := a.app.Snapshots.Rewind(context.Background(), sessionID, msg.MessageID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return util.InfoMsg{
+				Type: util.InfoTypeInfo,
+				Msg:  fmt.Sprintf("Rewound the working tree and removed %d messages", result.DeletedMessages),
+			}
+		}
+
 	case pubsub.Event[agent.AgentEvent]:
 		payload := msg.Payload
 		if payload.Error != nil {
@@ -343,6 +462,82 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Continue listening for events
 		return a, nil
 
+	case pubsub.Event[config.Alert]:
+		alert := msg.Payload
+		switch {
+		case alert.Severity >= config.AlertCritical:
+			return a, util.CmdHandler(util.InfoMsg{Type: util.InfoTypeError, Msg: alert.Message})
+		case alert.Severity == config.AlertWarning:
+			return a, util.ReportWarn(alert.Message)
+		default:
+			return a, util.ReportInfo(alert.Message)
+		}
+
+	case pubsub.Event[superclaude.SpawnEvent]:
+		payload := msg.Payload
+		if payload.ParentSessionID != a.selectedSession.ID {
+			return a, nil
+		}
+		switch payload.Type {
+		case superclaude.SpawnEventPlanned:
+			return a, util.ReportInfo(fmt.Sprintf("Spawning %d sub-agents", payload.Total))
+		case superclaude.SpawnEventStarted:
+			return a, util.ReportInfo(fmt.Sprintf("Sub-agent %d/%d started: %s", payload.Index, payload.Total, payload.Task))
+		case superclaude.SpawnEventCompleted:
+			return a, util.ReportInfo(fmt.Sprintf("Sub-agent %d/%d completed", payload.Index, payload.Total))
+		case superclaude.SpawnEventFailed:
+			if payload.Index == 0 {
+				return a, util.ReportError(fmt.Errorf("spawn failed: %w", payload.Error))
+			}
+			return a, util.ReportWarn(fmt.Sprintf("Sub-agent %d/%d failed: %v", payload.Index, payload.Total, payload.Error))
+		case superclaude.SpawnEventFinished:
+			return a, util.ReportInfo(fmt.Sprintf("All %d sub-agents finished", payload.Total))
+		}
+		return a, nil
+
+	case pubsub.Event[superclaude.PlanEvent]:
+		payload := msg.Payload
+		if payload.SessionID != a.selectedSession.ID {
+			return a, nil
+		}
+		switch payload.Type {
+		case superclaude.PlanEventProposed:
+			a.showPlanDialog = true
+			return a, a.planDialog.SetPlan(payload.Plan)
+		case superclaude.PlanEventStepCompleted:
+			return a, util.ReportInfo(fmt.Sprintf("Plan step %d/%d done", payload.Step, len(payload.Plan.Steps)))
+		case superclaude.PlanEventCompleted:
+			return a, util.ReportInfo("Plan executed")
+		case superclaude.PlanEventRejected:
+			return a, util.ReportInfo("Plan rejected, nothing was executed")
+		case superclaude.PlanEventFailed:
+			a.showPlanDialog = false
+			return a, util.ReportError(fmt.Errorf("plan failed: %w", payload.Error))
+		}
+		return a, nil
+
+	case pubsub.Event[superclaude.DryRunEvent]:
+		payload := msg.Payload
+		if payload.SessionID != a.selectedSession.ID {
+			return a, nil
+		}
+		switch payload.Type {
+		case superclaude.DryRunEventReady:
+			if len(payload.DryRun.Changes) == 0 {
+				return a, util.ReportInfo("Dry run finished without file changes")
+			}
+			a.showDryRunDialog = true
+			return a, a.dryRunDialog.SetDryRun(payload.DryRun)
+		case superclaude.DryRunEventApplied:
+			return a, util.ReportInfo(fmt.Sprintf("Applied %d of %d changed files", len(payload.Applied), len(payload.DryRun.Changes)))
+		case superclaude.DryRunEventDiscarded:
+			return a, util.ReportInfo("Dry run discarded, nothing was written")
+		case superclaude.DryRunEventFailed:
+			a.showDryRunDialog = false
+			return a, util.ReportError(fmt.Errorf("dry run failed: %w", payload.Error))
+		}
+		return a, nil
+
 	case dialog.CloseThemeDialogMsg:
 		a.showThemeDialog = false
 		return a, nil
@@ -450,6 +645,26 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, cmd
 		}
 
+		// The plan dialog owns esc and ctrl+s while it is open
+		if a.showPlanDialog && !key.Matches(msg, keys.Quit) {
+			plan, cmd := a.planDialog.Update(msg)
+			a.planDialog = plan.(dialog.PlanDialogCmp)
+			return a, cmd
+		}
+
+		// The dry run dialog owns esc and enter while it is open
+		if a.showDryRunDialog && !key.Matches(msg, keys.Quit) {
+			dryRun, cmd := a.dryRunDialog.Update(msg)
+			a.dryRunDialog = dryRun.(dialog.DryRunDialogCmp)
+			return a, cmd
+		}
+
+		if a.showRewindDialog && !key.Matches(msg, keys.Quit) {
+			rewind, cmd := a.rewindDialog.Update(msg)
+			a.rewindDialog = rewind.(dialog.RewindDialog)
+			return a, cmd
+		}
+
 		switch {
 
 		case key.Matches(msg, keys.Quit):
@@ -460,6 +675,9 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if a.showSessionDialog {
 				a.showSessionDialog = false
 			}
+			if a.showRewindDialog {
+				a.showRewindDialog = false
+			}
 			if a.showCommandDialog {
 				a.showCommandDialog = false
 			}
@@ -606,6 +824,26 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	if a.showPlanDialog {
+		d, planCmd := a.planDialog.Update(msg)
+		a.planDialog = d.(dialog.PlanDialogCmp)
+		cmds = append(cmds, planCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
+
+	if a.showDryRunDialog {
+		d, dryRunCmd := a.dryRunDialog.Update(msg)
+		a.dryRunDialog = d.(dialog.DryRunDialogCmp)
+		cmds = append(cmds, dryRunCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
+
 	if a.showSessionDialog {
 		d, sessionCmd := a.sessionDialog.Update(msg)
 		a.sessionDialog = d.(dialog.SessionDialog)
@@ -723,6 +961,36 @@ func (a appModel) View() string {
 		)
 	}
 
+	if a.showPlanDialog {
+		overlay := a.planDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
+	if a.showDryRunDialog {
+		overlay := a.dryRunDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
 	if a.showFilepicker {
 		overlay := a.filepicker.View()
 		row := lipgloss.Height(appView) / 2
@@ -772,6 +1040,15 @@ func (a appModel) View() string {
 		if a.showPermissions {
 			bindings = append(bindings, a.permissions.BindingKeys()...)
 		}
+		if a.showPlanDialog {
+			bindings = append(bindings, a.planDialog.BindingKeys()...)
+		}
+		if a.showDryRunDialog {
+			bindings = append(bindings, a.dryRunDialog.BindingKeys()...)
+		}
+		if a.showRewindDialog {
+			bindings = append(bindings, a.rewindDialog.BindingKeys()...)
+		}
 		if a.currentPage == page.LogsPage {
 			bindings = append(bindings, logsKeyReturnKey)
 		}
@@ -824,6 +1101,21 @@ func (a appModel) View() string {
 		)
 	}
 
+	if a.showRewindDialog {
+		overlay := a.rewindDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
 	if a.showModelDialog {
 		overlay := a.modelDialog.View()
 		row := lipgloss.Height(appView) / 2
@@ -903,13 +1195,16 @@ func New(app *app.App) tea.Model {
 	model := &appModel{
 		currentPage:   startPage,
 		loadedPages:   make(map[page.PageID]bool),
-		status:        core.NewStatusCmp(app.LSPClients),
+		status:        core.NewStatusCmp(app.LSPClients, app.MCP.States()),
 		help:          dialog.NewHelpCmp(),
 		quit:          dialog.NewQuitCmp(),
 		sessionDialog: dialog.NewSessionDialogCmp(),
+		rewindDialog:  dialog.NewRewindDialogCmp(),
 		commandDialog: dialog.NewCommandDialogCmp(),
 		modelDialog:   dialog.NewModelDialogCmp(),
 		permissions:   dialog.NewPermissionDialogCmp(),
+		planDialog:    dialog.NewPlanDialogCmp(),
+		dryRunDialog:  dialog.NewDryRunDialogCmp(),
 		initDialog:    dialog.NewInitDialogCmp(),
 		themeDialog:   dialog.NewThemeDialogCmp(),
 		app:           app,
@@ -941,6 +1236,24 @@ If there are Cursor rules (in .cursor/rules/ or .cursorrules) or Copilot rules (
 		},
 	})
 
+	model.RegisterCommand(dialog.Command{
+		ID:          "export-evidence",
+		Title:       "Export Evidence",
+		Description: "Write the --evidence ledgers of the current session to a JSON file",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(exportEvidenceMsg{})
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "rewind",
+		Title:       "Rewind Session",
+		Description: "Restore the working tree to before a message and drop the turns after it",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(showRewindDialogMsg{})
+		},
+	})
+
 	model.RegisterCommand(dialog.Command{
 		ID:          "compact",
 		Title:       "Compact Session",